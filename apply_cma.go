@@ -0,0 +1,768 @@
+package gontentful
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const (
+	defaultCMAApplyRetries = 3
+	cmaAssetProcessPolls   = 10
+)
+
+var cmaSysIDRegex = regexp.MustCompile(`^[a-zA-Z0-9\-_.]{1,64}$`)
+
+// CMAApply writes the moonbase content of a repository to a Contentful space through
+// the CMA: the content types are upserted and published first, then the assets and
+// entries in dependency order (referenced items first). The entries and assets are
+// all upserted before they are published, so reference cycles are resolved too.
+type CMAApply struct {
+	Client      *Client
+	Repo        ContentRepository
+	ContentType string
+	// DryRun compares the content with the space and reports the changes without writing
+	DryRun bool
+	// Retries of an update or publish on VersionMismatchError, with the refetched version
+	Retries int
+	// PollInterval between the checks of the asset processing
+	PollInterval time.Duration
+}
+
+// CMAApplyReport lists the applied items and maps the moonbase ids to the Contentful sys.ids.
+// The ids are kept, except the ones Contentful does not accept, which are hashed.
+type CMAApplyReport struct {
+	SpaceID        string            `json:"space"`
+	DryRun         bool              `json:"dryRun"`
+	IDs            map[string]string `json:"ids"`
+	Items          []*CMAApplyItem   `json:"items"`
+	SkippedLocales []string          `json:"skippedLocales,omitempty"`
+}
+
+type CMAApplyItem struct {
+	Type        string               `json:"type"`
+	ContentType string               `json:"contentType,omitempty"`
+	ID          string               `json:"id"`
+	SysID       string               `json:"sysId"`
+	Action      string               `json:"action"`
+	Version     int                  `json:"version,omitempty"`
+	Fields      []*CMAApplyFieldDiff `json:"fields,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+type CMAApplyFieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// cmaResource is a content type, entry or asset to apply.
+type cmaResource struct {
+	item *CMAApplyItem
+	body map[string]interface{}
+	// the remote item is published at its current version
+	published bool
+	// get returns the remote item, nil when it does not exist
+	get     func() (*Sys, map[string]interface{}, error)
+	create  func(body []byte) ([]byte, error)
+	update  func(version string, body []byte) ([]byte, error)
+	publish func(version string) ([]byte, error)
+	// prepare adjusts the body to the remote item before the diff
+	prepare func(remote map[string]interface{})
+	// process runs after the write and returns the version to publish
+	process func(version int) (int, error)
+}
+
+func NewCMAApply(client *Client, repo ContentRepository, contentType string) *CMAApply {
+	return &CMAApply{
+		Client:       client,
+		Repo:         repo,
+		ContentType:  contentType,
+		Retries:      defaultCMAApplyRetries,
+		PollInterval: time.Second,
+	}
+}
+
+// Exec applies the content and returns the report. The items are applied one by one,
+// the failed ones are reported and the error lists their count.
+func (a *CMAApply) Exec() (*CMAApplyReport, error) {
+	r := a.Repo
+	schemas, localizedData, err := getContentLocalized(r, a.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content localized: %s", err.Error())
+	}
+
+	locales, err := a.Client.Locales.GetCMALocales()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locales: %s", err.Error())
+	}
+	spaceLocales := make(map[string]string)
+	for _, l := range locales.Items {
+		spaceLocales[strings.ToLower(l.Code)] = l.Code
+	}
+	defaultLocale := getDefaultLocale(locales.Items)
+
+	report := &CMAApplyReport{
+		SpaceID: a.Client.Options.SpaceID,
+		DryRun:  a.DryRun,
+		IDs:     make(map[string]string),
+		Items:   make([]*CMAApplyItem, 0),
+	}
+	for _, locData := range localizedData {
+		for id := range locData {
+			report.IDs[id] = cmaSysID(id)
+		}
+	}
+
+	failed := 0
+	for _, ct := range cmaContentTypeOrder(schemas) {
+		if ct == ASSET_TABLE_NAME {
+			continue
+		}
+		res := a.contentTypeResource(formatSchema(schemas[ct]))
+		if !a.apply(res) || !a.publish(res) {
+			failed++
+		}
+		report.Items = append(report.Items, res.item)
+	}
+
+	skippedLocales := make(map[string]bool)
+	resources := make([]*cmaResource, 0)
+	for _, n := range cmaEntryOrder(schemas, localizedData) {
+		entry, refs, err := FormatData(n.contentType, n.id, schemas, localizedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format file content: %s", err.Error())
+		}
+		fields := a.formatFields(entry, refs, schemas[n.contentType], report.IDs, spaceLocales, defaultLocale, skippedLocales)
+		var res *cmaResource
+		if n.contentType == ASSET_TABLE_NAME {
+			res = a.assetResource(n.id, report.IDs[n.id], fields)
+		} else {
+			res = a.entryResource(n.contentType, n.id, report.IDs[n.id], fields)
+		}
+		resources = append(resources, res)
+		report.Items = append(report.Items, res.item)
+	}
+	report.SkippedLocales = sortedKeys(skippedLocales)
+
+	applied := make([]*cmaResource, 0)
+	for _, res := range resources {
+		if a.apply(res) {
+			applied = append(applied, res)
+		} else {
+			failed++
+		}
+	}
+	for _, res := range applied {
+		if !a.publish(res) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("failed to apply %d items", failed)
+	}
+	return report, nil
+}
+
+// apply creates or updates the resource, it is skipped when the remote item is unchanged.
+func (a *CMAApply) apply(res *cmaResource) bool {
+	sys, remote, err := res.get()
+	if err != nil {
+		res.item.Error = err.Error()
+		return false
+	}
+
+	if sys == nil {
+		res.item.Action = "create"
+		res.item.Fields = diffCMAValues("", nil, res.body)
+	} else {
+		if res.prepare != nil {
+			res.prepare(remote)
+		}
+		res.item.Fields = make([]*CMAApplyFieldDiff, 0)
+		for _, k := range sortedKeys(res.body) {
+			res.item.Fields = append(res.item.Fields, diffCMAValues(k, remote[k], res.body[k])...)
+		}
+		res.item.Action = "update"
+		res.item.Version = sys.Version
+		res.published = sys.PublishedVersion > 0 && sys.PublishedVersion+1 == sys.Version
+		if len(res.item.Fields) == 0 {
+			res.item.Action = "unchanged"
+			return true
+		}
+	}
+	if a.DryRun {
+		return true
+	}
+
+	body, err := json.Marshal(res.body)
+	if err != nil {
+		res.item.Error = err.Error()
+		return false
+	}
+
+	var data []byte
+	if sys == nil {
+		data, err = res.create(body)
+	} else {
+		data, err = a.retry(res, func(version int) ([]byte, error) {
+			return res.update(fmt.Sprint(version), body)
+		})
+	}
+	if err == nil {
+		res.item.Version, err = cmaVersion(data)
+	}
+	if err == nil && res.process != nil {
+		res.item.Version, err = res.process(res.item.Version)
+	}
+	if err != nil {
+		res.item.Error = fmt.Sprintf("failed to %s: %s", res.item.Action, err.Error())
+		return false
+	}
+	res.published = false
+
+	return true
+}
+
+// publish publishes the current version of the resource, unless it is already published.
+func (a *CMAApply) publish(res *cmaResource) bool {
+	if a.DryRun || res.published || res.item.Error != "" {
+		return res.item.Error == ""
+	}
+	data, err := a.retry(res, func(version int) ([]byte, error) {
+		return res.publish(fmt.Sprint(version))
+	})
+	if err == nil {
+		res.item.Version, err = cmaVersion(data)
+	}
+	if err != nil {
+		res.item.Error = fmt.Sprintf("failed to publish: %s", err.Error())
+		return false
+	}
+	res.published = true
+
+	return true
+}
+
+// retry calls fn with the item version, on VersionMismatchError with the refetched version.
+func (a *CMAApply) retry(res *cmaResource, fn func(version int) ([]byte, error)) ([]byte, error) {
+	version := res.item.Version
+	for i := 0; ; i++ {
+		data, err := fn(version)
+		if _, ok := err.(VersionMismatchError); !ok || i >= a.Retries {
+			return data, err
+		}
+		sys, _, err := res.get()
+		if err != nil {
+			return nil, err
+		}
+		if sys == nil {
+			return nil, fmt.Errorf("%s %s was deleted", res.item.Type, res.item.SysID)
+		}
+		version = sys.Version
+	}
+}
+
+func (a *CMAApply) contentTypeResource(ct *ContentType) *cmaResource {
+	id := ct.Sys.ID
+	svc := a.Client.ContentTypes
+	// the body is normalized like the remote content type
+	body := toCMAMap(&ContentType{
+		Name:         ct.Name,
+		Description:  ct.Description,
+		DisplayField: ct.DisplayField,
+		Fields:       ct.Fields,
+	})
+	delete(body, "sys")
+	return &cmaResource{
+		item: &CMAApplyItem{Type: CONTENT_TYPE, ID: id, SysID: id},
+		body: body,
+		get: func() (*Sys, map[string]interface{}, error) {
+			remote, err := svc.GetSingleCMA(id)
+			if _, ok := err.(NotFoundError); ok {
+				return nil, nil, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			return remote.Sys, toCMAMap(remote), nil
+		},
+		create: func(body []byte) ([]byte, error) {
+			return svc.Create(id, body)
+		},
+		update: func(version string, body []byte) ([]byte, error) {
+			return svc.Update(id, body, version)
+		},
+		publish: func(version string) ([]byte, error) {
+			return svc.Publish(id, version)
+		},
+	}
+}
+
+func (a *CMAApply) entryResource(contentType string, id string, sysID string, fields map[string]interface{}) *cmaResource {
+	svc := a.Client.Entries
+	return &cmaResource{
+		item: &CMAApplyItem{Type: ENTRY, ContentType: contentType, ID: id, SysID: sysID},
+		body: map[string]interface{}{"fields": fields},
+		get: func() (*Sys, map[string]interface{}, error) {
+			remote, err := svc.GetSingleCMA(sysID)
+			if _, ok := err.(NotFoundError); ok {
+				return nil, nil, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			return remote.Sys, toCMAMap(remote), nil
+		},
+		create: func(body []byte) ([]byte, error) {
+			return svc.CreateWithID(contentType, sysID, body)
+		},
+		update: func(version string, body []byte) ([]byte, error) {
+			return svc.Update(version, sysID, body)
+		},
+		publish: func(version string) ([]byte, error) {
+			return svc.Publish(sysID, version)
+		},
+	}
+}
+
+func (a *CMAApply) assetResource(id string, sysID string, fields map[string]interface{}) *cmaResource {
+	svc := a.Client.Assets
+	res := &cmaResource{
+		item: &CMAApplyItem{Type: ASSET, ContentType: ASSET_TABLE_NAME, ID: id, SysID: sysID},
+		body: map[string]interface{}{"fields": fields},
+		get: func() (*Sys, map[string]interface{}, error) {
+			data, err := svc.GetSingle(sysID)
+			if _, ok := err.(NotFoundError); ok {
+				return nil, nil, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			remote := &Entry{}
+			err = json.Unmarshal(data, remote)
+			if err != nil {
+				return nil, nil, err
+			}
+			return remote.Sys, toCMAMap(remote), nil
+		},
+		create: func(body []byte) ([]byte, error) {
+			return svc.CreateWithID(sysID, body)
+		},
+		update: func(version string, body []byte) ([]byte, error) {
+			return svc.Update(version, sysID, body)
+		},
+		publish: func(version string) ([]byte, error) {
+			return svc.Publish(sysID, version)
+		},
+	}
+
+	files, _ := fields["file"].(map[string]interface{})
+	for loc, f := range files {
+		files[loc] = cmaAssetUpload(f)
+	}
+
+	// the uploaded file of the same name is kept, it is processed already
+	res.prepare = func(remote map[string]interface{}) {
+		remoteFields, _ := remote["fields"].(map[string]interface{})
+		remoteFiles, _ := remoteFields["file"].(map[string]interface{})
+		for loc, f := range files {
+			rf, _ := remoteFiles[loc].(map[string]interface{})
+			nf, _ := f.(map[string]interface{})
+			if rf != nil && nf != nil && rf["fileName"] == nf["fileName"] && rf["contentType"] == nf["contentType"] {
+				files[loc] = rf
+			}
+		}
+	}
+
+	// the uploads are processed and the asset is published once all the files have urls
+	res.process = func(version int) (int, error) {
+		pending := false
+		for _, loc := range sortedKeys(files) {
+			f, _ := files[loc].(map[string]interface{})
+			if f == nil || f["upload"] == nil {
+				continue
+			}
+			pending = true
+			// the processing of the previous locale may have changed the version
+			sys, _, err := res.get()
+			if err == nil && sys != nil {
+				version = sys.Version
+			}
+			_, err = svc.Process(sysID, loc, fmt.Sprint(version))
+			if err != nil {
+				return 0, fmt.Errorf("failed to process %s file: %s", loc, err.Error())
+			}
+		}
+		for i := 0; pending && i < cmaAssetProcessPolls; i++ {
+			time.Sleep(a.PollInterval)
+			sys, remote, err := res.get()
+			if err != nil {
+				return 0, err
+			}
+			if sys == nil {
+				return 0, fmt.Errorf("asset %s was deleted", sysID)
+			}
+			version = sys.Version
+			pending = false
+			remoteFields, _ := remote["fields"].(map[string]interface{})
+			remoteFiles, _ := remoteFields["file"].(map[string]interface{})
+			for loc := range files {
+				rf, _ := remoteFiles[loc].(map[string]interface{})
+				if rf == nil || rf["url"] == nil {
+					pending = true
+				}
+			}
+		}
+		if pending {
+			return 0, fmt.Errorf("asset %s files are not processed", sysID)
+		}
+		return version, nil
+	}
+
+	return res
+}
+
+// formatFields converts the moonbase entry fields to CMA fields: the locales are mapped
+// to the space locales, the not localized fields have a single value in the space default
+// locale (see notLocalizedValue) and the links point to the Contentful ids with the entry
+// or asset link type.
+func (a *CMAApply) formatFields(entry *Entry, refs map[string]string, schema *content.Schema, ids map[string]string, spaceLocales map[string]string, defaultLocale string, skippedLocales map[string]bool) map[string]interface{} {
+	localized := make(map[string]bool)
+	if entry.Sys.Type == ASSET {
+		localized = localizedAssetColumns
+	} else if schema != nil {
+		for _, f := range schema.Fields {
+			localized[f.ID] = f.Localized
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for fn, fv := range entry.Fields {
+		locValues, ok := fv.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values := make(map[string]interface{})
+		if !localized[fn] {
+			if v := notLocalizedValue(locValues, defaultLocale); v != nil {
+				values[spaceLocales[defaultLocale]] = formatCMALinks(v, refs, ids)
+			}
+		} else {
+			for loc, v := range locValues {
+				if v == nil {
+					continue
+				}
+				code, ok := spaceLocales[strings.ToLower(loc)]
+				if !ok {
+					skippedLocales[loc] = true
+					continue
+				}
+				values[code] = formatCMALinks(v, refs, ids)
+			}
+		}
+		if len(values) > 0 {
+			fields[fn] = values
+		}
+	}
+	return fields
+}
+
+// notLocalizedValue returns the value of a not localized field: the space default locale
+// value, the moonbase default locale value or the value of the only locale that has one
+func notLocalizedValue(locValues map[string]interface{}, defaultLocale string) interface{} {
+	found := make(map[string]interface{})
+	for loc, v := range locValues {
+		if v != nil {
+			found[strings.ToLower(loc)] = v
+		}
+	}
+	if v, ok := found[defaultLocale]; ok {
+		return v
+	}
+	if v, ok := found[DefaultLocale]; ok {
+		return v
+	}
+	if len(found) == 1 {
+		for _, v := range found {
+			return v
+		}
+	}
+	return nil
+}
+
+func formatCMALinks(v interface{}, refs map[string]string, ids map[string]string) interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		res := make([]interface{}, 0)
+		for _, e := range t {
+			res = append(res, formatCMALinks(e, refs, ids))
+		}
+		return res
+	case map[string]interface{}:
+		sys, ok := t["sys"].(map[string]interface{})
+		if !ok || sys["type"] != LINK {
+			return t
+		}
+		id := fmt.Sprint(sys["id"])
+		linkType := ENTRY
+		if refs[id] == ASSET_TABLE_NAME {
+			linkType = ASSET
+		}
+		if sysID, ok := ids[id]; ok {
+			id = sysID
+		} else {
+			id = cmaSysID(id)
+		}
+		return map[string]interface{}{
+			"sys": map[string]interface{}{"type": LINK, "linkType": linkType, "id": id},
+		}
+	}
+	return v
+}
+
+// cmaAssetUpload replaces the file url with the upload url of the CMA.
+func cmaAssetUpload(file interface{}) interface{} {
+	f, ok := file.(map[string]interface{})
+	if !ok {
+		return file
+	}
+	res := make(map[string]interface{})
+	for _, k := range []string{"contentType", "fileName"} {
+		if f[k] != nil {
+			res[k] = f[k]
+		}
+	}
+	if u, ok := f["url"].(string); ok && u != "" {
+		if strings.HasPrefix(u, "//") {
+			u = "https:" + u
+		}
+		res["upload"] = u
+	}
+	return res
+}
+
+// cmaSysID keeps the moonbase id if Contentful accepts it, otherwise hashes it.
+func cmaSysID(id string) string {
+	if cmaSysIDRegex.MatchString(id) {
+		return id
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(id)))
+}
+
+func cmaVersion(data []byte) (int, error) {
+	res := &struct {
+		Sys *Sys `json:"sys"`
+	}{}
+	err := json.Unmarshal(data, res)
+	if err != nil {
+		return 0, err
+	}
+	if res.Sys == nil {
+		return 0, fmt.Errorf("missing sys in response")
+	}
+	return res.Sys.Version, nil
+}
+
+// toCMAMap normalizes v (numbers, nested structs) to be compared with a remote item.
+func toCMAMap(v interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	b, _ := json.Marshal(v)
+	json.Unmarshal(b, &res)
+	return res
+}
+
+// diffCMAValues lists the changed values by path, the arrays of fields by field id.
+func diffCMAValues(path string, old interface{}, new interface{}) []*CMAApplyFieldDiff {
+	om, oIsMap := cmaDiffMap(old)
+	nm, nIsMap := cmaDiffMap(new)
+	if (oIsMap || old == nil) && (nIsMap || new == nil) && (oIsMap || nIsMap) {
+		keys := make(map[string]bool)
+		for k := range om {
+			keys[k] = true
+		}
+		for k := range nm {
+			keys[k] = true
+		}
+		res := make([]*CMAApplyFieldDiff, 0)
+		for _, k := range sortedKeys(keys) {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			res = append(res, diffCMAValues(p, om[k], nm[k])...)
+		}
+		return res
+	}
+
+	oldValue := formatCMADiffValue(old)
+	newValue := formatCMADiffValue(new)
+	if oldValue == newValue {
+		return nil
+	}
+	return []*CMAApplyFieldDiff{{path, oldValue, newValue}}
+}
+
+// cmaDiffMap returns the map of v, arrays of objects with ids are mapped by id.
+func cmaDiffMap(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t, true
+	case []interface{}:
+		res := make(map[string]interface{})
+		for _, e := range t {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			id, ok := m["id"].(string)
+			if !ok {
+				return nil, false
+			}
+			res[id] = m
+		}
+		return res, len(res) > 0
+	}
+	return nil, false
+}
+
+func formatCMADiffValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+type cmaNode struct {
+	contentType string
+	id          string
+}
+
+// cmaEntryOrder returns the entries and assets, the referenced ones before the references.
+func cmaEntryOrder(schemas map[string]*content.Schema, localizedData map[string]map[string]map[string]content.ContentData) []cmaNode {
+	cts := make(map[string]string)
+	for ct, locData := range localizedData {
+		for id := range locData {
+			cts[id] = ct
+		}
+	}
+
+	refFields := make(map[string][]string)
+	for ct, schema := range schemas {
+		for _, f := range schema.Fields {
+			if f.Reference {
+				refFields[ct] = append(refFields[ct], f.ID)
+			}
+		}
+	}
+
+	deps := func(id string) []string {
+		refs := make(map[string]bool)
+		ct := cts[id]
+		for _, data := range localizedData[ct][id] {
+			for _, fn := range refFields[ct] {
+				for _, r := range flattenCMSValue(data.Fields[fn]) {
+					if rid, ok := r.(string); ok && cts[rid] != "" {
+						refs[rid] = true
+					}
+				}
+			}
+		}
+		return sortedKeys(refs)
+	}
+
+	order := make([]cmaNode, 0)
+	for _, id := range topoSort(sortedKeys(cts), deps) {
+		order = append(order, cmaNode{cts[id], id})
+	}
+	return order
+}
+
+// cmaContentTypeOrder returns the content types, the linked ones before the others.
+func cmaContentTypeOrder(schemas map[string]*content.Schema) []string {
+	return topoSort(sortedKeys(schemas), func(ct string) []string {
+		refs := make(map[string]bool)
+		for _, f := range schemas[ct].Fields {
+			if f.Reference && schemas[f.Type] != nil {
+				refs[f.Type] = true
+			}
+		}
+		return sortedKeys(refs)
+	})
+}
+
+// topoSort orders the keys after their dependencies, cycles are broken at the first visited key.
+func topoSort(keys []string, deps func(string) []string) []string {
+	res := make([]string, 0)
+	visited := make(map[string]bool)
+	var visit func(k string)
+	visit = func(k string) {
+		if visited[k] {
+			return
+		}
+		visited[k] = true
+		for _, d := range deps(k) {
+			visit(d)
+		}
+		res = append(res, k)
+	}
+	for _, k := range keys {
+		visit(k)
+	}
+	return res
+}
+
+// JSON returns the indented JSON of the report.
+func (r *CMAApplyReport) JSON() (string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *CMAApplyReport) String() string {
+	counts := make(map[string]int)
+	for _, item := range r.Items {
+		counts[item.Action]++
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "space %s: %d creates, %d updates, %d unchanged\n", r.SpaceID, counts["create"], counts["update"], counts["unchanged"])
+	for _, item := range r.Items {
+		sign := "~"
+		switch item.Action {
+		case "create":
+			sign = "+"
+		case "unchanged":
+			if item.Error == "" {
+				continue
+			}
+		}
+		name := item.ID
+		if item.ContentType != "" {
+			name = fmt.Sprintf("%s/%s", item.ContentType, item.ID)
+		}
+		if item.SysID != item.ID {
+			name = fmt.Sprintf("%s (%s)", name, item.SysID)
+		}
+		fmt.Fprintf(&sb, "%s %s %s\n", sign, item.Type, name)
+		for _, f := range item.Fields {
+			fmt.Fprintf(&sb, "    %s: %s -> %s\n", f.Field, f.Old, f.New)
+		}
+		if item.Error != "" {
+			fmt.Fprintf(&sb, "    error: %s\n", item.Error)
+		}
+	}
+	if len(r.SkippedLocales) > 0 {
+		fmt.Fprintf(&sb, "locales not in the space: %s\n", strings.Join(r.SkippedLocales, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,250 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var cmaApplyFiles = map[string]string{
+	"moonbase.yaml":                "workdir: _content\n",
+	"_content/game/_schema.json":   `{"id":"game","name":"Game","displayField":"name","fields":[{"id":"name","label":"Name","type":"text","localized":true},{"id":"rating","label":"Rating","type":"float"},{"id":"studio","label":"Studio","type":"studio","reference":true},{"id":"logo","label":"Logo","type":"_asset","reference":true}]}`,
+	"_content/game/g1/en.json":     `{"id":"g1","fields":{"name":"Starburst","rating":4.5,"studio":"net ent","logo":"a1"}}`,
+	"_content/game/g1/de.json":     `{"id":"g1","fields":{"name":"Sternexplosion","rating":4.5,"studio":"net ent","logo":"a1"}}`,
+	"_content/game/g1/fr.json":     `{"id":"g1","fields":{"name":"Starburst FR","rating":4.5,"studio":"net ent","logo":"a1"}}`,
+	"_content/studio/_schema.json": `{"id":"studio","name":"Studio","fields":[{"id":"name","label":"Name","type":"text"}]}`,
+	"_content/studio/s1/en.json":   `{"id":"net ent","fields":{"name":"NetEnt"}}`,
+	"_content/_asset/_schema.json": `{"id":"_asset","name":"Asset","fields":[{"id":"file","label":"File","type":"json"},{"id":"title","label":"Title","type":"text"}]}`,
+	"_content/_asset/a1/en.json":   `{"id":"a1","fields":{"title":"Logo","file":{"url":"//images.example.com/logo.png","fileName":"logo.png","contentType":"image/png"}}}`,
+}
+
+type fakeCMAItem struct {
+	sys  *Sys
+	body map[string]interface{}
+}
+
+func (i *fakeCMAItem) fields() map[string]interface{} {
+	fields, _ := i.body["fields"].(map[string]interface{})
+	return fields
+}
+
+func (i *fakeCMAItem) MarshalJSON() ([]byte, error) {
+	res := map[string]interface{}{"sys": i.sys}
+	for k, v := range i.body {
+		res[k] = v
+	}
+	return json.Marshal(res)
+}
+
+type fakeCMA struct {
+	mu        sync.Mutex
+	items     map[string]*fakeCMAItem
+	writes    []string
+	conflicts int
+	locales   string
+}
+
+func (f *fakeCMA) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/spaces/s/environments/master/"), "/")
+	if parts[0] == "locales" {
+		if f.locales == "" {
+			f.locales = `{"items":[{"code":"en","default":true},{"code":"de"}]}`
+		}
+		fmt.Fprint(w, f.locales)
+		return
+	}
+
+	key := parts[0] + "/" + parts[1]
+	item := f.items[key]
+	if req.Method == http.MethodGet {
+		if item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"sys":{"id":"NotFound"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(item)
+		return
+	}
+
+	f.writes = append(f.writes, req.URL.Path)
+	version := req.Header.Get(headerContentfulVersion)
+	if item != nil && (f.conflicts > 0 || version != strconv.Itoa(item.sys.Version)) {
+		f.conflicts--
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"sys":{"id":"VersionMismatch"}}`)
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		if item == nil {
+			item = &fakeCMAItem{sys: &Sys{ID: parts[1]}}
+			f.items[key] = item
+		}
+		item.body = make(map[string]interface{})
+		json.NewDecoder(req.Body).Decode(&item.body)
+		if ct := req.Header.Get(headerContentfulContentType); ct != "" {
+			item.sys.ContentType = &ContentType{Sys: &Sys{ID: ct}}
+		}
+	case parts[2] == "published":
+		item.sys.PublishedVersion = item.sys.Version
+	case parts[2] == "files":
+		files := item.fields()["file"].(map[string]interface{})
+		file := files[parts[3]].(map[string]interface{})
+		file["url"] = "//cdn.example.com/" + file["fileName"].(string)
+		delete(file, "upload")
+	}
+	io.Copy(io.Discard, req.Body)
+	item.sys.Version++
+	json.NewEncoder(w).Encode(item)
+}
+
+func newTestCMAApply(t *testing.T, f *fakeCMA, repo ContentRepository) *CMAApply {
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+
+	cli := NewClient(&ClientOptions{
+		SpaceID:       "s",
+		EnvironmentID: "master",
+		CmaURL:        strings.TrimPrefix(srv.URL, "https://"),
+		CmaToken:      "token",
+	})
+	cli.client = srv.Client()
+
+	a := NewCMAApply(cli, repo, "")
+	a.PollInterval = 0
+	return a
+}
+
+func TestCMAApply(t *testing.T) {
+	f := &fakeCMA{items: make(map[string]*fakeCMAItem)}
+	a := newTestCMAApply(t, f, NewMemoryRepository(cmaApplyFiles))
+
+	// dry run
+	a.DryRun = true
+	report, err := a.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.writes) != 0 || len(report.Items) != 5 {
+		t.Fatalf("dry run wrote %v, reported %d items", f.writes, len(report.Items))
+	}
+	if !strings.Contains(report.String(), "5 creates") {
+		t.Errorf("unexpected report %s", report.String())
+	}
+
+	a.DryRun = false
+	report, err = a.Exec()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, report.String())
+	}
+
+	studioID := cmaSysID("net ent")
+	if report.IDs["net ent"] != studioID || report.IDs["g1"] != "g1" || studioID == "net ent" {
+		t.Errorf("unexpected ids %v", report.IDs)
+	}
+	if len(report.SkippedLocales) != 1 || report.SkippedLocales[0] != "fr" {
+		t.Errorf("unexpected skipped locales %v", report.SkippedLocales)
+	}
+
+	// referenced items first
+	order := make(map[string]int)
+	for i, w := range f.writes {
+		if _, ok := order[w]; !ok {
+			order[w] = i
+		}
+	}
+	entries := "/spaces/s/environments/master/entries/"
+	if order[entries+studioID] > order[entries+"g1"] || order["/spaces/s/environments/master/content_types/studio"] > order["/spaces/s/environments/master/content_types/game"] {
+		t.Errorf("unexpected write order %v", f.writes)
+	}
+
+	for key, item := range f.items {
+		if item.sys.PublishedVersion == 0 || item.sys.PublishedVersion+1 != item.sys.Version {
+			t.Errorf("%s not published: %+v", key, item.sys)
+		}
+	}
+
+	game := f.items["entries/g1"].fields()
+	studio := game["studio"].(map[string]interface{})["en"].(map[string]interface{})["sys"].(map[string]interface{})
+	logo := game["logo"].(map[string]interface{})["en"].(map[string]interface{})["sys"].(map[string]interface{})
+	if studio["id"] != studioID || studio["linkType"] != ENTRY || logo["id"] != "a1" || logo["linkType"] != ASSET {
+		t.Errorf("unexpected links %v %v", studio, logo)
+	}
+	if rating := game["rating"].(map[string]interface{}); len(rating) != 1 {
+		t.Errorf("not localized field with locales %v", rating)
+	}
+	if name := game["name"].(map[string]interface{}); name["de"] != "Sternexplosion" || name["fr"] != nil {
+		t.Errorf("unexpected localized field %v", name)
+	}
+	file := f.items["assets/a1"].fields()["file"].(map[string]interface{})["en"].(map[string]interface{})
+	if file["url"] != "//cdn.example.com/logo.png" {
+		t.Errorf("asset not processed %v", file)
+	}
+
+	// nothing changed
+	writes := len(f.writes)
+	report, err = a.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.writes) != writes || !strings.Contains(report.String(), "5 unchanged") {
+		t.Errorf("unexpected writes %v\n%s", f.writes[writes:], report.String())
+	}
+}
+
+func TestCMAApplyVersionMismatch(t *testing.T) {
+	repo := NewMemoryRepository(cmaApplyFiles)
+	f := &fakeCMA{items: make(map[string]*fakeCMAItem)}
+	a := newTestCMAApply(t, f, repo)
+
+	report, err := a.Exec()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, report.String())
+	}
+
+	repo.Files["_content/studio/s1/en.json"] = []byte(`{"id":"net ent","fields":{"name":"NetEnt AB"}}`)
+	f.conflicts = 2
+	report, err = a.Exec()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, report.String())
+	}
+	if !strings.Contains(report.String(), `fields.name.en: "NetEnt" -> "NetEnt AB"`) {
+		t.Errorf("missing diff in %s", report.String())
+	}
+
+	f.conflicts = 10
+	repo.Files["_content/studio/s1/en.json"] = []byte(`{"id":"net ent","fields":{"name":"NetEnt"}}`)
+	_, err = a.Exec()
+	if err == nil {
+		t.Errorf("expected an error after the retries")
+	}
+}
+
+func TestCMAApplyDefaultLocale(t *testing.T) {
+	f := &fakeCMA{items: make(map[string]*fakeCMAItem), locales: `{"items":[{"code":"en"},{"code":"de","default":true}]}`}
+	a := newTestCMAApply(t, f, NewMemoryRepository(cmaApplyFiles))
+
+	report, err := a.Exec()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, report.String())
+	}
+	game := f.items["entries/g1"].fields()
+	if rating := game["rating"].(map[string]interface{}); len(rating) != 1 || rating["de"] != 4.5 {
+		t.Errorf("not localized field not in the default locale %v", rating)
+	}
+	// the studio has the en content only
+	studio := f.items["entries/"+cmaSysID("net ent")].fields()
+	if name, _ := studio["name"].(map[string]interface{}); len(name) != 1 || name["de"] != "NetEnt" {
+		t.Errorf("not localized field without default locale value %v", studio)
+	}
+}
@@ -0,0 +1,423 @@
+package gontentful
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	assetMirrorIndexKey      = "_index.json"
+	defaultMirrorConcurrency = 4
+	defaultMirrorRetries     = 3
+)
+
+// MirroredAssetFile is the mirrored file of an asset locale.
+type MirroredAssetFile struct {
+	URL         string `json:"url"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// AssetMirror copies the asset files of the synced items to a BlobStore. The blobs are
+// keyed by the sha256 of their content, so the same file is stored once. The mirrored
+// files are listed in the _index.json blob, the files of deleted assets are removed
+// when no other asset references them.
+//
+// The mirror is an AssetURLRewriter, the Mirror of the PGSyncOptions writes the mirror
+// urls to the _asset tables.
+type AssetMirror struct {
+	Store       BlobStore
+	Client      *http.Client
+	Concurrency int
+	Retries     int
+	// TempDir keeps the partial downloads, resumed by the next attempt or run
+	TempDir string
+
+	mu    sync.RWMutex
+	files map[string]*MirroredAssetFile
+	urls  map[string]string
+	keys  map[string]*sync.Mutex
+}
+
+type AssetMirrorReport struct {
+	Mirrored     []string          `json:"mirrored"`
+	Deduplicated []string          `json:"deduplicated"`
+	Unchanged    int               `json:"unchanged"`
+	Deleted      []string          `json:"deleted"`
+	Failed       map[string]string `json:"failed,omitempty"`
+}
+
+type assetMirrorJob struct {
+	id          string
+	url         string
+	fileName    string
+	contentType string
+}
+
+func NewAssetMirror(store BlobStore) *AssetMirror {
+	return &AssetMirror{
+		Store:       store,
+		Client:      http.DefaultClient,
+		Concurrency: defaultMirrorConcurrency,
+		Retries:     defaultMirrorRetries,
+		TempDir:     filepath.Join(os.TempDir(), "gontentful-mirror"),
+	}
+}
+
+// Exec mirrors the new or changed files of the assets and deletes the files of the
+// deleted ones. Failed downloads are reported, they are retried by the next run.
+func (m *AssetMirror) Exec(ctx context.Context, items []*Entry) (*AssetMirrorReport, error) {
+	err := m.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AssetMirrorReport{
+		Mirrored:     make([]string, 0),
+		Deduplicated: make([]string, 0),
+		Deleted:      make([]string, 0),
+		Failed:       make(map[string]string),
+	}
+
+	jobs := make([]*assetMirrorJob, 0)
+	removed := make(map[string]bool)
+
+	m.mu.Lock()
+	for _, item := range items {
+		switch item.Sys.Type {
+		case ASSET:
+			files := assetFiles(item)
+			for id, f := range m.files {
+				if strings.HasPrefix(id, item.Sys.ID+"/") && files[id] == nil {
+					removed[f.Key] = true
+					delete(m.files, id)
+				}
+			}
+			for _, id := range sortedKeys(files) {
+				job := files[id]
+				if f := m.files[id]; f != nil {
+					if f.URL == job.url {
+						report.Unchanged++
+						continue
+					}
+					removed[f.Key] = true
+				}
+				jobs = append(jobs, job)
+			}
+		case DELETED_ASSET:
+			for id, f := range m.files {
+				if strings.HasPrefix(id, item.Sys.ID+"/") {
+					removed[f.Key] = true
+					delete(m.files, id)
+				}
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	m.run(ctx, jobs, report)
+
+	m.mu.Lock()
+	for _, f := range m.files {
+		delete(removed, f.Key)
+	}
+	m.indexURLs()
+	m.mu.Unlock()
+
+	for _, key := range sortedKeys(removed) {
+		err = m.Store.Delete(ctx, key)
+		if err != nil {
+			return report, err
+		}
+		report.Deleted = append(report.Deleted, key)
+	}
+
+	return report, m.saveIndex(ctx)
+}
+
+func (m *AssetMirror) run(ctx context.Context, jobs []*assetMirrorJob, report *AssetMirrorReport) {
+	concurrency := m.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	queue := make(chan *assetMirrorJob)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				f, stored, err := m.mirror(ctx, job)
+				mu.Lock()
+				switch {
+				case err != nil:
+					report.Failed[job.id] = err.Error()
+				case stored:
+					report.Mirrored = append(report.Mirrored, job.id)
+				default:
+					report.Deduplicated = append(report.Deduplicated, job.id)
+				}
+				mu.Unlock()
+				if err == nil {
+					m.mu.Lock()
+					m.files[job.id] = f
+					m.mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// mirror downloads the file and stores it unless a blob with the same content exists
+func (m *AssetMirror) mirror(ctx context.Context, job *assetMirrorJob) (*MirroredAssetFile, bool, error) {
+	var tmp string
+	var err error
+	for i := 0; i <= m.Retries; i++ {
+		tmp, err = m.download(ctx, job.url)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	file, err := os.Open(tmp)
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(tmp)
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return nil, false, err
+	}
+	f := &MirroredAssetFile{
+		URL:         job.url,
+		Key:         hex.EncodeToString(h.Sum(nil)) + strings.ToLower(path.Ext(job.fileName)),
+		Size:        size,
+		ContentType: job.contentType,
+	}
+
+	// the same content may be downloaded by several workers
+	unlock := m.lockKey(f.Key)
+	defer unlock()
+
+	exists, err := m.Store.Exists(ctx, f.Key)
+	if err != nil || exists {
+		return f, false, err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, false, err
+	}
+	err = m.Store.Put(ctx, f.Key, file, size, f.ContentType)
+	if err != nil {
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+func (m *AssetMirror) lockKey(key string) func() {
+	m.mu.Lock()
+	if m.keys == nil {
+		m.keys = make(map[string]*sync.Mutex)
+	}
+	l := m.keys[key]
+	if l == nil {
+		l = &sync.Mutex{}
+		m.keys[key] = l
+	}
+	m.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// download writes the file to TempDir, resuming a previous partial download
+func (m *AssetMirror) download(ctx context.Context, fileURL string) (string, error) {
+	err := os.MkdirAll(m.TempDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	h := sha1.Sum([]byte(fileURL))
+	tmp := filepath.Join(m.TempDir, hex.EncodeToString(h[:])+".part")
+
+	var offset int64
+	if fi, err := os.Stat(tmp); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, contentfulFileURL(fileURL), nil)
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed fetch url %s: %s", fileURL, err.Error())
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch res.StatusCode {
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the previous download was complete
+		return tmp, nil
+	default:
+		return "", fmt.Errorf("failed to download %s: %d - %s", fileURL, res.StatusCode, res.Status)
+	}
+
+	f, err := os.OpenFile(tmp, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, res.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %s", fileURL, err.Error())
+	}
+	return tmp, nil
+}
+
+// URL returns the mirror url of a mirrored file, the contentful url otherwise.
+func (m *AssetMirror) URL(fileURL string, fileName string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if key, ok := m.urls[fileURL]; ok {
+		return m.Store.URL(key)
+	}
+	return fileURL
+}
+
+// Mirrored is false, the file names are kept.
+func (m *AssetMirror) Mirrored() bool {
+	return false
+}
+
+// Files returns the mirrored files by <sysid>/<locale>.
+func (m *AssetMirror) Files() map[string]*MirroredAssetFile {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	files := make(map[string]*MirroredAssetFile, len(m.files))
+	mergeMaps(files, m.files)
+	return files
+}
+
+func (m *AssetMirror) loadIndex(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files != nil {
+		return nil
+	}
+
+	m.files = make(map[string]*MirroredAssetFile)
+	r, err := m.Store.Get(ctx, assetMirrorIndexKey)
+	if errors.Is(err, fs.ErrNotExist) {
+		m.indexURLs()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(&m.files)
+	if err != nil {
+		return fmt.Errorf("invalid asset mirror index: %s", err.Error())
+	}
+	m.indexURLs()
+	return nil
+}
+
+func (m *AssetMirror) saveIndex(ctx context.Context) error {
+	m.mu.RLock()
+	b, err := json.MarshalIndent(m.files, "", "  ")
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return m.Store.Put(ctx, assetMirrorIndexKey, bytes.NewReader(b), int64(len(b)), "application/json")
+}
+
+func (m *AssetMirror) indexURLs() {
+	m.urls = make(map[string]string, len(m.files))
+	for _, f := range m.files {
+		m.urls[f.URL] = f.Key
+	}
+}
+
+// assetFiles returns the files of the asset locales by <sysid>/<locale>
+func assetFiles(item *Entry) map[string]*assetMirrorJob {
+	jobs := make(map[string]*assetMirrorJob)
+	locFiles, _ := item.Fields["file"].(map[string]interface{})
+	for loc, lf := range locFiles {
+		file, ok := lf.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		url, _ := file["url"].(string)
+		if url == "" {
+			continue
+		}
+		id := fmt.Sprintf("%s/%s", item.Sys.ID, loc)
+		jobs[id] = &assetMirrorJob{id: id, url: url}
+		jobs[id].fileName, _ = file["fileName"].(string)
+		jobs[id].contentType, _ = file["contentType"].(string)
+	}
+	return jobs
+}
+
+func (r *AssetMirrorReport) JSON() (string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *AssetMirrorReport) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "assets mirror: %d mirrored, %d deduplicated, %d unchanged, %d deleted, %d failed\n",
+		len(r.Mirrored), len(r.Deduplicated), r.Unchanged, len(r.Deleted), len(r.Failed))
+	for _, id := range sortedKeys(r.Failed) {
+		fmt.Fprintf(&buf, "  %s: %s\n", id, r.Failed[id])
+	}
+	return buf.String()
+}
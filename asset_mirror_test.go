@@ -0,0 +1,254 @@
+package gontentful
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a MinIO style stand-in of the object API with path style urls
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || req.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := req.URL.Path
+	switch req.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(req.Body)
+		if int64(len(b)) != req.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = b
+	case http.MethodGet, http.MethodHead:
+		b, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet {
+			w.Write(b)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type fakeAssetFiles struct {
+	mu     sync.Mutex
+	files  map[string]string
+	gets   int
+	ranges []string
+}
+
+func (f *fakeAssetFiles) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	f.gets++
+	if r := req.Header.Get("Range"); r != "" {
+		f.ranges = append(f.ranges, r)
+	}
+	content, ok := f.files[req.URL.Path]
+	f.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	http.ServeContent(w, req, req.URL.Path, time.Time{}, strings.NewReader(content))
+}
+
+func newMirrorAsset(id string, files map[string]string) *Entry {
+	locFiles := make(map[string]interface{})
+	for loc, u := range files {
+		locFiles[loc] = map[string]interface{}{
+			"url":         u,
+			"fileName":    filepath.Base(u),
+			"contentType": "image/png",
+		}
+	}
+	return &Entry{
+		Sys:    &Sys{ID: id, Type: ASSET},
+		Fields: map[string]interface{}{"file": locFiles},
+	}
+}
+
+func TestAssetMirror(t *testing.T) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	s3srv := httptest.NewServer(s3)
+	defer s3srv.Close()
+
+	stores := map[string]func() BlobStore{
+		"local": func() BlobStore {
+			return NewLocalBlobStore(t.TempDir(), "https://assets.example.com")
+		},
+		"s3": func() BlobStore {
+			s := NewS3BlobStore(s3srv.URL, "assets", "key", "secret")
+			s.Prefix = "brand"
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			files := &fakeAssetFiles{files: map[string]string{
+				"/a1/logo.png":    "logo",
+				"/a1/logo-de.png": "logo",
+				"/a2/banner.png":  "banner",
+			}}
+			srv := httptest.NewServer(files)
+			defer srv.Close()
+			host := strings.TrimPrefix(srv.URL, "http:")
+
+			store := newStore()
+			mirror := NewAssetMirror(store)
+			mirror.TempDir = t.TempDir()
+			items := []*Entry{
+				newMirrorAsset("a1", map[string]string{"en": host + "/a1/logo.png", "de": host + "/a1/logo-de.png"}),
+				newMirrorAsset("a2", map[string]string{"en": host + "/a2/banner.png"}),
+			}
+			// contentful urls are protocol relative, served over https
+			mirror.Client = &http.Client{Transport: rewriteScheme{}}
+
+			ctx := context.Background()
+			report, err := mirror.Exec(ctx, items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Mirrored) != 2 || len(report.Deduplicated) != 1 || len(report.Failed) != 0 {
+				t.Fatalf("unexpected report %s", report.String())
+			}
+
+			logo := mirror.Files()["a1/en"]
+			if logo == nil || logo.Key != sha256Hex([]byte("logo"))+".png" || mirror.Files()["a1/de"].Key != logo.Key {
+				t.Fatalf("unexpected files %v", mirror.Files())
+			}
+			if got := mirror.URL(host+"/a1/logo.png", "logo.png"); got != store.URL(logo.Key) || !strings.Contains(got, logo.Key) {
+				t.Errorf("unexpected mirror url %s", got)
+			}
+			assertBlob(t, store, logo.Key, "logo")
+
+			// a new mirror reads the index of the store
+			gets := files.gets
+			mirror = NewAssetMirror(store)
+			mirror.Client = &http.Client{Transport: rewriteScheme{}}
+			report, err = mirror.Exec(ctx, items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Unchanged != 3 || files.gets != gets {
+				t.Errorf("unchanged files downloaded: %s", report.String())
+			}
+
+			report, err = mirror.Exec(ctx, []*Entry{{Sys: &Sys{ID: "a1", Type: DELETED_ASSET}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Deleted) != 1 || report.Deleted[0] != logo.Key {
+				t.Errorf("unexpected deleted blobs %v", report.Deleted)
+			}
+			if exists, _ := store.Exists(ctx, logo.Key); exists {
+				t.Errorf("blob of the deleted asset exists")
+			}
+			assertBlob(t, store, mirror.Files()["a2/en"].Key, "banner")
+			if got := mirror.URL(host+"/a1/logo.png", "logo.png"); got != host+"/a1/logo.png" {
+				t.Errorf("deleted asset url rewritten to %s", got)
+			}
+		})
+	}
+}
+
+func TestAssetMirrorResume(t *testing.T) {
+	files := &fakeAssetFiles{files: map[string]string{"/a1/video.mp4": "0123456789"}}
+	srv := httptest.NewServer(files)
+	defer srv.Close()
+	fileURL := strings.TrimPrefix(srv.URL, "http:") + "/a1/video.mp4"
+
+	store := NewLocalBlobStore(t.TempDir(), "")
+	mirror := NewAssetMirror(store)
+	mirror.TempDir = t.TempDir()
+	mirror.Client = &http.Client{Transport: rewriteScheme{}}
+
+	// partial download of a previous run
+	tmp, err := mirror.download(context.Background(), fileURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(tmp, []byte("0123"), 0644)
+
+	report, err := mirror.Exec(context.Background(), []*Entry{newMirrorAsset("a1", map[string]string{"en": fileURL})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Mirrored) != 1 || len(files.ranges) != 1 || files.ranges[0] != "bytes=4-" {
+		t.Fatalf("not resumed: %v %s", files.ranges, report.String())
+	}
+	assertBlob(t, store, mirror.Files()["a1/en"].Key, "0123456789")
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("partial download not removed")
+	}
+}
+
+func TestAssetMirrorPGURLs(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir(), "https://assets.example.com")
+	mirror := NewAssetMirror(store)
+	mirror.files = map[string]*MirroredAssetFile{
+		"a1/en": {URL: "//images.ctfassets.net/a1/logo.png", Key: "abc.png"},
+	}
+	mirror.indexURLs()
+
+	item := newMirrorAsset("a1", map[string]string{"en": "//images.ctfassets.net/a1/logo.png"})
+	schema := NewPGSyncSchema("public", []*Locale{{Code: "en", Default: true}}, nil, []*Entry{item}, true, &PGSyncOptions{Mirror: mirror})
+	row := schema.Tables[ASSET_TABLE_NAME].Rows[0]
+	if row.FieldValues["url"] != "https://assets.example.com/abc.png" || row.FieldValues["file_name"] != "logo.png" {
+		t.Errorf("unexpected asset columns %v", row.FieldValues)
+	}
+
+	// the asset urls of the options are written instead of the mirror urls
+	for _, opts := range []*PGSyncOptions{nil, {AssetURLs: ContentfulAssetURLs{}, Mirror: mirror}} {
+		schema = NewPGSyncSchema("public", []*Locale{{Code: "en", Default: true}}, nil, []*Entry{item}, true, opts)
+		if url := schema.Tables[ASSET_TABLE_NAME].Rows[0].FieldValues["url"]; url != "//images.ctfassets.net/a1/logo.png" {
+			t.Errorf("unexpected url %v with %+v", url, opts)
+		}
+	}
+}
+
+// rewriteScheme sends the https requests of the tests to the http test servers
+type rewriteScheme struct{}
+
+func (rewriteScheme) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func assertBlob(t *testing.T, store BlobStore, key string, want string) {
+	t.Helper()
+	r, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, _ := io.ReadAll(r)
+	if !bytes.Equal(b, []byte(want)) {
+		t.Errorf("blob %s is %q, want %q", key, b, want)
+	}
+}
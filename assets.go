@@ -16,7 +16,7 @@ const (
 )
 
 var (
-	assetColumns = []string{"title", "description", "file_name", "content_type", "url"}
+	assetColumns = []string{"title", "description", "file_name", "content_type", "url", "size", "width", "height", "upload", "details", "focal_point"}
 )
 
 type AssetsService service
@@ -28,8 +28,24 @@ func (s *AssetsService) Create(body []byte) ([]byte, error) {
 	return s.client.post(path, bytes.NewBuffer(body))
 }
 
-func (s *AssetsService) Process(id string, locale string) ([]byte, error) {
+// CreateWithID creates the asset with the given id instead of a generated one.
+func (s *AssetsService) CreateWithID(id string, body []byte) ([]byte, error) {
+	path := fmt.Sprintf(pathAssetsEntry, s.client.Options.SpaceID, s.client.Options.EnvironmentID, id)
+	// Set header for content type
+	s.client.headers[headerContentType] = "application/vnd.contentful.management.v1+json"
+	return s.client.put(path, bytes.NewBuffer(body))
+}
+
+func (s *AssetsService) Update(version string, id string, body []byte) ([]byte, error) {
+	path := fmt.Sprintf(pathAssetsEntry, s.client.Options.SpaceID, s.client.Options.EnvironmentID, id)
+	s.client.headers[headerContentType] = "application/vnd.contentful.management.v1+json"
+	s.client.headers[headerContentfulVersion] = version
+	return s.client.put(path, bytes.NewBuffer(body))
+}
+
+func (s *AssetsService) Process(id string, locale string, version string) ([]byte, error) {
 	path := fmt.Sprintf(pathAssetsProcess, s.client.Options.SpaceID, s.client.Options.EnvironmentID, id, locale)
+	s.client.headers[headerContentfulVersion] = version
 	return s.client.put(path, nil)
 }
 
@@ -0,0 +1,183 @@
+package gontentful
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	AssetProviderContentful = "contentful"
+	AssetProviderCloudflare = "cloudflare"
+	AssetProviderImgix      = "imgix"
+	AssetProviderStatic     = "static"
+
+	defaultImageVariant = "public"
+	defaultVideoHost    = "assets.mw.zone"
+)
+
+// AssetURLRewriter rewrites the urls of the asset files.
+type AssetURLRewriter interface {
+	// URL returns the url the file is served from, url is the contentful url of the
+	// file and fileName its name in the repository (GetImageFileName).
+	URL(url string, fileName string) string
+	// Mirrored tells whether the files are copied to the repository (_images) to be
+	// served by the provider.
+	Mirrored() bool
+}
+
+// AssetsConfig is the assets section of moonbase.yaml:
+//
+//	assets:
+//	  provider: cloudflare # contentful, cloudflare, imgix or static
+//	  brand: mybrand       # cloudflare path, the repository name without cms-/mw- by default
+//	  account: <hash>      # cloudflare images account hash
+//	  variant: public      # cloudflare images variant
+//	  videoHost: assets.mw.zone
+//	  baseUrl: https://mybrand.imgix.net # imgix
+//	  params: auto=format&q=75           # imgix
+//	  path: /static/images               # static
+type AssetsConfig struct {
+	Provider  string `json:"provider" yaml:"provider"`
+	Brand     string `json:"brand" yaml:"brand"`
+	Account   string `json:"account" yaml:"account"`
+	Variant   string `json:"variant" yaml:"variant"`
+	VideoHost string `json:"videoHost" yaml:"videoHost"`
+	BaseURL   string `json:"baseUrl" yaml:"baseUrl"`
+	Params    string `json:"params" yaml:"params"`
+	Path      string `json:"path" yaml:"path"`
+}
+
+// GetAssetsConfig returns the assets config of the moonbase.yaml of the repository,
+// nil without config.
+func GetAssetsConfig(r ContentRepository) *AssetsConfig {
+	return getConfig(context.Background(), r).Assets
+}
+
+// NewAssetURLRewriter creates the rewriter of the repository config, Cloudflare Images
+// with the brand of the repository name without config.
+func NewAssetURLRewriter(cfg *AssetsConfig, repo string) (AssetURLRewriter, error) {
+	if cfg == nil {
+		cfg = &AssetsConfig{}
+	}
+
+	switch cfg.Provider {
+	case AssetProviderContentful:
+		return ContentfulAssetURLs{}, nil
+	case AssetProviderCloudflare, "":
+		r := NewCloudflareAssetURLs(GetCloudflareImagesID(repo))
+		if cfg.Brand != "" {
+			r.Brand = cfg.Brand
+		}
+		if cfg.Account != "" {
+			r.Account = cfg.Account
+		}
+		if cfg.Variant != "" {
+			r.Variant = cfg.Variant
+		}
+		if cfg.VideoHost != "" {
+			r.VideoHost = cfg.VideoHost
+		}
+		return r, nil
+	case AssetProviderImgix:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("missing imgix baseUrl")
+		}
+		params, err := url.ParseQuery(cfg.Params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid imgix params: %s", err.Error())
+		}
+		return &ImgixAssetURLs{BaseURL: cfg.BaseURL, Params: params}, nil
+	case AssetProviderStatic:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("missing static path")
+		}
+		return &StaticAssetURLs{Path: cfg.Path}, nil
+	}
+
+	return nil, fmt.Errorf("unknown asset provider: %s", cfg.Provider)
+}
+
+// ContentfulAssetURLs keeps the contentful urls.
+type ContentfulAssetURLs struct{}
+
+func (ContentfulAssetURLs) URL(url string, fileName string) string {
+	return url
+}
+
+func (ContentfulAssetURLs) Mirrored() bool {
+	return false
+}
+
+// CloudflareAssetURLs serves the images from Cloudflare Images and the videos from VideoHost.
+type CloudflareAssetURLs struct {
+	Account   string
+	Brand     string
+	Variant   string
+	VideoHost string
+}
+
+func NewCloudflareAssetURLs(brand string) *CloudflareAssetURLs {
+	return &CloudflareAssetURLs{
+		Account:   cdnClientID,
+		Brand:     brand,
+		Variant:   defaultImageVariant,
+		VideoHost: defaultVideoHost,
+	}
+}
+
+func (r *CloudflareAssetURLs) URL(fileURL string, fileName string) string {
+	if IsVideoFile(fileName) {
+		u := &url.URL{
+			Host: r.VideoHost,
+			Path: fmt.Sprintf("%s/%s", r.Brand, fileName),
+		}
+		return u.String()
+	}
+	return fmt.Sprintf(imageCDNFmt, r.Account, r.Brand, fileName, r.Variant)
+}
+
+func (r *CloudflareAssetURLs) Mirrored() bool {
+	return true
+}
+
+// ImgixAssetURLs serves the files from BaseURL with the Params query (e.g. auto=format).
+type ImgixAssetURLs struct {
+	BaseURL string
+	Params  url.Values
+}
+
+func (r *ImgixAssetURLs) URL(fileURL string, fileName string) string {
+	u := fmt.Sprintf("%s/%s", strings.TrimSuffix(r.BaseURL, "/"), url.PathEscape(fileName))
+	if len(r.Params) > 0 {
+		u = fmt.Sprintf("%s?%s", u, r.Params.Encode())
+	}
+	return u
+}
+
+func (r *ImgixAssetURLs) Mirrored() bool {
+	return true
+}
+
+// StaticAssetURLs serves the files from a local static path.
+type StaticAssetURLs struct {
+	Path string
+}
+
+func (r *StaticAssetURLs) URL(fileURL string, fileName string) string {
+	return path.Join(r.Path, fileName)
+}
+
+func (r *StaticAssetURLs) Mirrored() bool {
+	return true
+}
+
+// contentfulFileURL returns the download url of a contentful (protocol relative) file url.
+func contentfulFileURL(fileURL string) string {
+	if strings.HasPrefix(fileURL, "//") {
+		return "https:" + fileURL
+	}
+	return fileURL
+}
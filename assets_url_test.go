@@ -0,0 +1,88 @@
+package gontentful
+
+import (
+	"testing"
+)
+
+func TestAssetURLRewriters(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *AssetsConfig
+		fileName string
+		want     string
+		mirrored bool
+	}{
+		{"default", nil, "logo.png", "//imagedelivery.net/" + cdnClientID + "/brand/logo.png/public", true},
+		{"default video", nil, "intro.mp4", "//assets.mw.zone/brand/intro.mp4", true},
+		{"contentful", &AssetsConfig{Provider: AssetProviderContentful}, "logo.png", "//images.ctfassets.net/logo.png", false},
+		{"cloudflare", &AssetsConfig{Provider: AssetProviderCloudflare, Brand: "other", Account: "acc", Variant: "thumb"}, "logo.png", "//imagedelivery.net/acc/other/logo.png/thumb", true},
+		{"cloudflare video", &AssetsConfig{Provider: AssetProviderCloudflare, VideoHost: "video.example.com"}, "intro.mp4", "//video.example.com/brand/intro.mp4", true},
+		{"imgix", &AssetsConfig{Provider: AssetProviderImgix, BaseURL: "https://brand.imgix.net/", Params: "q=75&auto=format"}, "my logo.png", "https://brand.imgix.net/my%20logo.png?auto=format&q=75", true},
+		{"static", &AssetsConfig{Provider: AssetProviderStatic, Path: "/static/images"}, "logo.png", "/static/images/logo.png", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewAssetURLRewriter(tt.cfg, "cms-brand")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := r.URL("//images.ctfassets.net/"+tt.fileName, tt.fileName)
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if r.Mirrored() != tt.mirrored {
+				t.Errorf("mirrored %v, want %v", r.Mirrored(), tt.mirrored)
+			}
+		})
+	}
+
+	for _, cfg := range []*AssetsConfig{{Provider: "s3"}, {Provider: AssetProviderImgix}, {Provider: AssetProviderStatic}} {
+		_, err := NewAssetURLRewriter(cfg, "cms-brand")
+		if err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestGetAssetsConfig(t *testing.T) {
+	r := NewMemoryRepository(map[string]string{"moonbase.yaml": "assets:\n  provider: static\n  path: /images\n"})
+	cfg := GetAssetsConfig(r)
+	if cfg == nil || cfg.Provider != AssetProviderStatic || cfg.Path != "/images" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg := GetAssetsConfig(NewMemoryRepository(nil)); cfg != nil {
+		t.Errorf("unexpected config without moonbase.yaml %+v", cfg)
+	}
+}
+
+func TestTransformPublishedEntryAssetURLs(t *testing.T) {
+	locales := []*Locale{{Code: "en", Default: true}}
+	entry := &PublishedEntry{
+		Sys: &Sys{ID: "a1", Type: ASSET},
+		Fields: map[string]map[string]interface{}{
+			"file": {"en": map[string]interface{}{
+				"url":      "//images.ctfassets.net/s/a1/logo.png",
+				"fileName": "logo.png",
+			}},
+		},
+	}
+
+	res := TransformPublishedEntry(locales, entry, nil, ContentfulAssetURLs{})
+	file := res["en"].Fields["file"].(map[string]interface{})
+	if file["url"] != "//images.ctfassets.net/s/a1/logo.png" || file["fileName"] != "logo.png" {
+		t.Errorf("contentful file rewritten %v", file)
+	}
+
+	res = TransformPublishedEntry(locales, entry, nil, &StaticAssetURLs{Path: "/images"})
+	file = res["en"].Fields["file"].(map[string]interface{})
+	fn := GetImageFileName("logo.png", "a1", "en")
+	if file["url"] != "/images/"+fn || file["fileName"] != fn {
+		t.Errorf("unexpected static file %v", file)
+	}
+
+	imageURLs := getAssetImageURL(entry)
+	if imageURLs[fn] != "https://images.ctfassets.net/s/a1/logo.png" {
+		t.Errorf("unexpected download urls %v", imageURLs)
+	}
+}
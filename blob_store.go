@@ -0,0 +1,145 @@
+package gontentful
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore stores the mirrored asset files by key.
+type BlobStore interface {
+	// Get returns the content of the blob, an fs.ErrNotExist error when missing
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put writes the blob from r (size bytes, -1 when unknown)
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Exists tells whether the blob is stored
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the blob, missing blobs are not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public url the blob is served from
+	URL(key string) string
+}
+
+// NewBlobStore creates the store of a url:
+//
+//	file:///var/assets?baseUrl=https://assets.example.com
+//	s3://bucket/prefix?endpoint=https://minio:9000&region=eu-west-1&baseUrl=https://cdn.example.com
+//
+// The s3 credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func NewBlobStore(storeURL string) (BlobStore, error) {
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob store url: %s", err.Error())
+	}
+	q := u.Query()
+
+	switch u.Scheme {
+	case "file", "":
+		dir := u.Path
+		if u.Host != "" {
+			dir = path.Join(u.Host, dir)
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("missing blob store path: %s", storeURL)
+		}
+		return NewLocalBlobStore(filepath.FromSlash(dir), q.Get("baseUrl")), nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing s3 bucket: %s", storeURL)
+		}
+		s := NewS3BlobStore(q.Get("endpoint"), u.Host, os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+		s.Prefix = strings.Trim(u.Path, "/")
+		if region := q.Get("region"); region != "" {
+			s.Region = region
+		}
+		s.BaseURL = q.Get("baseUrl")
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("unknown blob store: %s", u.Scheme)
+}
+
+// LocalBlobStore stores the blobs as files under Dir, served from BaseURL.
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalBlobStore(dir string, baseURL string) *LocalBlobStore {
+	return &LocalBlobStore{
+		Dir:     dir,
+		BaseURL: baseURL,
+	}
+}
+
+func (s *LocalBlobStore) fullPath(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.fullPath(key))
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p := s.fullPath(key)
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// written next to the blob and renamed, readers never see a partial file
+	f, err := os.CreateTemp(filepath.Dir(p), ".blob-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (s *LocalBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.fullPath(key))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.fullPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	if s.BaseURL == "" {
+		return "file://" + filepath.ToSlash(s.fullPath(key))
+	}
+	return blobURL(s.BaseURL, key)
+}
+
+func blobURL(baseURL string, key string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL, "/"), (&url.URL{Path: key}).EscapedPath())
+}
+
+// errBlobNotFound is the fs.ErrNotExist error of the remote stores
+func errBlobNotFound(key string) error {
+	return &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,220 @@
+package gontentful
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	s3EndpointFmt     = "https://s3.%s.amazonaws.com"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+)
+
+// S3BlobStore stores the blobs in a bucket of an S3 compatible API (AWS, MinIO, R2...),
+// with path style urls and signature v4 requests.
+type S3BlobStore struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// BaseURL the blobs are served from, the bucket url by default
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewS3BlobStore(endpoint string, bucket string, accessKey string, secretKey string) *S3BlobStore {
+	return &S3BlobStore{
+		Endpoint:  endpoint,
+		Region:    defaultS3Region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    http.DefaultClient,
+	}
+}
+
+func (s *S3BlobStore) endpoint() string {
+	if s.Endpoint == "" {
+		return fmt.Sprintf(s3EndpointFmt, s.Region)
+	}
+	return strings.TrimSuffix(s.Endpoint, "/")
+}
+
+func (s *S3BlobStore) objectPath(key string) string {
+	return "/" + path.Join(s.Bucket, s.Prefix, key)
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, errBlobNotFound(key)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, s3Error(res, http.MethodGet, key)
+	}
+	return res.Body, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		// the content length is required without chunked signing
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+		size = int64(len(b))
+	}
+	res, err := s.do(ctx, http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res, http.MethodPut, key)
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *S3BlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	res, err := s.do(ctx, http.MethodHead, key, nil, 0, "")
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to %s %s: %s", http.MethodHead, key, res.Status)
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		res.Body.Close()
+		return nil
+	}
+	return s3Error(res, http.MethodDelete, key)
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	if s.BaseURL == "" {
+		return s.endpoint() + awsURIEncode(s.objectPath(key))
+	}
+	return blobURL(s.BaseURL, path.Join(s.Prefix, key))
+}
+
+func (s *S3BlobStore) do(ctx context.Context, method string, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	u, err := url.Parse(s.endpoint())
+	if err != nil {
+		return nil, err
+	}
+	u.Path = s.objectPath(key)
+	u.RawPath = awsURIEncode(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, time.Now().UTC())
+
+	return s.Client.Do(req)
+}
+
+// sign adds the signature v4 authorization header (unsigned payload) to the request
+func (s *S3BlobStore) sign(req *http.Request, t time.Time) {
+	amzDate := t.Format(s3TimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + s3UnsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.AccessKey, scope, signedHeaders, signature))
+}
+
+func s3Error(res *http.Response, method string, key string) error {
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	return fmt.Errorf("failed to %s %s: %s %s", method, key, res.Status, strings.TrimSpace(string(b)))
+}
+
+// awsURIEncode escapes everything but the unreserved characters and the path separators
+func awsURIEncode(p string) string {
+	var buf strings.Builder
+	for _, b := range []byte(p) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || strings.IndexByte("-_.~/", b) >= 0 {
+			buf.WriteByte(b)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
@@ -15,7 +15,7 @@ import (
 
 	"github.com/gosimple/slug"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 )
 
 const (
@@ -25,7 +25,6 @@ const (
 	configPath   = "moonbase.yaml"
 	include      = 0
 	outputFormat = "./_output/%s"
-	videoURLHost = "assets.mw.zone"
 )
 
 type Config struct {
@@ -102,6 +101,7 @@ func transformContent() {
 	displayFields[gontentful.ASSET_TABLE_NAME] = gontentful.ASSET_DISPLAYFIELD
 
 	imageURLs := make(map[string]string)
+	assetURLs := assetURLRewriter()
 
 	for _, item := range res.Items {
 		ct := contentType
@@ -117,7 +117,7 @@ func transformContent() {
 		}
 
 		if !onlyImages {
-			entries := gontentful.TransformEntry(locales.Items, item, brand, fmtVideoURL)
+			entries := gontentful.TransformEntry(locales.Items, item, assetURLs)
 
 			for l, e := range entries {
 				b, err := json.Marshal(e)
@@ -217,18 +217,10 @@ func transformContent() {
 	}
 }
 
-func fmtVideoURL(key string) string {
-	u := &url.URL{
-		Host: videoURLHost,
-		Path: key,
-	}
-	return u.String()
-}
-
 func formatContent() {
 	fmt.Println("Content formatting started")
 
-	entries, _, err := gontentful.GetCMSEntries(contentType, repo, include)
+	entries, _, err := gontentful.GetCMSEntries(contentType, contentRepository(), include)
 	if err != nil {
 		log.Fatalf("failed to format file content: %s", err.Error())
 	}
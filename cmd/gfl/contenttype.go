@@ -8,7 +8,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 	"github.com/moonwalker/moonbase/pkg/content"
 )
 
@@ -100,7 +100,7 @@ func transformContentType() {
 func formatContentType() {
 	fmt.Println(fmt.Sprintf("ContentType formatting started on:%s|%s", repo, contentType))
 
-	cts, err := gontentful.GetCMSSchemas(repo, contentType)
+	cts, err := gontentful.GetCMSSchemas(contentRepository(), contentType)
 	if err != nil {
 		log.Fatalf("Error in GetCMSSchemas: %s", err.Error())
 	}
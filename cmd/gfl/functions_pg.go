@@ -7,7 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 )
 
 var (
@@ -64,7 +64,10 @@ var pgFuncCmd = &cobra.Command{
 		}
 
 		log.Println("creating postgres schema...")
-		schema := gontentful.NewPGSQLSchema(schemaName, space.Locales, "", cmaTypes.Items, includeDepth)
+		schema, err := gontentful.NewPGSQLSchema(schemaName, space.Locales, "", cmaTypes.Items, includeDepth, pgSchemaConfig())
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		if storeToFile {
 			s, err := json.Marshal(schema)
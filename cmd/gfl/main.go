@@ -6,6 +6,8 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+
+	"github.com/james-elicx/gontentful"
 )
 
 var (
@@ -15,6 +17,8 @@ var (
 	cmaToken      string
 	databaseURL   string
 	schemaName    string
+	geography     bool
+	tablesPath    string
 )
 
 const (
@@ -35,6 +39,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&cmaToken, "cma", "c", "", "cma token (required)")
 	rootCmd.PersistentFlags().StringVarP(&databaseURL, "url", "u", "postgres://postgres@localhost:5432/?sslmode=disable", "database url")
 	rootCmd.PersistentFlags().StringVarP(&schemaName, "schema", "n", "", "schema name")
+	rootCmd.PersistentFlags().BoolVar(&geography, "geography", false, "store the Location fields as PostGIS geography instead of point")
+	rootCmd.PersistentFlags().StringVar(&tablesPath, "tables", "", "postgres schema config (yaml or json) renaming, excluding and indexing the columns")
 	//rootCmd.MarkFlagRequired("space")
 	//rootCmd.MarkFlagRequired("token")
 	//rootCmd.MarkFlagRequired("cma")
@@ -42,6 +48,29 @@ func init() {
 	//rootCmd.MarkFlagRequired("schema")
 }
 
+// pgSchemaConfig customizes the postgres tables, loaded from --tables, --geography
+// sets the location type
+func pgSchemaConfig() *gontentful.PGSchemaConfig {
+	cfg := &gontentful.PGSchemaConfig{}
+	if len(tablesPath) > 0 {
+		var err error
+		cfg, err = gontentful.LoadPGSchemaConfig(tablesPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if geography {
+		cfg.LocationType = gontentful.PGLocationGeography
+	}
+	return cfg
+}
+
+// pgSyncOptions are the options of the synced rows, the location type of pgSchemaConfig
+func pgSyncOptions() *gontentful.PGSyncOptions {
+	return &gontentful.PGSyncOptions{LocationType: pgSchemaConfig().LocationType}
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/spf13/cobra"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
+)
+
+var (
+	migrateAlter bool
+	migratePlan  bool
 )
 
 func init() {
+	pgMigrateCmd.PersistentFlags().BoolVar(&migrateAlter, "alter", false, "migrate the schema in place with ALTER statements, without reloading the data")
+	pgMigrateCmd.PersistentFlags().BoolVar(&migratePlan, "plan", false, "print the ALTER statements of --alter without applying them")
 	migrateCmd.AddCommand(pgMigrateCmd)
 }
 
@@ -51,6 +59,32 @@ var pgMigrateCmd = &cobra.Command{
 		}
 		log.Println("get cma types done")
 
+		if migrateAlter || migratePlan {
+			schema, err := gontentful.NewPGSQLSchema(schemaName, space.Locales, "", cmaTypes.Items, 0, pgSchemaConfig())
+			if err != nil {
+				log.Fatal(err)
+			}
+			diff := gontentful.NewPGSchemaDiff(schema)
+			if migratePlan {
+				_, err = diff.Plan(migrateDatabaseURL)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Print(diff.Render())
+				log.Print(diff.String())
+				return
+			}
+
+			log.Println("alter schema...")
+			err = diff.Exec(migrateDatabaseURL)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Print(diff.String())
+			log.Println("postgres schema successfully migrated")
+			return
+		}
+
 		log.Println("get data...")
 		res, err := client.Spaces.Sync("")
 		if err != nil {
@@ -59,7 +93,7 @@ var pgMigrateCmd = &cobra.Command{
 		log.Println("get data done")
 
 		log.Println("migrate database...")
-		err = gontentful.MigratePGSQL(migrateDatabaseURL, schemaName, space.Locales, types.Items, cmaTypes.Items, res.Items, res.Token, false, false)
+		err = gontentful.MigratePGSQL(migrateDatabaseURL, schemaName, space.Locales, types.Items, cmaTypes.Items, res.Items, res.Token, false, false, pgSchemaConfig(), pgSyncOptions())
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 )
 
 const publishRequest = `{
@@ -223,7 +223,7 @@ var pgPublishCmd = &cobra.Command{
 		}
 
 		var contentModel *gontentful.ContentType
-		for _, ct := range types.Items {
+		for _, ct := range pgSchemaConfig().ApplyContentTypes(types.Items) {
 			if ct.Sys.ID == item.Sys.ContentType.Sys.ID {
 				contentModel = ct
 				break
@@ -233,7 +233,7 @@ var pgPublishCmd = &cobra.Command{
 			log.Fatal("contentModel not found")
 		}
 		log.Printf("publishing content...")
-		pub := gontentful.NewPGPublish(schemaName, space.Locales, contentModel, item)
+		pub := gontentful.NewPGPublish(schemaName, space.Locales, contentModel, item, pgSyncOptions())
 		err = pub.Exec(databaseURL)
 		if err != nil {
 			log.Fatal(err)
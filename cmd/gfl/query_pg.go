@@ -10,7 +10,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 )
 
 var (
@@ -110,9 +110,12 @@ func execQuery(q string) int64 {
 	if err != nil {
 		log.Fatal(err)
 	}
-	query := gontentful.ParsePGQuery(schemaName, gontentful.DefaultLocale, qv)
+	query, err := gontentful.ParsePGQuery(schemaName, gontentful.DefaultLocale, qv, pgSchemaConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
 	// log.Println("executing query...")
-	_, _, err = query.Exec(databaseURL)
+	_, err = query.Exec(databaseURL)
 	if err != nil {
 		log.Fatal(err)
 	}
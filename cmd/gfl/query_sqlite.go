@@ -0,0 +1,43 @@
+//go:build sqlite
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/james-elicx/gontentful"
+)
+
+func init() {
+	sqliteQueryCmd.PersistentFlags().StringVar(&sqlitePath, "db", "content.db", "sqlite database file")
+	queryCmd.AddCommand(sqliteQueryCmd)
+}
+
+var sqliteQueryCmd = &cobra.Command{
+	Use:   "sqlite [query]",
+	Short: "Query the sqlite content database",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		qv, err := url.ParseQuery(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		query, err := gontentful.ParseSQLiteQuery(gontentful.DefaultLocale, qv)
+		if err != nil {
+			log.Fatal(err)
+		}
+		total, items, err := query.Exec(sqlitePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("total %d items", total)
+		fmt.Println(items)
+	},
+}
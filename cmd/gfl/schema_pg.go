@@ -9,6 +9,7 @@ import (
 )
 
 func init() {
+	pgSchemaCmd.PersistentFlags().StringVar(&spacesConfigPath, "config", "", "spaces config (yaml)")
 	schemaCmd.AddCommand(pgSchemaCmd)
 }
 
@@ -17,6 +18,11 @@ var pgSchemaCmd = &cobra.Command{
 	Short: "Creates postgres schema",
 
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(spacesConfigPath) > 0 {
+			createSpacesSchemas()
+			return
+		}
+
 		log.Println("creating postgres schema...")
 
 		client := gontentful.NewClient(&gontentful.ClientOptions{
@@ -41,7 +47,10 @@ var pgSchemaCmd = &cobra.Command{
 		}
 
 		log.Println("executing postgres schema...")
-		schema := gontentful.NewPGSQLSchema(schemaName, space.Locales, "", cmaTypes.Items, 0)
+		schema, err := gontentful.NewPGSQLSchema(schemaName, space.Locales, "", cmaTypes.Items, 0, pgSchemaConfig())
+		if err != nil {
+			log.Fatal(err)
+		}
 		err = schema.Exec(databaseURL)
 		if err != nil {
 			log.Fatal(err)
@@ -65,3 +74,47 @@ var pgSchemaCmd = &cobra.Command{
 		log.Println("postgres schema successfully executed")
 	},
 }
+
+// createSpacesSchemas creates the schemas of the spaces config, the shared tables
+// with the _space and _environment columns in sharedSchema mode
+func createSpacesSchemas() {
+	cfg, err := gontentful.LoadSpacesConfig(spacesConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	locales := make([][]*gontentful.Locale, 0)
+	types := make([][]*gontentful.ContentType, 0)
+	for _, sc := range cfg.Spaces {
+		client := gontentful.NewClient(sc.ClientOptions(apiURL, cmaURL))
+
+		log.Printf("[%s] get space...", sc.Name)
+		space, err := client.Spaces.GetSpace()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("[%s] get cma types...", sc.Name)
+		cmaTypes, err := client.ContentTypes.GetCMATypes()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		locales = append(locales, space.Locales)
+		types = append(types, cmaTypes.Items)
+	}
+
+	schemas, err := gontentful.NewPGSpacesSchemas(cfg, locales, types, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, schema := range schemas {
+		log.Printf("executing postgres schema %s...", schema.SchemaName)
+		err = schema.Exec(databaseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Printf("postgres schemas of %d spaces successfully executed", len(cfg.Spaces))
+}
@@ -0,0 +1,63 @@
+//go:build sqlite
+
+package main
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/james-elicx/gontentful"
+
+	// pure Go sqlite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+var sqlitePath string
+
+func init() {
+	sqliteSchemaCmd.PersistentFlags().StringVar(&sqlitePath, "db", "content.db", "sqlite database file")
+	schemaCmd.AddCommand(sqliteSchemaCmd)
+}
+
+var sqliteSchemaCmd = &cobra.Command{
+	Use:   "sqlite",
+	Short: "Creates sqlite schema",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Println("creating sqlite schema...")
+
+		client := gontentful.NewClient(&gontentful.ClientOptions{
+			CdnURL:        apiURL,
+			SpaceID:       spaceID,
+			EnvironmentID: environmentID,
+			CdnToken:      cdnToken,
+			CmaURL:        cmaURL,
+			CmaToken:      cmaToken,
+		})
+
+		log.Println("get space...")
+		space, err := client.Spaces.GetSpace()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("get cma types...")
+		cmaTypes, err := client.ContentTypes.GetCMATypes()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("executing sqlite schema...")
+		schema, err := gontentful.NewSQLiteSchema(space.Locales, cmaTypes.Items)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = schema.Exec(sqlitePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("sqlite schema successfully executed")
+	},
+}
@@ -1,20 +1,43 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/moonwalker/gontentful"
+	"github.com/james-elicx/gontentful"
 )
 
 var (
 	initSync, cacheResp bool
+	dryRun              bool
+	spacesConfigPath    string
+	reportFormat        string
+	watch               bool
+	watchInterval       time.Duration
+	watchReadyLag       time.Duration
+	watchAddr           string
+	schemaChanges       string
+	mirrorURL           string
 )
 
 func init() {
 	pgSyncCmd.PersistentFlags().BoolVarP(&initSync, "init", "i", false, "init sync")
+	pgSyncCmd.PersistentFlags().StringVar(&spacesConfigPath, "config", "", "spaces config (yaml)")
+	pgSyncCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "report changes without writing")
+	pgSyncCmd.PersistentFlags().BoolVar(&watch, "watch", false, "keep syncing on the stored sync token")
+	pgSyncCmd.PersistentFlags().DurationVar(&watchInterval, "interval", 30*time.Second, "watch sync interval")
+	pgSyncCmd.PersistentFlags().DurationVar(&watchReadyLag, "ready-lag", 0, "max sync lag reported as ready (default 5 intervals)")
+	pgSyncCmd.PersistentFlags().StringVar(&watchAddr, "addr", ":8080", "watch health and status endpoints address")
+	pgSyncCmd.PersistentFlags().StringVar(&schemaChanges, "schema-changes", "additive", "content model change policy (refuse, additive, migrate)")
+	pgSyncCmd.PersistentFlags().StringVar(&mirrorURL, "mirror", "", "asset mirror blob store (file:///path or s3://bucket/prefix?endpoint=...)")
+	pgSyncCmd.PersistentFlags().StringVarP(&reportFormat, "format", "f", "text", "dry run report format (text, json)")
 	syncCmd.AddCommand(pgSyncCmd)
 }
 
@@ -23,6 +46,28 @@ var pgSyncCmd = &cobra.Command{
 	Short: "Sync data to postgres",
 
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(spacesConfigPath) > 0 {
+			cfg, err := gontentful.LoadSpacesConfig(spacesConfigPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("syncing %d spaces...", len(cfg.Spaces))
+			spacesSync := gontentful.NewPGSpacesSync(cfg, apiURL, initSync)
+			spacesSync.DryRun = dryRun
+			spacesSync.SyncOptions = pgSyncOptions()
+			err = spacesSync.Exec(databaseURL)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, sc := range cfg.Spaces {
+				if r := spacesSync.Reports[sc.Name]; r != nil {
+					printReport(r)
+				}
+			}
+			log.Println("spaces sync done")
+			return
+		}
+
 		client := gontentful.NewClient(&gontentful.ClientOptions{
 			CdnURL:        apiURL,
 			SpaceID:       spaceID,
@@ -30,15 +75,26 @@ var pgSyncCmd = &cobra.Command{
 			CdnToken:      cdnToken,
 		})
 
-		var err error
+		policy, err := gontentful.ParseSchemaChangePolicy(schemaChanges)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if watch {
+			watchSync(client, policy)
+			return
+		}
+
 		var syncToken string
 		if !initSync {
 			// retrieve token from db, if exists
 			if len(databaseURL) > 0 {
 				log.Println("retrieving sync token...")
 				syncToken, err = gontentful.GetSyncToken(databaseURL, schemaName)
-				if err != nil {
+				if gontentful.IsNoSyncToken(err) {
 					log.Println("no sync token found")
+				} else if err != nil {
+					log.Fatal(fmt.Errorf("failed to get sync token: %s", err.Error()))
 				} else {
 					log.Println("sync token found")
 				}
@@ -57,8 +113,22 @@ var pgSyncCmd = &cobra.Command{
 		}
 		log.Println("sync done")
 
-		for _, i := range res.Items {
-			fmt.Println(i.Sys.ID)
+		if !dryRun {
+			for _, i := range res.Items {
+				fmt.Println(i.Sys.ID)
+			}
+		}
+
+		syncOptions := pgSyncOptions()
+		if len(mirrorURL) > 0 && !dryRun {
+			log.Println("mirroring assets...")
+			mirror := newAssetMirror()
+			report, err := mirror.Exec(context.Background(), res.Items)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(report.String())
+			syncOptions.Mirror = mirror
 		}
 
 		log.Println("get space...")
@@ -73,9 +143,47 @@ var pgSyncCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 		log.Println("get types done")
+		cfg := pgSchemaConfig()
+		contentTypes := cfg.ApplyContentTypes(types.Items)
+
+		schemaSync, err := gontentful.NewPGSchemaSync(schemaName, space.Locales, contentTypes, policy, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		schemaSync.SyncOptions = syncOptions
+		if dryRun {
+			changes, err := schemaSync.Detect(databaseURL)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, c := range changes {
+				log.Printf("content model change: %s", c.String())
+			}
+		} else {
+			err = schemaSync.Exec(databaseURL, client)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if schemaSync.Migrated {
+				// the migration synced all the entries and saved the token
+				log.Println("schema migrated")
+				return
+			}
+		}
+
+		schema := gontentful.NewPGSyncSchema(schemaName, space.Locales, contentTypes, res.Items, len(syncToken) == 0, syncOptions)
+		if dryRun {
+			// nothing is written, the sync token is not saved
+			log.Println("dry run...")
+			report, err := schema.DryRun(databaseURL)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printReport(report)
+			return
+		}
 
 		log.Println("exec...")
-		schema := gontentful.NewPGSyncSchema(schemaName, space.Locales, types.Items, res.Items, len(syncToken) == 0)
 		err = schema.Exec(databaseURL)
 		if err != nil {
 			log.Fatal(err)
@@ -93,3 +201,55 @@ var pgSyncCmd = &cobra.Command{
 		}
 	},
 }
+
+func printReport(report *gontentful.PGSyncReport) {
+	if reportFormat == "json" {
+		out, err := report.JSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(out)
+		return
+	}
+	fmt.Print(report.String())
+}
+
+func watchSync(client *gontentful.Client, policy gontentful.SchemaChangePolicy) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	watcher := gontentful.NewPGSyncWatcher(schemaName, client, watchInterval)
+	watcher.InitSync = initSync
+	watcher.SchemaPolicy = policy
+	watcher.SchemaConfig = pgSchemaConfig()
+	watcher.SyncOptions = pgSyncOptions()
+	if watchReadyLag > 0 {
+		watcher.ReadyLag = watchReadyLag
+	}
+	if len(mirrorURL) > 0 {
+		watcher.SyncOptions.Mirror = newAssetMirror()
+	}
+
+	server := &http.Server{Addr: watchAddr, Handler: watcher.Handler()}
+	go func() {
+		log.Printf("serving health and status on %s", watchAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Println(err)
+		}
+	}()
+
+	log.Printf("watching sync every %s...", watchInterval)
+	watcher.Run(ctx, databaseURL)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+}
+
+func newAssetMirror() *gontentful.AssetMirror {
+	store, err := gontentful.NewBlobStore(mirrorURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return gontentful.NewAssetMirror(store)
+}
@@ -0,0 +1,74 @@
+//go:build sqlite
+
+package main
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/james-elicx/gontentful"
+)
+
+func init() {
+	sqliteSyncCmd.PersistentFlags().StringVar(&sqlitePath, "db", "content.db", "sqlite database file")
+	sqliteSyncCmd.PersistentFlags().BoolVarP(&initSync, "init", "i", false, "init sync")
+	syncCmd.AddCommand(sqliteSyncCmd)
+}
+
+var sqliteSyncCmd = &cobra.Command{
+	Use:   "sqlite",
+	Short: "Sync data to sqlite",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := gontentful.NewClient(&gontentful.ClientOptions{
+			CdnURL:        apiURL,
+			SpaceID:       spaceID,
+			EnvironmentID: environmentID,
+			CdnToken:      cdnToken,
+		})
+
+		syncToken := ""
+		if !initSync {
+			syncToken, _ = gontentful.GetSQLiteSyncToken(sqlitePath)
+		}
+
+		log.Println("sync init...")
+		res, err := client.Spaces.Sync(syncToken)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("sync items: %d", len(res.Items))
+
+		if len(res.Items) > 0 {
+			log.Println("get space...")
+			space, err := client.Spaces.GetSpace()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			log.Println("get types...")
+			types, err := client.ContentTypes.GetTypes()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			log.Println("executing sqlite sync...")
+			sync, err := gontentful.NewSQLiteSync(space.Locales, types.Items, res.Items, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			err = sync.Exec(sqlitePath)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		err = gontentful.SaveSQLiteSyncToken(sqlitePath, res.Token)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("sqlite sync done")
+	},
+}
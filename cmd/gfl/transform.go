@@ -1,8 +1,10 @@
 package main
 
 import (
+	"log"
 	"regexp"
 
+	"github.com/james-elicx/gontentful"
 	"github.com/spf13/cobra"
 )
 
@@ -10,10 +12,14 @@ var (
 	ctTransform = false
 	noImages    = false
 	onlyImages  = false
+	apply       = false
+	reportPath  string
 	direction,
 	contentType,
 	brand,
-	repo string
+	repo,
+	repoOwner,
+	repoBranch string
 	snake        = regexp.MustCompile(`([_ ]\w)`)
 	transformCmd = &cobra.Command{
 		Use:   "trans",
@@ -21,6 +27,10 @@ var (
 		PreRun: func(cmd *cobra.Command, args []string) {
 			if direction == "tocf" {
 				cmd.MarkFlagRequired("repo")
+				if apply {
+					rootCmd.MarkPersistentFlagRequired("space")
+					rootCmd.MarkPersistentFlagRequired("cma")
+				}
 			} else {
 				rootCmd.MarkPersistentFlagRequired("space")
 				rootCmd.MarkPersistentFlagRequired("token")
@@ -33,7 +43,9 @@ var (
 		Run: func(cmd *cobra.Command, args []string) {
 			switch direction {
 			case "tocf":
-				if ctTransform {
+				if apply {
+					applyContent()
+				} else if ctTransform {
 					formatContentType()
 				} else {
 					formatContent()
@@ -56,8 +68,36 @@ func init() {
 	transformCmd.Flags().BoolVarP(&onlyImages, "only-images", "o", false, "only download images")
 	transformCmd.Flags().StringVarP(&contentType, "contentModel", "m", "", "type of the content to migrate")
 	transformCmd.Flags().StringVarP(&repo, "repo", "r", "", "repo of the content to migrate")
+	transformCmd.Flags().StringVar(&repoOwner, "owner", "moonwalker", "owner of the content repo")
+	transformCmd.Flags().StringVar(&repoBranch, "branch", "main", "branch of the content repo")
 	transformCmd.Flags().StringVarP(&brand, "brand", "b", "", "brand")
+	transformCmd.Flags().BoolVar(&apply, "apply", false, "tocf: upsert and publish the content types, entries and assets in the space")
+	transformCmd.Flags().BoolVar(&dryRun, "dry-run", false, "tocf: report the changes of apply without writing")
+	transformCmd.Flags().StringVar(&reportPath, "report", "", "tocf: write the apply report (json) with the moonbase id to sys.id mapping")
 	transformCmd.PersistentFlags().StringVarP(&direction, "direction", "d", "", "directions: <fromcf|tocf>")
 	transformCmd.MarkPersistentFlagRequired("direction")
 	rootCmd.AddCommand(transformCmd)
 }
+
+func contentRepository() gontentful.ContentRepository {
+	return gontentful.NewGitHubRepository(repoOwner, repo, repoBranch)
+}
+
+// assetURLRewriter creates the rewriter of the assets config of the repo, the brand
+// flag sets the cloudflare brand.
+func assetURLRewriter() gontentful.AssetURLRewriter {
+	cfg := &gontentful.AssetsConfig{}
+	if len(repo) > 0 {
+		if c := gontentful.GetAssetsConfig(contentRepository()); c != nil {
+			cfg = c
+		}
+	}
+	if len(brand) > 0 {
+		cfg.Brand = brand
+	}
+	r, err := gontentful.NewAssetURLRewriter(cfg, repo)
+	if err != nil {
+		log.Fatalf("failed to create the asset urls: %s", err.Error())
+	}
+	return r
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/james-elicx/gontentful"
+)
+
+func applyContent() {
+	start := time.Now()
+	fmt.Println(fmt.Sprintf("Content apply started on:%s|%s", repo, contentType))
+
+	cli := gontentful.NewClient(&gontentful.ClientOptions{
+		SpaceID:       spaceID,
+		EnvironmentID: environmentID,
+		CmaToken:      cmaToken,
+		CmaURL:        cmaURL,
+	})
+
+	a := gontentful.NewCMAApply(cli, contentRepository(), contentType)
+	a.DryRun = dryRun
+	report, applyErr := a.Exec()
+	if report == nil {
+		log.Fatalf("failed to apply content: %s", applyErr.Error())
+	}
+
+	fmt.Print(report.String())
+
+	if reportPath != "" {
+		out, err := report.JSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = os.WriteFile(reportPath, []byte(out), 0644)
+		if err != nil {
+			log.Fatalf("failed to write report %s: %s", reportPath, err.Error())
+		}
+	}
+
+	if applyErr != nil {
+		log.Fatalf("failed to apply content: %s", applyErr.Error())
+	}
+
+	fmt.Printf("Content successfully applied in %.1fs\n", time.Since(start).Seconds())
+}
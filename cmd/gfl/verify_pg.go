@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/james-elicx/gontentful"
+)
+
+var (
+	repair bool
+)
+
+func init() {
+	pgVerifyCmd.PersistentFlags().BoolVar(&repair, "repair", false, "re-sync the drifted entries")
+	pgVerifyCmd.PersistentFlags().StringVarP(&reportFormat, "format", "f", "text", "report format (text, json)")
+	verifyCmd.AddCommand(pgVerifyCmd)
+}
+
+var pgVerifyCmd = &cobra.Command{
+	Use:   "pg",
+	Short: "Compare contentful with the postgres mirror",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := gontentful.NewClient(&gontentful.ClientOptions{
+			CdnURL:        apiURL,
+			SpaceID:       spaceID,
+			EnvironmentID: environmentID,
+			CdnToken:      cdnToken,
+		})
+
+		log.Println("verifying postgres mirror...")
+		verify := gontentful.NewPGVerify(schemaName, client)
+		verify.SchemaConfig = pgSchemaConfig()
+		verify.SyncOptions = pgSyncOptions()
+		report, err := verify.Exec(databaseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if reportFormat == "json" {
+			out, err := report.JSON()
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(out)
+		} else {
+			fmt.Print(report.String())
+		}
+
+		if report.OK() {
+			log.Println("postgres mirror is consistent")
+			return
+		}
+
+		if !repair {
+			log.Printf("%d drifted items found", len(report.Drifted()))
+			os.Exit(1)
+		}
+
+		err = verify.Repair(databaseURL, report)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("repair done")
+	},
+}
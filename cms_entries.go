@@ -11,18 +11,15 @@ import (
 
 	"github.com/google/go-github/v48/github"
 	"github.com/moonwalker/moonbase/pkg/content"
-	gh "github.com/moonwalker/moonbase/pkg/github"
 )
 
 type Config struct {
-	Token   string
-	WorkDir string `json:"workdir" yaml:"workdir"`
+	WorkDir string        `json:"workdir" yaml:"workdir"`
+	Assets  *AssetsConfig `json:"assets" yaml:"assets"`
 }
 
 const (
 	queryLimit = 1000
-	owner      = "moonwalker"
-	branch     = "main"
 	configPath = "moonbase.yaml"
 )
 
@@ -31,12 +28,12 @@ var localeVariants = map[string]string{
 	"no": "nb",
 }
 
-func GetCMSEntries(contentType string, repo string, include int) (*Entries, *ContentTypes, error) {
-	schemas, localizedData, err := getContentLocalized(repo, contentType)
+func GetCMSEntries(contentType string, r ContentRepository, include int) (*Entries, *ContentTypes, error) {
+	schemas, localizedData, err := getContentLocalized(r, contentType)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get content localized: %s", err.Error())
 	}
-	entries, err := createEntriesFromLocalizedData(repo, schemas, localizedData, include)
+	entries, err := createEntriesFromLocalizedData(r, schemas, localizedData, include)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create loclalized entires: %s", err.Error())
 	}
@@ -63,7 +60,7 @@ func GetCMSEntries(contentType string, repo string, include int) (*Entries, *Con
 	return entries, contentTypes, nil
 }
 
-func createEntriesFromLocalizedData(repo string, schemas map[string]*content.Schema, localizedData map[string]map[string]map[string]content.ContentData, include int) (*Entries, error) {
+func createEntriesFromLocalizedData(r ContentRepository, schemas map[string]*content.Schema, localizedData map[string]map[string]map[string]content.ContentData, include int) (*Entries, error) {
 	entries := &Entries{
 		Sys: &Sys{
 			Type: "Array",
@@ -88,7 +85,7 @@ func createEntriesFromLocalizedData(repo string, schemas map[string]*content.Sch
 			entries.Includes = &Include{}
 		}
 
-		includedEntries, includedAssets, err := formatIncludesRecursive(repo, includes, include, schemas, localizedData)
+		includedEntries, includedAssets, err := formatIncludesRecursive(r, includes, include, schemas, localizedData)
 		if err != nil {
 
 			return nil, fmt.Errorf("failed to fetch includes list: %s", err.Error())
@@ -100,9 +97,9 @@ func createEntriesFromLocalizedData(repo string, schemas map[string]*content.Sch
 	return entries, nil
 }
 
-func GetCMSEntry(contentType string, repo string, name string, locales []*Locale, include int) (*Entries, error) {
+func GetCMSEntry(contentType string, r ContentRepository, name string, locales []*Locale, include int) (*Entries, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, contentType)
 
 	/*files := make([]string, 0)
@@ -110,14 +107,14 @@ func GetCMSEntry(contentType string, repo string, name string, locales []*Locale
 		files = append(files, fmt.Sprintf("%s/%s.json", name, l.Code))
 	}
 	files = append(files, content.JsonSchemaName)
-	rcs, _, err := gh.GetFilesContent(ctx, cfg.Token, owner, repo, branch, path, files)
+	rcs, err := r.GetFiles(ctx, path, files)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all localized contents: %s", err.Error())
 	}*/
 
 	// get contentType's schema
 	rcs := make([]*github.RepositoryContent, 0)
-	rcSchema, _, err := gh.GetSchema(ctx, cfg.Token, owner, repo, branch, path)
+	rcSchema, err := r.GetSchema(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get schema(%s): %s", contentType, err.Error())
 	}
@@ -130,7 +127,7 @@ func GetCMSEntry(contentType string, repo string, name string, locales []*Locale
 
 	// get all localized jsons
 	itemPath := fmt.Sprintf("%s/%s", path, name)
-	rcsAll, _, err := gh.GetContentsRecursive(ctx, cfg.Token, owner, repo, branch, itemPath)
+	rcsAll, err := r.GetContents(ctx, itemPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all localized contents: %s", err.Error())
 	}
@@ -173,7 +170,7 @@ func GetCMSEntry(contentType string, repo string, name string, locales []*Locale
 		return nil, fmt.Errorf("failed to format repository content: %s", err.Error())
 	}
 
-	entries, err := createEntriesFromLocalizedData(repo, schemas, localizedData, include)
+	entries, err := createEntriesFromLocalizedData(r, schemas, localizedData, include)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create loclalized entires: %s", err.Error())
 	}
@@ -181,11 +178,10 @@ func GetCMSEntry(contentType string, repo string, name string, locales []*Locale
 	return entries, nil
 }
 
-func GetBlob(repo string, path string, file string) (*string, error) {
+func GetBlob(r ContentRepository, path string, file string) (*string, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
 
-	rcs, _, err := gh.GetFilesContent(ctx, cfg.Token, owner, repo, branch, path, []string{file})
+	rcs, err := r.GetFiles(ctx, path, []string{file})
 	if err != nil {
 		return nil, err
 	}
@@ -193,25 +189,27 @@ func GetBlob(repo string, path string, file string) (*string, error) {
 	return rcs[0].Content, nil
 }
 
-func GetBlobURL(repo string, path string, file string) (string, error) {
+func GetBlobURL(r ContentRepository, path string, file string) (string, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
 
-	rcs, _, err := gh.GetFilesContent(ctx, cfg.Token, owner, repo, branch, path, []string{file})
+	rcs, err := r.GetFiles(ctx, path, []string{file})
 	if err != nil {
 		return "", err
 	}
 
+	if rcs[0].DownloadURL == nil {
+		return "", fmt.Errorf("no download url for %s/%s", path, file)
+	}
 	return *rcs[0].DownloadURL, nil
 }
 
-func GetPublishedEntry(repo string, contentType string, files []string) (*PublishedEntry, error) {
+func GetPublishedEntry(r ContentRepository, contentType string, files []string) (*PublishedEntry, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, contentType)
 
 	files = append(files, content.JsonSchemaName)
-	rcs, _, err := gh.GetFilesContent(ctx, cfg.Token, owner, repo, branch, path, files)
+	rcs, err := r.GetFiles(ctx, path, files)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all localized contents: %s", err.Error())
 	}
@@ -321,34 +319,21 @@ func clearPublishedEntryFallbackValues(localizedFields map[string]bool, fields m
 	}
 }
 
-func getContentLocalized(repo string, ct string) (map[string]*content.Schema, map[string]map[string]map[string]content.ContentData, error) {
+func getContentLocalized(r ContentRepository, ct string) (map[string]*content.Schema, map[string]map[string]map[string]content.ContentData, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 
 	path := filepath.Join(cfg.WorkDir, ct)
 	var rcs []*github.RepositoryContent
 	var err error
-	/*if len(ct) == 0 || ct != ASSET_TABLE_NAME {
-		//localPath := fmt.Sprintf("/Users/rolandhelli/Development/src/github.com/moonwalker/%s", repo)
-		//rcs, err = GetLocalContentsRecursive(localPath)
-		rcs, _, err = gh.GetArchivedContents(ctx, cfg.Token, owner, repo, branch, path)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get json(s) from github: %s", err.Error())
-		}
-	} else {
-		rcs, _, err = gh.GetContentsRecursive(ctx, cfg.Token, owner, repo, branch, path)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get json(s) from github: %s", err.Error())
-		}
-	}*/
-	if ct != "" {
-		rcs, _, err = gh.GetContentsRecursive(ctx, cfg.Token, owner, repo, branch, path)
-	} else {
+	if a, ok := r.(archivedContentReader); ok && ct == "" {
 		// test, use GetArchivedContents in all use cases
-		rcs, _, err = gh.GetArchivedContents(ctx, cfg.Token, owner, repo, branch, path)
+		rcs, err = a.GetArchivedContents(ctx, path)
+	} else {
+		rcs, err = r.GetContents(ctx, path)
 	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get json(s) from github: %s", err.Error())
+		return nil, nil, fmt.Errorf("failed to get json(s) from repository: %s", err.Error())
 	}
 
 	schemas, localizedData, err := formatRepositoryContent(rcs, ct)
@@ -493,7 +478,7 @@ func GetLocalContentsRecursive(path string) ([]*github.RepositoryContent, error)
 	return resp, nil
 }
 
-func formatIncludesRecursive(repo string, entryRefs map[string]string, include int, schemas map[string]*content.Schema, localizedData map[string]map[string]map[string]content.ContentData) ([]*Entry, []*Entry, error) {
+func formatIncludesRecursive(r ContentRepository, entryRefs map[string]string, include int, schemas map[string]*content.Schema, localizedData map[string]map[string]map[string]content.ContentData) ([]*Entry, []*Entry, error) {
 	includes := make([]*Entry, 0)
 	assets := make([]*Entry, 0)
 
@@ -502,7 +487,7 @@ func formatIncludesRecursive(repo string, entryRefs map[string]string, include i
 	for id, ct := range entryRefs {
 		// fetch schema and data if needed
 		if schemas[ct] == nil {
-			isc, ild, err := getContentLocalized(repo, ct)
+			isc, ild, err := getContentLocalized(r, ct)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to get format localized: %s", err.Error())
 			}
@@ -526,12 +511,12 @@ func formatIncludesRecursive(repo string, entryRefs map[string]string, include i
 			includes = append(includes, e)
 		}
 		if len(er) > 0 && include > 0 {
-			en, as, err := formatIncludesRecursive(repo, er, include, schemas, localizedData)
+			en, as, err := formatIncludesRecursive(r, er, include, schemas, localizedData)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to get format includes recursive: %s", err.Error())
 			}
 			includes = append(includes, en...)
-			assets = append(includes, as...)
+			assets = append(assets, as...)
 		}
 	}
 
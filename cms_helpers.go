@@ -9,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/gosimple/slug"
-	gh "github.com/moonwalker/moonbase/pkg/github"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,16 +20,13 @@ func getAccessToken() string {
 	return ght
 }
 
-func getConfig(ctx context.Context, owner string, repo string, ref string) *Config {
-	accessToken := getAccessToken()
-	data, _, _ := gh.GetBlob(ctx, accessToken, owner, repo, ref, configPath)
-	return parseConfig(data, accessToken)
+func getConfig(ctx context.Context, r ContentRepository) *Config {
+	data, _ := r.ReadFile(ctx, configPath)
+	return parseConfig(data)
 }
 
-func parseConfig(data []byte, token string) *Config {
-	cfg := &Config{
-		Token: token,
-	}
+func parseConfig(data []byte) *Config {
+	cfg := &Config{}
 
 	err := yaml.Unmarshal(data, cfg)
 	if err != nil {
@@ -0,0 +1,475 @@
+package gontentful
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultCMSQueryLimit   = 100
+	defaultCMSQueryInclude = 1
+	maxCMSQueryInclude     = 10
+)
+
+// cmsComparers are the supported filter operators, the empty one is the equality
+var cmsComparers = map[string]bool{
+	"": true, "exists": true, "ne": true, "in": true, "nin": true, "all": true, "match": true,
+	"lt": true, "lte": true, "gt": true, "gte": true, "near": true, "within": true,
+}
+
+// CMSQuery queries the moonbase content with the CDA (and ParsePGQuery) parameters:
+// content_type, fields.x[ne|in|nin|all|exists|match|lt|lte|gt|gte|near|within], sys.x,
+// order, skip, limit, locale, include and select. The near filters order the entries
+// by the distance before the order parameter.
+type CMSQuery struct {
+	ContentType   string
+	Locale        string
+	DefaultLocale string
+	Filters       url.Values
+	Order         string
+	Skip          int
+	Limit         int
+	Include       int
+	Select        []string
+}
+
+type cmsItem struct {
+	entry *Entry
+	refs  map[string]string
+	// sys and the localized fields, as the query values
+	values map[string]interface{}
+}
+
+func ParseCMSQuery(defaultLocale string, q url.Values) *CMSQuery {
+	query := &CMSQuery{
+		ContentType:   q.Get("content_type"),
+		Locale:        q.Get("locale"),
+		DefaultLocale: defaultLocale,
+		Order:         q.Get("order"),
+		Limit:         defaultCMSQueryLimit,
+		Include:       defaultCMSQueryInclude,
+		Filters:       url.Values{},
+	}
+	if query.Locale == "" {
+		query.Locale = defaultLocale
+	}
+
+	if skip, err := strconv.Atoi(q.Get("skip")); err == nil && skip > 0 {
+		query.Skip = skip
+	}
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit >= 0 {
+		query.Limit = limit
+	}
+	if query.Limit > queryLimit {
+		query.Limit = queryLimit
+	}
+	if include, err := strconv.Atoi(q.Get("include")); err == nil && include >= 0 {
+		query.Include = include
+	}
+	if query.Include > maxCMSQueryInclude {
+		query.Include = maxCMSQueryInclude
+	}
+	if sel := q.Get("select"); sel != "" {
+		query.Select = strings.Split(sel, ",")
+	}
+
+	for key, values := range q {
+		switch key {
+		case "content_type", "locale", "order", "skip", "limit", "include", "select":
+			continue
+		}
+		query.Filters[key] = values
+	}
+
+	return query
+}
+
+// Exec reads the content of the repository and returns a page of the matching entries.
+func (s *CMSQuery) Exec(r ContentRepository) (*Entries, error) {
+	for key := range s.Filters {
+		if _, c := cmsFilterKey(key); !cmsComparers[c] {
+			return nil, fmt.Errorf("unsupported filter: %s", key)
+		}
+	}
+
+	schemas, localizedData, err := getContentLocalized(r, s.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content localized: %s", err.Error())
+	}
+
+	items := make([]*cmsItem, 0)
+	for ct, locData := range localizedData {
+		if ct == ASSET_TABLE_NAME && s.ContentType != ASSET_TABLE_NAME {
+			continue
+		}
+		for id := range locData {
+			entry, refs, err := FormatData(ct, id, schemas, localizedData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to format file content: %s", err.Error())
+			}
+			item := &cmsItem{
+				entry:  entry,
+				refs:   refs,
+				values: s.itemValues(entry),
+			}
+			if s.match(item) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	s.sort(items)
+
+	entries := &Entries{
+		Sys:   &Sys{Type: "Array"},
+		Total: len(items),
+		Skip:  s.Skip,
+		Limit: s.Limit,
+		Items: make([]*Entry, 0),
+	}
+
+	includes := make(map[string]string)
+	for i := s.Skip; i < len(items) && i < s.Skip+s.Limit; i++ {
+		entries.Items = append(entries.Items, s.format(items[i].entry))
+		mergeMaps(includes, items[i].refs)
+	}
+
+	if s.Include > 0 && len(includes) > 0 {
+		includedEntries, includedAssets, err := formatIncludesRecursive(r, includes, s.Include, schemas, localizedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch includes list: %s", err.Error())
+		}
+		entries.Includes = &Include{
+			Entry: s.formatIncludes(includedEntries),
+			Asset: s.formatIncludes(includedAssets),
+		}
+	}
+
+	return entries, nil
+}
+
+// QueryCMSEntries is ParseCMSQuery and Exec with the default locale.
+func QueryCMSEntries(r ContentRepository, q url.Values) (*Entries, error) {
+	return ParseCMSQuery(DefaultLocale, q).Exec(r)
+}
+
+func (s *CMSQuery) itemValues(entry *Entry) map[string]interface{} {
+	sys := map[string]interface{}{
+		"id":        entry.Sys.ID,
+		"type":      entry.Sys.Type,
+		"createdAt": entry.Sys.CreatedAt,
+		"updatedAt": entry.Sys.UpdatedAt,
+		"version":   float64(entry.Sys.Version),
+	}
+	if entry.Sys.ContentType != nil && entry.Sys.ContentType.Sys != nil {
+		sys["contentType"] = map[string]interface{}{
+			"sys": map[string]interface{}{"id": entry.Sys.ContentType.Sys.ID},
+		}
+	}
+
+	// filters and order use the default locale values of all the locales queries
+	locale := s.Locale
+	if locale == "*" {
+		locale = s.DefaultLocale
+	}
+	values := map[string]interface{}{"sys": sys}
+	for fn, v := range s.localizeFields(entry.Fields, locale) {
+		values[fn] = v
+	}
+	return values
+}
+
+func (s *CMSQuery) localizeFields(fields Fields, locale string) Fields {
+	res := make(Fields)
+	for fn, fv := range fields {
+		locValues, ok := fv.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		v := locValues[locale]
+		if v == nil {
+			v = locValues[s.DefaultLocale]
+		}
+		if v != nil {
+			res[fn] = v
+		}
+	}
+	return res
+}
+
+// format localizes and selects the fields like the CDA.
+func (s *CMSQuery) format(entry *Entry) *Entry {
+	res := &Entry{Sys: entry.Sys, Fields: entry.Fields}
+	if s.Locale != "*" {
+		res.Locale = s.Locale
+		res.Fields = s.localizeFields(entry.Fields, s.Locale)
+	}
+
+	if len(s.Select) > 0 {
+		selected := make(map[string]bool)
+		for _, sel := range s.Select {
+			if sel == "fields" {
+				return res
+			}
+			selected[strings.TrimPrefix(sel, "fields.")] = true
+		}
+		fields := make(Fields)
+		for fn, fv := range res.Fields {
+			if selected[fn] {
+				fields[fn] = fv
+			}
+		}
+		res.Fields = fields
+	}
+
+	return res
+}
+
+func (s *CMSQuery) formatIncludes(entries []*Entry) []*Entry {
+	res := make([]*Entry, 0)
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if seen[e.Sys.ID] {
+			continue
+		}
+		seen[e.Sys.ID] = true
+		inc := &Entry{Sys: e.Sys, Fields: e.Fields}
+		if s.Locale != "*" {
+			inc.Locale = s.Locale
+			inc.Fields = s.localizeFields(e.Fields, s.Locale)
+		}
+		res = append(res, inc)
+	}
+	return res
+}
+
+func (s *CMSQuery) sort(items []*cmsItem) {
+	// stable base order, the repository content has none
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].entry.Sys.ID < items[j].entry.Sys.ID
+	})
+	nears := s.nearFilters()
+	if s.Order == "" && len(nears) == 0 {
+		return
+	}
+	orders := make([]string, 0)
+	if s.Order != "" {
+		orders = strings.Split(s.Order, ",")
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, n := range nears {
+			di, iok := nearestCMSDistance(cmsPathValues(items[i].values, n.field), n.point)
+			dj, jok := nearestCMSDistance(cmsPathValues(items[j].values, n.field), n.point)
+			// the entries without location are the last
+			if iok != jok {
+				return iok
+			}
+			if di != dj {
+				return di < dj
+			}
+		}
+		for _, o := range orders {
+			desc := strings.HasPrefix(o, "-")
+			key := strings.TrimPrefix(o, "-")
+			c := compareValues(itemValue(items[i].values, key), itemValue(items[j].values, key))
+			if c == 0 {
+				continue
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func (s *CMSQuery) match(item *cmsItem) bool {
+	for key, values := range s.Filters {
+		f, c := cmsFilterKey(key)
+		if !matchCMSFilter(cmsPathValues(item.values, f), c, strings.Join(values, ",")) {
+			return false
+		}
+	}
+	return true
+}
+
+// cmsFilterKey splits the filter key to the field path and the operator
+func cmsFilterKey(key string) (string, string) {
+	comparerMatch := comparerRegex.FindStringSubmatch(key)
+	if len(comparerMatch) == 0 {
+		return key, ""
+	}
+	c := comparerMatch[1]
+	return strings.Replace(key, fmt.Sprintf("[%s]", c), "", 1), c
+}
+
+type cmsNearFilter struct {
+	field string
+	point geoPoint
+}
+
+// nearFilters returns the valid near filters in the order of the keys
+func (s *CMSQuery) nearFilters() []*cmsNearFilter {
+	keys := make([]string, 0)
+	for key := range s.Filters {
+		if strings.HasSuffix(key, "[near]") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	res := make([]*cmsNearFilter, 0)
+	for _, key := range keys {
+		p, err := parseGeoNear(strings.Join(s.Filters[key], ","))
+		if err != nil {
+			continue
+		}
+		res = append(res, &cmsNearFilter{field: strings.TrimSuffix(key, "[near]"), point: p})
+	}
+	return res
+}
+
+// nearestCMSDistance returns the distance in km of the nearest location of the values
+func nearestCMSDistance(values []interface{}, p geoPoint) (float64, bool) {
+	res, ok := 0.0, false
+	for _, v := range values {
+		if l, isLocation := geoLocation(v); isLocation {
+			if d := p.distanceKm(l); !ok || d < res {
+				res, ok = d, true
+			}
+		}
+	}
+	return res, ok
+}
+
+// cmsPathValues returns the values at the path (fields.x, fields.x.sys.id, sys.id, ...),
+// lists are flattened.
+func cmsPathValues(values map[string]interface{}, path string) []interface{} {
+	current := []interface{}{values}
+	parts := strings.Split(strings.TrimPrefix(path, "fields."), ".")
+	for _, p := range parts {
+		next := make([]interface{}, 0)
+		for _, c := range current {
+			m, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			next = append(next, flattenCMSValue(m[p])...)
+		}
+		current = next
+	}
+	return current
+}
+
+func flattenCMSValue(v interface{}) []interface{} {
+	switch l := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		res := make([]interface{}, 0)
+		for _, e := range l {
+			res = append(res, flattenCMSValue(e)...)
+		}
+		return res
+	case []string:
+		res := make([]interface{}, 0)
+		for _, e := range l {
+			res = append(res, e)
+		}
+		return res
+	}
+	return []interface{}{v}
+}
+
+func matchCMSFilter(values []interface{}, comparer string, value string) bool {
+	switch comparer {
+	case "":
+		return containsCMSValue(values, value)
+	case "exists":
+		return (len(values) > 0) == (value != "false")
+	case "ne":
+		return !containsCMSValue(values, value)
+	case "in":
+		for _, v := range strings.Split(value, ",") {
+			if containsCMSValue(values, v) {
+				return true
+			}
+		}
+		return false
+	case "nin":
+		for _, v := range strings.Split(value, ",") {
+			if containsCMSValue(values, v) {
+				return false
+			}
+		}
+		return true
+	case "all":
+		for _, v := range strings.Split(value, ",") {
+			if !containsCMSValue(values, v) {
+				return false
+			}
+		}
+		return true
+	case "match":
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(formatCMSValue(v)), strings.ToLower(value)) {
+				return true
+			}
+		}
+		return false
+	case "near":
+		// near orders the entries only
+		_, err := parseGeoNear(value)
+		return err == nil
+	case "within":
+		area, err := parseGeoWithin(value)
+		if err != nil {
+			return false
+		}
+		for _, v := range values {
+			if l, ok := geoLocation(v); ok && area.contains(l) {
+				return true
+			}
+		}
+		return false
+	case "lt", "lte", "gt", "gte":
+		for _, v := range values {
+			c := compareCMSValue(v, value)
+			if (comparer == "lt" && c < 0) || (comparer == "lte" && c <= 0) ||
+				(comparer == "gt" && c > 0) || (comparer == "gte" && c >= 0) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func containsCMSValue(values []interface{}, value string) bool {
+	for _, v := range values {
+		if formatCMSValue(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatCMSValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// compareCMSValue compares numbers numerically, everything else (e.g. dates) as text.
+func compareCMSValue(v interface{}, value string) int {
+	if f, ok := v.(float64); ok {
+		if fv, err := strconv.ParseFloat(value, 64); err == nil {
+			return compareValues(f, fv)
+		}
+	}
+	return strings.Compare(formatCMSValue(v), value)
+}
@@ -0,0 +1,114 @@
+package gontentful
+
+import (
+	"net/url"
+	"testing"
+)
+
+var cmsQueryFiles = map[string]string{
+	"moonbase.yaml":                "workdir: _content\n",
+	"_content/game/_schema.json":   `{"id":"game","name":"Game","fields":[{"id":"name","label":"Name","type":"text","localized":true},{"id":"rating","label":"Rating","type":"float"},{"id":"tags","label":"Tags","type":"text","list":true},{"id":"studio","label":"Studio","type":"studio","reference":true},{"id":"location","label":"Location","type":"location"}]}`,
+	"_content/game/g1/en.json":     `{"id":"g1","fields":{"name":"Starburst","rating":4.5,"tags":["top","new"],"studio":"s1","location":{"lat":52.52,"lon":13.405}},"updatedAt":"2024-01-02T00:00:00Z"}`,
+	"_content/game/g1/de.json":     `{"id":"g1","fields":{"name":"Sternexplosion","rating":4.5,"tags":["top","new"],"studio":"s1"}}`,
+	"_content/game/g2/en.json":     `{"id":"g2","fields":{"name":"Book of Dead","rating":3,"tags":["top"],"studio":"s2","location":{"lat":59.3293,"lon":18.0686}},"updatedAt":"2024-01-03T00:00:00Z"}`,
+	"_content/game/g3/en.json":     `{"id":"g3","fields":{"name":"Dead or Alive","tags":["old"],"studio":"s1"},"updatedAt":"2024-01-01T00:00:00Z"}`,
+	"_content/studio/_schema.json": `{"id":"studio","name":"Studio","fields":[{"id":"name","label":"Name","type":"text"}]}`,
+	"_content/studio/s1/en.json":   `{"id":"s1","fields":{"name":"NetEnt"}}`,
+	"_content/studio/s2/en.json":   `{"id":"s2","fields":{"name":"Play'n GO"}}`,
+}
+
+func queryCMSIDs(t *testing.T, r ContentRepository, query string) (*Entries, []string) {
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := QueryCMSEntries(r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0)
+	for _, e := range entries.Items {
+		ids = append(ids, e.Sys.ID)
+	}
+	return entries, ids
+}
+
+func TestQueryCMSEntries(t *testing.T) {
+	r := NewMemoryRepository(cmsQueryFiles)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"content_type=game", []string{"g1", "g2", "g3"}},
+		{"content_type=game&fields.tags=top&order=-fields.rating", []string{"g1", "g2"}},
+		{"content_type=game&fields.tags[all]=top,new", []string{"g1"}},
+		{"content_type=game&fields.tags[nin]=new", []string{"g2", "g3"}},
+		{"content_type=game&fields.rating[gte]=3&fields.rating[lt]=4", []string{"g2"}},
+		{"content_type=game&fields.rating[exists]=false", []string{"g3"}},
+		{"content_type=game&fields.name[match]=dead&order=sys.updatedAt", []string{"g3", "g2"}},
+		{"content_type=game&fields.studio.sys.id=s1&fields.name[ne]=Starburst", []string{"g3"}},
+		{"content_type=game&sys.id[in]=g3,g1&order=-sys.id", []string{"g3", "g1"}},
+		{"content_type=game&order=fields.rating", []string{"g2", "g1", "g3"}},
+		{"content_type=game&fields.location[near]=59,18", []string{"g2", "g1", "g3"}},
+		{"content_type=game&fields.location[near]=52,13&order=-fields.rating", []string{"g1", "g2", "g3"}},
+		{"content_type=game&fields.location[within]=52,13,53,14", []string{"g1"}},
+		{"content_type=game&fields.location[within]=52.52,13.405,500", []string{"g1"}},
+		{"content_type=game&fields.location[within]=52.52,13.405,1000&fields.location[near]=60,18", []string{"g2", "g1"}},
+		{"content_type=game&fields.location[within]=52,13", []string{}},
+	}
+	for _, tt := range tests {
+		_, ids := queryCMSIDs(t, r, tt.query)
+		if len(ids) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.query, ids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestQueryCMSEntriesOperator(t *testing.T) {
+	r := NewMemoryRepository(cmsQueryFiles)
+
+	q, err := url.ParseQuery("content_type=game&fields.rating[gtx]=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = QueryCMSEntries(r, q)
+	if err == nil || err.Error() != "unsupported filter: fields.rating[gtx]" {
+		t.Errorf("unsupported operator accepted: %v", err)
+	}
+}
+
+func TestQueryCMSEntriesPage(t *testing.T) {
+	r := NewMemoryRepository(cmsQueryFiles)
+
+	entries, ids := queryCMSIDs(t, r, "content_type=game&skip=1&limit=1&locale=de&select=fields.name&include=1")
+	if entries.Total != 3 || entries.Skip != 1 || entries.Limit != 1 || len(ids) != 1 || ids[0] != "g2" {
+		t.Fatalf("unexpected page total %d skip %d limit %d %v", entries.Total, entries.Skip, entries.Limit, ids)
+	}
+	// fallback to the default locale, only the selected field
+	item := entries.Items[0]
+	if item.Fields["name"] != "Book of Dead" || len(item.Fields) != 1 || item.Locale != "de" {
+		t.Errorf("unexpected fields %v (%s)", item.Fields, item.Locale)
+	}
+	if entries.Includes == nil || len(entries.Includes.Entry) != 1 || entries.Includes.Entry[0].Fields["name"] != "Play'n GO" {
+		t.Errorf("unexpected includes %+v", entries.Includes)
+	}
+
+	entries, _ = queryCMSIDs(t, r, "content_type=game&sys.id=g1&locale=de")
+	if entries.Items[0].Fields["name"] != "Sternexplosion" {
+		t.Errorf("got %v, want the localized name", entries.Items[0].Fields["name"])
+	}
+
+	entries, _ = queryCMSIDs(t, r, "content_type=game&sys.id=g1&locale=*&include=0")
+	name, ok := entries.Items[0].Fields["name"].(map[string]interface{})
+	if !ok || name["de"] != "Sternexplosion" || entries.Includes != nil {
+		t.Errorf("unexpected all locales entry %v", entries.Items[0].Fields)
+	}
+}
@@ -7,16 +7,15 @@ import (
 	"path/filepath"
 
 	"github.com/moonwalker/moonbase/pkg/content"
-	gh "github.com/moonwalker/moonbase/pkg/github"
 )
 
-func GetCMSSchema(repo string, ct string) (*ContentType, error) {
+func GetCMSSchema(r ContentRepository, ct string) (*ContentType, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, ct)
-	res, _, err := gh.GetSchema(ctx, cfg.Token, owner, repo, branch, path)
+	res, err := r.GetSchema(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get schema from github: %s", err.Error())
+		return nil, fmt.Errorf("failed to get schema from repository: %s", err.Error())
 	}
 
 	ghc, err := res.GetContent()
@@ -32,13 +31,13 @@ func GetCMSSchema(repo string, ct string) (*ContentType, error) {
 	return formatSchema(m), nil
 }
 
-func GetCMSSchemas(repo string, ct string) (*ContentTypes, error) {
+func GetCMSSchemas(r ContentRepository, ct string) (*ContentTypes, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, ct)
-	res, _, err := gh.GetSchemasRecursive(ctx, cfg.Token, owner, repo, branch, path)
+	res, err := r.GetSchemas(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get schemas from github: %s", err.Error())
+		return nil, fmt.Errorf("failed to get schemas from repository: %s", err.Error())
 	}
 
 	schemas := &ContentTypes{
@@ -63,13 +62,13 @@ func GetCMSSchemas(repo string, ct string) (*ContentTypes, error) {
 	return schemas, nil
 }
 
-func GetCMSSchemasExpanded(repo string, ct string) (*ContentTypes, error) {
+func GetCMSSchemasExpanded(r ContentRepository, ct string) (*ContentTypes, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, ct)
-	res, _, err := gh.GetSchemasRecursive(ctx, cfg.Token, owner, repo, branch, path)
+	res, err := r.GetSchemas(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get schemas from github: %s", err.Error())
+		return nil, fmt.Errorf("failed to get schemas from repository: %s", err.Error())
 	}
 
 	schemas := &ContentTypes{
@@ -3,8 +3,6 @@ package gontentful
 import (
 	"context"
 	"path/filepath"
-
-	gh "github.com/moonwalker/moonbase/pkg/github"
 )
 
 type GHDeleteContentType struct {
@@ -20,12 +18,11 @@ func NewGHDeleteContentType(sys *Sys) *GHDeleteContentType {
 	}
 }
 
-func (s *GHDeleteContentType) Exec(repo string) error {
+func (s *GHDeleteContentType) Exec(r ContentRepository) error {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 
 	path := filepath.Join(cfg.WorkDir, s.FolderName)
 
-	_, err := gh.DeleteFolder(ctx, cfg.Token, owner, repo, branch, path, "feat(content): delete content type")
-	return err
+	return r.DeletePath(ctx, path, "feat(content): delete content type")
 }
@@ -8,15 +8,15 @@ import (
 )
 
 const delContentTypeTemplate = `
-DROP TABLE IF EXISTS {{ $.SchemaName }}.{{ $.TableName }} CASCADE;
+DROP TABLE IF EXISTS {{ $.SchemaName }}."{{ $.TableName }}" CASCADE;
 --
-DROP FUNCTION IF EXISTS {{ $.SchemaName }}._get_{{ $.TableName }}_items CASCADE;
+DROP FUNCTION IF EXISTS {{ $.SchemaName }}."_get_{{ $.TableName }}_items" CASCADE;
 --
-DROP FUNCTION IF EXISTS {{ $.SchemaName }}.{{ $.TableName }}_items CASCADE;
+DROP FUNCTION IF EXISTS {{ $.SchemaName }}."{{ $.TableName }}_items" CASCADE;
 --
-DROP FUNCTION IF EXISTS {{ $.SchemaName }}.{{ $.TableName }}_query CASCADE;
+DROP FUNCTION IF EXISTS {{ $.SchemaName }}."{{ $.TableName }}_query" CASCADE;
 --
-DELETE FROM {{ $.SchemaName }}._schema WHERE table_name = {{ $.TableName }};
+DELETE FROM {{ $.SchemaName }}._schema WHERE table_name = '{{ $.TableName }}';
 `
 
 type PGDeleteContentType struct {
@@ -28,7 +28,7 @@ type PGDeleteContentType struct {
 func NewPGDeleteContentType(schemaName string, sys *Sys) *PGDeleteContentType {
 	return &PGDeleteContentType{
 		SchemaName: schemaName,
-		TableName:  toSnakeCase(sys.ID),
+		TableName:  pgTableName(sys.ID),
 		SysID:      sys.ID,
 	}
 }
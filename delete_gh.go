@@ -30,14 +30,13 @@ func NewGHDelete(sys *Sys, filaName string, locales *Locales) *GHDelete {
 	}
 }
 
-func (s *GHDelete) Exec(repo string) ([]gh.BlobEntry, error) {
+func (s *GHDelete) Exec(r ContentRepository) ([]gh.BlobEntry, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
+	cfg := getConfig(ctx, r)
 	path := filepath.Join(cfg.WorkDir, s.FolderName)
 
 	//_, err := gh.DeleteFiles(ctx, cfg.Token, owner, repo, branch, path, "feat(content): delete files", fileNames)
 	//return err
 
-	items, err := gh.GetDeleteFileEntries(ctx, cfg.Token, owner, repo, branch, path, "feat(content): delete files")
-	return items, err
+	return r.DeleteEntries(ctx, path)
 }
@@ -8,7 +8,7 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-const deleteTemplate = "DELETE FROM {{ .SchemaName }}.{{ .TableName }} WHERE _sys_id = '{{ .SysID }}';"
+const deleteTemplate = `DELETE FROM {{ .SchemaName }}."{{ .TableName }}" WHERE _sys_id = '{{ .SysID }}';`
 
 type PGDelete struct {
 	SchemaName string
@@ -19,7 +19,7 @@ type PGDelete struct {
 func NewPGDelete(schemaName string, sys *Sys) *PGDelete {
 	tableName := ""
 	if sys.Type == DELETED_ENTRY {
-		tableName = toSnakeCase(sys.ContentType.Sys.ID)
+		tableName = pgTableName(sys.ContentType.Sys.ID)
 	} else if sys.Type == DELETED_ASSET {
 		tableName = ASSET_TABLE_NAME
 	}
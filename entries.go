@@ -37,6 +37,20 @@ func (s *EntriesService) GetSingle(entryId string) ([]byte, error) {
 	return s.client.get(path, nil)
 }
 
+func (s *EntriesService) GetSingleCMA(entryId string) (*Entry, error) {
+	path := fmt.Sprintf(pathEntry, s.client.Options.SpaceID, s.client.Options.EnvironmentID, entryId)
+	data, err := s.client.getCMA(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := &Entry{}
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (s *EntriesService) Create(contentType string, body []byte) ([]byte, error) {
 	path := fmt.Sprintf(pathEntries, s.client.Options.SpaceID, s.client.Options.EnvironmentID)
 	// Set header for content type
@@ -44,6 +58,14 @@ func (s *EntriesService) Create(contentType string, body []byte) ([]byte, error)
 	return s.client.post(path, bytes.NewBuffer(body))
 }
 
+// CreateWithID creates the entry with the given id instead of a generated one.
+func (s *EntriesService) CreateWithID(contentType string, entryId string, body []byte) ([]byte, error) {
+	path := fmt.Sprintf(pathEntry, s.client.Options.SpaceID, s.client.Options.EnvironmentID, entryId)
+	// Set header for content type
+	s.client.headers[headerContentfulContentType] = contentType
+	return s.client.put(path, bytes.NewBuffer(body))
+}
+
 func (s *EntriesService) Update(version string, entryId string, body []byte) ([]byte, error) {
 	path := fmt.Sprintf(pathEntry, s.client.Options.SpaceID, s.client.Options.EnvironmentID, entryId)
 	// Set header for content type
@@ -21,6 +21,24 @@ var funcMap = template.FuncMap{
 	"Overwritable": func(f string) bool {
 		return overwritableFields[f]
 	},
+	"SpaceScope": func(alias string, parent string) string {
+		return ""
+	},
+	"IsLocation": func(sqlType string) bool {
+		return sqlType == PGLocationPoint || sqlType == PGLocationGeography
+	},
+	"LocationJSON": pgLocationJSON,
+	"QuoteLiteral": func(s string) string {
+		return strings.ReplaceAll(s, "'", "''")
+	},
+}
+
+// pgLocationJSON returns the Location column of the sql type as a {lat, lon} object, like the CDA
+func pgLocationJSON(expr string, sqlType string) string {
+	if sqlType == PGLocationGeography {
+		return fmt.Sprintf("(CASE WHEN %[1]s IS NULL THEN NULL ELSE json_build_object('lat', ST_Y(%[1]s::geometry), 'lon', ST_X(%[1]s::geometry)) END)", expr)
+	}
+	return fmt.Sprintf("(CASE WHEN %[1]s IS NULL THEN NULL ELSE json_build_object('lat', (%[1]s)[1], 'lon', (%[1]s)[0]) END)", expr)
 }
 
 type PGFunctions struct {
@@ -34,7 +52,7 @@ func NewPGFunctions(schema *PGSQLSchema) *PGFunctions {
 }
 
 func (s *PGFunctions) Exec(databaseURL string) error {
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgFuncTemplate)
+	tmpl, err := template.New("").Funcs(s.funcMap()).Parse(pgFuncTemplate)
 
 	if err != nil {
 		return err
@@ -81,7 +99,7 @@ func (s *PGFunctions) Exec(databaseURL string) error {
 }
 
 func (s *PGFunctions) Render() (string, error) {
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgFuncTemplate)
+	tmpl, err := template.New("").Funcs(s.funcMap()).Parse(pgFuncTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -94,3 +112,40 @@ func (s *PGFunctions) Render() (string, error) {
 
 	return buff.String(), nil
 }
+
+func (s *PGFunctions) funcMap() template.FuncMap {
+	fm := make(template.FuncMap)
+	for k, v := range pgIdentifierFuncMap {
+		fm[k] = v
+	}
+	for k, v := range funcMap {
+		fm[k] = v
+	}
+	fm["SearchVector"] = func(cols []*PGSQLSearchColumn, locale string) string {
+		return pgSearchVector(cols, pgTextSearchConfig(locale, s.Schema.TextSearch))
+	}
+	// the views join the rows of every fallback locale of the longest chain
+	depth := pgFallbackDepth(s.Schema.Fallbacks)
+	fm["FallbackJoins"] = func(alias string) []*pgFallbackJoin {
+		return pgFallbackJoins(alias, depth)
+	}
+	fm["FallbackAliases"] = func(alias string) []string {
+		return pgFallbackAliases(alias, depth)
+	}
+	fm["Coalesce"] = func(alias string, column string) string {
+		return pgFallbackCoalesce(alias, column, depth)
+	}
+	fm["FallbackRow"] = func(alias string, check string, column string) string {
+		return pgFallbackRow(alias, check, column, depth)
+	}
+	fm["FallbackLocales"] = func(locale string) string {
+		return pgFallbackArray(s.Schema.Fallbacks[strings.ToLower(locale)])
+	}
+	if s.Schema.SpaceColumns {
+		// rows of a shared schema may only be joined within the same space and environment
+		fm["SpaceScope"] = func(alias string, parent string) string {
+			return fmt.Sprintf(" AND %[1]s._space = %[2]s._space AND %[1]s._environment = %[2]s._environment", pgQuoteIdent(alias), pgQuoteIdent(parent))
+		}
+	}
+	return fm
+}
@@ -20,7 +20,7 @@ func NewPGFunctionsPublish(schema *PGSQLSchema, locale string, fallbackLocale st
 }
 
 func (s *PGFunctionsPublish) Render() (string, error) {
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgFuncPublishTemplate)
+	tmpl, err := template.New("").Funcs(pgIdentifierFuncMap).Funcs(funcMap).Parse(pgFuncPublishTemplate)
 	if err != nil {
 		return "", err
 	}
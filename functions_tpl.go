@@ -2,7 +2,7 @@ package gontentful
 
 const pgRefreshMatViewsTemplate = `
 {{ range $i, $l := $.Locales }}
-REFRESH MATERIALIZED VIEW "mv_{{ $.TableName }}_{{ .Code | ToLower }}";
+REFRESH MATERIALIZED VIEW {{ MatView $.TableName .Code | Ident }};
 {{- end }}`
 
 const pgRefreshMatViewsGetDepsTemplate = `
@@ -39,114 +39,159 @@ END $$;
 {{ end -}}
 {{ range $i, $t := $.Tables }}
 {{- if $.DropTables }}
-DROP FUNCTION IF EXISTS {{ .TableName }}_view CASCADE;
+DROP FUNCTION IF EXISTS "{{ .TableName }}_view" CASCADE;
 {{ end -}}
 {{ end }}
 --
+{{- define "assetFile" -}}
+json_build_object(
+	'contentType', "{{ . }}".content_type,
+	'fileName', "{{ . }}".file_name,
+	'url', "{{ . }}".url,
+	'upload', "{{ . }}".upload,
+	'details', "{{ . }}".details
+)
+{{- end -}}
+{{- define "assetFileRow" -}}
+(CASE WHEN "{{ . }}"._sys_id IS NOT NULL THEN {{ template "assetFile" . }}
+{{- range FallbackAliases . }}
+	WHEN "{{ . }}"._sys_id IS NOT NULL THEN {{ template "assetFile" . }}
+{{- end }}
+	ELSE {{ template "assetFile" $ }} END)
+{{- end -}}
 {{- define "assetRef" -}}
-(CASE WHEN 
-	{{ .Reference.JoinAlias }}._sys_id IS NULL AND 
-	{{ .Reference.JoinAlias }}_fallbacklocale._sys_id IS NULL AND 
-	{{ .Reference.JoinAlias }}_deflocale._sys_id IS NULL THEN NULL 
+(CASE WHEN {{ Coalesce .Reference.JoinAlias "_sys_id" }} IS NULL THEN NULL
 ELSE
 json_build_object(
-	'title', COALESCE({{ .Reference.JoinAlias }}.title, {{ .Reference.JoinAlias }}_fallbacklocale.title, {{ .Reference.JoinAlias }}_deflocale.title),
-	'description', COALESCE({{ .Reference.JoinAlias }}.description, {{ .Reference.JoinAlias }}_fallbacklocale.description, {{ .Reference.JoinAlias }}_deflocale.description),
-	'file', (CASE WHEN {{ .Reference.JoinAlias }}._sys_id IS NULL THEN 
-		(CASE WHEN {{ .Reference.JoinAlias }}_fallbacklocale._sys_id IS NULL THEN
-			json_build_object(
-				'contentType', {{ .Reference.JoinAlias }}_deflocale.content_type,
-				'fileName', {{ .Reference.JoinAlias }}_deflocale.file_name,
-				'url', {{ .Reference.JoinAlias }}_deflocale.url
-			)
-		ELSE 
-		json_build_object(
-			'contentType', {{ .Reference.JoinAlias }}_fallbacklocale.content_type,
-			'fileName', {{ .Reference.JoinAlias }}_fallbacklocale.file_name,
-			'url', {{ .Reference.JoinAlias }}_fallbacklocale.url
-		) END)	
-	ELSE 
-		json_build_object(
-			'contentType', {{ .Reference.JoinAlias }}.content_type,
-			'fileName', {{ .Reference.JoinAlias }}.file_name,
-			'url', {{ .Reference.JoinAlias }}.url
-		)
-	END)
+	'sys', json_build_object('id', {{ Coalesce .Reference.JoinAlias "_sys_id" }}, 'type', 'Asset'),
+	'title', {{ Coalesce .Reference.JoinAlias "title" }},
+	'description', {{ Coalesce .Reference.JoinAlias "description" }},
+	'file', {{ template "assetFileRow" .Reference.JoinAlias }}
 )
 END)
 {{- end -}}
 {{- define "assetCon" -}}
-		json_build_object('id', COALESCE({{ .Reference.JoinAlias }}._sys_id, {{ .Reference.JoinAlias }}_fallbacklocale._sys_id, {{ .Reference.JoinAlias }}_deflocale._sys_id)) AS sys,
-								(CASE WHEN {{ .Reference.JoinAlias }}._sys_id IS NULL THEN (CASE WHEN {{ .Reference.JoinAlias }}_fallbacklocale._sys_id IS NULL THEN {{ .Reference.JoinAlias }}_deflocale.title ELSE {{ .Reference.JoinAlias }}_fallbacklocale.title END) ELSE {{ .Reference.JoinAlias }}.title END) AS "title",
-								(CASE WHEN {{ .Reference.JoinAlias }}._sys_id IS NULL THEN (CASE WHEN {{ .Reference.JoinAlias }}_fallbacklocale._sys_id IS NULL THEN {{ .Reference.JoinAlias }}_deflocale.description ELSE {{ .Reference.JoinAlias }}_fallbacklocale.description END) ELSE {{ .Reference.JoinAlias }}.description END) AS "description",
-								(CASE WHEN {{ .Reference.JoinAlias }}._sys_id IS NULL THEN 
-									(CASE WHEN {{ .Reference.JoinAlias }}_fallbacklocale._sys_id IS NULL THEN
-										json_build_object(
-											'contentType', {{ .Reference.JoinAlias }}_deflocale.content_type,
-											'fileName', {{ .Reference.JoinAlias }}_deflocale.file_name,
-											'url', {{ .Reference.JoinAlias }}_deflocale.url
-										)
-									ELSE 
-									json_build_object(
-										'contentType', {{ .Reference.JoinAlias }}_fallbacklocale.content_type,
-										'fileName', {{ .Reference.JoinAlias }}_fallbacklocale.file_name,
-										'url', {{ .Reference.JoinAlias }}_fallbacklocale.url
-									) END)	
-								ELSE 
-									json_build_object(
-										'contentType', {{ .Reference.JoinAlias }}.content_type,
-										'fileName', {{ .Reference.JoinAlias }}.file_name,
-										'url', {{ .Reference.JoinAlias }}.url
-									)
-								END) AS "file"
+		json_build_object('id', {{ Coalesce .Reference.JoinAlias "_sys_id" }}, 'type', 'Asset') AS sys,
+								{{ FallbackRow .Reference.JoinAlias "_sys_id" "title" }} AS "title",
+								{{ FallbackRow .Reference.JoinAlias "_sys_id" "description" }} AS "description",
+								{{ template "assetFileRow" .Reference.JoinAlias }} AS "file"
+{{- end -}}
+{{- define "entrySys" -}}
+, 'type', 'Entry'
+{{- if .ContentType -}}, 'contentType', json_build_object('sys', json_build_object('id', '{{ .ContentType }}')){{- end }}
 {{- end -}}
-{{- define "refColumn" -}} 
+{{- define "candidates" -}}
+COALESCE(
+	{{- range $i, $c := .Candidates -}}
+	{{ if $i }},{{ end }}
+	{{ template "refColumn" .Reference }}
+	{{- end -}})
+{{- end -}}
+{{- define "refColumn" -}}
 {{ if .Localized -}}
-(CASE WHEN COALESCE({{ .JoinAlias }}._sys_id, {{ .JoinAlias }}_fallbacklocale._sys_id, {{ .JoinAlias }}_deflocale._sys_id) IS NULL THEN NULL ELSE json_build_object(
-	'sys', json_build_object('id', COALESCE({{ .JoinAlias }}._sys_id, {{ .JoinAlias }}_fallbacklocale._sys_id, {{ .JoinAlias }}_deflocale._sys_id))
+(CASE WHEN {{ Coalesce .JoinAlias "_sys_id" }} IS NULL THEN NULL ELSE json_build_object(
+	'sys', json_build_object('id', {{ Coalesce .JoinAlias "_sys_id" }}{{ template "entrySys" . }})
 {{- else -}}
-(CASE WHEN {{ .JoinAlias }}._sys_id IS NULL THEN NULL ELSE json_build_object(
-	'sys', json_build_object('id', {{ .JoinAlias }}._sys_id)
+(CASE WHEN "{{ .JoinAlias }}"._sys_id IS NULL THEN NULL ELSE json_build_object(
+	'sys', json_build_object('id', "{{ .JoinAlias }}"._sys_id{{ template "entrySys" . }})
 {{- end -}}
 					{{- range $i, $c:= .Columns -}}
 					,
 					'{{ .Alias }}',
 					{{- if .ConTableName -}}
-						_included_{{ .Reference.JoinAlias }}.res
+						"_included_{{ .Reference.JoinAlias }}".res
 					{{- else if .IsAsset -}}
-						{{ template "assetRef" . }}	
+						{{ template "assetRef" . }}
+					{{- else if .Candidates -}}
+						{{ template "candidates" . }}
 					{{- else if .Reference -}}
 						{{ template "refColumn" .Reference }}
+					{{- else if IsLocation .SqlType -}}
+						{{ if .Localized -}}
+							{{ LocationJSON (Coalesce .JoinAlias .ColumnName) .SqlType }}
+						{{- else -}}
+							{{ LocationJSON (printf "\"%s\".\"%s\"" .JoinAlias .ColumnName) .SqlType }}
+						{{- end -}}
 					{{- else -}}
 						{{ if .Localized -}}
-							COALESCE({{ .JoinAlias }}.{{ .ColumnName }}, {{ .JoinAlias }}_fallbacklocale.{{ .ColumnName }}, {{ .JoinAlias }}_deflocale.{{ .ColumnName }}) 
+							{{ Coalesce .JoinAlias .ColumnName }}
 						{{- else -}}
-							{{ .JoinAlias }}.{{ .ColumnName }}
-						{{- end -}}	
+							"{{ .JoinAlias }}"."{{ .ColumnName }}"
+						{{- end -}}
 					{{- end -}}
 					{{- end }}) END)
 {{- end -}}
-{{- define "conColumn" -}} 
-json_build_object('id', {{ .JoinAlias }}._sys_id) AS sys
+{{- define "conColumn" -}}
+json_build_object('id', "{{ .JoinAlias }}"._sys_id{{ template "entrySys" . }}) AS sys
 						{{- range $i, $c:= .Columns -}}
 						,
 						{{ if .ConTableName -}}
-							_included_{{ .Reference.JoinAlias }}.res
+							"_included_{{ .Reference.JoinAlias }}".res
 						{{- else if .IsAsset -}}
 						{{ template "assetRef" . }}
+						{{- else if .Candidates -}}
+							{{ template "candidates" . }}
 						{{- else if .Reference -}}
 							{{ template "refColumn" .Reference }}
+						{{- else if IsLocation .SqlType -}}
+							{{ if .Localized -}}
+								{{ LocationJSON (Coalesce .JoinAlias .ColumnName) .SqlType }}
+							{{- else -}}
+								{{ LocationJSON (printf "\"%s\".\"%s\"" .JoinAlias .ColumnName) .SqlType }}
+							{{- end -}}
 						{{- else -}}
 							{{ if .Localized -}}
-								COALESCE({{ .JoinAlias }}.{{ .ColumnName }}, {{ .JoinAlias }}_fallbacklocale.{{ .ColumnName }}, {{ .JoinAlias }}_deflocale.{{ .ColumnName }}) 
+								{{ Coalesce .JoinAlias .ColumnName }}
 							{{- else -}}
-								{{ .JoinAlias }}.{{ .ColumnName }}
-							{{- end -}}	
+								"{{ .JoinAlias }}"."{{ .ColumnName }}"
+							{{- end -}}
 						{{- end }} AS "{{ .Alias }}"
 						{{- end }}
 {{- end -}}
+{{- define "conContentType" -}}
+("{{ .ConTableName }}"._content_type IS NULL OR "{{ .ConTableName }}"._content_type = '{{ .Reference.ContentType }}')
+{{- end -}}
 {{- define "join" -}}
+	{{- if .Candidates }}
 	{{- if .ConTableName }}
+		LEFT JOIN LATERAL (
+			SELECT json_agg(l.item) AS res FROM (
+				SELECT
+					{{ template "candidates" . }} AS item
+				FROM "{{ .ConTableName }}"
+				{{- range $c := .Candidates }}
+				{{ if .Localized -}}
+				LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._sys_id = "{{ .ConTableName }}"._entry_sys_id AND "{{ .Reference.JoinAlias }}"._locale = localeArg {{- SpaceScope .Reference.JoinAlias .JoinAlias }} AND {{ template "conContentType" . }}
+				{{- else -}}
+				LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._id = "{{ .ConTableName }}"._entry AND {{ template "conContentType" . }}
+				{{- end }}
+				{{ if or .Localized .Reference.HasLocalized -}}
+				-- Join (Localized:{{ .Localized }}, Reference HasLocalized:{{ .Reference.HasLocalized }}, Reference Localized:{{ .Reference.Localized }})
+				{{- range FallbackJoins .Reference.JoinAlias }}
+				LEFT JOIN "{{ $c.Reference.TableName }}" "{{ .Alias }}" ON "{{ .Alias }}"._sys_id = "{{ $c.ConTableName }}"._entry_sys_id AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $c.JoinAlias }} AND {{ template "conContentType" $c }}
+				{{- end }}
+				{{- end -}}
+				{{- range .Reference.Columns }}
+				{{- template "join" . }}
+				{{- end }}
+				{{- end }}
+				WHERE "{{ .ConTableName }}"."{{ .TableName }}" =
+				{{- if .Localized -}}
+				-- IsLocalized join
+				{{ FallbackRow .JoinAlias .ColumnName "_id" }}
+				{{- else -}}
+				"{{ .JoinAlias }}"._id
+				{{- end }}
+				ORDER BY "{{ .ConTableName }}"._id
+			) l WHERE l.item IS NOT NULL
+		) "_included_{{ .Reference.JoinAlias }}" ON true
+	{{- else }}
+		{{- range .Candidates }}
+		{{- template "join" . }}
+		{{- end }}
+	{{- end }}
+	{{- else if .ConTableName }}
+		{{- $c := . }}
 		LEFT JOIN LATERAL (
 			SELECT json_agg(l) AS res FROM (
 				SELECT
@@ -155,49 +200,54 @@ json_build_object('id', {{ .JoinAlias }}._sys_id) AS sys
 					{{- else -}}
 					{{ template "conColumn" .Reference }}
 					{{- end }}
-				FROM {{ .ConTableName }}
+				FROM "{{ .ConTableName }}"
 				{{ if .Localized -}}
-				LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }} ON {{ .Reference.JoinAlias }}._sys_id = {{ .ConTableName }}.{{ .Reference.TableName }}_sys_id AND {{ .Reference.JoinAlias }}._locale = localeArg
+				LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._sys_id = "{{ .ConTableName }}"."{{ .Reference.TableName }}_sys_id" AND "{{ .Reference.JoinAlias }}"._locale = localeArg {{- SpaceScope .Reference.JoinAlias .JoinAlias }}
 				{{- else -}}
-				LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }} ON {{ .Reference.JoinAlias }}._id = {{ .ConTableName }}.{{ .Reference.TableName }}
+				LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._id = "{{ .ConTableName }}"."{{ .Reference.TableName }}"
 				{{- end }}
 				{{ if or .Localized .IsAsset .Reference.HasLocalized -}}
 				-- Join (Localized:{{ .Localized }}, IsAsset:{{ .IsAsset }}, Reference HasLocalized:{{ .Reference.HasLocalized }}, Reference Localized:{{ .Reference.Localized }})
-				LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_fallbacklocale ON {{ .Reference.JoinAlias }}_fallbacklocale._sys_id = {{ .ConTableName }}.{{ .Reference.TableName }}_sys_id AND {{ .Reference.JoinAlias }}_fallbacklocale._locale = fallbackLocaleArg
-				LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_deflocale ON {{ .Reference.JoinAlias }}_deflocale._sys_id = {{ .ConTableName }}.{{ .Reference.TableName }}_sys_id AND {{ .Reference.JoinAlias }}_deflocale._locale = defLocaleArg
+				{{- range FallbackJoins .Reference.JoinAlias }}
+				LEFT JOIN "{{ $c.Reference.TableName }}" "{{ .Alias }}" ON "{{ .Alias }}"._sys_id = "{{ $c.ConTableName }}"."{{ $c.Reference.TableName }}_sys_id" AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $c.JoinAlias }}
+				{{- end }}
 				{{- end -}}
 				{{- range .Reference.Columns }}
 				{{- template "join" . }}
 				{{- end }}
-				WHERE {{ .ConTableName }}.{{ .TableName }} = 
-				{{- if .Localized -}}	
+				WHERE "{{ .ConTableName }}"."{{ .TableName }}" =
+				{{- if .Localized -}}
 				-- IsLocalized join
-				(CASE WHEN {{ .JoinAlias }}.{{ .ColumnName }} IS NULL THEN (CASE WHEN {{ .JoinAlias }}_fallbacklocale.{{ .ColumnName }} IS NULL THEN {{ .JoinAlias }}_deflocale._id ELSE {{ .JoinAlias }}_fallbacklocale._id END) ELSE {{ .JoinAlias }}._id END)
+				{{ FallbackRow .JoinAlias .ColumnName "_id" }}
 				{{- else -}}
-				{{ .JoinAlias }}._id
+				"{{ .JoinAlias }}"._id
 				{{- end }}
-				ORDER BY {{ .ConTableName }}._id
+				ORDER BY "{{ .ConTableName }}"._id
 			) l
-		) _included_{{ .Reference.JoinAlias }} ON true
+		) "_included_{{ .Reference.JoinAlias }}" ON true
 	{{- else if .Reference }}
+		{{- $c := . }}
 		{{ if .Localized -}}
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }} ON {{ .Reference.JoinAlias }}._sys_id = COALESCE({{ .JoinAlias }}.{{ .Reference.ForeignKey }},{{ .JoinAlias }}_fallbacklocale.{{ .Reference.ForeignKey }} ,{{ .JoinAlias }}_deflocale.{{ .Reference.ForeignKey }}) AND {{ .Reference.JoinAlias }}._locale = localeArg
+		LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._sys_id = {{ Coalesce .JoinAlias .Reference.ForeignKey }} AND "{{ .Reference.JoinAlias }}"._locale = localeArg {{- SpaceScope .Reference.JoinAlias .JoinAlias }}
 		{{- else -}}
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }} ON {{ .Reference.JoinAlias }}._sys_id = {{ .JoinAlias }}.{{ .Reference.ForeignKey }} AND {{ .Reference.JoinAlias }}._locale = localeArg
+		LEFT JOIN "{{ .Reference.TableName }}" "{{ .Reference.JoinAlias }}" ON "{{ .Reference.JoinAlias }}"._sys_id = "{{ .JoinAlias }}"."{{ .Reference.ForeignKey }}" AND "{{ .Reference.JoinAlias }}"._locale = localeArg {{- SpaceScope .Reference.JoinAlias .JoinAlias }}
 		{{- end -}}
 		{{ if or .Localized .IsAsset .Reference.HasLocalized }}
 		{{ if .IsAsset -}}
 		-- IsAsset join
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_fallbacklocale ON {{ .Reference.JoinAlias }}_fallbacklocale._sys_id = {{ .JoinAlias }}.{{ .Reference.ForeignKey }} AND {{ .Reference.JoinAlias }}_fallbacklocale._locale = fallbackLocaleArg
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_deflocale ON {{ .Reference.JoinAlias }}_deflocale._sys_id = {{ .JoinAlias }}.{{ .Reference.ForeignKey }} AND {{ .Reference.JoinAlias }}_deflocale._locale = defLocaleArg
+		{{- range FallbackJoins .Reference.JoinAlias }}
+		LEFT JOIN "{{ $c.Reference.TableName }}" "{{ .Alias }}" ON "{{ .Alias }}"._sys_id = "{{ $c.JoinAlias }}"."{{ $c.Reference.ForeignKey }}" AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $c.JoinAlias }}
+		{{- end }}
 		{{- else -}}
 		-- Reference (Localized:{{ .Localized }}, Reference Localized:{{ .Reference.Localized }}, Reference HasLocalized:{{ .Reference.HasLocalized }})
 		{{ if .Reference.Localized }}
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_fallbacklocale ON {{ .Reference.JoinAlias }}_fallbacklocale._sys_id = COALESCE({{ .JoinAlias }}.{{ .Reference.ForeignKey }},{{ .JoinAlias }}_fallbacklocale.{{ .Reference.ForeignKey }} ,{{ .JoinAlias }}_deflocale.{{ .Reference.ForeignKey }}) AND {{ .Reference.JoinAlias }}_fallbacklocale._locale = fallbackLocaleArg
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_deflocale ON {{ .Reference.JoinAlias }}_deflocale._sys_id = COALESCE({{ .JoinAlias }}.{{ .Reference.ForeignKey }},{{ .JoinAlias }}_fallbacklocale.{{ .Reference.ForeignKey }} ,{{ .JoinAlias }}_deflocale.{{ .Reference.ForeignKey }}) AND {{ .Reference.JoinAlias }}_deflocale._locale = defLocaleArg
+		{{- range FallbackJoins .Reference.JoinAlias }}
+		LEFT JOIN "{{ $c.Reference.TableName }}" "{{ .Alias }}" ON "{{ .Alias }}"._sys_id = {{ Coalesce $c.JoinAlias $c.Reference.ForeignKey }} AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $c.JoinAlias }}
+		{{- end }}
 		{{- else -}}
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_fallbacklocale ON {{ .Reference.JoinAlias }}_fallbacklocale._sys_id = {{ .JoinAlias }}.{{ .Reference.ForeignKey }} AND {{ .Reference.JoinAlias }}_fallbacklocale._locale = fallbackLocaleArg
-		LEFT JOIN {{ .Reference.TableName }} {{ .Reference.JoinAlias }}_deflocale ON {{ .Reference.JoinAlias }}_deflocale._sys_id = {{ .JoinAlias }}.{{ .Reference.ForeignKey }} AND {{ .Reference.JoinAlias }}_deflocale._locale = defLocaleArg
+		{{- range FallbackJoins .Reference.JoinAlias }}
+		LEFT JOIN "{{ $c.Reference.TableName }}" "{{ .Alias }}" ON "{{ .Alias }}"._sys_id = "{{ $c.JoinAlias }}"."{{ $c.Reference.ForeignKey }}" AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $c.JoinAlias }}
+		{{- end }}
 		{{- end -}}
 		{{- end -}}
 		{{- end -}}
@@ -208,7 +258,7 @@ json_build_object('id', {{ .JoinAlias }}._sys_id) AS sys
 {{- end -}}
 --
 {{- define "query" -}}
-CREATE OR REPLACE FUNCTION {{ .TableName }}_query(localeArg TEXT, filters TEXT[], orderBy TEXT, skip INTEGER, take INTEGER)
+CREATE OR REPLACE FUNCTION "{{ .TableName }}_query"(localeArg TEXT, filters TEXT[], args TEXT[], orderBy TEXT, skip INTEGER, take INTEGER)
 RETURNS _result AS $body$
 DECLARE 
 	res _result;
@@ -223,15 +273,15 @@ BEGIN
 		qs:= qs || ' ORDER BY ' || orderBy;
 	END IF;
 
-	qs:= qs || ') AS _idx,' || '{{ .TableName }}.* FROM "mv_{{ .TableName}}_' || lower(localeArg) || '" {{ .TableName }}';
+	qs:= qs || ') AS _idx,' || '"{{ .TableName }}".* FROM ' || format('%I', 'mv_{{ .TableName }}_' || lower(localeArg)) || ' "{{ .TableName }}"';
 	
-	IF filters IS NOT NULL THEN
+	IF cardinality(filters) > 0 THEN
 		qs := qs || ' WHERE';
 		FOREACH filter IN ARRAY filters LOOP
 			if counter > 0 then
 				qs := qs || ' AND ';
 	 		end if;
-			qs := qs || ' (' || '{{ .TableName }}' || '.' || filter || ')';
+			qs := qs || ' (' || filter || ')';
 			counter := counter + 1;
 		END LOOP;
 	END IF;
@@ -247,16 +297,20 @@ BEGIN
 	qs:= qs || ') ';
 			
 	qs:= qs || 'SELECT (SELECT _count FROM filtered LIMIT 1)::INTEGER, json_agg(t)::json FROM (
-	SELECT json_build_object(''id'', {{ .TableName }}._sys_id) AS sys
+	SELECT json_build_object(''id'', "{{ .TableName }}"._sys_id, ''updatedAt'', "{{ .TableName }}"._updated_at) AS sys
 	{{- range .Columns -}}
 		,
-		{{ .TableName }}.{{ .ColumnName }} AS "{{ .Alias }}"
+		{{ if IsLocation .SqlType -}}
+		{{ LocationJSON (printf "\"%s\".\"%s\"" .TableName .ColumnName) .SqlType | QuoteLiteral }}
+		{{- else -}}
+		"{{ .TableName }}"."{{ .ColumnName }}"
+		{{- end }} AS "{{ .Alias }}"
 	{{- end }}
-	FROM filtered {{ .TableName }}';
+	FROM filtered "{{ .TableName }}"';
 
-	qs:= qs || ' ORDER BY {{ .TableName }}._idx ) t;';
+	qs:= qs || ' ORDER BY "{{ .TableName }}"._idx ) t;';
 
-	EXECUTE qs INTO res;
+	EXECUTE qs INTO res USING args;
 
 	IF res.items IS NULL THEN
 		res.items:= '[]'::JSON;
@@ -267,11 +321,13 @@ END $body$ LANGUAGE 'plpgsql';
 {{- end -}}
 --
 {{ range $i, $t := $.Functions }}
+-- the query functions without the arguments of the filters are replaced
+DROP FUNCTION IF EXISTS "{{ .TableName }}_query"(TEXT, TEXT[], TEXT, INTEGER, INTEGER);
 {{ if $.ContentSchema -}}
 DO $$
 BEGIN
 	IF EXISTS (SELECT FROM pg_tables WHERE  schemaname = '{{ $.ContentSchema }}' AND tablename  = 'game_{{ .TableName}}') THEN
-		CREATE OR REPLACE FUNCTION {{ .TableName }}_query(localeArg TEXT, filters TEXT[], orderBy TEXT, skip INTEGER, take INTEGER)
+		CREATE OR REPLACE FUNCTION "{{ .TableName }}_query"(localeArg TEXT, filters TEXT[], args TEXT[], orderBy TEXT, skip INTEGER, take INTEGER)
 		RETURNS _result AS $body$
 		DECLARE 
 			res _result;
@@ -286,15 +342,15 @@ BEGIN
 				qs:= qs || ' ORDER BY ' || orderBy;
 			END IF;
 		
-			qs:= qs || ') AS _idx,' || '{{ .TableName }}.* FROM "mv_{{ .TableName}}_' || lower(localeArg) || '" {{ .TableName }}';
+			qs:= qs || ') AS _idx,' || '"{{ .TableName }}".* FROM ' || format('%I', 'mv_{{ .TableName }}_' || lower(localeArg)) || ' "{{ .TableName }}"';
 			
-			IF filters IS NOT NULL THEN
+			IF cardinality(filters) > 0 THEN
 				qs := qs || ' WHERE';
 				FOREACH filter IN ARRAY filters LOOP
 					if counter > 0 then
 						qs := qs || ' AND ';
 					end if;
-					qs := qs || ' (' || '{{ .TableName }}' || '.' || filter || ')';
+					qs := qs || ' (' || filter || ')';
 					counter := counter + 1;
 				END LOOP;
 			END IF;
@@ -310,25 +366,27 @@ BEGIN
 			qs:= qs || ') ';
 					
 			qs:= qs || 'SELECT (SELECT _count FROM filtered LIMIT 1)::INTEGER, json_agg(t)::json FROM (
-			SELECT json_build_object(''id'', {{ .TableName }}._sys_id) AS sys
+			SELECT json_build_object(''id'', "{{ .TableName }}"._sys_id, ''updatedAt'', "{{ .TableName }}"._updated_at) AS sys
 			{{- range .Columns -}}
 				,
 				{{ if and ($.ContentSchema) (.ColumnName | Overwritable) -}}
-				COALESCE(c_{{ .TableName }}.{{ .ColumnName }}, {{ .TableName }}.{{ .ColumnName }}) AS "{{ .Alias }}"
+				COALESCE("c_{{ .TableName }}"."{{ .ColumnName }}", "{{ .TableName }}"."{{ .ColumnName }}") AS "{{ .Alias }}"
+				{{- else if IsLocation .SqlType -}}
+				{{ LocationJSON (printf "\"%s\".\"%s\"" .TableName .ColumnName) .SqlType | QuoteLiteral }} AS "{{ .Alias }}"
 				{{- else -}}
-				{{ .TableName }}.{{ .ColumnName }} AS "{{ .Alias }}"
+				"{{ .TableName }}"."{{ .ColumnName }}" AS "{{ .Alias }}"
 				{{- end -}}
 				{{- end }}
-			FROM filtered {{ .TableName }}
+			FROM filtered "{{ .TableName }}"
 			{{ if $.ContentSchema -}}
-			LEFT JOIN {{ $.ContentSchema }}."mv_game_{{ .TableName}}_' || lower(localeArg) || '" c_{{ .TableName }} ON (c_{{ .TableName }}.slug = {{ .TableName }}.slug)';
+			LEFT JOIN {{ $.ContentSchema }}.' || format('%I', 'mv_game_{{ .TableName }}_' || lower(localeArg)) || ' "c_{{ .TableName }}" ON ("c_{{ .TableName }}".slug = "{{ .TableName }}".slug)';
 			{{- else -}}
 			';
 			{{- end }}											
 
-			qs:= qs || ' ORDER BY {{ .TableName }}._idx ) t;';
+			qs:= qs || ' ORDER BY "{{ .TableName }}"._idx ) t;';
 
-			EXECUTE qs INTO res;
+			EXECUTE qs INTO res USING args;
 
 			IF res.items IS NULL THEN
 				res.items:= '[]'::JSON;
@@ -348,94 +406,108 @@ END $$;
 {{ template "query" . }}	
 {{-  end -}}
 --
-CREATE OR REPLACE FUNCTION {{ .TableName }}_view(localeArg TEXT, fallbackLocaleArg TEXT, defLocaleArg TEXT)
+-- the view functions of a single fallback locale and the default locale are replaced
+DROP FUNCTION IF EXISTS "{{ .TableName }}_view"(TEXT, TEXT, TEXT) CASCADE;
+CREATE OR REPLACE FUNCTION "{{ .TableName }}_view"(localeArg TEXT, fallbackLocalesArg TEXT[])
 RETURNS table(_id text, _sys_id text {{- range .Columns -}}
 		,
-		{{ if eq .ColumnName "limit" -}}_{{- end -}}
-		{{- .ColumnName }} {{ .SqlType -}} 
+		{{ Ident .ColumnName }} {{ .SqlType -}} 
+	{{- end -}}
+	{{- if $.SpaceColumns -}}
+	, _space text, _environment text
 	{{- end -}}
-	, _updated_at timestamp) AS $$
+	, _updated_at timestamptz) AS $$
 BEGIN
 	RETURN QUERY
 		SELECT
-			{{ .TableName }}._id AS _id,
-			{{ .TableName }}._sys_id AS _sys_id
+			"{{ .TableName }}"._id AS _id,
+			"{{ .TableName }}"._sys_id AS _sys_id
 		{{- range .Columns -}}
 			,
 			{{ if .ConTableName -}}
-				_included_{{ .Reference.JoinAlias }}.res
+				"_included_{{ .Reference.JoinAlias }}".res
 			{{- else if .IsAsset -}}
 				{{ template "assetRef" . }}
+			{{- else if .Candidates -}}
+				{{ template "candidates" . }}
 			{{- else if .Reference -}}
 				{{ template "refColumn" .Reference }}
 			{{- else -}}
 			{{ if .Localized -}}
-				COALESCE({{ .TableName }}.{{ .ColumnName }}, {{ .TableName }}_fallbacklocale.{{ .ColumnName }}, {{ .TableName }}_deflocale.{{ .ColumnName }}) 
+				{{ Coalesce .TableName .ColumnName }}
 			{{- else -}}
-				{{ .TableName }}.{{ .ColumnName }}
+				"{{ .TableName }}"."{{ .ColumnName }}"
 			{{- end -}}
 			{{- end }} AS "{{ .ColumnName }}"
 		{{- end }},
-			{{ .TableName }}._updated_at AS _updated_at
-		FROM {{ .TableName }}
+		{{- if $.SpaceColumns }}
+			"{{ .TableName }}"._space AS _space,
+			"{{ .TableName }}"._environment AS _environment,
+		{{- end }}
+			"{{ .TableName }}"._updated_at AS _updated_at
+		FROM "{{ .TableName }}"
 		{{ if .HasLocalized -}}
-		LEFT JOIN {{ .TableName }} {{ .TableName }}_fallbacklocale ON {{ .TableName }}._sys_id = {{ .TableName }}_fallbacklocale._sys_id AND {{ .TableName }}_fallbacklocale._locale = fallbackLocaleArg
-		LEFT JOIN {{ .TableName }} {{ .TableName }}_deflocale ON {{ .TableName }}._sys_id = {{ .TableName }}_deflocale._sys_id AND {{ .TableName }}_deflocale._locale = defLocaleArg
+		{{- range FallbackJoins $t.TableName }}
+		LEFT JOIN "{{ $t.TableName }}" "{{ .Alias }}" ON "{{ $t.TableName }}"._sys_id = "{{ .Alias }}"._sys_id AND "{{ .Alias }}"._locale = fallbackLocalesArg[{{ .Level }}] {{- SpaceScope .Alias $t.TableName }}
+		{{- end }}
 		{{- end }}
 		{{- range .Columns -}}
 			{{ template "join" . }}
 		{{- end }}
-		WHERE {{ .TableName }}._locale = localeArg;
+		WHERE "{{ .TableName }}"._locale = localeArg;
 END;
 $$ LANGUAGE 'plpgsql';
 --
 {{ range $i, $l := $.Locales }}
-{{ $fallbackLocale := .FallbackCode }}
-{{- if eq $fallbackLocale "" -}}
-	{{ $fallbackLocale := "en" }}
-{{- end -}}
-
-{{- if $.DropTables -}}
-CREATE MATERIALIZED VIEW IF NOT EXISTS "mv_{{ $t.TableName }}_{{ .Code | ToLower }}" AS SELECT * FROM {{ $t.TableName }}_view('{{ .Code | ToLower }}', '{{ $fallbackLocale | ToLower }}', 'en');
+{{ if $.DropTables -}}
+CREATE MATERIALIZED VIEW IF NOT EXISTS {{ MatView $t.TableName .Code | Ident }} AS SELECT *{{ if $t.Search }}, {{ SearchVector $t.Search .Code }} AS _search{{ end }} FROM "{{ $t.TableName }}_view"('{{ .Code | ToLower }}', {{ FallbackLocales .Code }});
 {{- else -}}
-CREATE MATERIALIZED VIEW IF NOT EXISTS "mv_{{ $t.TableName }}_{{ .Code | ToLower }}" AS SELECT * FROM {{ $t.TableName }}_view('{{ .Code | ToLower }}', '{{ $fallbackLocale | ToLower }}', 'en') WITH NO DATA;
+CREATE MATERIALIZED VIEW IF NOT EXISTS {{ MatView $t.TableName .Code | Ident }} AS SELECT *{{ if $t.Search }}, {{ SearchVector $t.Search .Code }} AS _search{{ end }} FROM "{{ $t.TableName }}_view"('{{ .Code | ToLower }}', {{ FallbackLocales .Code }}) WITH NO DATA;
+{{- end }}
+CREATE UNIQUE INDEX IF NOT EXISTS {{ MatViewIndex $t.TableName .Code | Ident }} ON {{ MatView $t.TableName .Code | Ident }} (_id);
+{{- if $t.Search }}
+CREATE INDEX IF NOT EXISTS {{ MatViewSearchIndex $t.TableName .Code | Ident }} ON {{ MatView $t.TableName .Code | Ident }} USING gin (_search);
+{{- end }}
+{{- range $t.Columns }}
+{{- if IsLocation .SqlType }}
+CREATE INDEX IF NOT EXISTS {{ MatViewGeoIndex $t.TableName $l.Code .ColumnName | Ident }} ON {{ MatView $t.TableName $l.Code | Ident }} USING gist ({{ .ColumnName | Ident }});
+{{- end }}
 {{- end }}
-CREATE UNIQUE INDEX IF NOT EXISTS "mv_{{ $t.TableName }}_{{ .Code | ToLower }}_idx" ON "mv_{{ $t.TableName }}_{{ .Code | ToLower }}" (_id);
 --
 {{ range $cfi, $cfl := .CFLocales }}
-CREATE OR REPLACE VIEW "mv_{{ $t.TableName }}_{{ $cfl | ToLower }}" AS SELECT * FROM "mv_{{ $t.TableName }}_{{ $l.Code | ToLower }}";
+CREATE OR REPLACE VIEW {{ MatView $t.TableName $cfl | Ident }} AS SELECT * FROM {{ MatView $t.TableName $l.Code | Ident }};
 {{- end }}
 --
 {{- end }}
 {{- end }}
 --
 {{- range $i, $t := $.DeleteTriggers }}
-CREATE OR REPLACE FUNCTION {{ .TableName }}_delete_trigger() 
+CREATE OR REPLACE FUNCTION "{{ .TableName }}_delete_trigger"() 
    RETURNS TRIGGER 
 AS $$
 BEGIN
 	{{- range $idx, $c := .ConTables }}
-	DELETE FROM {{ . }} where {{ . }}.{{ $t.TableName }} = OLD._id;
+	DELETE FROM "{{ . }}" where "{{ . }}"."{{ $t.TableName }}" = OLD._id;
 	{{- end }}
 	RETURN OLD;
 END
 $$ LANGUAGE 'plpgsql';
 --
-DROP TRIGGER IF EXISTS {{ .TableName }}_delete
-ON {{ .TableName }};
+DROP TRIGGER IF EXISTS "{{ .TableName }}_delete"
+ON "{{ .TableName }}";
 
-CREATE TRIGGER {{ .TableName }}_delete 
+CREATE TRIGGER "{{ .TableName }}_delete" 
 AFTER DELETE 
-ON {{ .TableName }} 
+ON "{{ .TableName }}" 
 FOR EACH ROW 
-EXECUTE PROCEDURE {{ .TableName }}_delete_trigger();
+EXECUTE PROCEDURE "{{ .TableName }}_delete_trigger"();
 
 {{- end }}
 `
 
 const pgFuncPublishTemplate = `
 {{ range $i, $t := $.Functions }}
-	DROP VIEW IF EXISTS "mv_{{ $t.TableName }}_{{ $.Locale | ToLower }}"; 
-	CREATE OR REPLACE VIEW "mv_{{ $t.TableName }}_{{ $.Locale | ToLower }}" AS SELECT * FROM "mv_{{ $t.TableName }}_{{ $.FallbackLocale | ToLower }}";
+	DROP VIEW IF EXISTS {{ MatView $t.TableName $.Locale | Ident }}; 
+	CREATE OR REPLACE VIEW {{ MatView $t.TableName $.Locale | Ident }} AS SELECT * FROM {{ MatView $t.TableName $.FallbackLocale | Ident }};
 {{- end }}
 `
@@ -29,6 +29,7 @@ const (
 	pathContentType         = pathContentTypes + "/%s"
 	pathContentTypesPublish = pathContentType + "/published"
 	pathLocales             = pathSpaces + "/locales"
+	pathEnvironmentLocales  = pathSpaces + pathEnvironments + "/locales"
 
 	headerContentfulContentType  = "X-Contentful-Content-Type"
 	headerContentfulVersion      = "X-Contentful-Version"
@@ -166,9 +167,10 @@ func (c *Client) do(req *http.Request) ([]byte, error) {
 		c.AfterRequest(c, req, res, time.Since(start))
 	}
 
+	// clear headers so that they will not infect the next request (a failed one as well).
+	c.headers = getHeadersMap(c.Options.OrgID)
+
 	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusBadRequest {
-		// clear headers so that they will not infect the next request.
-		c.headers = getHeadersMap(c.Options.OrgID)
 		// return the response
 		return io.ReadAll(res.Body)
 	}
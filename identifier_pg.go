@@ -0,0 +1,195 @@
+package gontentful
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// pgMaxIdentifierLength is the longest identifier postgres keeps, NAMEDATALEN - 1
+	pgMaxIdentifierLength = 63
+	// the table names and the join aliases are suffixed by the fallback joins of the
+	// views (_fallback1...), the room left is kept from the former _fallbacklocale suffix
+	// so the shortened names don't change
+	pgMaxAliasLength = pgMaxIdentifierLength - len("_fallbacklocale")
+)
+
+var pgIdentifierFuncMap = template.FuncMap{
+	"Ident":              pgQuoteIdent,
+	"MatView":            pgMatViewName,
+	"MatViewIndex":       pgMatViewIndexName,
+	"MatViewSearchIndex": pgMatViewSearchIndexName,
+	"MatViewGeoIndex":    pgMatViewGeoIndexName,
+	"Index":              pgIndexName,
+}
+
+// pgIdentifier shortens the names longer than max: the kept prefix is followed by the
+// hash of the whole name, the same name is always shortened the same way
+func pgIdentifier(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	return name[:max-len(suffix)] + suffix
+}
+
+// pgQuoteIdent quotes the identifier, the reserved words (order, user, group...) and
+// the content type ids with - or . are valid names then
+func pgQuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// pgTableName returns the table of the content type
+func pgTableName(contentType string) string {
+	return pgIdentifier(toSnakeCase(contentType), pgMaxAliasLength)
+}
+
+// pgColumnName returns the column of the field
+func pgColumnName(fieldID string) string {
+	return pgIdentifier(toSnakeCase(fieldID), pgMaxIdentifierLength)
+}
+
+// pgMatViewName returns the materialized view of the table in the locale. It is not
+// shortened, the query functions build the name from the locale argument.
+func pgMatViewName(tableName string, locale string) string {
+	return fmt.Sprintf("mv_%s_%s", tableName, strings.ToLower(locale))
+}
+
+// pgMatViewIndexName returns the unique index of the materialized view
+func pgMatViewIndexName(tableName string, locale string) string {
+	return pgIdentifier(pgMatViewName(tableName, locale)+"_idx", pgMaxIdentifierLength)
+}
+
+// pgMatViewSearchIndexName returns the full-text search index of the materialized view
+func pgMatViewSearchIndexName(tableName string, locale string) string {
+	return pgIdentifier(pgMatViewName(tableName, locale)+"_search_idx", pgMaxIdentifierLength)
+}
+
+// pgMatViewGeoIndexName returns the gist index of the location column of the
+// materialized view
+func pgMatViewGeoIndexName(tableName string, locale string, column string) string {
+	return pgIdentifier(pgMatViewName(tableName, locale)+"_"+column+"_geo_idx", pgMaxIdentifierLength)
+}
+
+// pgIndexName returns the index named by the parts joined with _
+func pgIndexName(parts ...string) string {
+	return pgIdentifier("idx_"+strings.Join(parts, "_"), pgMaxIdentifierLength)
+}
+
+// pgIdentifiers detects the names generated for different content types, fields
+// or joins which are the same after the snake casing or the truncation
+type pgIdentifiers struct {
+	names map[string]string
+}
+
+func newPGIdentifiers() *pgIdentifiers {
+	return &pgIdentifiers{
+		names: make(map[string]string),
+	}
+}
+
+// add registers the identifier of the source in the scope (the schema or a table)
+func (ids *pgIdentifiers) add(scope string, name string, source string) error {
+	key := scope + "." + name
+	if prev, ok := ids.names[key]; ok && prev != source {
+		if scope == "" {
+			return fmt.Errorf("%s and %s are both named %s", prev, source, pgQuoteIdent(name))
+		}
+		return fmt.Errorf("%s and %s are both named %s in %s", prev, source, pgQuoteIdent(name), pgQuoteIdent(scope))
+	}
+	ids.names[key] = source
+	return nil
+}
+
+// validatePGIdentifiers returns an error if two tables, two columns of a table, two
+// materialized views or two joins of a view have the same name
+func validatePGIdentifiers(schema *PGSQLSchema) error {
+	ids := newPGIdentifiers()
+	for _, name := range []string{ASSET_TABLE_NAME, "_schema", "table_references"} {
+		ids.add("", name, fmt.Sprintf("the %s table", name))
+	}
+	contentTypes := make(map[string]string)
+	for _, tbl := range schema.Tables {
+		contentTypes[tbl.TableName] = tbl.Schema.ID
+		err := ids.add("", tbl.TableName, fmt.Sprintf("the content type %q", tbl.Schema.ID))
+		if err != nil {
+			return err
+		}
+		for _, l := range schema.Locales {
+			for _, code := range append([]string{l.Code}, l.CFLocales...) {
+				mv := pgMatViewName(tbl.TableName, code)
+				if len(mv) > pgMaxIdentifierLength {
+					return fmt.Errorf("the %s view of %q is longer than %d characters: %s", code, tbl.Schema.ID, pgMaxIdentifierLength, mv)
+				}
+				err = ids.add("", mv, fmt.Sprintf("the %s view of %q", code, tbl.Schema.ID))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		// the indexes and the tables share the names of the schema
+		for _, idx := range tbl.Indexes {
+			err = ids.add("", idx.Name, fmt.Sprintf("the index on %s of %q", idx.Columns, tbl.Schema.ID))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	conSources := make(map[string]string)
+	for _, proc := range schema.Functions {
+		for _, col := range proc.Columns {
+			source := fmt.Sprintf("the field %q of %q", col.Alias, contentTypes[proc.TableName])
+			err := ids.add(proc.TableName, col.ColumnName, source)
+			if err != nil {
+				return err
+			}
+			if col.ConTableName != "" {
+				conSources[col.ConTableName] = "the connection table of " + source
+			}
+		}
+		err := addPGJoinAliases(ids, proc.TableName, proc.Columns)
+		if err != nil {
+			return err
+		}
+	}
+	for _, tbl := range schema.ConTables {
+		source := conSources[tbl.TableName]
+		if source == "" {
+			source = fmt.Sprintf("the connection table %s", tbl.TableName)
+		}
+		err := ids.add("", tbl.TableName, source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPGJoinAliases registers the aliases of the tables joined by the view of the table
+func addPGJoinAliases(ids *pgIdentifiers, tableName string, columns []*PGSQLProcedureColumn) error {
+	for _, col := range columns {
+		refs := make([]*PGSQLProcedureReference, 0)
+		if col.Reference != nil {
+			refs = append(refs, col.Reference)
+		}
+		for _, c := range col.Candidates {
+			refs = append(refs, c.Reference)
+		}
+		for _, ref := range refs {
+			source := fmt.Sprintf("the join of %s on %s", ref.TableName, ref.ForeignKey)
+			err := ids.add(tableName+"_view", ref.JoinAlias, source)
+			if err != nil {
+				return err
+			}
+			err = addPGJoinAliases(ids, tableName, ref.Columns)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package gontentful
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPGIdentifier(t *testing.T) {
+	if got := pgIdentifier("game", pgMaxIdentifierLength); got != "game" {
+		t.Errorf("short name changed: %s", got)
+	}
+	long := strings.Repeat("a", 70)
+	got := pgIdentifier(long, pgMaxIdentifierLength)
+	if len(got) != pgMaxIdentifierLength || !strings.HasPrefix(got, strings.Repeat("a", 50)) {
+		t.Errorf("unexpected shortened name %s", got)
+	}
+	if pgIdentifier(long, pgMaxIdentifierLength) != got {
+		t.Errorf("shortened name is not deterministic")
+	}
+	if pgIdentifier(long+"b", pgMaxIdentifierLength) == got {
+		t.Errorf("names with the same prefix are shortened the same way")
+	}
+	if got := pgQuoteIdent(`a"b`); got != `"a""b"` {
+		t.Errorf("unexpected quoted identifier %s", got)
+	}
+}
+
+func TestPGIdentifierCollisions(t *testing.T) {
+	types := []*ContentType{
+		{Sys: &Sys{ID: "gameTag"}, Fields: []*ContentTypeField{{ID: "name", Type: "Symbol"}}},
+		{Sys: &Sys{ID: "game_tag"}, Fields: []*ContentTypeField{{ID: "name", Type: "Symbol"}}},
+	}
+	_, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), `"gameTag"`) || !strings.Contains(err.Error(), `"game_tag"`) {
+		t.Errorf("table collision not detected: %v", err)
+	}
+
+	types = []*ContentType{
+		{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{{ID: "releaseDate", Type: "Date"}, {ID: "release_date", Type: "Date"}}},
+	}
+	_, err = NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), `"release_date" in "game"`) {
+		t.Errorf("column collision not detected: %v", err)
+	}
+
+	types = []*ContentType{{Sys: &Sys{ID: "tableReferences"}}}
+	_, err = NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "table_references") {
+		t.Errorf("reserved table collision not detected: %v", err)
+	}
+}
+
+func TestPGIdentifiersQuoted(t *testing.T) {
+	long := "aVeryLongContentTypeIdentifierThatDoesNotFitPostgres"
+	types := []*ContentType{
+		{Sys: &Sys{ID: "order"}, Fields: []*ContentTypeField{
+			{ID: "limit", Type: "Integer"},
+			{ID: "user", Type: "Link", LinkType: "Entry", Validations: []*FieldValidation{{LinkContentType: []string{long}}}},
+		}},
+		{Sys: &Sys{ID: long}, Fields: []*ContentTypeField{{ID: "name", Type: "Symbol"}}},
+	}
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableName := pgTableName(long)
+	if len(tableName) > pgMaxAliasLength || !strings.HasPrefix(tableName, "a_very_long_content_type") {
+		t.Errorf("unexpected table name %s", tableName)
+	}
+
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias := getJoinAlias("", "user", tableName)
+	for _, want := range []string{
+		`CREATE OR REPLACE FUNCTION "order_view"`,
+		`"limit" integer`,
+		`"order"."limit"`,
+		`LEFT JOIN "` + tableName + `" "` + alias + `" ON "` + alias + `"._sys_id = "order"."user"`,
+	} {
+		if !strings.Contains(funcs, want) {
+			t.Errorf("missing %q in:\n%s", want, funcs)
+		}
+	}
+	if strings.Contains(funcs, "_limit") {
+		t.Errorf("renamed limit column:\n%s", funcs)
+	}
+
+	tables, err := schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tables, `CREATE TABLE IF NOT EXISTS "order" (`) || !strings.Contains(tables, `CREATE INDEX IF NOT EXISTS "idx_order__locale" ON "order"(_locale);`) {
+		t.Errorf("unexpected tables:\n%s", tables)
+	}
+}
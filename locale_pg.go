@@ -0,0 +1,106 @@
+package gontentful
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pgFallbackJoin is a join of the view on the rows of a fallback locale, the level is
+// the index of the locale in the fallbackLocalesArg array of the view function
+type pgFallbackJoin struct {
+	Alias string
+	Level int
+}
+
+// pgFallbackChains returns the fallback locales of each locale in order, following the
+// fallback codes to the end like the CDA: a locale without fallback code has none
+func pgFallbackChains(locales []*Locale) (map[string][]string, error) {
+	byCode := make(map[string]*Locale)
+	for _, l := range locales {
+		byCode[strings.ToLower(l.Code)] = l
+	}
+	chains := make(map[string][]string)
+	for _, l := range locales {
+		code := strings.ToLower(l.Code)
+		seen := map[string]bool{code: true}
+		chain := make([]string, 0)
+		for fb := l.FallbackCode; fb != ""; {
+			fbCode := strings.ToLower(fb)
+			if seen[fbCode] {
+				return nil, fmt.Errorf("the fallback locales of %s form a cycle: %s -> %s", l.Code, strings.Join(append([]string{code}, chain...), " -> "), fbCode)
+			}
+			next := byCode[fbCode]
+			if next == nil {
+				return nil, fmt.Errorf("the fallback locale %s of %s is unknown", fb, l.Code)
+			}
+			seen[fbCode] = true
+			chain = append(chain, fbCode)
+			fb = next.FallbackCode
+		}
+		chains[code] = chain
+	}
+	return chains, nil
+}
+
+// pgFallbackDepth returns the length of the longest fallback chain, the number of
+// fallback joins of the views
+func pgFallbackDepth(chains map[string][]string) int {
+	depth := 0
+	for _, chain := range chains {
+		if len(chain) > depth {
+			depth = len(chain)
+		}
+	}
+	return depth
+}
+
+// pgFallbackJoins returns the fallback joins of the alias
+func pgFallbackJoins(alias string, depth int) []*pgFallbackJoin {
+	joins := make([]*pgFallbackJoin, 0)
+	for level := 1; level <= depth; level++ {
+		joins = append(joins, &pgFallbackJoin{
+			Alias: fmt.Sprintf("%s_fallback%d", alias, level),
+			Level: level,
+		})
+	}
+	return joins
+}
+
+// pgFallbackCoalesce returns the column of the first locale of the chain having a value
+func pgFallbackCoalesce(alias string, column string, depth int) string {
+	cols := []string{fmt.Sprintf("%s.%s", pgQuoteIdent(alias), pgQuoteIdent(column))}
+	for _, j := range pgFallbackJoins(alias, depth) {
+		cols = append(cols, fmt.Sprintf("%s.%s", pgQuoteIdent(j.Alias), pgQuoteIdent(column)))
+	}
+	return fmt.Sprintf("COALESCE(%s)", strings.Join(cols, ", "))
+}
+
+// pgFallbackRow returns the column of the first locale of the chain having the row,
+// or the locale itself without any
+func pgFallbackRow(alias string, check string, column string, depth int) string {
+	var sb strings.Builder
+	sb.WriteString("(CASE")
+	for _, a := range append([]string{alias}, pgFallbackAliases(alias, depth)...) {
+		sb.WriteString(fmt.Sprintf(" WHEN %[1]s.%[2]s IS NOT NULL THEN %[1]s.%[3]s", pgQuoteIdent(a), pgQuoteIdent(check), pgQuoteIdent(column)))
+	}
+	sb.WriteString(fmt.Sprintf(" ELSE %s.%s END)", pgQuoteIdent(alias), pgQuoteIdent(column)))
+	return sb.String()
+}
+
+// pgFallbackAliases returns the aliases of the fallback joins
+func pgFallbackAliases(alias string, depth int) []string {
+	aliases := make([]string, 0)
+	for _, j := range pgFallbackJoins(alias, depth) {
+		aliases = append(aliases, j.Alias)
+	}
+	return aliases
+}
+
+// pgFallbackArray returns the fallback locales argument of the view function
+func pgFallbackArray(chain []string) string {
+	codes := make([]string, 0)
+	for _, c := range chain {
+		codes = append(codes, "'"+strings.ReplaceAll(c, "'", "''")+"'")
+	}
+	return fmt.Sprintf("ARRAY[%s]::text[]", strings.Join(codes, ", "))
+}
@@ -0,0 +1,79 @@
+package gontentful
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPGFallbackChains(t *testing.T) {
+	chains, err := pgFallbackChains([]*Locale{
+		{Code: "en", Default: true},
+		{Code: "en-GB", FallbackCode: "en"},
+		{Code: "de-DE", FallbackCode: "en-GB"},
+		{Code: "de-AT", FallbackCode: "de-DE"},
+		{Code: "fi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"en":    {},
+		"en-gb": {"en"},
+		"de-de": {"en-gb", "en"},
+		"de-at": {"de-de", "en-gb", "en"},
+		"fi":    {},
+	}
+	if !reflect.DeepEqual(chains, want) {
+		t.Errorf("unexpected chains %v", chains)
+	}
+	if depth := pgFallbackDepth(chains); depth != 3 {
+		t.Errorf("unexpected depth %d", depth)
+	}
+
+	_, err = pgFallbackChains([]*Locale{{Code: "en", FallbackCode: "de"}, {Code: "de", FallbackCode: "sv"}, {Code: "sv", FallbackCode: "de"}})
+	if err == nil || !strings.Contains(err.Error(), "cycle: en -> de -> sv -> de") {
+		t.Errorf("cycle not detected: %v", err)
+	}
+	_, err = pgFallbackChains([]*Locale{{Code: "en", FallbackCode: "de"}})
+	if err == nil {
+		t.Error("unknown fallback locale accepted")
+	}
+}
+
+func TestPGFallbackViews(t *testing.T) {
+	locales := []*Locale{
+		{Code: "en", Default: true},
+		{Code: "de-DE", FallbackCode: "en"},
+		{Code: "de-AT", FallbackCode: "de-DE", CFLocales: []string{"at"}},
+	}
+	schema, err := NewPGSQLSchema("public", locales, "", schemaConfigTestTypes(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`DROP FUNCTION IF EXISTS "game_view"(TEXT, TEXT, TEXT) CASCADE;`,
+		`CREATE OR REPLACE FUNCTION "game_view"(localeArg TEXT, fallbackLocalesArg TEXT[])`,
+		`COALESCE("game"."name", "game_fallback1"."name", "game_fallback2"."name") AS "name"`,
+		`LEFT JOIN "game" "game_fallback2" ON "game"._sys_id = "game_fallback2"._sys_id AND "game_fallback2"._locale = fallbackLocalesArg[2]`,
+		`FROM "game_view"('en', ARRAY[]::text[]) WITH NO DATA;`,
+		`FROM "game_view"('de-at', ARRAY['de-de', 'en']::text[]) WITH NO DATA;`,
+		`CREATE OR REPLACE VIEW "mv_game_at" AS SELECT * FROM "mv_game_de-at";`,
+	} {
+		if !strings.Contains(funcs, want) {
+			t.Errorf("missing %q in:\n%s", want, funcs)
+		}
+	}
+	if strings.Contains(funcs, "--CREATE") || strings.Contains(funcs, "'en')") {
+		t.Errorf("unexpected view:\n%s", funcs)
+	}
+
+	_, err = NewPGSQLSchema("public", []*Locale{{Code: "en", FallbackCode: "de"}, {Code: "de", FallbackCode: "en"}}, "", schemaConfigTestTypes(), 0, nil)
+	if err == nil {
+		t.Error("fallback cycle accepted")
+	}
+}
@@ -29,3 +29,17 @@ func (s *LocalesService) GetLocales() (*Locales, error) {
 	}
 	return res, nil
 }
+
+func (s *LocalesService) GetCMALocales() (*Locales, error) {
+	path := fmt.Sprintf(pathEnvironmentLocales, s.client.Options.SpaceID, s.client.Options.EnvironmentID)
+	data, err := s.client.getCMA(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := &Locales{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,140 @@
+package gontentful
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// earthRadiusKm is the mean radius of the earth the distances are calculated with
+	earthRadiusKm = 6371.0
+	// kmPerDegree is the length of a degree of latitude
+	kmPerDegree = 111.32
+)
+
+// geoPoint is the {lat, lon} value of a Location field
+type geoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+// geoArea is the area of a within filter: the box of the bottom left and top right
+// corners or the circle of the center and the radius in km
+type geoArea struct {
+	Box      bool
+	Min      geoPoint
+	Max      geoPoint
+	Center   geoPoint
+	RadiusKm float64
+}
+
+// parseGeoNear parses the lat,lon value of a near filter
+func parseGeoNear(value string) (geoPoint, error) {
+	coords, err := parseGeoCoords(value)
+	if err != nil || len(coords) != 2 {
+		return geoPoint{}, fmt.Errorf("invalid location %q, expected lat,lon", value)
+	}
+	return newGeoPoint(coords[0], coords[1], value)
+}
+
+// parseGeoWithin parses the value of a within filter, the lat,lon of the bottom left and
+// top right corners of a box or the lat,lon,radius in km of a circle
+func parseGeoWithin(value string) (*geoArea, error) {
+	coords, err := parseGeoCoords(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid area %q: %s", value, err.Error())
+	}
+	switch len(coords) {
+	case 3:
+		center, err := newGeoPoint(coords[0], coords[1], value)
+		if err != nil {
+			return nil, err
+		}
+		if coords[2] <= 0 {
+			return nil, fmt.Errorf("invalid area %q, the radius is not positive", value)
+		}
+		return &geoArea{Center: center, RadiusKm: coords[2]}, nil
+	case 4:
+		min, err := newGeoPoint(coords[0], coords[1], value)
+		if err != nil {
+			return nil, err
+		}
+		max, err := newGeoPoint(coords[2], coords[3], value)
+		if err != nil {
+			return nil, err
+		}
+		if min.Lat > max.Lat || min.Lon > max.Lon {
+			return nil, fmt.Errorf("invalid area %q, expected the bottom left and top right corners", value)
+		}
+		return &geoArea{Box: true, Min: min, Max: max}, nil
+	}
+	return nil, fmt.Errorf("invalid area %q, expected lat,lon,lat,lon or lat,lon,radius", value)
+}
+
+func parseGeoCoords(value string) ([]float64, error) {
+	res := make([]float64, 0)
+	for _, v := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("not a number: %s", v)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+func newGeoPoint(lat float64, lon float64, value string) (geoPoint, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return geoPoint{}, fmt.Errorf("invalid location %q, the coordinates are out of range", value)
+	}
+	return geoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// geoLocation returns the point of a {lat, lon} field value
+func geoLocation(v interface{}) (geoPoint, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return geoPoint{}, false
+	}
+	lat, latOK := m["lat"].(float64)
+	lon, lonOK := m["lon"].(float64)
+	return geoPoint{Lat: lat, Lon: lon}, latOK && lonOK
+}
+
+// distanceKm returns the great-circle distance of the points (haversine)
+func (p geoPoint) distanceKm(q geoPoint) float64 {
+	dLat := (q.Lat - p.Lat) * math.Pi / 180
+	dLon := (q.Lon - p.Lon) * math.Pi / 180
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(p.Lat*math.Pi/180)*math.Cos(q.Lat*math.Pi/180)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// contains tells whether the point is in the box or the circle
+func (a *geoArea) contains(p geoPoint) bool {
+	if a.Box {
+		return p.Lat >= a.Min.Lat && p.Lat <= a.Max.Lat && p.Lon >= a.Min.Lon && p.Lon <= a.Max.Lon
+	}
+	return a.Center.distanceKm(p) <= a.RadiusKm
+}
+
+// bounds returns the box around the circle, the longitudes span the whole earth near
+// the poles and across the antimeridian
+func (a *geoArea) bounds() (geoPoint, geoPoint) {
+	if a.Box {
+		return a.Min, a.Max
+	}
+	dLat := a.RadiusKm / kmPerDegree
+	min := geoPoint{Lat: math.Max(-90, a.Center.Lat-dLat), Lon: -180}
+	max := geoPoint{Lat: math.Min(90, a.Center.Lat+dLat), Lon: 180}
+	cos := math.Cos(a.Center.Lat * math.Pi / 180)
+	if cos > 0.01 {
+		dLon := a.RadiusKm / (kmPerDegree * cos)
+		if a.Center.Lon-dLon >= -180 && a.Center.Lon+dLon <= 180 {
+			min.Lon = a.Center.Lon - dLon
+			max.Lon = a.Center.Lon + dLon
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,46 @@
+package gontentful
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeoDistance(t *testing.T) {
+	berlin := geoPoint{Lat: 52.52, Lon: 13.405}
+	paris := geoPoint{Lat: 48.8566, Lon: 2.3522}
+	if d := berlin.distanceKm(paris); math.Abs(d-878) > 2 {
+		t.Errorf("unexpected distance %f", d)
+	}
+	if d := berlin.distanceKm(berlin); d != 0 {
+		t.Errorf("unexpected distance %f", d)
+	}
+}
+
+func TestGeoWithin(t *testing.T) {
+	tests := []struct {
+		area  string
+		point geoPoint
+		want  bool
+	}{
+		{"52,13,53,14", geoPoint{Lat: 52.52, Lon: 13.405}, true},
+		{"52,13,53,14", geoPoint{Lat: 48.8566, Lon: 2.3522}, false},
+		{"52.52,13.405,900", geoPoint{Lat: 48.8566, Lon: 2.3522}, true},
+		{"52.52,13.405,800", geoPoint{Lat: 48.8566, Lon: 2.3522}, false},
+		{"0,179.9,50", geoPoint{Lat: 0, Lon: -179.9}, true},
+	}
+	for _, tt := range tests {
+		area, err := parseGeoWithin(tt.area)
+		if err != nil {
+			t.Errorf("%s: %s", tt.area, err)
+			continue
+		}
+		if area.contains(tt.point) != tt.want {
+			t.Errorf("%s contains %v: got %v", tt.area, tt.point, !tt.want)
+		}
+		// the bounds of the area contain the points of the area
+		min, max := area.bounds()
+		if tt.want && (tt.point.Lat < min.Lat || tt.point.Lat > max.Lat || tt.point.Lon < min.Lon || tt.point.Lon > max.Lon) {
+			t.Errorf("%s bounds %v %v without %v", tt.area, min, max, tt.point)
+		}
+	}
+}
@@ -32,7 +32,7 @@ func (s *PGMatViews) Exec(databaseURL string, schemaName string) error {
 	funcMap := template.FuncMap{
 		"ToLower": strings.ToLower,
 	}
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgRefreshMatViewsTemplate)
+	tmpl, err := template.New("").Funcs(pgIdentifierFuncMap).Funcs(funcMap).Parse(pgRefreshMatViewsTemplate)
 	if err != nil {
 		return err
 	}
@@ -53,11 +53,11 @@ func (s *PGMatViews) ExecPublish(databaseURL string, schemaName string, tableNam
 		"ToLower": strings.ToLower,
 	}
 
-	tableNames, err := getDependencies(databaseURL, schemaName, toSnakeCase(tableName))
+	tableNames, err := getDependencies(databaseURL, schemaName, pgTableName(tableName))
 	if err != nil {
 		return "", err
 	}
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgRefreshMatViewsTemplate)
+	tmpl, err := template.New("").Funcs(pgIdentifierFuncMap).Funcs(funcMap).Parse(pgRefreshMatViewsTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -71,7 +71,7 @@ func (s *PGMatViews) ExecPublish(databaseURL string, schemaName string, tableNam
 	for _, tn := range tableNames {
 		params = append(params, &PGMatView{
 			Locales:   s.Schema.Locales,
-			TableName: toSnakeCase(tn),
+			TableName: pgTableName(tn),
 		})
 	}
 
@@ -80,6 +80,45 @@ func (s *PGMatViews) ExecPublish(databaseURL string, schemaName string, tableNam
 	return fmt.Sprintf("refreshing content types (%s) materialized views started for locales: %s", strings.Join(tableNames, ","), strings.Join(locales, ",")), nil
 }
 
+// ExecTables refreshes the materialized views of the changed tables and of the tables
+// depending on them, it returns the refreshed table names.
+func (s *PGMatViews) ExecTables(databaseURL string, schemaName string, changedTables []string) ([]string, error) {
+	funcMap := template.FuncMap{
+		"ToLower": strings.ToLower,
+	}
+	tmpl, err := template.New("").Funcs(pgIdentifierFuncMap).Funcs(funcMap).Parse(pgRefreshMatViewsTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	added := make(map[string]bool)
+	params := make([]*PGMatView, 0)
+	for _, changed := range changedTables {
+		tableNames, err := getDependencies(databaseURL, schemaName, pgTableName(changed))
+		if err != nil {
+			return nil, err
+		}
+		for _, tn := range tableNames {
+			tn = pgTableName(tn)
+			if added[tn] {
+				continue
+			}
+			added[tn] = true
+			params = append(params, &PGMatView{
+				Locales:   s.Schema.Locales,
+				TableName: tn,
+			})
+		}
+	}
+
+	tableNames := make([]string, 0)
+	for _, p := range params {
+		tableNames = append(tableNames, p.TableName)
+	}
+
+	return tableNames, doRefresh(databaseURL, schemaName, tmpl, params)
+}
+
 func getDependencies(databaseURL string, schemaName string, tableName string) ([]string, error) {
 	db, err := sqlx.Connect("postgres", databaseURL)
 	if err != nil {
@@ -16,7 +16,7 @@ const (
 	copyTableTpl = `INSERT INTO %[1]s.%[3]s SELECT * FROM %[2]s.%[3]s;`
 )
 
-func MigratePGSQL(databaseURL string, newSchemaName string, locales []*Locale, types []*ContentType, cmaTypes []*ContentType, entries []*Entry, syncToken string, createFunctions bool, incrementalMigration bool) error {
+func MigratePGSQL(databaseURL string, newSchemaName string, locales []*Locale, types []*ContentType, cmaTypes []*ContentType, entries []*Entry, syncToken string, createFunctions bool, incrementalMigration bool, cfg *PGSchemaConfig, opts *PGSyncOptions) error {
 
 	var err error
 	if !incrementalMigration {
@@ -29,7 +29,10 @@ func MigratePGSQL(databaseURL string, newSchemaName string, locales []*Locale, t
 	}
 
 	// 1) re-create schema
-	schema := NewPGSQLSchema(newSchemaName, locales, "", cmaTypes, 0)
+	schema, err := NewPGSQLSchema(newSchemaName, locales, "", cmaTypes, 0, cfg)
+	if err != nil {
+		return err
+	}
 	schema.DropTables = incrementalMigration
 	err = schema.Exec(databaseURL)
 	if err != nil {
@@ -37,7 +40,7 @@ func MigratePGSQL(databaseURL string, newSchemaName string, locales []*Locale, t
 	}
 
 	// 2) sync data & save token
-	sync := NewPGSyncSchema(newSchemaName, locales, types, entries, true)
+	sync := NewPGSyncSchema(newSchemaName, locales, cfg.ApplyContentTypes(types), entries, true, opts)
 	err = sync.Exec(databaseURL)
 	if err != nil {
 		return err
@@ -68,7 +71,7 @@ func MigratePGSQL(databaseURL string, newSchemaName string, locales []*Locale, t
 	return nil
 }
 
-func MigrateGamesPGSQL(databaseURL string, newSchemaName string, contentSchemaName string, locales []*Locale, types []*ContentType, cmaTypes []*ContentType, entries []*Entry, syncToken string) error {
+func MigrateGamesPGSQL(databaseURL string, newSchemaName string, contentSchemaName string, locales []*Locale, types []*ContentType, cmaTypes []*ContentType, entries []*Entry, syncToken string, opts *PGSyncOptions) error {
 
 	// 0) drop newSchema if exists
 	drop := NewPGDrop(newSchemaName)
@@ -78,7 +81,10 @@ func MigrateGamesPGSQL(databaseURL string, newSchemaName string, contentSchemaNa
 	}
 
 	// 1) re-create schema
-	schema := NewPGSQLSchema(newSchemaName, locales, "", cmaTypes, 0)
+	schema, err := NewPGSQLSchema(newSchemaName, locales, "", cmaTypes, 0, nil)
+	if err != nil {
+		return err
+	}
 	schema.ContentSchema = contentSchemaName
 	err = schema.Exec(databaseURL)
 	if err != nil {
@@ -86,7 +92,7 @@ func MigrateGamesPGSQL(databaseURL string, newSchemaName string, contentSchemaNa
 	}
 
 	// 2) sync data & save token
-	sync := NewPGSyncSchema(newSchemaName, locales, types, entries, true)
+	sync := NewPGSyncSchema(newSchemaName, locales, types, entries, true, opts)
 	err = sync.Exec(databaseURL)
 	if err != nil {
 		return err
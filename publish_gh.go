@@ -12,45 +12,56 @@ import (
 
 type GHPublish struct {
 	Entry           *PublishedEntry
-	RepoName        string
+	Repo            ContentRepository
 	FileName        string
 	Locales         []*Locale
 	LocalizedFields map[string]bool
+	// AssetURLs rewrites the asset urls, created from the assets config of moonbase.yaml when nil
+	AssetURLs AssetURLRewriter
 }
 
-func NewGHPublish(entry *PublishedEntry, repoName, fileName string, locales *Locales, localizedFields map[string]bool) *GHPublish {
+func NewGHPublish(entry *PublishedEntry, repo ContentRepository, fileName string, locales *Locales, localizedFields map[string]bool) *GHPublish {
 	return &GHPublish{
 		Entry:           entry,
-		RepoName:        repoName,
+		Repo:            repo,
 		FileName:        fileName,
 		Locales:         locales.Items,
 		LocalizedFields: localizedFields,
 	}
 }
 
-func getDeleteEntries(ctx context.Context, cfg *Config, s *GHPublish, folderName string) ([]gh.BlobEntry, error) {
+func getDeleteEntries(ctx context.Context, r ContentRepository, cfg *Config, s *GHPublish, folderName string) ([]gh.BlobEntry, error) {
 	path := filepath.Join(cfg.WorkDir, folderName, s.FileName)
-	return gh.GetDeleteFileEntries(ctx, cfg.Token, owner, s.RepoName, branch, path, "feat(content): delete files")
+	return r.DeleteEntries(ctx, path)
 }
 
-func getAssetImages(ctx context.Context, cfg *Config, repo, url string) (*string, error) {
+func getAssetImages(ctx context.Context, r ContentRepository, url string) (*string, error) {
 	// download image
 	imageContent, err := downloadImage(url)
 	if err != nil {
 		return nil, err
 	}
 	// create the blobs with the image's content (encoding base64)
-	encoding := "base64"
-	blob, _, err := gh.CreateBlob(ctx, cfg.Token, owner, repo, branch, &imageContent, &encoding)
+	sha, err := r.CreateBlob(ctx, imageContent, "base64")
 	if err != nil {
 		return nil, err
 	}
-	return blob.SHA, nil
+	return &sha, nil
 }
 
-func (s *GHPublish) Exec(fmtVideoURL func(string) string) ([]gh.BlobEntry, error) {
+func (s *GHPublish) Exec() ([]gh.BlobEntry, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, s.RepoName, branch)
+	r := s.Repo
+	cfg := getConfig(ctx, r)
+
+	assetURLs := s.AssetURLs
+	if assetURLs == nil {
+		var err error
+		assetURLs, err = NewAssetURLRewriter(cfg.Assets, r.Name())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	entryType := s.Entry.Sys.Type
 	entries := make([]gh.BlobEntry, 0)
@@ -58,14 +69,17 @@ func (s *GHPublish) Exec(fmtVideoURL func(string) string) ([]gh.BlobEntry, error
 
 	switch entryType {
 	case DELETED_ASSET:
-		return getDeleteEntries(ctx, cfg, s, ASSET_TABLE_NAME)
+		return getDeleteEntries(ctx, r, cfg, s, ASSET_TABLE_NAME)
 	case DELETED_ENTRY:
-		return getDeleteEntries(ctx, cfg, s, s.Entry.Sys.ContentType.Sys.ID)
+		return getDeleteEntries(ctx, r, cfg, s, s.Entry.Sys.ContentType.Sys.ID)
 	case ASSET:
 		folderName = ASSET_TABLE_NAME
+		if !assetURLs.Mirrored() {
+			break
+		}
 		imageURLs := getAssetImageURL(s.Entry)
 		for fn, url := range imageURLs {
-			sha, err := getAssetImages(ctx, cfg, s.RepoName, url)
+			sha, err := getAssetImages(ctx, r, url)
 			if err != nil {
 				return nil, err
 			}
@@ -79,10 +93,9 @@ func (s *GHPublish) Exec(fmtVideoURL func(string) string) ([]gh.BlobEntry, error
 		folderName = s.Entry.Sys.ContentType.Sys.ID
 	}
 
-	cflId := GetCloudflareImagesID(s.RepoName)
-	cd := TransformPublishedEntry(s.Locales, s.Entry, s.LocalizedFields, cflId, fmtVideoURL)
+	cd := TransformPublishedEntry(s.Locales, s.Entry, s.LocalizedFields, assetURLs)
 
-	// upload to github
+	// upload to the repository
 	for l, c := range cd {
 		fileName := fmt.Sprintf("%s/%s.json", s.FileName, l)
 		contentBytes, err := json.Marshal(c)
@@ -101,12 +114,10 @@ func (s *GHPublish) Exec(fmtVideoURL func(string) string) ([]gh.BlobEntry, error
 	return entries, nil
 }
 
-func PublishCFChanges(repo string, entries []gh.BlobEntry) (github.Rate, error) {
+func PublishCFChanges(r ContentRepository, entries []gh.BlobEntry) (github.Rate, error) {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
 
-	resp, err := gh.CommitBlobs(ctx, cfg.Token, owner, repo, branch, entries, "feat(content): update files")
-	return resp.Rate, err
+	return r.CommitBlobs(ctx, entries, "feat(content): update files")
 }
 
 func getAssetImageURL(entry *PublishedEntry) map[string]string {
@@ -119,7 +130,7 @@ func getAssetImageURL(entry *PublishedEntry) map[string]string {
 			if fileName != "" {
 				url := fileContent["url"].(string)
 				if url != "" {
-					imageURLs[GetImageFileName(fileName, entry.Sys.ID, loc)] = fmt.Sprintf("http:%s", url)
+					imageURLs[GetImageFileName(fileName, entry.Sys.ID, loc)] = contentfulFileURL(url)
 				}
 			}
 		}
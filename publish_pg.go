@@ -19,7 +19,7 @@ type PGPublish struct {
 	Locales          []*Locale
 }
 
-func NewPGPublish(schemaName string, locales []*Locale, contentModel *ContentType, item *PublishedEntry) *PGPublish {
+func NewPGPublish(schemaName string, locales []*Locale, contentModel *ContentType, item *PublishedEntry, opts *PGSyncOptions) *PGPublish {
 
 	defLocale := getDefaultLocale(locales)
 
@@ -34,14 +34,19 @@ func NewPGPublish(schemaName string, locales []*Locale, contentModel *ContentTyp
 	switch item.Sys.Type {
 	case ENTRY:
 		contentTypeColumns, columnReferences, localizedColumns := getContentTypeColumns(contentModel)
+		columnTypes := getContentTypeColumnTypes(contentModel)
+		fieldIDs := make(map[string]string)
+		for fieldID, col := range getContentTypeColumnNames(contentModel) {
+			fieldIDs[col] = fieldID
+		}
 		contentType := item.Sys.ContentType.Sys.ID
-		q.TableName = toSnakeCase(contentType)
+		q.TableName = pgTableName(contentType)
 		for _, oLoc := range locales {
 			loc := strings.ToLower(oLoc.Code)
 			fieldValues := make(map[string]interface{})
 			id := fmtSysID(item.Sys.ID, true, loc)
 			for _, col := range contentTypeColumns {
-				prop := toCamelCase(col)
+				prop := fieldIDs[col]
 				oLocCode := oLoc.Code
 				if !localizedColumns[col] {
 					oLocCode = defLocale
@@ -51,7 +56,7 @@ func NewPGPublish(schemaName string, locales []*Locale, contentModel *ContentTyp
 					if sv, ok := fieldValue.(string); fieldValue == nil || (ok && sv == "") {
 						continue
 					}
-					fieldValues[col] = convertFieldValue(fieldValue, true, loc)
+					fieldValues[col] = convertColumnValue(fieldValue, columnTypes[col], true, loc, opts.locationType())
 					if columnReferences[col] != "" {
 						appendPublishColCons(q, columnReferences[col], col, fieldValue, item.Sys.ID, id, loc)
 					}
@@ -64,22 +69,24 @@ func NewPGPublish(schemaName string, locales []*Locale, contentModel *ContentTyp
 	case ASSET:
 		q.TableName = ASSET_TABLE_NAME
 		for _, oLoc := range locales {
+			loc := strings.ToLower(oLoc.Code)
 			fieldValues := make(map[string]interface{})
 			locTitle := item.Fields["title"][oLoc.Code]
 			if locTitle != nil {
-				fieldValues["title"] = fmt.Sprintf("'%s'", locTitle)
+				fieldValues["title"] = convertFieldValue(locTitle, true, loc)
+			}
+			locDescription := item.Fields["description"][oLoc.Code]
+			if locDescription != nil {
+				fieldValues["description"] = convertFieldValue(locDescription, true, loc)
 			}
 			locFile := item.Fields["file"][oLoc.Code]
-			file, ok := locFile.(map[string]interface{})
-			if ok {
-				fieldValues["url"] = fmt.Sprintf("'%s'", file["url"])
-				fieldValues["file_name"] = fmt.Sprintf("'%s'", file["fileName"])
-				fieldValues["content_type"] = fmt.Sprintf("'%s'", file["contentType"])
+			if file, ok := convertFieldValue(locFile, true, loc).(*AssetFile); ok {
+				mergeMaps(fieldValues, assetFileValues(file, item.Sys.ID, loc, true, opts.assetURLs()))
 			}
 			if locTitle == nil && locFile == nil {
 				continue
 			}
-			q.Rows = append(q.Rows, newPGPublishRow(item.Sys, assetColumns, fieldValues, strings.ToLower(oLoc.Code)))
+			q.Rows = append(q.Rows, newPGPublishRow(item.Sys, assetColumns, fieldValues, loc))
 		}
 	}
 	return q
@@ -89,7 +96,7 @@ func (s *PGPublish) Exec(databaseURL string) error {
 	funcMap := template.FuncMap{
 		"ToLower": strings.ToLower,
 	}
-	tmpl, err := template.New("").Funcs(funcMap).Parse(pgPublishTemplate)
+	tmpl, err := template.New("").Funcs(pgIdentifierFuncMap).Funcs(funcMap).Parse(pgPublishTemplate)
 	if err != nil {
 		return err
 	}
@@ -161,7 +168,7 @@ func appendPublishColCons(q *PGPublish, columnReference string, col string, fiel
 		if q.ConTables[conTableName] == nil {
 			q.ConTables[conTableName] = &PGSyncConTable{
 				TableName: conTableName,
-				Columns:   []string{q.TableName, fmt.Sprintf("%s_sys_id", q.TableName), columnReference, fmt.Sprintf("%s_sys_id", columnReference), "_locale"},
+				Columns:   getConTableColumnNames(q.TableName, columnReference),
 				Rows:      make([][]interface{}, 0),
 			}
 		}
@@ -170,9 +177,14 @@ func appendPublishColCons(q *PGPublish, columnReference string, col string, fiel
 			f, ok := e.(map[string]interface{})
 			if ok {
 				conSysID := convertSysID(f, true)
-				conID := convertSys(f, true, loc)
+				conID := convertSys(f, true, loc, "")
 				if id != "" && conID != "" && !addedRefs[conID] {
-					conRow := []interface{}{id, fmt.Sprintf("'%s'", sys_id), conID, conSysID, fmt.Sprintf("'%s'", loc)}
+					conRow := []interface{}{id, fmt.Sprintf("'%s'", sys_id), conID, conSysID}
+					if columnReference == ENTRY {
+						// the content type of the linked entry is resolved by the views
+						conRow = append(conRow, conContentType("", true))
+					}
+					conRow = append(conRow, fmt.Sprintf("'%s'", loc))
 					q.ConTables[conTableName].Rows = append(q.ConTables[conTableName].Rows, conRow)
 					addedRefs[conID] = true
 				} else {
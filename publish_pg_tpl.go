@@ -2,11 +2,11 @@ package gontentful
 
 const pgPublishTemplate = `
 {{ range $itemidx, $item := .Rows }}
-INSERT INTO {{ $.SchemaName }}.{{ $.TableName }} (
+INSERT INTO {{ $.SchemaName }}."{{ $.TableName }}" (
 	_id,
 	_sys_id,
 	{{- range $k, $v := .FieldColumns }}
-	{{ $v }},
+	"{{ $v }}",
 	{{- end }}
 	_locale,
 	_version,
@@ -22,15 +22,15 @@ INSERT INTO {{ $.SchemaName }}.{{ $.TableName }} (
 	{{- end }}
 	'{{ .Locale }}',
 	'{{ .Version }}',
-	to_timestamp('{{ .CreatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ .CreatedAt }}'::timestamptz,
 	'sync',
-	to_timestamp('{{ .UpdatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ .UpdatedAt }}'::timestamptz,
 	'sync'
 )
 ON CONFLICT (_id) DO UPDATE
 SET
 	{{- range $k, $v := .FieldColumns }}
-	{{ $v }} = EXCLUDED.{{ $v }},
+	"{{ $v }}" = EXCLUDED."{{ $v }}",
 	{{- end }}
 	_locale = EXCLUDED._locale,
 	_version= EXCLUDED._version,
@@ -42,19 +42,19 @@ SET
 {{- end -}}
 {{ range $tblidx, $tbl := .DeletedConTables }}
 {{ range $rowidx, $row := $tbl.Rows }}
-DELETE FROM {{ $.SchemaName }}.{{ $tbl.TableName }} WHERE {{ index $tbl.Columns 0 }} = {{ (index $row 0) }};
+DELETE FROM {{ $.SchemaName }}."{{ $tbl.TableName }}" WHERE "{{ index $tbl.Columns 0 }}" = {{ (index $row 0) }};
 {{- end -}}
 {{- end -}}
 {{ range $tblidx, $tbl := .ConTables }}
 {{ $prevId := "" }}
 {{ range $rowidx, $row := $tbl.Rows }}
 {{if ne $prevId (index $row 0) -}}
-DELETE FROM {{ $.SchemaName }}.{{ $tbl.TableName }} WHERE {{ index $tbl.Columns 0 }} = {{ (index $row 0) }};
+DELETE FROM {{ $.SchemaName }}."{{ $tbl.TableName }}" WHERE "{{ index $tbl.Columns 0 }}" = {{ (index $row 0) }};
 {{ end -}}
 {{ $prevId = (index $row 0) -}}
-INSERT INTO {{ $.SchemaName }}.{{ $tbl.TableName }} (
+INSERT INTO {{ $.SchemaName }}."{{ $tbl.TableName }}" (
 	{{- range $k, $v := $tbl.Columns }}
-	{{- if $k -}},{{- end -}}{{ $v }}
+	{{- if $k -}},{{- end -}}"{{ $v }}"
 	{{- end }}
 ) VALUES (
 	{{- range $k, $v := $row }}
@@ -0,0 +1,369 @@
+package gontentful
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+const (
+	defaultPRBranchTemplate = `content/{{ .ContentType }}`
+	defaultPRCommitTemplate = `feat(content): {{ join .Actions "/" }} {{ join .ContentTypes ", " }} {{ join .EntryIDs ", " }}
+{{ if .Locales }}
+Locales: {{ join .Locales ", " }}{{ end }}{{ if .Editors }}
+Editors: {{ join .Editors ", " }}{{ end }}`
+	defaultPRTitleTemplate = `Content {{ join .Actions "/" }}: {{ join .ContentTypes ", " }} ({{ len .EntryIDs }} {{ if eq (len .EntryIDs) 1 }}entry{{ else }}entries{{ end }})`
+	defaultPRBodyTemplate  = `Content changes of {{ join .ContentTypes ", " }}.
+
+| | |
+|---|---|
+| Entries | {{ join .EntryIDs ", " }} |
+| Locales | {{ join .Locales ", " }} |
+| Editors | {{ join .Editors ", " }} |
+| Events | {{ .Events }} |
+`
+)
+
+var prFuncMap = template.FuncMap{
+	"join": strings.Join,
+}
+
+// PRChange is the result of a webhook event (publish, delete or schema change)
+// to be published through a pull request.
+type PRChange struct {
+	Action      string
+	ContentType string
+	EntryID     string
+	Locales     []string
+	Editor      string
+	Entries     []gh.BlobEntry
+}
+
+// PRMessageData is the data of the branch, commit, title and body templates.
+// The branch template is executed with the PRChange.
+type PRMessageData struct {
+	Branch       string
+	Actions      []string
+	ContentTypes []string
+	EntryIDs     []string
+	Locales      []string
+	Editors      []string
+	Events       int
+}
+
+// PRPublisher publishes the content changes to branches and pull requests instead
+// of committing to the base branch. The changes of the same branch received within
+// Window are committed together and the pull request of the branch is opened or
+// updated. With AutoMerge the pull request is merged once the checks pass.
+type PRPublisher struct {
+	Repository     PullRequestRepository
+	Window         time.Duration
+	AutoMerge      bool
+	MergeMethod    string
+	BranchTemplate string
+	CommitTemplate string
+	TitleTemplate  string
+	BodyTemplate   string
+	// OnPublish is called after each published batch, with the error if it failed
+	OnPublish func(pr *PullRequest, data *PRMessageData, err error)
+
+	mu      sync.Mutex
+	batches map[string]*prBatch
+	// open pull requests content, the title and body describe all the changes of the PR
+	published map[string]*PRMessageData
+}
+
+type prBatch struct {
+	changes []*PRChange
+	timer   *time.Timer
+}
+
+func NewPRPublisher(repository PullRequestRepository, window time.Duration) *PRPublisher {
+	return &PRPublisher{
+		Repository:     repository,
+		Window:         window,
+		MergeMethod:    "SQUASH",
+		BranchTemplate: defaultPRBranchTemplate,
+		CommitTemplate: defaultPRCommitTemplate,
+		TitleTemplate:  defaultPRTitleTemplate,
+		BodyTemplate:   defaultPRBodyTemplate,
+		batches:        make(map[string]*prBatch),
+		published:      make(map[string]*PRMessageData),
+	}
+}
+
+// NewPRChange creates the change of a published, unpublished or deleted entry or asset.
+func NewPRChange(entry *PublishedEntry, entries []gh.BlobEntry) *PRChange {
+	change := &PRChange{
+		Action:  "publish",
+		EntryID: entry.Sys.ID,
+		Locales: make([]string, 0),
+		Entries: entries,
+	}
+
+	switch entry.Sys.Type {
+	case DELETED_ENTRY, DELETED_ASSET:
+		change.Action = "delete"
+	}
+	switch {
+	case entry.Sys.Type == ASSET || entry.Sys.Type == DELETED_ASSET:
+		change.ContentType = ASSET_TABLE_NAME
+	case entry.Sys.ContentType != nil && entry.Sys.ContentType.Sys != nil:
+		change.ContentType = entry.Sys.ContentType.Sys.ID
+	}
+
+	editor := entry.Sys.UpdatedBy
+	if editor == nil {
+		editor = entry.Sys.DeletedBy
+	}
+	if editor != nil && editor.Sys != nil {
+		change.Editor = editor.Sys.ID
+	}
+
+	locales := make(map[string]bool)
+	for _, values := range entry.Fields {
+		for loc := range values {
+			locales[loc] = true
+		}
+	}
+	change.Locales = sortedKeys(locales)
+
+	return change
+}
+
+// NewSchemaPRChange creates the change of a content type (GHSyncSchema.BlobEntries).
+func NewSchemaPRChange(schema *ContentType, entries []gh.BlobEntry) *PRChange {
+	change := &PRChange{
+		Action:      "schema",
+		ContentType: schema.Sys.ID,
+		Entries:     entries,
+	}
+	if schema.Sys.UpdatedBy != nil && schema.Sys.UpdatedBy.Sys != nil {
+		change.Editor = schema.Sys.UpdatedBy.Sys.ID
+	}
+	return change
+}
+
+// Add queues the change, its batch is published when the window elapses.
+// Without a window the change is published immediately.
+func (p *PRPublisher) Add(change *PRChange) error {
+	branch, err := p.render(p.BranchTemplate, change)
+	if err != nil {
+		return err
+	}
+
+	if p.Window <= 0 {
+		_, err = p.publish(context.Background(), branch, []*PRChange{change})
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.batches == nil {
+		p.batches = make(map[string]*prBatch)
+	}
+	batch := p.batches[branch]
+	if batch == nil {
+		batch = &prBatch{}
+		batch.timer = time.AfterFunc(p.Window, func() {
+			p.flushBranch(context.Background(), branch)
+		})
+		p.batches[branch] = batch
+	}
+	batch.changes = append(batch.changes, change)
+
+	return nil
+}
+
+// Flush publishes all the pending batches immediately.
+func (p *PRPublisher) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	branches := sortedKeys(p.batches)
+	p.mu.Unlock()
+
+	var errs []string
+	for _, branch := range branches {
+		err := p.flushBranch(ctx, branch)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish pull requests: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (p *PRPublisher) flushBranch(ctx context.Context, branch string) error {
+	p.mu.Lock()
+	batch := p.batches[branch]
+	delete(p.batches, branch)
+	p.mu.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	batch.timer.Stop()
+
+	_, err := p.publish(ctx, branch, batch.changes)
+	if err != nil {
+		log.Printf("failed to publish %d changes to %s: %s", len(batch.changes), branch, err.Error())
+	}
+	return err
+}
+
+func (p *PRPublisher) publish(ctx context.Context, branch string, changes []*PRChange) (pr *PullRequest, err error) {
+	data := newPRMessageData(branch, changes)
+	defer func() {
+		if p.OnPublish != nil {
+			p.OnPublish(pr, data, err)
+		}
+	}()
+
+	commitMessage, err := p.render(p.CommitTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err = p.Repository.FindPullRequest(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Repository.CommitBranch(ctx, branch, pr == nil, mergeBlobEntries(changes), commitMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	// the pull request describes all its changes
+	p.mu.Lock()
+	if p.published == nil {
+		p.published = make(map[string]*PRMessageData)
+	}
+	prData := data
+	if prev := p.published[branch]; pr != nil && prev != nil {
+		prData = prev.merge(data)
+	}
+	p.published[branch] = prData
+	p.mu.Unlock()
+
+	title, err := p.render(p.TitleTemplate, prData)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.render(p.BodyTemplate, prData)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr != nil {
+		err = p.Repository.UpdatePullRequest(ctx, pr, title, body)
+		return pr, err
+	}
+
+	pr, err = p.Repository.CreatePullRequest(ctx, branch, title, body)
+	if err != nil {
+		return nil, err
+	}
+	if p.AutoMerge {
+		err = p.Repository.EnableAutoMerge(ctx, pr, p.MergeMethod)
+		if err != nil {
+			return pr, fmt.Errorf("failed to enable auto-merge on #%d: %s", pr.Number, err.Error())
+		}
+	}
+
+	return pr, nil
+}
+
+func (p *PRPublisher) render(tpl string, data interface{}) (string, error) {
+	tmpl, err := template.New("").Funcs(prFuncMap).Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buff bytes.Buffer
+	err = tmpl.Execute(&buff, data)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buff.String()), nil
+}
+
+func newPRMessageData(branch string, changes []*PRChange) *PRMessageData {
+	actions := make(map[string]bool)
+	contentTypes := make(map[string]bool)
+	entryIDs := make(map[string]bool)
+	locales := make(map[string]bool)
+	editors := make(map[string]bool)
+	for _, c := range changes {
+		actions[c.Action] = true
+		if c.ContentType != "" {
+			contentTypes[c.ContentType] = true
+		}
+		if c.EntryID != "" {
+			entryIDs[c.EntryID] = true
+		}
+		for _, l := range c.Locales {
+			locales[l] = true
+		}
+		if c.Editor != "" {
+			editors[c.Editor] = true
+		}
+	}
+	return &PRMessageData{
+		Branch:       branch,
+		Actions:      sortedKeys(actions),
+		ContentTypes: sortedKeys(contentTypes),
+		EntryIDs:     sortedKeys(entryIDs),
+		Locales:      sortedKeys(locales),
+		Editors:      sortedKeys(editors),
+		Events:       len(changes),
+	}
+}
+
+func (d *PRMessageData) merge(o *PRMessageData) *PRMessageData {
+	return &PRMessageData{
+		Branch:       d.Branch,
+		Actions:      mergeSorted(d.Actions, o.Actions),
+		ContentTypes: mergeSorted(d.ContentTypes, o.ContentTypes),
+		EntryIDs:     mergeSorted(d.EntryIDs, o.EntryIDs),
+		Locales:      mergeSorted(d.Locales, o.Locales),
+		Editors:      mergeSorted(d.Editors, o.Editors),
+		Events:       d.Events + o.Events,
+	}
+}
+
+func mergeSorted(a []string, b []string) []string {
+	m := make(map[string]bool)
+	for _, s := range append(append([]string{}, a...), b...) {
+		m[s] = true
+	}
+	return sortedKeys(m)
+}
+
+// mergeBlobEntries keeps the last entry of each path, in the order of the changes.
+func mergeBlobEntries(changes []*PRChange) []gh.BlobEntry {
+	last := make(map[string]int)
+	all := make([]gh.BlobEntry, 0)
+	for _, c := range changes {
+		for _, e := range c.Entries {
+			last[e.Path] = len(all)
+			all = append(all, e)
+		}
+	}
+	entries := make([]gh.BlobEntry, 0)
+	for i, e := range all {
+		if last[e.Path] == i {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,152 @@
+package gontentful
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+type fakePRRepository struct {
+	*MemoryRepository
+	open      map[string]*PullRequest
+	commits   map[string][]string
+	fromBase  []bool
+	titles    []string
+	bodies    []string
+	autoMerge []int
+}
+
+func newFakePRRepository() *fakePRRepository {
+	return &fakePRRepository{
+		MemoryRepository: NewMemoryRepository(nil),
+		open:             make(map[string]*PullRequest),
+		commits:          make(map[string][]string),
+	}
+}
+
+func (r *fakePRRepository) FindPullRequest(ctx context.Context, branch string) (*PullRequest, error) {
+	return r.open[branch], nil
+}
+
+func (r *fakePRRepository) CommitBranch(ctx context.Context, branch string, fromBase bool, entries []gh.BlobEntry, message string) error {
+	r.fromBase = append(r.fromBase, fromBase)
+	r.commits[branch] = append(r.commits[branch], message)
+	_, err := r.CommitBlobs(ctx, entries, message)
+	return err
+}
+
+func (r *fakePRRepository) CreatePullRequest(ctx context.Context, branch string, title string, body string) (*PullRequest, error) {
+	pr := &PullRequest{Number: len(r.open) + 1, Branch: branch}
+	r.open[branch] = pr
+	r.titles = append(r.titles, title)
+	r.bodies = append(r.bodies, body)
+	return pr, nil
+}
+
+func (r *fakePRRepository) UpdatePullRequest(ctx context.Context, pr *PullRequest, title string, body string) error {
+	r.titles = append(r.titles, title)
+	r.bodies = append(r.bodies, body)
+	return nil
+}
+
+func (r *fakePRRepository) EnableAutoMerge(ctx context.Context, pr *PullRequest, mergeMethod string) error {
+	r.autoMerge = append(r.autoMerge, pr.Number)
+	return nil
+}
+
+func testPRChange(id string, content string) *PRChange {
+	entry := &PublishedEntry{
+		Sys: &Sys{
+			ID:          id,
+			Type:        ENTRY,
+			ContentType: &ContentType{Sys: &Sys{ID: "article"}},
+			UpdatedBy:   &Entry{Sys: &Sys{ID: "editor1"}},
+		},
+		Fields: PublishFields{"title": {"en": content, "de": content}},
+	}
+	return NewPRChange(entry, []gh.BlobEntry{
+		{Path: "_content/article/" + id + "/en.json", Content: &content},
+	})
+}
+
+func TestPRPublisherBatch(t *testing.T) {
+	repo := newFakePRRepository()
+	p := NewPRPublisher(repo, time.Hour)
+	p.AutoMerge = true
+
+	for _, c := range []*PRChange{testPRChange("a1", "v1"), testPRChange("a2", "v1"), testPRChange("a1", "v2")} {
+		err := p.Add(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := p.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := repo.commits["content/article"]
+	if len(commits) != 1 {
+		t.Fatalf("got %d commits, want 1", len(commits))
+	}
+	for _, s := range []string{"publish", "article", "a1, a2", "Locales: de, en", "Editors: editor1"} {
+		if !strings.Contains(commits[0], s) {
+			t.Errorf("missing %q in commit message %q", s, commits[0])
+		}
+	}
+	if data, _ := repo.ReadFile(context.Background(), "_content/article/a1/en.json"); string(data) != "v2" {
+		t.Errorf("got %q, want the last change", data)
+	}
+	if len(repo.titles) != 1 || !strings.Contains(repo.titles[0], "(2 entries)") {
+		t.Errorf("unexpected titles %v", repo.titles)
+	}
+	if len(repo.autoMerge) != 1 {
+		t.Errorf("auto-merge not enabled")
+	}
+
+	// the next batch updates the open pull request
+	err = p.Add(testPRChange("a3", "v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.open) != 1 || len(repo.commits["content/article"]) != 2 {
+		t.Errorf("expected a second commit on the same pull request")
+	}
+	if repo.fromBase[0] != true || repo.fromBase[1] != false {
+		t.Errorf("unexpected branch resets %v", repo.fromBase)
+	}
+	if !strings.Contains(repo.bodies[1], "a1, a2, a3") || !strings.Contains(repo.bodies[1], "| Events | 4 |") {
+		t.Errorf("pull request body does not describe all the changes: %s", repo.bodies[1])
+	}
+}
+
+func TestPRPublisherWindow(t *testing.T) {
+	repo := newFakePRRepository()
+	p := NewPRPublisher(repo, 10*time.Millisecond)
+	done := make(chan *PRMessageData, 1)
+	p.OnPublish = func(pr *PullRequest, data *PRMessageData, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		done <- data
+	}
+
+	p.Add(testPRChange("a1", "v1"))
+	p.Add(testPRChange("a2", "v1"))
+
+	select {
+	case data := <-done:
+		if data.Events != 2 {
+			t.Errorf("got %d events, want 2", data.Events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch not published")
+	}
+}
@@ -1,41 +1,30 @@
 package gontentful
 
 import (
-	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/moonwalker/moonbase/pkg/content"
 )
 
-const queryTemplate = `
-{{- if .SchemaName -}}
-	SET search_path='{{ .SchemaName }}';
-{{- end }}
-SELECT * FROM {{ .TableName }}_query(
-'{{ .Locale }}',
-{{- if $.Filters }}ARRAY[
-{{- range $idx, $filter := $.Filters -}}
-{{- if $idx -}},{{- end -}}'{{ $filter }}'
-{{- end -}}]
-{{- else -}}NULL{{- end -}},
-'{{- $.Order -}}',
-{{- $.Skip -}},
-{{- $.Limit -}}
-)
-`
+// queryStatement calls the _query function of the table, the filters and the order
+// refer to the arguments of the query
+const queryStatement = `SELECT * FROM %s($1, $2, $3, $4, $5, $6)`
 
 var (
 	comparerRegex      = regexp.MustCompile(`[^[]+\[([^]]+)+]`)
-	joinedContentRegex = regexp.MustCompile(`(?:fields.)?([^.]+)\.sys\.contentType\.sys\.id`)
-	foreignKeyRegex    = regexp.MustCompile(`([^.]+)\.(?:fields.)?(.+)`)
+	joinedContentRegex = regexp.MustCompile(`^(?:fields\.)?([^.]+)\.sys\.contentType\.sys\.id$`)
+	pgJSONPathKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 	once               = new(sync.Once)
 	db                 *sqlx.DB
 )
@@ -46,16 +35,29 @@ const (
 )
 
 type PGQuery struct {
-	SchemaName string
-	TableName  string
-	Locale     string
-	Filters    *[]string
-	Order      string
-	Limit      int
-	Skip       int
+	SchemaName  string
+	TableName   string
+	ContentType string
+	Locale      string
+	Limit       int
+	Skip        int
+	// Include is the depth of the linked entries returned in the includes
+	Include int
+	// Select are the sys and fields.x paths of the items, all the fields without
+	Select []string
+	// LocationType is the column type of the Location fields, from the schema config
+	LocationType string
+
+	filters    []*pgFilter
+	orders     []*pgOrder
+	config     *PGContentTypeConfig
+	tsConfig   string
+	searchText string
 }
 
-func ParsePGQuery(schemaName string, defaultLocale string, q url.Values) *PGQuery {
+// ParsePGQuery parses the query parameters, the fields are mapped to the columns
+// renamed by the optional schema config. Unsupported filters and orders are errors.
+func ParsePGQuery(schemaName string, defaultLocale string, q url.Values, cfg *PGSchemaConfig) (*PGQuery, error) {
 	contentType := q.Get("content_type")
 	q.Del("content_type")
 
@@ -82,163 +84,94 @@ func ParsePGQuery(schemaName string, defaultLocale string, q url.Values) *PGQuer
 	order := q.Get("order")
 	q.Del("order")
 
+	include := defaultPGQueryInclude
+	includeQ := q.Get("include")
 	q.Del("include")
-	q.Del("select")
-
-	return NewPGQuery(schemaName, contentType, locale, q, order, skip, limit)
-}
-func NewPGQuery(schemaName string, contentType string, locale string, filters url.Values, order string, skip int, limit int) *PGQuery {
-	tableName := toSnakeCase(contentType)
-	q := PGQuery{
-		SchemaName: schemaName,
-		TableName:  tableName,
-		Locale:     fmtLocale(locale),
-		Order:      formatOrder(order, tableName),
-		Skip:       skip,
-		Limit:      limit,
-	}
-
-	q.Filters = createFilters(filters)
-
-	return &q
-}
-
-func createFilters(filters url.Values) *[]string {
-	if filters != nil && len(filters) > 0 {
-		filterFields := make([]string, 0)
-		for key, values := range filters {
-			vals := ""
-			for _, val := range values {
-				for i, v := range strings.Split(val, ",") {
-					if i > 0 {
-						vals = vals + ","
-					}
-					vals = vals + formatValue(v)
-				}
-			}
-			f := getFilterFormat(key, vals, values)
-			if f != "" {
-				filterFields = append(filterFields, f)
-			}
-		}
-		if len(filterFields) > 0 {
-			return &filterFields
-		}
-	}
-	return nil
-}
-
-func getFilterFormat(key string, value string, values []string) string {
-	f := key
-	c := ""
-
-	comparerMatch := comparerRegex.FindStringSubmatch(f)
-	if len(comparerMatch) > 0 {
-		c = comparerMatch[1]
-		f = strings.Replace(f, fmt.Sprintf("[%s]", c), "", 1)
+	if includeQ != "" {
+		include, _ = strconv.Atoi(includeQ)
 	}
 
-	f = formatField(f)
-	if f == "" {
-		return f
+	var sel []string
+	selectQ := q.Get("select")
+	q.Del("select")
+	if selectQ != "" {
+		sel = strings.Split(selectQ, ",")
 	}
 
-	if strings.Contains(f, ".") {
-		return ""
+	query, err := NewPGQuery(schemaName, contentType, locale, q, order, skip, limit, cfg)
+	if err != nil {
+		return nil, err
 	}
-	// if strings.Contains(colName, ".") {
-	// 	// content.fields.name%5Bmatch%5D=jack&content.sys.contentType.sys.id=gameInfo
-	// 	// content.sys.contentType.sys.id=gameId&deviceConfigurations.sys.id=1yyHAve4aE6AQgkIyYG4im
-	// 	fkeysMatch := foreignKeyRegex.FindStringSubmatch(colName)
-	// 	if len(fkeysMatch) > 0 {
-	// 		if fkeysMatch[2] != "sys.id" && strings.HasPrefix(fkeysMatch[2], "sys.") {
-	// 			// ignore sys fields
-	// 			return "", ""
-	// 		}
-	// 		colName = fmt.Sprintf("%s.%s", fkeysMatch[1], fkeysMatch[2])
-	// 	}
-	// }
-
-	col := toSnakeCase(f)
-	switch c {
-	case "":
-		return fmt.Sprintf("%s = %s", col, value)
-	case "ne":
-		return fmt.Sprintf("%s IS DISTINCT FROM %s", col, value)
-	case "exists":
-		return fmt.Sprintf("%s IS NOT NULL", col)
-	case "lt":
-		return fmt.Sprintf("%s < %s", col, value)
-	case "lte":
-		return fmt.Sprintf("%s <= %s", col, value)
-	case "gt":
-		return fmt.Sprintf("%s > %s", col, value)
-	case "gte":
-		return fmt.Sprintf("%s >= %s", col, value)
-	case "match":
-		return fmt.Sprintf("%s ILIKE ''%%'' || ''%s'' || ''%%''", col, strings.ReplaceAll(strings.Join(values, ","), "'", "'''"))
-	case "all":
-		return fmt.Sprintf("%s @> ARRAY[%s]", col, value)
-	case "in":
-		// IF isArray THEN
-		// RETURN 	' && ARRAY[' || fmtVal || ']';
-		return fmt.Sprintf("%s = ANY(ARRAY[%s])", col, value)
-	case "nin":
-		// IF isArray THEN
-		// 			RETURN 	' && ARRAY[' || fmtVal || '] = false';
-		return fmt.Sprintf("%s != ALL(ARRAY[%s])", col, value)
+	query.Include = include
+	if query.Include < 0 {
+		query.Include = 0
+	} else if query.Include > maxPGQueryInclude {
+		query.Include = maxPGQueryInclude
 	}
-	return ""
+	query.Select = sel
+	return query, nil
 }
 
-func formatValue(s string) string {
-	if s == "true" || s == "false" {
-		return fmt.Sprintf("%s", s)
+func NewPGQuery(schemaName string, contentType string, locale string, filters url.Values, order string, skip int, limit int, cfg *PGSchemaConfig) (*PGQuery, error) {
+	ctc := cfg.contentType(contentType)
+	q := PGQuery{
+		SchemaName:   schemaName,
+		TableName:    pgTableName(contentType),
+		ContentType:  contentType,
+		Locale:       fmtLocale(locale),
+		Skip:         skip,
+		Limit:        limit,
+		Include:      defaultPGQueryInclude,
+		LocationType: cfg.locationType(),
+		config:       ctc,
+		tsConfig:     cfg.textSearchConfig(locale),
+		searchText:   pgSearchText(filters, ctc),
+	}
+
+	var err error
+	q.orders, err = parsePGOrder(order, q.searchText)
+	if err != nil {
+		return nil, err
 	}
-
-	f, err := strconv.ParseFloat(s, 64)
-	if err == nil {
-		return fmt.Sprintf("%f", f)
+	q.filters, err = createFilters(filters)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("''%s''", s)
+	return &q, nil
 }
 
-func formatField(f string) string {
-	if f == "sys.id" {
-		return "_sys_id"
+// createFilters parses the filter parameters in the order of the keys
+func createFilters(filters url.Values) ([]*pgFilter, error) {
+	res := make([]*pgFilter, 0)
+	keys := make([]string, 0)
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f, err := parsePGFilter(key, filters[key])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, f)
 	}
-	return strings.TrimPrefix(strings.TrimPrefix(f, "fields."), "sys.")
+	return res, nil
 }
 
-func formatOrder(order string, tableName string) string {
-	if order == "" {
-		return order
-	}
-	orders := make([]string, 0)
-	for _, o := range strings.Split(order, ",") {
-		value := o
-		desc := ""
-		if o[:1] == "-" {
-			desc = " DESC"
-			value = o[1:]
-		}
-		var field string
-		if value == "sys.id" {
-			field = fmt.Sprintf("%s._sys_id", tableName)
-		} else if strings.HasPrefix(value, "sys.") {
-			field = fmt.Sprintf("%s._%s", tableName, strings.TrimPrefix(toSnakeCase(value), "sys."))
-		} else {
-			field = fmt.Sprintf("%s.%s", tableName, strings.TrimPrefix(toSnakeCase(value), "fields."))
-		}
-
-		orders = append(orders, fmt.Sprintf("%s%s NULLS LAST", field, desc))
+// Exec runs the query and returns the page of entries like the CDA, with the linked
+// entries and assets in the includes
+func (s *PGQuery) Exec(databaseURL string) (*Entries, error) {
+	total, rows, err := s.exec(databaseURL)
+	if err != nil {
+		return nil, err
 	}
-
-	return strings.Join(orders, ",")
+	return s.entries(total, s.Skip, s.Limit, rows), nil
 }
 
-func (s *PGQuery) Exec(databaseURL string) (int64, string, error) {
+// exec runs the _query function of the table and returns the total and the rows, the
+// filters are compiled with the model of the table stored in the _schema table
+func (s *PGQuery) exec(databaseURL string) (int64, []map[string]interface{}, error) {
 	var dbErr error
 	once.Do(func() {
 		db, dbErr = sqlx.Connect("postgres", databaseURL)
@@ -250,31 +183,52 @@ func (s *PGQuery) Exec(databaseURL string) (int64, string, error) {
 	})
 	if dbErr != nil {
 		once = new(sync.Once)
-		return 0, "", dbErr
+		return 0, nil, dbErr
 	}
 
-	tmpl, err := template.New("").Parse(queryTemplate)
+	txn, err := db.Beginx()
 	if err != nil {
-		return 0, "", err
+		return 0, nil, err
 	}
+	defer txn.Rollback()
 
-	var buff bytes.Buffer
-	err = tmpl.Execute(&buff, s)
+	if s.SchemaName != "" {
+		// the search path of the transaction only, the connections are pooled
+		_, err = txn.Exec("SELECT set_config('search_path', $1, true)", s.SchemaName)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var fields content.Fields
+	err = txn.Get(&fields, selectPGModel, s.TableName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, fmt.Errorf("unknown content type: %s", s.ContentType)
+		}
+		return 0, nil, err
+	}
+
+	q, err := s.compile(fields)
 	if err != nil {
-		return 0, "", err
+		return 0, nil, err
 	}
-	// fmt.Println(buff.String())
 
 	var count int64
 	var items string
-	res := db.QueryRow(buff.String())
+	res := txn.QueryRow(fmt.Sprintf(queryStatement, pgQuoteIdent(s.TableName+"_query")), s.Locale, pq.Array(q.Filters), pq.Array(q.Args), q.Order, s.Skip, s.Limit)
 	err = res.Scan(&count, &items)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, "[]", nil
+			return 0, nil, nil
 		}
-		return 0, "", err
+		return 0, nil, err
 	}
 
-	return count, items, nil
+	rows := make([]map[string]interface{}, 0)
+	err = json.Unmarshal([]byte(items), &rows)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, rows, nil
 }
@@ -0,0 +1,164 @@
+package gontentful
+
+import (
+	"strings"
+)
+
+const (
+	defaultPGQueryInclude = 1
+	maxPGQueryInclude     = 10
+)
+
+// pgEntries builds the CDA response of the query rows: the entries and assets embedded
+// by the views are replaced by links and returned once in the includes, up to the
+// include depth of the query (and the depth the views were generated with)
+type pgEntries struct {
+	query    *PGQuery
+	entries  map[string]*Entry
+	assets   map[string]*Entry
+	included *Include
+}
+
+func newPGEntries(query *PGQuery) *pgEntries {
+	return &pgEntries{
+		query:    query,
+		entries:  make(map[string]*Entry),
+		assets:   make(map[string]*Entry),
+		included: &Include{Entry: make([]*Entry, 0), Asset: make([]*Entry, 0)},
+	}
+}
+
+// entries returns the page of the rows returned by the _query functions
+func (s *PGQuery) entries(total int64, skip int, limit int, rows []map[string]interface{}) *Entries {
+	res := &Entries{
+		Sys:   &Sys{Type: "Array"},
+		Total: int(total),
+		Skip:  skip,
+		Limit: limit,
+		Items: make([]*Entry, 0),
+	}
+	b := newPGEntries(s)
+	selected := s.selectedFields()
+	items := make(map[string]bool)
+	for _, row := range rows {
+		sys, _ := row["sys"].(map[string]interface{})
+		entry := &Entry{
+			Sys:    b.sys(sys, ENTRY),
+			Locale: s.Locale,
+			Fields: make(Fields),
+		}
+		if entry.Sys.ContentType == nil && s.ContentType != "" {
+			entry.Sys.ContentType = contentTypeLink(s.ContentType)
+		}
+		for fn, v := range row {
+			if fn == "sys" || v == nil || (selected != nil && !selected[fn]) {
+				continue
+			}
+			entry.Fields[fn] = b.link(v, 1)
+		}
+		items[entry.Sys.ID] = true
+		res.Items = append(res.Items, entry)
+	}
+
+	// the entries of the page are not included again
+	includes := &Include{Entry: make([]*Entry, 0), Asset: b.included.Asset}
+	for _, e := range b.included.Entry {
+		if !items[e.Sys.ID] {
+			includes.Entry = append(includes.Entry, e)
+		}
+	}
+	if len(includes.Entry) > 0 || len(includes.Asset) > 0 {
+		res.Includes = includes
+	}
+	return res
+}
+
+// selectedFields returns the fields of the select parameter, nil selects them all
+func (s *PGQuery) selectedFields() map[string]bool {
+	if len(s.Select) == 0 {
+		return nil
+	}
+	selected := make(map[string]bool)
+	for _, sel := range s.Select {
+		if sel == "fields" {
+			return nil
+		}
+		if strings.HasPrefix(sel, "fields.") {
+			selected[strings.TrimPrefix(sel, "fields.")] = true
+		}
+	}
+	return selected
+}
+
+// link replaces the embedded entries and assets of the value by links, the embedded
+// ones are included at the level
+func (b *pgEntries) link(v interface{}, level int) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		sys, _ := value["sys"].(map[string]interface{})
+		kind, _ := sys["type"].(string)
+		id, _ := sys["id"].(string)
+		if id == "" || (kind != ENTRY && kind != ASSET) {
+			return v
+		}
+		b.include(value, b.sys(sys, kind), level)
+		return map[string]interface{}{
+			"sys": map[string]interface{}{"type": LINK, "linkType": kind, "id": id},
+		}
+	case []interface{}:
+		res := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if item != nil {
+				res = append(res, b.link(item, level))
+			}
+		}
+		return res
+	}
+	return v
+}
+
+// include adds the embedded entry or asset to the includes, the fields embedded at
+// several places (the deepest level has no fields) are merged
+func (b *pgEntries) include(value map[string]interface{}, sys *Sys, level int) {
+	if level > b.query.Include || len(value) < 2 {
+		return
+	}
+	included, list := b.entries, &b.included.Entry
+	if sys.Type == ASSET {
+		included, list = b.assets, &b.included.Asset
+	}
+	e := included[sys.ID]
+	if e == nil {
+		e = &Entry{Sys: sys, Locale: b.query.Locale, Fields: make(Fields)}
+		included[sys.ID] = e
+		*list = append(*list, e)
+	}
+	for fn, v := range value {
+		if fn == "sys" || v == nil {
+			continue
+		}
+		// the links are followed at every place, the deeper ones may be included here
+		linked := b.link(v, level+1)
+		if _, ok := e.Fields[fn]; !ok {
+			e.Fields[fn] = linked
+		}
+	}
+}
+
+// sys returns the sys of an entry or asset returned by the views
+func (b *pgEntries) sys(sys map[string]interface{}, kind string) *Sys {
+	res := &Sys{Type: kind}
+	res.ID, _ = sys["id"].(string)
+	res.UpdatedAt, _ = sys["updatedAt"].(string)
+	if ct, ok := sys["contentType"].(map[string]interface{}); ok {
+		ctSys, _ := ct["sys"].(map[string]interface{})
+		if id, _ := ctSys["id"].(string); id != "" {
+			res.ContentType = contentTypeLink(id)
+		}
+	}
+	return res
+}
+
+func contentTypeLink(id string) *ContentType {
+	return &ContentType{Sys: &Sys{ID: id, Type: LINK, LinkType: "ContentType"}}
+}
@@ -0,0 +1,84 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+const pgEntriesTestRows = `[
+	{"sys": {"id": "g1", "updatedAt": "2023-01-02T10:00:00+00:00"}, "name": "Starburst", "rtp": 96.1, "description": null,
+		"logo": {"sys": {"id": "a1", "type": "Asset"}, "title": "Logo", "description": null, "file": {"url": "//images/logo.png"}},
+		"studio": {"sys": {"id": "s1", "type": "Entry", "contentType": {"sys": {"id": "studio"}}}, "name": "NetEnt",
+			"owner": {"sys": {"id": "o1", "type": "Entry", "contentType": {"sys": {"id": "owner"}}}, "name": "Evolution",
+				"parent": {"sys": {"id": "o2", "type": "Entry", "contentType": {"sys": {"id": "owner"}}}}}},
+		"related": [
+			{"sys": {"id": "g2", "type": "Entry", "contentType": {"sys": {"id": "game"}}}, "name": "Dead or Alive"},
+			{"sys": {"id": "s1", "type": "Entry", "contentType": {"sys": {"id": "studio"}}}, "name": "NetEnt", "country": "SE"}
+		]},
+	{"sys": {"id": "g2"}, "name": "Dead or Alive", "rtp": null, "logo": null, "studio": null, "related": null}
+]`
+
+func pgEntriesTest(t *testing.T, q url.Values) *Entries {
+	rows := make([]map[string]interface{}, 0)
+	err := json.Unmarshal([]byte(pgEntriesTestRows), &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := ParsePGQuery("", "en", q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query.entries(2, query.Skip, query.Limit, rows)
+}
+
+func TestPGQueryEntries(t *testing.T) {
+	entries := pgEntriesTest(t, url.Values{"content_type": []string{"game"}, "limit": []string{"10"}})
+	if entries.Total != 2 || entries.Limit != 10 || len(entries.Items) != 2 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	g1 := entries.Items[0]
+	if g1.Sys.ID != "g1" || g1.Sys.Type != ENTRY || g1.Sys.ContentType.Sys.ID != "game" || g1.Sys.UpdatedAt == "" || g1.Locale != "en" {
+		t.Errorf("unexpected sys %+v", g1.Sys)
+	}
+	if _, ok := g1.Fields["description"]; ok {
+		t.Errorf("null field returned %v", g1.Fields)
+	}
+	studio, _ := json.Marshal(g1.Fields["studio"])
+	if string(studio) != `{"sys":{"id":"s1","linkType":"Entry","type":"Link"}}` {
+		t.Errorf("unexpected link %s", studio)
+	}
+	related, _ := g1.Fields["related"].([]interface{})
+	if len(related) != 2 {
+		t.Errorf("unexpected links %v", g1.Fields["related"])
+	}
+
+	// include=1: the studio once with the merged fields, g2 is an item already
+	if entries.Includes == nil || len(entries.Includes.Entry) != 1 || len(entries.Includes.Asset) != 1 {
+		t.Fatalf("unexpected includes %+v", entries.Includes)
+	}
+	s1 := entries.Includes.Entry[0]
+	if s1.Sys.ID != "s1" || s1.Sys.ContentType.Sys.ID != "studio" || s1.Fields["country"] != "SE" || s1.Fields["name"] != "NetEnt" {
+		t.Errorf("unexpected include %+v %v", s1.Sys, s1.Fields)
+	}
+	if a1 := entries.Includes.Asset[0]; a1.Sys.ID != "a1" || a1.Sys.Type != ASSET || a1.Fields["title"] != "Logo" {
+		t.Errorf("unexpected asset %+v %v", a1.Sys, a1.Fields)
+	}
+	b, _ := json.Marshal(entries)
+	var res map[string]interface{}
+	json.Unmarshal(b, &res)
+	if includes, _ := res["includes"].(map[string]interface{}); includes["Entry"] == nil || includes["Asset"] == nil {
+		t.Errorf("unexpected json %s", b)
+	}
+
+	// include=2 adds the owner, the parent of the owner is beyond the depth of the view
+	entries = pgEntriesTest(t, url.Values{"content_type": []string{"game"}, "include": []string{"2"}})
+	if len(entries.Includes.Entry) != 2 || entries.Includes.Entry[1].Sys.ID != "o1" {
+		t.Errorf("unexpected includes %+v", entries.Includes.Entry)
+	}
+
+	entries = pgEntriesTest(t, url.Values{"content_type": []string{"game"}, "include": []string{"0"}, "select": []string{"sys.id,fields.name,fields.logo"}})
+	if entries.Includes != nil || len(entries.Items[0].Fields) != 2 || entries.Items[0].Fields["logo"] == nil {
+		t.Errorf("unexpected selection %v %+v", entries.Items[0].Fields, entries.Includes)
+	}
+}
@@ -0,0 +1,646 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const selectPGModel = "SELECT fields FROM _schema WHERE table_name = $1"
+
+// pgComparers are the comparers of the filter parameters
+var pgComparers = map[string]bool{
+	"":       true,
+	"ne":     true,
+	"exists": true,
+	"lt":     true,
+	"lte":    true,
+	"gt":     true,
+	"gte":    true,
+	"match":  true,
+	"in":     true,
+	"nin":    true,
+	"all":    true,
+	"near":   true,
+	"within": true,
+}
+
+// pgComparerOps are the sql and json path operators of the comparers of a single value
+var pgComparerOps = map[string]string{
+	"lt":  "<",
+	"lte": "<=",
+	"gt":  ">",
+	"gte": ">=",
+}
+
+// pgFilterKind is what a filter or an order of the queries refers to
+type pgFilterKind int
+
+const (
+	// pgFilterField is a field of the entries
+	pgFilterField pgFilterKind = iota
+	// pgFilterReference is a field or the sys of the linked entries
+	pgFilterReference
+	// pgFilterContentType is the content type of the linked entries
+	pgFilterContentType
+	// pgFilterSys is the id or the update date of the entries
+	pgFilterSys
+	// pgFilterSearch is the full-text search of the query parameter, the relevance order
+	pgFilterSearch
+	// pgFilterSpace is the space and environment of the rows of a shared schema
+	pgFilterSpace
+)
+
+// pgFilter is a parsed filter parameter, it is compiled with the model of the table
+type pgFilter struct {
+	Kind pgFilterKind
+	// Key is the parameter the filter was parsed from
+	Key      string
+	Field    string
+	Path     []string
+	Comparer string
+	Values   []string
+}
+
+// pgOrder is a parsed field of the order parameter
+type pgOrder struct {
+	Kind  pgFilterKind
+	Key   string
+	Field string
+	Path  []string
+	Desc  bool
+}
+
+// parsePGFilter parses the filter parameter, the comparers and the field paths are
+// checked, the fields are checked against the model when compiled
+func parsePGFilter(key string, values []string) (*pgFilter, error) {
+	if key == "query" {
+		return &pgFilter{Kind: pgFilterSearch, Key: key, Values: values}, nil
+	}
+
+	f := key
+	c := ""
+	comparerMatch := comparerRegex.FindStringSubmatch(f)
+	if len(comparerMatch) > 0 {
+		c = comparerMatch[1]
+		f = strings.Replace(f, fmt.Sprintf("[%s]", c), "", 1)
+	}
+	if !pgComparers[c] || len(values) == 0 {
+		return nil, fmt.Errorf("unsupported filter: %s", key)
+	}
+	filter := &pgFilter{Key: key, Comparer: c, Values: values}
+
+	joinedContentMatch := joinedContentRegex.FindStringSubmatch(f)
+	if len(joinedContentMatch) > 0 {
+		if c != "" && c != "in" && c != "ne" && c != "nin" && c != "exists" {
+			return nil, fmt.Errorf("unsupported filter: %s", key)
+		}
+		filter.Kind = pgFilterContentType
+		filter.Field = joinedContentMatch[1]
+		return filter, nil
+	}
+
+	if strings.HasPrefix(f, "sys.") {
+		col, err := formatSysField(f)
+		if err != nil || c == "match" || c == "all" || c == "near" || c == "within" {
+			return nil, fmt.Errorf("unsupported filter: %s", key)
+		}
+		filter.Kind = pgFilterSys
+		filter.Field = col
+		return filter, nil
+	}
+
+	field, path, err := pgReferencePath(f)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported filter: %s", key)
+	}
+	filter.Field = field
+	if len(path) > 0 {
+		if c == "near" || c == "within" {
+			return nil, fmt.Errorf("unsupported filter: %s", key)
+		}
+		filter.Kind = pgFilterReference
+		filter.Path = path
+	}
+	switch c {
+	case "near":
+		_, err = parseGeoNear(strings.Join(values, ","))
+	case "within":
+		_, err = parseGeoWithin(strings.Join(values, ","))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unsupported filter %s: %s", key, err.Error())
+	}
+	return filter, nil
+}
+
+// parsePGOrder parses the order parameter, relevance is skipped without a search text
+func parsePGOrder(order string, searchText string) ([]*pgOrder, error) {
+	orders := make([]*pgOrder, 0)
+	if order == "" {
+		return orders, nil
+	}
+	for _, o := range strings.Split(order, ",") {
+		res := &pgOrder{Key: o, Field: o}
+		if strings.HasPrefix(o, "-") {
+			res.Desc = true
+			res.Field = o[1:]
+		}
+		if res.Field == pgRelevanceOrder {
+			if searchText == "" {
+				continue
+			}
+			res.Kind = pgFilterSearch
+		} else if strings.HasPrefix(res.Field, "sys.") {
+			col, err := formatSysField(res.Field)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported order: %s", o)
+			}
+			res.Kind = pgFilterSys
+			res.Field = col
+		} else {
+			field, path, err := pgReferencePath(res.Field)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported order: %s", o)
+			}
+			res.Field = field
+			if len(path) > 0 {
+				res.Kind = pgFilterReference
+				res.Path = path
+			}
+		}
+		orders = append(orders, res)
+	}
+	return orders, nil
+}
+
+// pgReferencePath splits the field path of a filter or order into the field of the
+// table and the json path in the linked entries: fields.studio.fields.owner.sys.id is
+// the studio column and owner.sys.id
+func pgReferencePath(f string) (string, []string, error) {
+	parts := strings.Split(strings.TrimPrefix(f, "fields."), ".")
+	field := parts[0]
+	if !pgJSONPathKeyRegex.MatchString(field) {
+		return "", nil, fmt.Errorf("invalid field: %s", f)
+	}
+	path := make([]string, 0)
+	for i := 1; i < len(parts); i++ {
+		if i+1 == len(parts) {
+			return "", nil, fmt.Errorf("invalid field: %s", f)
+		}
+		switch parts[i] {
+		case "fields":
+			i++
+			path = append(path, parts[i])
+		case "sys":
+			path = append(path, parts[i:]...)
+			i = len(parts)
+		default:
+			return "", nil, fmt.Errorf("invalid field: %s", f)
+		}
+	}
+	for _, p := range path {
+		if !pgJSONPathKeyRegex.MatchString(p) {
+			return "", nil, fmt.Errorf("invalid field: %s", f)
+		}
+	}
+	return field, path, nil
+}
+
+// formatSysField returns the sys column of the views
+func formatSysField(f string) (string, error) {
+	switch f {
+	case "sys.id":
+		return "_sys_id", nil
+	case "sys.updatedAt":
+		return "_updated_at", nil
+	}
+	return "", fmt.Errorf("invalid field: %s", f)
+}
+
+// pgCompiledQuery is the filters, the arguments and the order of a _query function,
+// the filters and the order refer to the arguments as $1[n]
+type pgCompiledQuery struct {
+	Filters []string
+	Args    []string
+	Order   string
+}
+
+// pgQueryCompiler compiles the filters and orders of a query to sql, only the fields
+// of the model with the comparers of their types are accepted and every value is an
+// argument of the query
+type pgQueryCompiler struct {
+	tableName    string
+	ctc          *PGContentTypeConfig
+	tsConfig     string
+	searchText   string
+	locationType string
+	fields       map[string]*content.Field
+	args         []string
+}
+
+// compile returns the arguments of the _query function of the table with the fields
+func (s *PGQuery) compile(fields content.Fields) (*pgCompiledQuery, error) {
+	c := &pgQueryCompiler{
+		tableName:    s.TableName,
+		ctc:          s.config,
+		tsConfig:     s.tsConfig,
+		searchText:   s.searchText,
+		locationType: s.LocationType,
+		fields:       make(map[string]*content.Field),
+		args:         make([]string, 0),
+	}
+	for _, f := range fields {
+		if fc := s.config.field(f.ID); fc != nil && fc.Exclude != nil && *fc.Exclude {
+			continue
+		}
+		c.fields[f.ID] = f
+	}
+
+	res := &pgCompiledQuery{Filters: make([]string, 0)}
+	// the near filters order by the distance, before the order parameter
+	orders := make([]string, 0)
+	for _, f := range s.filters {
+		if f.Comparer == "near" {
+			order, err := c.nearOrder(f)
+			if err != nil {
+				return nil, err
+			}
+			orders = append(orders, order)
+			continue
+		}
+		filter, err := c.filter(f)
+		if err != nil {
+			return nil, err
+		}
+		res.Filters = append(res.Filters, filter)
+	}
+	for _, o := range s.orders {
+		order, err := c.order(o)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	res.Order = strings.Join(orders, ",")
+	res.Args = c.args
+	return res, nil
+}
+
+// arg adds the value to the arguments
+func (c *pgQueryCompiler) arg(v string) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$1[%d]", len(c.args))
+}
+
+// argList adds the values to the arguments, the slice of them is a text array
+func (c *pgQueryCompiler) argList(vals []string) string {
+	first := len(c.args) + 1
+	c.args = append(c.args, vals...)
+	return fmt.Sprintf("$1[%d:%d]", first, len(c.args))
+}
+
+// column returns the column of the field in the materialized view
+func (c *pgQueryCompiler) column(field string) string {
+	return fmt.Sprintf("%s.%s", pgQuoteIdent(c.tableName), pgQuoteIdent(c.ctc.columnName(field)))
+}
+
+// field returns the field of the model
+func (c *pgQueryCompiler) field(id string, key string) (*content.Field, error) {
+	f := c.fields[id]
+	if f == nil {
+		return nil, fmt.Errorf("unknown field %s of %s: %s", id, c.tableName, key)
+	}
+	return f, nil
+}
+
+func (c *pgQueryCompiler) filter(f *pgFilter) (string, error) {
+	switch f.Kind {
+	case pgFilterSearch:
+		if !c.ctc.searchable() {
+			return "", fmt.Errorf("the content type has no searchable fields: %s", f.Key)
+		}
+		return pgSearchFilter(c.tableName, c.tsConfig, c.arg(strings.Join(f.Values, ",")), ""), nil
+	case pgFilterSpace:
+		return fmt.Sprintf("%[1]s._space || ':' || %[1]s._environment = ANY(%[2]s)", pgQuoteIdent(c.tableName), c.argList(f.Values)), nil
+	case pgFilterSys:
+		cast := "text"
+		if f.Field == "_updated_at" {
+			cast = "timestamptz"
+		}
+		return c.columnFilter(fmt.Sprintf("%s.%s", pgQuoteIdent(c.tableName), f.Field), cast, false, f)
+	case pgFilterContentType:
+		return c.contentTypeFilter(f)
+	case pgFilterReference:
+		return c.referenceFilter(f)
+	}
+
+	mf, err := c.field(f.Field, f.Key)
+	if err != nil {
+		return "", err
+	}
+	col := c.column(f.Field)
+	if f.Comparer == "within" {
+		if mf.Type != "location" {
+			return "", fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		area, err := parseGeoWithin(strings.Join(f.Values, ","))
+		if err != nil {
+			return "", err
+		}
+		return c.withinFilter(col, area), nil
+	}
+	if f.Comparer == "match" {
+		if c.ctc.searchField(f.Field) {
+			return pgSearchFilter(c.tableName, c.tsConfig, c.arg(strings.Join(f.Values, ",")), col), nil
+		}
+		if pgModelCast(mf) != "text" {
+			return "", fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		if mf.List {
+			col = fmt.Sprintf("array_to_string(%s, ' ')", col)
+		}
+		return fmt.Sprintf("%s ILIKE '%%' || %s || '%%'", col, c.arg(strings.Join(f.Values, ","))), nil
+	}
+	if mf.Reference && f.Comparer != "exists" {
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	return c.columnFilter(col, pgModelCast(mf), mf.List, f)
+}
+
+// columnFilter compares the column with the values cast to the type of the column,
+// the lists contain any of the values (all of them for all)
+func (c *pgQueryCompiler) columnFilter(col string, cast string, list bool, f *pgFilter) (string, error) {
+	value := strings.Join(f.Values, ",")
+	if f.Comparer == "exists" {
+		if value == "false" {
+			return fmt.Sprintf("%s IS NULL", col), nil
+		}
+		return fmt.Sprintf("%s IS NOT NULL", col), nil
+	}
+	if cast == "" {
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	vals := splitPGValues(f.Values)
+	if list {
+		switch f.Comparer {
+		case "", "in":
+			return fmt.Sprintf("%s && %s::%s[]", col, c.argList(vals), cast), nil
+		case "ne", "nin":
+			return fmt.Sprintf("NOT COALESCE(%s && %s::%s[], false)", col, c.argList(vals), cast), nil
+		case "all":
+			return fmt.Sprintf("%s @> %s::%s[]", col, c.argList(vals), cast), nil
+		}
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	switch f.Comparer {
+	case "":
+		return fmt.Sprintf("%s = %s::%s", col, c.arg(value), cast), nil
+	case "ne":
+		return fmt.Sprintf("%s IS DISTINCT FROM %s::%s", col, c.arg(value), cast), nil
+	case "lt", "lte", "gt", "gte":
+		return fmt.Sprintf("%s %s %s::%s", col, pgComparerOps[f.Comparer], c.arg(value), cast), nil
+	case "in":
+		return fmt.Sprintf("%s = ANY(%s::%s[])", col, c.argList(vals), cast), nil
+	case "nin":
+		return fmt.Sprintf("%s != ALL(%s::%s[])", col, c.argList(vals), cast), nil
+	}
+	return "", fmt.Errorf("unsupported filter: %s", f.Key)
+}
+
+// contentTypeFilter filters the content type of the linked entries, the json path
+// matches a single link and any item of the links
+func (c *pgQueryCompiler) contentTypeFilter(f *pgFilter) (string, error) {
+	mf, err := c.field(f.Field, f.Key)
+	if err != nil {
+		return "", err
+	}
+	if !mf.Reference {
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	if f.Comparer == "exists" {
+		return fmt.Sprintf("jsonb_path_exists(%s::jsonb, %s::jsonpath)", c.column(f.Field), c.arg("$.sys.contentType")), nil
+	}
+	return c.referenceFilter(&pgFilter{
+		Kind:     pgFilterReference,
+		Key:      f.Key,
+		Field:    f.Field,
+		Path:     []string{"sys", "contentType", "sys", "id"},
+		Comparer: f.Comparer,
+		Values:   f.Values,
+	})
+}
+
+// referenceFilter filters the fields of the linked entries embedded in the column, in
+// lax mode the json path matches a single link and any item of the links at every
+// level of the path. The path and its variables are arguments.
+func (c *pgQueryCompiler) referenceFilter(f *pgFilter) (string, error) {
+	mf, err := c.field(f.Field, f.Key)
+	if err != nil {
+		return "", err
+	}
+	if !mf.Reference {
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	vals := splitPGValues(f.Values)
+	vars := make(map[string]interface{})
+	// equals returns the condition of any of the values, the numbers and booleans match
+	// the json value and the text of the value
+	equals := func(vals []string) string {
+		eqs := make([]string, 0)
+		for _, v := range vals {
+			if lit := pgJSONValue(v); lit != v {
+				eqs = append(eqs, fmt.Sprintf("@ == $v%d", len(vars)))
+				vars[fmt.Sprintf("v%d", len(vars))] = lit
+			}
+			eqs = append(eqs, fmt.Sprintf("@ == $v%d", len(vars)))
+			vars[fmt.Sprintf("v%d", len(vars))] = v
+		}
+		return strings.Join(eqs, " || ")
+	}
+
+	jsonPath := "$." + strings.Join(f.Path, ".")
+	var cond string
+	negate := false
+	switch f.Comparer {
+	case "", "in":
+		cond = equals(vals)
+	case "ne", "nin":
+		cond = equals(vals)
+		negate = true
+	case "exists":
+		cond = "@ != null"
+		negate = len(vals) > 0 && vals[0] == "false"
+	case "lt", "lte", "gt", "gte":
+		if len(vals) != 1 {
+			return "", fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		cond = fmt.Sprintf("@ %s $v0", pgComparerOps[f.Comparer])
+		vars["v0"] = pgJSONValue(vals[0])
+	case "match":
+		// like_regex takes a literal pattern, the quoted text is a json path string
+		pattern, _ := json.Marshal(regexp.QuoteMeta(strings.Join(f.Values, ",")))
+		cond = fmt.Sprintf("@ like_regex %s flag \"i\"", pattern)
+	case "all":
+		all := make([]string, 0)
+		for _, v := range vals {
+			all = append(all, c.jsonPathFilter(c.column(f.Field), jsonPath, equals([]string{v}), vars))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(all, " AND ")), nil
+	default:
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+
+	filter := c.jsonPathFilter(c.column(f.Field), jsonPath, cond, vars)
+	if negate {
+		return fmt.Sprintf("%s IS NOT TRUE", filter), nil
+	}
+	return filter, nil
+}
+
+// jsonPathFilter returns the json path filter of the column with the path and the
+// variables as arguments
+func (c *pgQueryCompiler) jsonPathFilter(col string, jsonPath string, cond string, vars map[string]interface{}) string {
+	b, _ := json.Marshal(vars)
+	return fmt.Sprintf("jsonb_path_exists(%s::jsonb, %s::jsonpath, %s::jsonb)", col, c.arg(fmt.Sprintf("%s ? (%s)", jsonPath, cond)), c.arg(string(b)))
+}
+
+func (c *pgQueryCompiler) order(o *pgOrder) (string, error) {
+	desc := ""
+	if o.Desc {
+		desc = " DESC"
+	}
+	var field string
+	switch o.Kind {
+	case pgFilterSearch:
+		// the most relevant first, -relevance reverses it
+		if o.Desc {
+			desc = ""
+		} else {
+			desc = " DESC"
+		}
+		field = pgSearchRank(c.tableName, c.tsConfig, c.arg(c.searchText))
+	case pgFilterSys:
+		field = fmt.Sprintf("%s.%s", pgQuoteIdent(c.tableName), o.Field)
+	case pgFilterReference:
+		mf, err := c.field(o.Field, o.Key)
+		if err != nil {
+			return "", err
+		}
+		if !mf.Reference {
+			return "", fmt.Errorf("unsupported order: %s", o.Key)
+		}
+		// the jsonb values order the numbers numerically, the arrays have no value
+		field = fmt.Sprintf("(%s::jsonb #> %s::text[])", c.column(o.Field), c.arg("{"+strings.Join(o.Path, ",")+"}"))
+	default:
+		mf, err := c.field(o.Field, o.Key)
+		if err != nil {
+			return "", err
+		}
+		if mf.Reference || mf.List {
+			return "", fmt.Errorf("unsupported order: %s", o.Key)
+		}
+		field = c.column(o.Field)
+	}
+	return fmt.Sprintf("%s%s NULLS LAST", field, desc), nil
+}
+
+// nearOrder orders by the distance to the location of the near filter, the planar
+// distance in degrees of the points and the geodesic distance of the geographies
+func (c *pgQueryCompiler) nearOrder(f *pgFilter) (string, error) {
+	mf, err := c.field(f.Field, f.Key)
+	if err != nil {
+		return "", err
+	}
+	if mf.Type != "location" || mf.List {
+		return "", fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	p, err := parseGeoNear(strings.Join(f.Values, ","))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s <-> %s NULLS LAST", c.column(f.Field), c.location(p)), nil
+}
+
+// withinFilter returns the filter of the locations in the box or the circle, the box
+// around the circle is checked first with the gist index of the points
+func (c *pgQueryCompiler) withinFilter(col string, a *geoArea) string {
+	min, max := a.bounds()
+	if c.locationType == PGLocationGeography {
+		if a.Box {
+			return fmt.Sprintf("ST_Intersects(%s, ST_MakeEnvelope(%s::float8, %s::float8, %s::float8, %s::float8, 4326)::geography)",
+				col, c.geoArg(min.Lon), c.geoArg(min.Lat), c.geoArg(max.Lon), c.geoArg(max.Lat))
+		}
+		return fmt.Sprintf("ST_DWithin(%s, %s, %s::float8 * 1000)", col, c.location(a.Center), c.geoArg(a.RadiusKm))
+	}
+	box := fmt.Sprintf("%s <@ box(point(%s::float8, %s::float8), point(%s::float8, %s::float8))",
+		col, c.geoArg(min.Lon), c.geoArg(min.Lat), c.geoArg(max.Lon), c.geoArg(max.Lat))
+	if a.Box {
+		return box
+	}
+	return fmt.Sprintf("(%s AND %s <= %s::float8)", box, pgHaversine(col, c.geoArg(a.Center.Lat), c.geoArg(a.Center.Lon)), c.geoArg(a.RadiusKm))
+}
+
+// location returns the point or the geography of the location
+func (c *pgQueryCompiler) location(p geoPoint) string {
+	if c.locationType == PGLocationGeography {
+		return fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s::float8, %s::float8), 4326)::geography", c.geoArg(p.Lon), c.geoArg(p.Lat))
+	}
+	return fmt.Sprintf("point(%s::float8, %s::float8)", c.geoArg(p.Lon), c.geoArg(p.Lat))
+}
+
+func (c *pgQueryCompiler) geoArg(v float64) string {
+	return c.arg(strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// pgHaversine returns the great-circle distance in km of the (lon,lat) point column
+// and the location
+func pgHaversine(col string, lat string, lon string) string {
+	return fmt.Sprintf("2 * %[4]g * asin(least(1, sqrt(power(sin(radians(%[1]s[1] - %[2]s::float8) / 2), 2) + cos(radians(%[2]s::float8)) * cos(radians(%[1]s[1])) * power(sin(radians(%[1]s[0] - %[3]s::float8) / 2), 2))))",
+		col, lat, lon, earthRadiusKm)
+}
+
+// pgModelCast returns the type the values of the field are cast to, empty if the
+// field is compared to nothing but null
+func pgModelCast(f *content.Field) string {
+	if f.Reference {
+		return ""
+	}
+	switch f.Type {
+	case "text", "longtext":
+		return "text"
+	case "int":
+		return "integer"
+	case "float":
+		return "numeric"
+	case "bool":
+		return "boolean"
+	case "date":
+		return "timestamptz"
+	}
+	return ""
+}
+
+// pgJSONValue returns the json value of the text, the numbers and booleans are typed
+func pgJSONValue(v string) interface{} {
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// splitPGValues splits the comma separated values of the filter
+func splitPGValues(values []string) []string {
+	res := make([]string, 0)
+	for _, val := range values {
+		res = append(res, strings.Split(val, ",")...)
+	}
+	return res
+}
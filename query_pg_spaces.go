@@ -0,0 +1,211 @@
+package gontentful
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const spaceQueryParam = "space"
+
+// PGSpacesQuery routes a query to one or several configured spaces. Shared schemas
+// are filtered by _space/_environment, separate schemas are queried one by one and
+// their results are merged, ordered and paginated in memory.
+type PGSpacesQuery struct {
+	Queries []*PGQuery
+	Order   string
+	Skip    int
+	Limit   int
+}
+
+func ParsePGSpacesQuery(cfg *SpacesConfig, defaultLocale string, q url.Values) (*PGSpacesQuery, error) {
+	spaces, err := cfg.selectSpaces(q.Get(spaceQueryParam))
+	if err != nil {
+		return nil, err
+	}
+	q.Del(spaceQueryParam)
+
+	res := &PGSpacesQuery{
+		Queries: make([]*PGQuery, 0),
+		Order:   q.Get("order"),
+	}
+	res.Skip, _ = strconv.Atoi(q.Get("skip"))
+	res.Limit, _ = strconv.Atoi(q.Get("limit"))
+
+	if cfg.Shared() {
+		query, err := ParsePGQuery(cfg.SharedSchema, defaultLocale, cloneQuery(q), cfg.Tables)
+		if err != nil {
+			return nil, err
+		}
+		query.addFilter(spaceFilter(spaces))
+		res.Queries = append(res.Queries, query)
+		return res, nil
+	}
+
+	for _, s := range spaces {
+		query, err := ParsePGQuery(s.SchemaName, defaultLocale, cloneQuery(q), cfg.Tables)
+		if err != nil {
+			return nil, err
+		}
+		if len(spaces) > 1 {
+			// every schema has to return enough rows to paginate the merged result
+			query.Skip = 0
+			if query.Limit > 0 {
+				query.Limit = res.Skip + res.Limit
+			}
+		}
+		res.Queries = append(res.Queries, query)
+	}
+
+	return res, nil
+}
+
+func (c *SpacesConfig) selectSpaces(param string) ([]*SpaceConfig, error) {
+	if param == "" || param == "*" {
+		return c.Spaces, nil
+	}
+	res := make([]*SpaceConfig, 0)
+	for _, name := range strings.Split(param, ",") {
+		s := c.GetSpace(name)
+		if s == nil {
+			return nil, fmt.Errorf("unknown space: %s", name)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func spaceFilter(spaces []*SpaceConfig) *pgFilter {
+	keys := make([]string, 0)
+	for _, s := range spaces {
+		keys = append(keys, fmt.Sprintf("%s:%s", s.SpaceID, s.EnvironmentID))
+	}
+	return &pgFilter{Kind: pgFilterSpace, Key: "space", Values: keys}
+}
+
+func (s *PGQuery) addFilter(filter *pgFilter) {
+	s.filters = append(s.filters, filter)
+}
+
+func cloneQuery(q url.Values) url.Values {
+	c := url.Values{}
+	for k, v := range q {
+		c[k] = append(make([]string, 0), v...)
+	}
+	return c
+}
+
+// Exec runs the queries and returns the page of the merged entries like the CDA
+func (s *PGSpacesQuery) Exec(databaseURL string) (*Entries, error) {
+	if len(s.Queries) == 1 {
+		return s.Queries[0].Exec(databaseURL)
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int64, len(s.Queries))
+	results := make([][]map[string]interface{}, len(s.Queries))
+	errs := make([]error, len(s.Queries))
+
+	wg.Add(len(s.Queries))
+	for i, q := range s.Queries {
+		go func(i int, q *PGQuery) {
+			defer wg.Done()
+			counts[i], results[i], errs[i] = q.exec(databaseURL)
+		}(i, q)
+	}
+	wg.Wait()
+
+	var total int64
+	items := make([]map[string]interface{}, 0)
+	for i := range s.Queries {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		total += counts[i]
+		items = append(items, results[i]...)
+	}
+
+	sortItems(items, s.Order)
+
+	if s.Skip > 0 {
+		if s.Skip > len(items) {
+			s.Skip = len(items)
+		}
+		items = items[s.Skip:]
+	}
+	if s.Limit > 0 && s.Limit < len(items) {
+		items = items[:s.Limit]
+	}
+
+	// the queries of the spaces only differ by schema
+	return s.Queries[0].entries(total, s.Skip, s.Limit, items), nil
+}
+
+func sortItems(items []map[string]interface{}, order string) {
+	if order == "" {
+		return
+	}
+	orders := strings.Split(order, ",")
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, o := range orders {
+			desc := strings.HasPrefix(o, "-")
+			key := strings.TrimPrefix(o, "-")
+			a, b := itemValue(items[i], key), itemValue(items[j], key)
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+			// the nil values stay last in descending orders too
+			if desc && a != nil && b != nil {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func itemValue(item map[string]interface{}, key string) interface{} {
+	if strings.HasPrefix(key, "sys.") {
+		sys, _ := item["sys"].(map[string]interface{})
+		return sys[strings.TrimPrefix(key, "sys.")]
+	}
+	return item[strings.TrimPrefix(key, "fields.")]
+}
+
+// compareValues orders nil values last regardless of the direction, like NULLS LAST.
+func compareValues(a interface{}, b interface{}) int {
+	if a == nil || b == nil {
+		if a == b {
+			return 0
+		}
+		if a == nil {
+			return 1
+		}
+		return -1
+	}
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			if av < bv {
+				return -1
+			} else if av > bv {
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			if av == bv {
+				return 0
+			} else if !av {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
@@ -0,0 +1,269 @@
+package gontentful
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+// pgQueryTestModel is the _schema model of the game content type
+func pgQueryTestModel() content.Fields {
+	ct := schemaConfigTestTypes()[0]
+	ct.Fields = append(ct.Fields,
+		&ContentTypeField{ID: "studio", Type: "Link", LinkType: "Entry", Validations: []*FieldValidation{{LinkContentType: []string{"studio"}}}},
+		&ContentTypeField{ID: "related", Type: "Array", Items: &FieldTypeArrayItem{Type: "Link", LinkType: "Entry"}},
+		&ContentTypeField{ID: "heroBlock", Type: "Link", LinkType: "Entry"},
+		&ContentTypeField{ID: "logo", Type: "Link", LinkType: "Asset"},
+		&ContentTypeField{ID: "location", Type: "Location"},
+	)
+	return TransformModel(ct).Fields
+}
+
+// pgQueryTest compiles the query of the game content type
+func pgQueryTest(q url.Values, cfg *PGSchemaConfig) (*pgCompiledQuery, error) {
+	q.Set("content_type", "game")
+	query, err := ParsePGQuery("public", "en", q, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return query.compile(pgQueryTestModel())
+}
+
+func TestPGQueryFilters(t *testing.T) {
+	tests := []struct {
+		key    string
+		value  string
+		filter string
+		args   []string
+	}{
+		{"fields.name", "it's", `"game"."name" = $1[1]::text`, []string{"it's"}},
+		{"name[ne]", "a,b", `"game"."name" IS DISTINCT FROM $1[1]::text`, []string{"a,b"}},
+		{"fields.rtp[gte]", "96", `"game"."rtp" >= $1[1]::numeric`, []string{"96"}},
+		{"fields.releaseDate[lt]", "2023-01-01", `"game"."release_date" < $1[1]::timestamptz`, []string{"2023-01-01"}},
+		{"fields.name[in]", "a,b", `"game"."name" = ANY($1[1:2]::text[])`, []string{"a", "b"}},
+		{"fields.name[nin]", "a", `"game"."name" != ALL($1[1:1]::text[])`, []string{"a"}},
+		{"fields.name[exists]", "false", `"game"."name" IS NULL`, []string{}},
+		{"fields.name[match]", "50%'", `"game"."name" ILIKE '%' || $1[1] || '%'`, []string{"50%'"}},
+		{"fields.tags", "a", `"game"."tags" && $1[1:1]::text[]`, []string{"a"}},
+		{"fields.tags[nin]", "a,b", `NOT COALESCE("game"."tags" && $1[1:2]::text[], false)`, []string{"a", "b"}},
+		{"fields.tags[all]", "a,b", `"game"."tags" @> $1[1:2]::text[]`, []string{"a", "b"}},
+		{"fields.tags[match]", "a", `array_to_string("game"."tags", ' ') ILIKE '%' || $1[1] || '%'`, []string{"a"}},
+		{"sys.id[in]", "a,b", `"game"._sys_id = ANY($1[1:2]::text[])`, []string{"a", "b"}},
+		{"sys.updatedAt[gte]", "2023-01-01", `"game"._updated_at >= $1[1]::timestamptz`, []string{"2023-01-01"}},
+		{"fields.studio[exists]", "true", `"game"."studio" IS NOT NULL`, []string{}},
+	}
+	for _, tt := range tests {
+		q, err := pgQueryTest(url.Values{tt.key: []string{tt.value}}, nil)
+		if err != nil {
+			t.Errorf("%s: %s", tt.key, err)
+			continue
+		}
+		if len(q.Filters) != 1 || q.Filters[0] != tt.filter || !reflect.DeepEqual(q.Args, tt.args) {
+			t.Errorf("%s: got %v %q, want %s %q", tt.key, q.Filters, q.Args, tt.filter, tt.args)
+		}
+	}
+}
+
+func TestPGQueryReferenceFilters(t *testing.T) {
+	tests := []struct {
+		key    string
+		value  string
+		filter string
+		args   []string
+	}{
+		{"fields.heroBlock.sys.contentType.sys.id", "game", `jsonb_path_exists("game"."hero_block"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.sys.contentType.sys.id ? (@ == $v0)`, `{"v0":"game"}`}},
+		{"related.sys.contentType.sys.id[nin]", "game,it's", `jsonb_path_exists("game"."related"::jsonb, $1[1]::jsonpath, $1[2]::jsonb) IS NOT TRUE`, []string{`$.sys.contentType.sys.id ? (@ == $v0 || @ == $v1)`, `{"v0":"game","v1":"it's"}`}},
+		{"fields.related.sys.contentType.sys.id[exists]", "true", `jsonb_path_exists("game"."related"::jsonb, $1[1]::jsonpath)`, []string{`$.sys.contentType`}},
+		{"fields.studio.fields.name", "NetEnt", `jsonb_path_exists("game"."studio"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.name ? (@ == $v0)`, `{"v0":"NetEnt"}`}},
+		{"fields.studio.fields.owner.sys.id[in]", "o1,o2", `jsonb_path_exists("game"."studio"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.owner.sys.id ? (@ == $v0 || @ == $v1)`, `{"v0":"o1","v1":"o2"}`}},
+		{"fields.studio.fields.rank", "3", `jsonb_path_exists("game"."studio"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.rank ? (@ == $v0 || @ == $v1)`, `{"v0":3,"v1":"3"}`}},
+		{"fields.studio.fields.rank[gte]", "3", `jsonb_path_exists("game"."studio"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.rank ? (@ >= $v0)`, `{"v0":3}`}},
+		{"fields.related.fields.logo[exists]", "false", `jsonb_path_exists("game"."related"::jsonb, $1[1]::jsonpath, $1[2]::jsonb) IS NOT TRUE`, []string{`$.logo ? (@ != null)`, `{}`}},
+		{"fields.logo.fields.title[match]", `dead.or"`, `jsonb_path_exists("game"."logo"::jsonb, $1[1]::jsonpath, $1[2]::jsonb)`, []string{`$.title ? (@ like_regex "dead\\.or\"" flag "i")`, `{}`}},
+		{"fields.related.sys.id[all]", "a,b", `(jsonb_path_exists("game"."related"::jsonb, $1[1]::jsonpath, $1[2]::jsonb) AND jsonb_path_exists("game"."related"::jsonb, $1[3]::jsonpath, $1[4]::jsonb))`, []string{`$.sys.id ? (@ == $v0)`, `{"v0":"a"}`, `$.sys.id ? (@ == $v1)`, `{"v0":"a","v1":"b"}`}},
+	}
+	for _, tt := range tests {
+		q, err := pgQueryTest(url.Values{tt.key: []string{tt.value}}, nil)
+		if err != nil {
+			t.Errorf("%s: %s", tt.key, err)
+			continue
+		}
+		if len(q.Filters) != 1 || q.Filters[0] != tt.filter || !reflect.DeepEqual(q.Args, tt.args) {
+			t.Errorf("%s: got %v %q, want %s %q", tt.key, q.Filters, q.Args, tt.filter, tt.args)
+		}
+	}
+}
+
+func TestPGQueryUnsupportedFilters(t *testing.T) {
+	for _, key := range []string{
+		"fields.unknown",
+		"fields.name[near]",
+		"fields.name[within]",
+		"fields.location[within]",
+		"fields.location[lt]",
+		"fields.studio.fields.location[within]",
+		"sys.id[near]",
+		"fields.name.fields.slug",
+		"fields.tags[lt]",
+		"fields.studio",
+		"fields.studio.name",
+		"fields.studio.fields",
+		"fields.studio.fields.rank[lt]",
+		"fields.heroBlock.sys.contentType.sys.id[lt]",
+		"fields.name.sys.contentType.sys.id",
+		"sys.contentType.sys.id",
+		"sys.id[match]",
+		"fields.name\"; DROP TABLE game; --",
+		"query",
+	} {
+		if _, err := pgQueryTest(url.Values{key: []string{"1,2"}}, nil); err == nil {
+			t.Errorf("unsupported filter accepted: %s", key)
+		}
+	}
+	for _, order := range []string{"sys.createdAt", "fields.unknown", "fields.tags", "fields.studio", "fields.name.fields.slug", "fields.studio.name", "name,(SELECT 1)"} {
+		if _, err := pgQueryTest(url.Values{"order": []string{order}}, nil); err == nil {
+			t.Errorf("unsupported order accepted: %s", order)
+		}
+	}
+}
+
+func TestPGQueryOrder(t *testing.T) {
+	q, err := pgQueryTest(url.Values{
+		"fields.studio.fields.name": []string{"NetEnt"},
+		"order":                     []string{"-fields.studio.fields.owner.fields.name,sys.updatedAt,name"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Order != `("game"."studio"::jsonb #> $1[3]::text[]) DESC NULLS LAST,"game"._updated_at NULLS LAST,"game"."name" NULLS LAST` {
+		t.Errorf("unexpected order %s", q.Order)
+	}
+	if len(q.Args) != 3 || q.Args[2] != "{owner,name}" {
+		t.Errorf("unexpected args %q", q.Args)
+	}
+}
+
+func TestPGQueryLocation(t *testing.T) {
+	tests := []struct {
+		key    string
+		value  string
+		filter string
+		args   []string
+	}{
+		{"fields.location[within]", "52.5,13.3,52.6,13.5", `"game"."location" <@ box(point($1[1]::float8, $1[2]::float8), point($1[3]::float8, $1[4]::float8))`, []string{"13.3", "52.5", "13.5", "52.6"}},
+		{"fields.location[within]", "0,10,111.32", `("game"."location" <@ box(point($1[1]::float8, $1[2]::float8), point($1[3]::float8, $1[4]::float8)) AND 2 * 6371 * asin(least(1, sqrt(power(sin(radians("game"."location"[1] - $1[5]::float8) / 2), 2) + cos(radians($1[5]::float8)) * cos(radians("game"."location"[1])) * power(sin(radians("game"."location"[0] - $1[6]::float8) / 2), 2)))) <= $1[7]::float8)`, []string{"9", "-1", "11", "1", "0", "10", "111.32"}},
+		{"fields.location[within]", "89.5,10,100", `("game"."location" <@ box(point($1[1]::float8, $1[2]::float8), point($1[3]::float8, $1[4]::float8)) AND 2 * 6371 * asin(least(1, sqrt(power(sin(radians("game"."location"[1] - $1[5]::float8) / 2), 2) + cos(radians($1[5]::float8)) * cos(radians("game"."location"[1])) * power(sin(radians("game"."location"[0] - $1[6]::float8) / 2), 2)))) <= $1[7]::float8)`, []string{"-180", "88.60168882500898", "180", "90", "89.5", "10", "100"}},
+	}
+	for _, tt := range tests {
+		q, err := pgQueryTest(url.Values{tt.key: []string{tt.value}}, nil)
+		if err != nil {
+			t.Errorf("%s=%s: %s", tt.key, tt.value, err)
+			continue
+		}
+		if len(q.Filters) != 1 || q.Filters[0] != tt.filter || !reflect.DeepEqual(q.Args, tt.args) {
+			t.Errorf("%s=%s: got %v %q, want %s %q", tt.key, tt.value, q.Filters, q.Args, tt.filter, tt.args)
+		}
+	}
+
+	q, err := pgQueryTest(url.Values{
+		"fields.location[near]": []string{"52.52,13.405"},
+		"fields.name":           []string{"Starburst"},
+		"order":                 []string{"-sys.updatedAt"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Filters) != 1 || q.Order != `"game"."location" <-> point($1[1]::float8, $1[2]::float8) NULLS LAST,"game"._updated_at DESC NULLS LAST` ||
+		!reflect.DeepEqual(q.Args, []string{"13.405", "52.52", "Starburst"}) {
+		t.Errorf("unexpected near query %v %s %q", q.Filters, q.Order, q.Args)
+	}
+
+	for _, value := range []string{"52.52", "91,0", "a,b", "52,13,x", "53,13,52,14", "52,13,0", "52,13,1,2,3"} {
+		key := "fields.location[within]"
+		if len(strings.Split(value, ",")) < 3 {
+			key = "fields.location[near]"
+		}
+		if _, err := pgQueryTest(url.Values{key: []string{value}}, nil); err == nil {
+			t.Errorf("invalid location accepted: %s=%s", key, value)
+		}
+	}
+}
+
+func TestPGQueryGeography(t *testing.T) {
+	cfg := &PGSchemaConfig{LocationType: PGLocationGeography}
+	q, err := pgQueryTest(url.Values{
+		"fields.location[near]":   []string{"52.52,13.405"},
+		"fields.location[within]": []string{"52.52,13.405,5"},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Order != `"game"."location" <-> ST_SetSRID(ST_MakePoint($1[1]::float8, $1[2]::float8), 4326)::geography NULLS LAST` ||
+		q.Filters[0] != `ST_DWithin("game"."location", ST_SetSRID(ST_MakePoint($1[3]::float8, $1[4]::float8), 4326)::geography, $1[5]::float8 * 1000)` ||
+		!reflect.DeepEqual(q.Args, []string{"13.405", "52.52", "13.405", "52.52", "5"}) {
+		t.Errorf("unexpected geography query %v %s %q", q.Filters, q.Order, q.Args)
+	}
+
+	q, err = pgQueryTest(url.Values{"fields.location[within]": []string{"52.5,13.3,52.6,13.5"}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Filters[0] != `ST_Intersects("game"."location", ST_MakeEnvelope($1[1]::float8, $1[2]::float8, $1[3]::float8, $1[4]::float8, 4326)::geography)` {
+		t.Errorf("unexpected geography box %v", q.Filters)
+	}
+}
+
+func TestPGQueryValues(t *testing.T) {
+	// the values are arguments only, the filters are made of the model and the comparers
+	value := `x'); DROP TABLE game; --`
+	q, err := pgQueryTest(url.Values{
+		"fields.name":               []string{value},
+		"fields.name[match]":        []string{value},
+		"fields.studio.fields.name": []string{value},
+		"fields.tags[in]":           []string{value},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := strings.Join(q.Filters, " AND ")
+	if strings.Contains(filters, "DROP") || !strings.Contains(strings.Join(q.Args, ""), "DROP") {
+		t.Errorf("unexpected query %s %q", filters, q.Args)
+	}
+
+	query, err := ParsePGQuery("", "en", url.Values{"content_type": []string{"game"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.addFilter(spaceFilter([]*SpaceConfig{{SpaceID: "s1", EnvironmentID: "master"}, {SpaceID: "s2", EnvironmentID: "dev"}}))
+	c, err := query.compile(pgQueryTestModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Filters[0] != `"game"._space || ':' || "game"._environment = ANY($1[1:2])` || !reflect.DeepEqual(c.Args, []string{"s1:master", "s2:dev"}) {
+		t.Errorf("unexpected space filter %v %q", c.Filters, c.Args)
+	}
+}
+
+func TestPGQueryFunctions(t *testing.T) {
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", schemaConfigTestTypes(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`DROP FUNCTION IF EXISTS "game_query"(TEXT, TEXT[], TEXT, INTEGER, INTEGER);`,
+		`CREATE OR REPLACE FUNCTION "game_query"(localeArg TEXT, filters TEXT[], args TEXT[], orderBy TEXT, skip INTEGER, take INTEGER)`,
+		`FROM ' || format('%I', 'mv_game_' || lower(localeArg)) || ' "game"'`,
+		`EXECUTE qs INTO res USING args;`,
+	} {
+		if !strings.Contains(funcs, want) {
+			t.Errorf("missing %q in:\n%s", want, funcs)
+		}
+	}
+}
@@ -57,7 +57,7 @@ func (s *PGReferences) Exec(databaseURL string) error {
 }
 
 func (s *PGReferences) Render() (string, error) {
-	tmpl, err := template.New("referencesTemplate").Parse(pgReferencesTemplate)
+	tmpl, err := template.New("referencesTemplate").Funcs(pgIdentifierFuncMap).Parse(pgReferencesTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -2,15 +2,15 @@ package gontentful
 
 const pgReferencesTemplate = `
 {{ range $idx, $tbl := $.Schema.ConTables }}
-CREATE TABLE IF NOT EXISTS {{ .TableName }} (
+CREATE TABLE IF NOT EXISTS "{{ .TableName }}" (
 	_id SERIAL primary key,
 	{{- range $colidx, $col := .Columns }}
 	{{- if $colidx -}},{{- end }}
-	"{{ .ColumnName }}" TEXT NOT NULL
+	"{{ .ColumnName }}" TEXT{{ if .Required }} NOT NULL{{ end }}
 	{{- end }}
 );
 {{ range $idxn, $idxf := .Indices }}
-CREATE INDEX IF NOT EXISTS idx_{{ $tbl.TableName }}_{{ $tbl.TableName }}_{{ $idxn }} ON {{ $tbl.TableName }} ({{ $idxf }});
+CREATE INDEX IF NOT EXISTS {{ Index $tbl.TableName $tbl.TableName $idxn | Ident }} ON "{{ $tbl.TableName }}" ({{ $idxf }});
 {{- end }}
 {{ end -}}
 --
@@ -38,7 +38,7 @@ ON CONFLICT (tablename, reference) DO NOTHING;
 --  REFERENCES {{ .Reference }} (_id)
 --  ON DELETE CASCADE;
 --
-CREATE INDEX IF NOT EXISTS idx_{{ .TableName }}_{{ .ForeignKey }} ON {{ .TableName }}({{ .ForeignKey }});
+CREATE INDEX IF NOT EXISTS {{ Index .TableName .ForeignKey | Ident }} ON "{{ .TableName }}"("{{ .ForeignKey }}");
 --
 {{- end -}}
 `
@@ -0,0 +1,91 @@
+package gontentful
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/moonwalker/moonbase/pkg/content"
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+// ContentRepository is the storage of the moonbase CMS content: a _schema.json and
+// one <id>/<locale>.json folder per content type under the configured workdir.
+// Paths are relative to the repository root. The CMS functions (GetCMSEntries,
+// GHPublish, ...) take the repository, GitHub, a local directory or in memory.
+type ContentRepository interface {
+	// Name is the name of the repository, the default brand of the asset urls
+	Name() string
+	// ReadFile returns the content of a single file
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	// GetFiles returns the named files of the folder at path
+	GetFiles(ctx context.Context, path string, files []string) ([]*github.RepositoryContent, error)
+	// GetContents returns all the files under path
+	GetContents(ctx context.Context, path string) ([]*github.RepositoryContent, error)
+	// GetSchema returns the _schema.json of the content type folder at path
+	GetSchema(ctx context.Context, path string) (*github.RepositoryContent, error)
+	// GetSchemas returns all the _schema.json files under path
+	GetSchemas(ctx context.Context, path string) ([]*github.RepositoryContent, error)
+	// CreateBlob stores content (utf-8 or base64 encoded) and returns its sha,
+	// to be committed with a BlobEntry
+	CreateBlob(ctx context.Context, content string, encoding string) (string, error)
+	// CommitBlobs writes the entries in a single commit: entries with content or
+	// sha are written, entries with neither are deleted
+	CommitBlobs(ctx context.Context, entries []gh.BlobEntry, message string) (github.Rate, error)
+	// DeleteEntries returns the entries deleting the files of the folder at path
+	DeleteEntries(ctx context.Context, path string) ([]gh.BlobEntry, error)
+	// DeletePath deletes all the files under path in a single commit
+	DeletePath(ctx context.Context, path string, message string) error
+}
+
+// archivedContentReader is implemented by the repositories that can read a whole
+// folder faster at once (GitHubRepository).
+type archivedContentReader interface {
+	GetArchivedContents(ctx context.Context, path string) ([]*github.RepositoryContent, error)
+}
+
+func newRepositoryContent(filePath string, data []byte) *github.RepositoryContent {
+	name := path.Base(filePath)
+	content := string(data)
+	return &github.RepositoryContent{
+		Name:    &name,
+		Path:    &filePath,
+		Content: &content,
+	}
+}
+
+// filterRepositoryContents returns the files under dir (recursive) from the file map, sorted by path.
+func filterRepositoryContents(files map[string][]byte, dir string, schemasOnly bool) []*github.RepositoryContent {
+	paths := make([]string, 0)
+	for p := range files {
+		if !inRepositoryPath(p, dir) {
+			continue
+		}
+		if schemasOnly && path.Base(p) != content.JsonSchemaName {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	rcs := make([]*github.RepositoryContent, 0)
+	for _, p := range paths {
+		rcs = append(rcs, newRepositoryContent(p, files[p]))
+	}
+	return rcs
+}
+
+func inRepositoryPath(p string, dir string) bool {
+	dir = cleanRepositoryPath(dir)
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func cleanRepositoryPath(p string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
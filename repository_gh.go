@@ -0,0 +1,91 @@
+package gontentful
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v48/github"
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+// GitHubRepository reads and commits the content through the GitHub API.
+type GitHubRepository struct {
+	Token  string
+	Owner  string
+	Repo   string
+	Branch string
+}
+
+// NewGitHubRepository uses the GITHUB_TOKEN (or GH_TOKEN) environment variable for authentication.
+func NewGitHubRepository(owner string, repo string, branch string) *GitHubRepository {
+	return &GitHubRepository{
+		Token:  getAccessToken(),
+		Owner:  owner,
+		Repo:   repo,
+		Branch: branch,
+	}
+}
+
+func (r *GitHubRepository) Name() string {
+	return r.Repo
+}
+
+func (r *GitHubRepository) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	data, _, err := gh.GetBlob(ctx, r.Token, r.Owner, r.Repo, r.Branch, path)
+	return data, err
+}
+
+func (r *GitHubRepository) GetFiles(ctx context.Context, path string, files []string) ([]*github.RepositoryContent, error) {
+	rcs, _, err := gh.GetFilesContent(ctx, r.Token, r.Owner, r.Repo, r.Branch, path, files)
+	return rcs, err
+}
+
+func (r *GitHubRepository) GetContents(ctx context.Context, path string) ([]*github.RepositoryContent, error) {
+	rcs, _, err := gh.GetContentsRecursive(ctx, r.Token, r.Owner, r.Repo, r.Branch, path)
+	return rcs, err
+}
+
+// GetArchivedContents downloads all the files under path in a single archive,
+// faster than GetContents for the whole workdir.
+func (r *GitHubRepository) GetArchivedContents(ctx context.Context, path string) ([]*github.RepositoryContent, error) {
+	rcs, _, err := gh.GetArchivedContents(ctx, r.Token, r.Owner, r.Repo, r.Branch, path)
+	return rcs, err
+}
+
+func (r *GitHubRepository) GetSchema(ctx context.Context, path string) (*github.RepositoryContent, error) {
+	rc, _, err := gh.GetSchema(ctx, r.Token, r.Owner, r.Repo, r.Branch, path)
+	return rc, err
+}
+
+func (r *GitHubRepository) GetSchemas(ctx context.Context, path string) ([]*github.RepositoryContent, error) {
+	rcs, _, err := gh.GetSchemasRecursive(ctx, r.Token, r.Owner, r.Repo, r.Branch, path)
+	return rcs, err
+}
+
+func (r *GitHubRepository) CreateBlob(ctx context.Context, content string, encoding string) (string, error) {
+	blob, _, err := gh.CreateBlob(ctx, r.Token, r.Owner, r.Repo, r.Branch, &content, &encoding)
+	if err != nil {
+		return "", err
+	}
+	if blob.SHA == nil {
+		return "", fmt.Errorf("blob created without sha")
+	}
+	return *blob.SHA, nil
+}
+
+func (r *GitHubRepository) CommitBlobs(ctx context.Context, entries []gh.BlobEntry, message string) (github.Rate, error) {
+	resp, err := gh.CommitBlobs(ctx, r.Token, r.Owner, r.Repo, r.Branch, entries, message)
+	if resp == nil {
+		return github.Rate{}, err
+	}
+	return resp.Rate, err
+}
+
+func (r *GitHubRepository) DeleteEntries(ctx context.Context, path string) ([]gh.BlobEntry, error) {
+	return gh.GetDeleteFileEntries(ctx, r.Token, r.Owner, r.Repo, r.Branch, path, "")
+}
+
+func (r *GitHubRepository) DeletePath(ctx context.Context, path string, message string) error {
+	_, err := gh.DeleteFolder(ctx, r.Token, r.Owner, r.Repo, r.Branch, path, message)
+	return err
+}
@@ -0,0 +1,176 @@
+package gontentful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v48/github"
+	gh "github.com/moonwalker/moonbase/pkg/github"
+	"golang.org/x/oauth2"
+)
+
+const enableAutoMergeMutation = `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`
+
+// PullRequest is an open pull request of a content branch.
+type PullRequest struct {
+	Number int
+	URL    string
+	NodeID string
+	Branch string
+}
+
+// PullRequestRepository is a ContentRepository that can publish the changes
+// through pull requests instead of committing to the base branch.
+type PullRequestRepository interface {
+	ContentRepository
+	// FindPullRequest returns the open pull request of branch, nil if there is none
+	FindPullRequest(ctx context.Context, branch string) (*PullRequest, error)
+	// CommitBranch commits the entries to branch. The branch is created from (or
+	// reset to) the head of the base branch when fromBase is set or it is missing.
+	CommitBranch(ctx context.Context, branch string, fromBase bool, entries []gh.BlobEntry, message string) error
+	CreatePullRequest(ctx context.Context, branch string, title string, body string) (*PullRequest, error)
+	UpdatePullRequest(ctx context.Context, pr *PullRequest, title string, body string) error
+	// EnableAutoMerge merges the pull request once the required checks pass
+	EnableAutoMerge(ctx context.Context, pr *PullRequest, mergeMethod string) error
+}
+
+func (r *GitHubRepository) client(ctx context.Context) *github.Client {
+	return github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: r.Token})))
+}
+
+func (r *GitHubRepository) FindPullRequest(ctx context.Context, branch string) (*PullRequest, error) {
+	prs, _, err := r.client(ctx).PullRequests.List(ctx, r.Owner, r.Repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", r.Owner, branch),
+		Base:  r.Branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return newPullRequest(prs[0], branch), nil
+}
+
+func (r *GitHubRepository) CommitBranch(ctx context.Context, branch string, fromBase bool, entries []gh.BlobEntry, message string) error {
+	client := r.client(ctx)
+
+	base, _, err := client.Git.GetRef(ctx, r.Owner, r.Repo, "refs/heads/"+r.Branch)
+	if err != nil {
+		return err
+	}
+
+	ref, resp, err := client.Git.GetRef(ctx, r.Owner, r.Repo, "refs/heads/"+branch)
+	switch {
+	case err != nil && resp != nil && resp.StatusCode == http.StatusNotFound:
+		ref, _, err = client.Git.CreateRef(ctx, r.Owner, r.Repo, &github.Reference{
+			Ref:    github.String("refs/heads/" + branch),
+			Object: &github.GitObject{SHA: base.Object.SHA},
+		})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case fromBase:
+		// a previous pull request of the branch was merged or closed
+		ref.Object.SHA = base.Object.SHA
+		ref, _, err = client.Git.UpdateRef(ctx, r.Owner, r.Repo, ref, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	treeEntries := make([]*github.TreeEntry, 0)
+	for _, e := range entries {
+		treeEntries = append(treeEntries, &github.TreeEntry{
+			Path:    github.String(e.Path),
+			Type:    github.String("blob"),
+			Mode:    github.String("100644"),
+			Content: e.Content,
+			SHA:     e.SHA,
+		})
+	}
+	tree, _, err := client.Git.CreateTree(ctx, r.Owner, r.Repo, *ref.Object.SHA, treeEntries)
+	if err != nil {
+		return err
+	}
+
+	commit, _, err := client.Git.CreateCommit(ctx, r.Owner, r.Repo, &github.Commit{
+		Message: &message,
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: ref.Object.SHA}},
+	})
+	if err != nil {
+		return err
+	}
+
+	ref.Object.SHA = commit.SHA
+	_, _, err = client.Git.UpdateRef(ctx, r.Owner, r.Repo, ref, false)
+	return err
+}
+
+func (r *GitHubRepository) CreatePullRequest(ctx context.Context, branch string, title string, body string) (*PullRequest, error) {
+	pr, _, err := r.client(ctx).PullRequests.Create(ctx, r.Owner, r.Repo, &github.NewPullRequest{
+		Title:               &title,
+		Body:                &body,
+		Head:                &branch,
+		Base:                &r.Branch,
+		MaintainerCanModify: github.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newPullRequest(pr, branch), nil
+}
+
+func (r *GitHubRepository) UpdatePullRequest(ctx context.Context, pr *PullRequest, title string, body string) error {
+	_, _, err := r.client(ctx).PullRequests.Edit(ctx, r.Owner, r.Repo, pr.Number, &github.PullRequest{
+		Title: &title,
+		Body:  &body,
+	})
+	return err
+}
+
+// EnableAutoMerge uses the GraphQL API, auto-merge is not available in the REST API.
+func (r *GitHubRepository) EnableAutoMerge(ctx context.Context, pr *PullRequest, mergeMethod string) error {
+	client := r.client(ctx)
+	req, err := client.NewRequest(http.MethodPost, "graphql", map[string]interface{}{
+		"query": enableAutoMergeMutation,
+		"variables": map[string]interface{}{
+			"id":     pr.NodeID,
+			"method": mergeMethod,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	res := struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	_, err = client.Do(ctx, req, &res)
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return errors.New(res.Errors[0].Message)
+	}
+	return nil
+}
+
+func newPullRequest(pr *github.PullRequest, branch string) *PullRequest {
+	return &PullRequest{
+		Number: pr.GetNumber(),
+		URL:    pr.GetHTMLURL(),
+		NodeID: pr.GetNodeID(),
+		Branch: branch,
+	}
+}
@@ -0,0 +1,251 @@
+package gontentful
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/moonwalker/moonbase/pkg/content"
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+// LocalRepository reads and writes the content in a local directory (e.g. a
+// checkout of the content repository). With Git set the written files are
+// committed in the worktree with the git command.
+type LocalRepository struct {
+	Dir string
+	Git bool
+
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func NewLocalRepository(dir string, git bool) *LocalRepository {
+	return &LocalRepository{
+		Dir:   dir,
+		Git:   git,
+		blobs: make(map[string][]byte),
+	}
+}
+
+func (r *LocalRepository) fullPath(p string) string {
+	return filepath.Join(r.Dir, filepath.FromSlash(cleanRepositoryPath(p)))
+}
+
+// Name is the name of the directory
+func (r *LocalRepository) Name() string {
+	return filepath.Base(r.Dir)
+}
+
+func (r *LocalRepository) ReadFile(ctx context.Context, p string) ([]byte, error) {
+	return os.ReadFile(r.fullPath(p))
+}
+
+func (r *LocalRepository) GetFiles(ctx context.Context, dir string, files []string) ([]*github.RepositoryContent, error) {
+	rcs := make([]*github.RepositoryContent, 0)
+	for _, fn := range files {
+		p := cleanRepositoryPath(path.Join(dir, fn))
+		data, err := os.ReadFile(r.fullPath(p))
+		if err != nil {
+			return nil, err
+		}
+		rc := newRepositoryContent(p, data)
+		downloadURL := "file://" + r.fullPath(p)
+		rc.DownloadURL = &downloadURL
+		rcs = append(rcs, rc)
+	}
+	return rcs, nil
+}
+
+func (r *LocalRepository) GetContents(ctx context.Context, dir string) ([]*github.RepositoryContent, error) {
+	files, err := r.readFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return filterRepositoryContents(files, dir, false), nil
+}
+
+func (r *LocalRepository) GetSchema(ctx context.Context, dir string) (*github.RepositoryContent, error) {
+	p := cleanRepositoryPath(path.Join(dir, content.JsonSchemaName))
+	data, err := os.ReadFile(r.fullPath(p))
+	if err != nil {
+		return nil, err
+	}
+	return newRepositoryContent(p, data), nil
+}
+
+func (r *LocalRepository) GetSchemas(ctx context.Context, dir string) ([]*github.RepositoryContent, error) {
+	files, err := r.readFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return filterRepositoryContents(files, dir, true), nil
+}
+
+func (r *LocalRepository) readFiles(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(r.fullPath(dir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(r.Dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read file at %s: %s", p, err.Error())
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	return files, err
+}
+
+func (r *LocalRepository) CreateBlob(ctx context.Context, content string, encoding string) (string, error) {
+	data, err := decodeBlob(content, encoding)
+	if err != nil {
+		return "", err
+	}
+	sha := blobSHA(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.blobs == nil {
+		r.blobs = make(map[string][]byte)
+	}
+	r.blobs[sha] = data
+	return sha, nil
+}
+
+func (r *LocalRepository) CommitBlobs(ctx context.Context, entries []gh.BlobEntry, message string) (github.Rate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resolved := make([][]byte, len(entries))
+	for i, e := range entries {
+		data, err := resolveBlobEntry(r.blobs, e)
+		if err != nil {
+			return github.Rate{}, err
+		}
+		resolved[i] = data
+	}
+
+	paths := make([]string, 0)
+	for i, e := range entries {
+		data := resolved[i]
+		p := r.fullPath(e.Path)
+		if data == nil {
+			err := os.Remove(p)
+			if err != nil && !os.IsNotExist(err) {
+				return github.Rate{}, err
+			}
+		} else {
+			err := os.MkdirAll(filepath.Dir(p), 0755)
+			if err != nil {
+				return github.Rate{}, err
+			}
+			err = os.WriteFile(p, data, 0644)
+			if err != nil {
+				return github.Rate{}, err
+			}
+		}
+		paths = append(paths, cleanRepositoryPath(e.Path))
+	}
+
+	return github.Rate{}, r.commit(paths, message)
+}
+
+func (r *LocalRepository) DeleteEntries(ctx context.Context, dir string) ([]gh.BlobEntry, error) {
+	des, err := os.ReadDir(r.fullPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]gh.BlobEntry, 0)
+	for _, de := range des {
+		if !de.IsDir() {
+			entries = append(entries, gh.BlobEntry{Path: cleanRepositoryPath(path.Join(dir, de.Name()))})
+		}
+	}
+	return entries, nil
+}
+
+func (r *LocalRepository) DeletePath(ctx context.Context, dir string, message string) error {
+	err := os.RemoveAll(r.fullPath(dir))
+	if err != nil {
+		return err
+	}
+	return r.commit([]string{cleanRepositoryPath(dir)}, message)
+}
+
+func (r *LocalRepository) commit(paths []string, message string) error {
+	if !r.Git || len(paths) == 0 {
+		return nil
+	}
+	err := r.git(append([]string{"add", "-A", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	// nothing staged, nothing to commit
+	if r.git("diff", "--cached", "--quiet") == nil {
+		return nil
+	}
+	return r.git("commit", "-m", message)
+}
+
+func (r *LocalRepository) git(args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("git %s failed: %s %s", args[0], err.Error(), stderr.String())
+	}
+	return nil
+}
+
+func decodeBlob(content string, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(content)
+	}
+	return []byte(content), nil
+}
+
+// blobSHA is the git object id of the content.
+func blobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// resolveBlobEntry returns the content of the entry, nil for deleted files.
+func resolveBlobEntry(blobs map[string][]byte, e gh.BlobEntry) ([]byte, error) {
+	switch {
+	case e.Content != nil:
+		return []byte(*e.Content), nil
+	case e.SHA != nil:
+		data, ok := blobs[*e.SHA]
+		if !ok {
+			return nil, fmt.Errorf("unknown blob %s for %s", *e.SHA, e.Path)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
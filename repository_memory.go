@@ -0,0 +1,156 @@
+package gontentful
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/moonwalker/moonbase/pkg/content"
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+// MemoryRepository keeps the content in memory, the commit messages are recorded in Commits.
+type MemoryRepository struct {
+	// RepoName is returned by Name
+	RepoName string
+	Files    map[string][]byte
+	Commits  []string
+
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryRepository(files map[string]string) *MemoryRepository {
+	r := &MemoryRepository{
+		Files:   make(map[string][]byte),
+		Commits: make([]string, 0),
+		blobs:   make(map[string][]byte),
+	}
+	for p, data := range files {
+		r.Files[cleanRepositoryPath(p)] = []byte(data)
+	}
+	return r
+}
+
+func (r *MemoryRepository) Name() string {
+	return r.RepoName
+}
+
+func (r *MemoryRepository) ReadFile(ctx context.Context, p string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	data, ok := r.Files[cleanRepositoryPath(p)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p, os.ErrNotExist)
+	}
+	return data, nil
+}
+
+func (r *MemoryRepository) GetFiles(ctx context.Context, dir string, files []string) ([]*github.RepositoryContent, error) {
+	rcs := make([]*github.RepositoryContent, 0)
+	for _, fn := range files {
+		p := cleanRepositoryPath(path.Join(dir, fn))
+		data, err := r.ReadFile(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		rcs = append(rcs, newRepositoryContent(p, data))
+	}
+	return rcs, nil
+}
+
+func (r *MemoryRepository) GetContents(ctx context.Context, dir string) ([]*github.RepositoryContent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return filterRepositoryContents(r.Files, dir, false), nil
+}
+
+func (r *MemoryRepository) GetSchema(ctx context.Context, dir string) (*github.RepositoryContent, error) {
+	p := cleanRepositoryPath(path.Join(dir, content.JsonSchemaName))
+	data, err := r.ReadFile(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return newRepositoryContent(p, data), nil
+}
+
+func (r *MemoryRepository) GetSchemas(ctx context.Context, dir string) ([]*github.RepositoryContent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return filterRepositoryContents(r.Files, dir, true), nil
+}
+
+func (r *MemoryRepository) CreateBlob(ctx context.Context, content string, encoding string) (string, error) {
+	data, err := decodeBlob(content, encoding)
+	if err != nil {
+		return "", err
+	}
+	sha := blobSHA(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.blobs == nil {
+		r.blobs = make(map[string][]byte)
+	}
+	r.blobs[sha] = data
+	return sha, nil
+}
+
+func (r *MemoryRepository) CommitBlobs(ctx context.Context, entries []gh.BlobEntry, message string) (github.Rate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// resolve all the entries first, the commit is applied entirely or not at all
+	resolved := make([][]byte, len(entries))
+	for i, e := range entries {
+		data, err := resolveBlobEntry(r.blobs, e)
+		if err != nil {
+			return github.Rate{}, err
+		}
+		resolved[i] = data
+	}
+
+	for i, e := range entries {
+		p := cleanRepositoryPath(e.Path)
+		if resolved[i] == nil {
+			delete(r.Files, p)
+		} else {
+			r.Files[p] = resolved[i]
+		}
+	}
+	r.Commits = append(r.Commits, message)
+
+	return github.Rate{}, nil
+}
+
+func (r *MemoryRepository) DeleteEntries(ctx context.Context, dir string) ([]gh.BlobEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dir = cleanRepositoryPath(dir)
+	entries := make([]gh.BlobEntry, 0)
+	for _, rc := range filterRepositoryContents(r.Files, dir, false) {
+		// files of the folder, not of the sub folders
+		if path.Dir(*rc.Path) == dir || (dir == "" && path.Dir(*rc.Path) == ".") {
+			entries = append(entries, gh.BlobEntry{Path: *rc.Path})
+		}
+	}
+	return entries, nil
+}
+
+func (r *MemoryRepository) DeletePath(ctx context.Context, dir string, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for p := range r.Files {
+		if inRepositoryPath(p, dir) {
+			delete(r.Files, p)
+		}
+	}
+	r.Commits = append(r.Commits, message)
+
+	return nil
+}
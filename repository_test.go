@@ -0,0 +1,133 @@
+package gontentful
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	gh "github.com/moonwalker/moonbase/pkg/github"
+)
+
+var repositoryFiles = map[string]string{
+	"moonbase.yaml":                  "workdir: _content\n",
+	"_content/article/_schema.json":  `{"id":"article","name":"Article","fields":[{"id":"title","label":"Title","type":"text","localized":true},{"id":"author","label":"Author","type":"author","reference":true}]}`,
+	"_content/article/a1/en.json":    `{"id":"a1","fields":{"title":"Hello","author":"p1"},"version":2}`,
+	"_content/article/a1/de.json":    `{"id":"a1","fields":{"title":"Hallo","author":"p1"},"version":2}`,
+	"_content/author/_schema.json":   `{"id":"author","name":"Author","fields":[{"id":"name","label":"Name","type":"text"}]}`,
+	"_content/author/p1/en.json":     `{"id":"p1","fields":{"name":"Jane"}}`,
+	"_content/_asset/_schema.json":   `{"id":"_asset","name":"Asset","fields":[]}`,
+	"_content/article/images/a1.png": "png",
+	"_content/article/a1/notes.txt":  "ignored",
+}
+
+func TestCMSEntriesFromMemoryRepository(t *testing.T) {
+	r := NewMemoryRepository(repositoryFiles)
+
+	entries, types, err := GetCMSEntries("article", r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries.Total != 1 || entries.Items[0].Sys.ID != "a1" {
+		t.Fatalf("unexpected entries: %+v", entries.Items)
+	}
+	title := entries.Items[0].Fields["title"].(map[string]interface{})
+	if title["en"] != "Hello" || title["de"] != "Hallo" {
+		t.Errorf("unexpected title: %v", title)
+	}
+	// the schemas of the included entries are loaded too
+	if types.Total != 2 {
+		t.Errorf("got %d types, want 2", types.Total)
+	}
+	if entries.Includes == nil || len(entries.Includes.Entry) != 1 || entries.Includes.Entry[0].Sys.ID != "p1" {
+		t.Errorf("missing included author: %+v", entries.Includes)
+	}
+
+	schemas, err := GetCMSSchemas(r, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schemas.Total != 3 {
+		t.Errorf("got %d schemas, want 3", schemas.Total)
+	}
+}
+
+func TestMemoryRepositoryCommit(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository(repositoryFiles)
+
+	sha, err := r.CreateBlob(ctx, "aW1n", "base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletes, err := r.DeleteEntries(ctx, "_content/article/a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deletes) != 3 {
+		t.Fatalf("got %d delete entries, want 3", len(deletes))
+	}
+
+	content := `{"id":"a3"}`
+	entries := append(deletes,
+		gh.BlobEntry{Path: "_content/article/a3/en.json", Content: &content},
+		gh.BlobEntry{Path: "_content/_asset/images/a3.png", SHA: &sha},
+	)
+	_, err = r.CommitBlobs(ctx, entries, "feat(content): update files")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = r.ReadFile(ctx, "_content/article/a1/en.json"); err == nil {
+		t.Error("deleted file still exists")
+	}
+	if data, _ := r.ReadFile(ctx, "_content/_asset/images/a3.png"); string(data) != "img" {
+		t.Errorf("unexpected blob content %q", data)
+	}
+	if len(r.Commits) != 1 {
+		t.Errorf("got %d commits, want 1", len(r.Commits))
+	}
+
+	unknown := "0000"
+	_, err = r.CommitBlobs(ctx, []gh.BlobEntry{{Path: "x", SHA: &unknown}}, "")
+	if err == nil {
+		t.Error("commit with unknown blob succeeded")
+	}
+}
+
+func TestLocalRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r := NewLocalRepository(dir, false)
+	if r.Name() != filepath.Base(dir) {
+		t.Errorf("unexpected name %s", r.Name())
+	}
+
+	entries := make([]gh.BlobEntry, 0)
+	for p, c := range repositoryFiles {
+		c := c
+		entries = append(entries, gh.BlobEntry{Path: p, Content: &c})
+	}
+	_, err := r.CommitBlobs(ctx, entries, "init")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries2, _, err := GetCMSEntries("author", r, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries2.Total != 1 || entries2.Items[0].Sys.ID != "p1" {
+		t.Errorf("unexpected entries: %+v", entries2.Items)
+	}
+
+	err = NewGHDeleteContentType(&Sys{ID: "author"}).Exec(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := r.GetSchemas(ctx, "_content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != 2 {
+		t.Errorf("got %d schemas after delete, want 2", len(schemas))
+	}
+}
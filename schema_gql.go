@@ -55,10 +55,20 @@ type {{ .TypeName }} implements Entry {
   {{- end }}
 }
 {{- end }}
+{{- range $u := .Unions }}
+
+union {{ .TypeName }} = {{ range $i, $t := .Types }}{{ if $i }} | {{ end }}{{ $t }}{{ end }}
+{{- end }}
 
 type FileDetailsImage {
   height: Int
   width: Int
+  focalPoint: FileDetailsFocalPoint
+}
+
+type FileDetailsFocalPoint {
+  x: Float
+  y: Float
 }
 
 type FileDetails {
@@ -70,6 +80,7 @@ type File {
   contentType: String
   fileName: String
   url: String
+  upload: String
   details: FileDetails
 }
 
@@ -135,15 +146,22 @@ type GraphQLType struct {
 	Resolvers []*GraphQLResolver
 }
 
+type GraphQLUnion struct {
+	TypeName string
+	Types    []string
+}
+
 type GraphQLSchema struct {
 	Items    []*ContentType
 	TypeDefs []*GraphQLType
+	Unions   []*GraphQLUnion
 }
 
 func NewGraphQLSchema(items []*ContentType) *GraphQLSchema {
 	schema := &GraphQLSchema{
 		Items:    items,
 		TypeDefs: make([]*GraphQLType, 0),
+		Unions:   make([]*GraphQLUnion, 0),
 	}
 
 	for _, item := range items {
@@ -313,18 +331,41 @@ func getLinkType(schema *GraphQLSchema, field *ContentTypeField) string {
 	return getValidationContentType(schema, field.LinkType, field.Validations)
 }
 
+// getValidationContentType returns the type of the linked content types, a union
+// of them if the link allows several
 func getValidationContentType(schema *GraphQLSchema, t string, validations []*FieldValidation) string {
-	if len(validations) > 0 && len(validations[0].LinkContentType) > 0 {
-		vt := validations[0].LinkContentType[0]
+	types := make([]string, 0)
+	for _, vt := range getFieldLinkContentTypes(validations) {
 		// check if validation content type exists
 		for _, item := range schema.Items {
 			if item.Sys.ID == vt {
-				t = vt
+				types = append(types, cases.Title(language.Und, cases.NoLower).String(vt))
 				break
 			}
 		}
 	}
-	return cases.Title(language.Und, cases.NoLower).String(t)
+	switch len(types) {
+	case 0:
+		return cases.Title(language.Und, cases.NoLower).String(t)
+	case 1:
+		return types[0]
+	}
+	return schema.union(types)
+}
+
+// union returns the name of the union of the types, it is added once
+func (s *GraphQLSchema) union(types []string) string {
+	name := strings.Join(types, "Or")
+	for _, u := range s.Unions {
+		if u.TypeName == name {
+			return name
+		}
+	}
+	s.Unions = append(s.Unions, &GraphQLUnion{
+		TypeName: name,
+		Types:    types,
+	})
+	return name
 }
 
 func pluralName(typeName string) string {
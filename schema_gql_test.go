@@ -0,0 +1,26 @@
+package gontentful
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphQLUnions(t *testing.T) {
+	str, err := NewGraphQLSchema(polymorphicTestTypes()).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"hero: GameOrTag\n",
+		"blocks: [GameOrTag]\n",
+		"related: [Entry]\n",
+		"union GameOrTag = Game | Tag\n",
+	} {
+		if !strings.Contains(str, want) {
+			t.Errorf("missing %q in:\n%s", want, str)
+		}
+	}
+	if strings.Count(str, "union ") != 1 {
+		t.Errorf("unexpected unions:\n%s", str)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strings"
 	"text/template"
 
 	"github.com/jmoiron/sqlx"
@@ -16,6 +14,13 @@ import (
 
 const (
 	defaultMaxIncludeDepth = 3
+	// PGLocationPoint stores the Location fields as (lon,lat) points
+	PGLocationPoint = "point"
+	// PGLocationGeography stores the Location fields as WGS 84 points, requires PostGIS
+	PGLocationGeography = "geography(Point,4326)"
+	// the reference columns of the connection tables linking entries of several content types
+	pgEntryReference    = "_entry"
+	pgContentTypeColumn = "_content_type"
 )
 
 type PGSQLProcedureColumn struct {
@@ -28,10 +33,12 @@ type PGSQLProcedureColumn struct {
 	IsAsset      bool
 	Localized    bool
 	SqlType      string
+	Candidates   []*PGSQLProcedureColumn
 }
 
 type PGSQLProcedureReference struct {
 	TableName    string
+	ContentType  string
 	ForeignKey   string
 	Columns      []*PGSQLProcedureColumn
 	JoinAlias    string
@@ -43,6 +50,8 @@ type PGSQLProcedure struct {
 	TableName    string
 	Columns      []*PGSQLProcedureColumn
 	HasLocalized bool
+	// Search are the columns of the full-text search vector of the materialized views
+	Search []*PGSQLSearchColumn
 }
 
 type PGSQLColumn struct {
@@ -85,9 +94,19 @@ type PGSQLTable struct {
 	Data      *PGSQLData
 	Columns   []*PGSQLColumn
 	Indices   map[string]string
+	Indexes   []*PGSQLIndex
 	Schema    *content.Schema
 }
 
+// PGSQLIndex is an index of the schema config
+type PGSQLIndex struct {
+	Name    string
+	Method  string
+	Columns string
+	Where   string
+	Unique  bool
+}
+
 type PGSQLReference struct {
 	TableName    string
 	ForeignKey   string
@@ -114,6 +133,19 @@ type PGSQLSchema struct {
 	DropTables         bool
 	ContentTypePublish bool
 	ContentSchema      string
+	SpaceColumns       bool
+	// Trigram is set when a trigram index requires the pg_trgm extension
+	Trigram bool
+	// Search is set when the full-text search requires the unaccent extension
+	Search bool
+	// LocationType is the column type of the Location fields, from the schema config
+	LocationType string
+	// PostGIS is set when a geography column requires the postgis extension
+	PostGIS bool
+	// TextSearch overrides the text search configurations of the locales
+	TextSearch map[string]string
+	// Fallbacks are the fallback locales of each locale, walked in order by the views
+	Fallbacks map[string][]string
 }
 
 type PGSQLDeleteTrigger struct {
@@ -128,7 +160,19 @@ var schemaFuncMap = template.FuncMap{
 	},
 }
 
-func NewPGSQLSchema(schemaName string, locales []*Locale, contentTypeFilter string, items []*ContentType, includeDepth int64) *PGSQLSchema {
+// NewPGSQLSchema creates the schema of the content types, the tables are customized
+// by the optional schema config.
+func NewPGSQLSchema(schemaName string, locales []*Locale, contentTypeFilter string, items []*ContentType, includeDepth int64, cfg *PGSchemaConfig) (*PGSQLSchema, error) {
+	items = cfg.ApplyContentTypes(items)
+	err := cfg.validateContentTypes(items)
+	if err != nil {
+		return nil, err
+	}
+	fallbacks, err := pgFallbackChains(locales)
+	if err != nil {
+		return nil, err
+	}
+
 	schema := &PGSQLSchema{
 		SchemaName:     schemaName,
 		Locales:        locales,
@@ -139,7 +183,12 @@ func NewPGSQLSchema(schemaName string, locales []*Locale, contentTypeFilter stri
 		Functions:      make([]*PGSQLProcedure, 0),
 		DeleteTriggers: make([]*PGSQLDeleteTrigger, 0),
 		AssetColumns:   assetColumns,
+		Fallbacks:      fallbacks,
 	}
+	if cfg != nil {
+		schema.TextSearch = cfg.TextSearch
+	}
+	schema.LocationType = cfg.locationType()
 
 	itemsMap := make(map[string]*ContentType)
 	for _, item := range items {
@@ -158,10 +207,28 @@ func NewPGSQLSchema(schemaName string, locales []*Locale, contentTypeFilter stri
 		schema.References = append(schema.References, references...)
 		schema.Dependencies = append(schema.Dependencies, dependencies...)
 		schema.Functions = append(schema.Functions, proc)
+		for _, idx := range table.Indexes {
+			if idx.Method == PGIndexTrigram {
+				schema.Trigram = true
+			}
+		}
+		for _, col := range table.Columns {
+			if col.ColumnType == PGLocationGeography {
+				schema.PostGIS = true
+			}
+		}
+		if len(proc.Search) > 0 {
+			schema.Search = true
+		}
 	}
 	schema.DeleteTriggers = getDeleteTriggers(schema.References)
 
-	return schema
+	err = validatePGIdentifiers(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
 }
 
 func (s *PGSQLSchema) Exec(databaseURL string) error {
@@ -218,7 +285,7 @@ func (s *PGSQLSchema) Exec(databaseURL string) error {
 }
 
 func (s *PGSQLSchema) Render() (string, error) {
-	tmpl, err := template.New("schemaTemplate").Funcs(schemaFuncMap).Parse(pgTemplate)
+	tmpl, err := template.New("schemaTemplate").Funcs(pgIdentifierFuncMap).Funcs(schemaFuncMap).Parse(pgTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -234,7 +301,7 @@ func (s *PGSQLSchema) Render() (string, error) {
 
 func NewPGSQLTable(item *ContentType, items map[string]*ContentType, includeDepth int64) (*PGSQLTable, []*PGSQLTable, []*PGSQLReference, []*PGSQLDependency, *PGSQLProcedure) {
 	table := &PGSQLTable{
-		TableName: toSnakeCase(item.Sys.ID),
+		TableName: pgTableName(item.Sys.ID),
 		Columns:   make([]*PGSQLColumn, 0),
 		Data:      makeModelData(item),
 		Schema:    TransformModel(item),
@@ -258,9 +325,9 @@ func NewPGSQLTable(item *ContentType, items map[string]*ContentType, includeDept
 			procColumn := NewPGSQLProcedureColumn(column.ColumnName, field, items, table.TableName, include, 0, "")
 
 			if field.LinkType != "" {
-				references, dependencies = addOneTOne(references, dependencies, table.TableName, field)
+				references, dependencies = addOneTOne(references, dependencies, table.TableName, field, items)
 			} else if field.Items != nil {
-				conTables, references, dependencies = addManyToMany(conTables, references, dependencies, table.TableName, field)
+				conTables, references, dependencies = addManyToMany(conTables, references, dependencies, table.TableName, field, items)
 			}
 			proc.Columns = append(proc.Columns, procColumn)
 			if procColumn.Localized {
@@ -271,14 +338,16 @@ func NewPGSQLTable(item *ContentType, items map[string]*ContentType, includeDept
 			// 	fmt.Println("Ignoring omitted field", field.ID, "in", table.TableName)
 		}
 	}
+	table.Indexes = newPGSQLIndexes(table.TableName, item)
+	proc.Search = newPGSQLSearchColumns(item)
 
 	return table, conTables, references, dependencies, proc
 }
 
 func NewPGSQLColumn(field *ContentTypeField) *PGSQLColumn {
 	column := &PGSQLColumn{
-		ColumnName: toSnakeCase(field.ID),
-		IsIndex:    isIndex(field.ID),
+		ColumnName: fieldColumnName(field),
+		IsIndex:    fieldIndex(field) == PGIndexBtree,
 	}
 	column.getColumnDesc(field)
 	return column
@@ -294,7 +363,7 @@ func (c *PGSQLColumn) getColumnDesc(field *ContentTypeField) {
 		columnDesc += " unique"
 	}
 	c.Required = field.Required && !field.Omitted
-	c.ColumnType = getColumnType(field.Type, field.Items)
+	c.ColumnType = fieldColumnType(field)
 	c.ColumnDesc = columnDesc
 }
 
@@ -307,11 +376,11 @@ func getColumnType(fieldType string, fieldItems *FieldTypeArrayItem) string {
 	case "Integer":
 		return "integer"
 	case "Number":
-		return "decimal"
+		return "numeric"
 	case "Date":
-		return "date"
+		return "timestamptz"
 	case "Location":
-		return "point"
+		return PGLocationPoint
 	case "Boolean":
 		return "boolean"
 	case "Link":
@@ -322,7 +391,7 @@ func getColumnType(fieldType string, fieldItems *FieldTypeArrayItem) string {
 			return fmt.Sprintf("%s ARRAY", getColumnType(fieldItems.Type, nil))
 		}
 		return "text ARRAY"
-	case "Object":
+	case "Object", "RichText":
 		return "jsonb"
 	default:
 		return "text"
@@ -347,15 +416,49 @@ func getFieldLinkContentType(validations []*FieldValidation) string {
 	return ""
 }
 
+// getFieldLinkContentTypes returns the content types the link validations allow
+func getFieldLinkContentTypes(validations []*FieldValidation) []string {
+	res := make([]string, 0)
+	added := make(map[string]bool)
+	for _, v := range validations {
+		for _, ct := range v.LinkContentType {
+			if !added[ct] {
+				res = append(res, ct)
+				added[ct] = true
+			}
+		}
+	}
+	return res
+}
+
+// isPolymorphicLink tells whether the entry link can reference several content types,
+// either allowed by the validations or not validated at all
+func isPolymorphicLink(linkType string, validations []*FieldValidation) bool {
+	return linkType == ENTRY && len(getFieldLinkContentTypes(validations)) != 1
+}
+
+// getLinkCandidates returns the content types a polymorphic link is resolved against,
+// every content type when the link is not validated
+func getLinkCandidates(validations []*FieldValidation, items map[string]*ContentType) []string {
+	res := make([]string, 0)
+	lct := getFieldLinkContentTypes(validations)
+	if len(lct) == 0 {
+		return sortedKeys(items)
+	}
+	for _, ct := range lct {
+		if items[ct] != nil {
+			res = append(res, ct)
+		}
+	}
+	return res
+}
+
 func getFieldLinkType(linkType string, validations []*FieldValidation) string {
 	if linkType == ASSET {
 		return ASSET_TABLE_NAME
 	}
-	if linkType == ENTRY {
-		lct := getFieldLinkContentType(validations)
-		if lct != "" {
-			return toSnakeCase(lct)
-		}
+	if linkType == ENTRY && !isPolymorphicLink(linkType, validations) {
+		return pgTableName(getFieldLinkContentType(validations))
 	}
 	return linkType
 }
@@ -376,7 +479,7 @@ func makeModelData(item *ContentType) *PGSQLData {
 
 func makeMeta(field *ContentTypeField) *PGSQLMeta {
 	meta := &PGSQLMeta{
-		Name:      toSnakeCase(field.ID),
+		Name:      fieldColumnName(field),
 		Label:     formatText(field.Name),
 		Type:      field.Type,
 		Required:  field.Required,
@@ -403,41 +506,72 @@ func makeMeta(field *ContentTypeField) *PGSQLMeta {
 }
 
 func NewPGSQLCon(tableName string, fieldName string, reference string) *PGSQLTable {
+	if reference == ENTRY {
+		reference = pgEntryReference
+	}
 	return &PGSQLTable{
 		TableName: getConTableName(tableName, fieldName),
 		Columns:   getConTableColumns(tableName, reference),
-		Indices:   map[string]string{"id_locale": fmt.Sprintf("%s_sys_id,_locale", tableName), "sys_id_locale": fmt.Sprintf("%s_sys_id,_locale", reference)},
+		Indices:   map[string]string{"id_locale": fmt.Sprintf(`"%s_sys_id",_locale`, tableName), "sys_id_locale": fmt.Sprintf(`"%s_sys_id",_locale`, reference)},
 	}
 }
 
 func getConTableName(tableName string, fieldName string) string {
-	return fmt.Sprintf("%.63s", fmt.Sprintf("%s__%s", tableName, fieldName))
+	return pgIdentifier(fmt.Sprintf("%s__%s", tableName, fieldName), pgMaxAliasLength)
 }
 
+// getConTableColumns returns the columns of the connection table, the entries of
+// several content types are referenced by _entry and the _content_type of the entry
 func getConTableColumns(tableName string, reference string) []*PGSQLColumn {
-	return []*PGSQLColumn{
+	columns := []*PGSQLColumn{
 		&PGSQLColumn{
 			ColumnName: tableName,
+			Required:   true,
 		},
 		&PGSQLColumn{
 			ColumnName: fmt.Sprintf("%s_sys_id", tableName),
+			Required:   true,
 		},
 		&PGSQLColumn{
 			ColumnName: reference,
+			Required:   true,
 		},
 		&PGSQLColumn{
 			ColumnName: fmt.Sprintf("%s_sys_id", reference),
+			Required:   true,
 		},
-		&PGSQLColumn{
-			ColumnName: "_locale",
-		},
 	}
+	if reference == pgEntryReference {
+		// unknown until the linked entry is synced, the views resolve it
+		columns = append(columns, &PGSQLColumn{
+			ColumnName: pgContentTypeColumn,
+		})
+	}
+	return append(columns, &PGSQLColumn{
+		ColumnName: "_locale",
+		Required:   true,
+	})
 }
 
-func addOneTOne(references []*PGSQLReference, dependencies []*PGSQLDependency, tableName string, field *ContentTypeField) ([]*PGSQLReference, []*PGSQLDependency) {
+func getConTableColumnNames(tableName string, reference string) []string {
+	if reference == ENTRY {
+		reference = pgEntryReference
+	}
+	names := make([]string, 0)
+	for _, col := range getConTableColumns(tableName, reference) {
+		names = append(names, col.ColumnName)
+	}
+	return names
+}
+
+func addOneTOne(references []*PGSQLReference, dependencies []*PGSQLDependency, tableName string, field *ContentTypeField, items map[string]*ContentType) ([]*PGSQLReference, []*PGSQLDependency) {
+	if isPolymorphicLink(field.LinkType, field.Validations) {
+		dependencies = addCandidateDependencies(dependencies, tableName, field.Validations, items)
+		return references, dependencies
+	}
 	linkType := getFieldLinkType(field.LinkType, field.Validations)
 	if linkType != "" && linkType != ENTRY {
-		foreignKey := toSnakeCase(field.ID)
+		foreignKey := fieldColumnName(field)
 		references = append(references, &PGSQLReference{
 			TableName:    tableName,
 			Reference:    linkType,
@@ -452,10 +586,23 @@ func addOneTOne(references []*PGSQLReference, dependencies []*PGSQLDependency, t
 	return references, dependencies
 }
 
-func addManyToMany(conTables []*PGSQLTable, references []*PGSQLReference, dependencies []*PGSQLDependency, tableName string, field *ContentTypeField) ([]*PGSQLTable, []*PGSQLReference, []*PGSQLDependency) {
+func addManyToMany(conTables []*PGSQLTable, references []*PGSQLReference, dependencies []*PGSQLDependency, tableName string, field *ContentTypeField, items map[string]*ContentType) ([]*PGSQLTable, []*PGSQLReference, []*PGSQLDependency) {
+	if isPolymorphicLink(field.Items.LinkType, field.Items.Validations) {
+		// the linked entries are not deleted by triggers, the views skip the missing ones
+		conTable := NewPGSQLCon(tableName, fieldColumnName(field), ENTRY)
+		conTables = append(conTables, conTable)
+		references = append(references, &PGSQLReference{
+			TableName:    conTable.TableName,
+			Reference:    tableName,
+			ForeignKey:   tableName,
+			IsManyToMany: true,
+		})
+		dependencies = addCandidateDependencies(dependencies, tableName, field.Items.Validations, items)
+		return conTables, references, dependencies
+	}
 	linkType := getFieldLinkType(field.Items.LinkType, field.Items.Validations)
 	if linkType != "" && linkType != ENTRY {
-		conTable := NewPGSQLCon(tableName, toSnakeCase(field.ID), linkType)
+		conTable := NewPGSQLCon(tableName, fieldColumnName(field), linkType)
 		conTables = append(conTables, conTable)
 		references = append(references, &PGSQLReference{
 			TableName:    conTable.TableName,
@@ -476,6 +623,16 @@ func addManyToMany(conTables []*PGSQLTable, references []*PGSQLReference, depend
 	return conTables, references, dependencies
 }
 
+func addCandidateDependencies(dependencies []*PGSQLDependency, tableName string, validations []*FieldValidation, items map[string]*ContentType) []*PGSQLDependency {
+	for _, ct := range getLinkCandidates(validations, items) {
+		dependencies = append(dependencies, &PGSQLDependency{
+			TableName: tableName,
+			Reference: pgTableName(ct),
+		})
+	}
+	return dependencies
+}
+
 func NewPGSQLProcedureColumn(columnName string, field *ContentTypeField, items map[string]*ContentType, tableName string, maxIncludeDepth int64, includeDepth int64, path string) *PGSQLProcedureColumn {
 	col := &PGSQLProcedureColumn{
 		TableName:  tableName,
@@ -484,6 +641,9 @@ func NewPGSQLProcedureColumn(columnName string, field *ContentTypeField, items m
 		Localized:  field.Localized,
 		SqlType:    mapFieldType(columnName, field.Type, field.Items, field),
 	}
+	if field.pg != nil && field.pg.Type != "" {
+		col.SqlType = field.pg.Type
+	}
 
 	if field.LinkType == ASSET {
 		col.IsAsset = true
@@ -495,43 +655,28 @@ func NewPGSQLProcedureColumn(columnName string, field *ContentTypeField, items m
 		}
 		col.Reference = &PGSQLProcedureReference{
 			TableName:  ASSET_TABLE_NAME,
-			ForeignKey: toSnakeCase(field.ID),
+			ForeignKey: fieldColumnName(field),
 			JoinAlias:  assetJoinAlias,
 			Localized:  col.Localized,
 		}
 	} else if field.LinkType != "" {
-		linkType := getFieldLinkContentType(field.Validations)
-		linkTableName := toSnakeCase(linkType)
-		if linkType != "" && linkType != ENTRY {
-			joinAlias := getJoinAlias(path, columnName, linkTableName)
+		if isPolymorphicLink(field.LinkType, field.Validations) {
+			addProcedureCandidates(col, field.Validations, items, maxIncludeDepth, includeDepth, path)
+			if len(col.Candidates) == 0 {
+				col.SqlType = "text"
+			}
+		} else if linkType := getFieldLinkContentType(field.Validations); linkType != "" {
+			joinAlias := getJoinAlias(path, columnName, pgTableName(linkType))
 			if path == "" {
 				col.JoinAlias = tableName
 			} else {
 				col.JoinAlias = joinAlias
 			}
-			col.Reference = &PGSQLProcedureReference{
-				TableName:  linkTableName,
-				ForeignKey: toSnakeCase(field.ID),
-				Columns:    make([]*PGSQLProcedureColumn, 0),
-				JoinAlias:  joinAlias,
-				Localized:  col.Localized,
-			}
-
-			if includeDepth <= maxIncludeDepth && items[linkType] != nil {
-				itemTableName := toSnakeCase(items[linkType].Sys.ID)
-				for _, f := range items[linkType].Fields {
-					if !f.Omitted {
-						fieldColumnName := toSnakeCase(f.ID)
-						procColumn := NewPGSQLProcedureColumn(fieldColumnName, f, items, itemTableName, maxIncludeDepth, includeDepth+1, getPath(path, columnName))
-						procColumn.JoinAlias = joinAlias
-						col.Reference.Columns = append(col.Reference.Columns, procColumn)
-					}
-				}
-			}
+			col.Reference = newPGSQLProcedureReference(linkType, fieldColumnName(field), joinAlias, col.Localized, items, maxIncludeDepth, includeDepth, getPath(path, columnName), true)
 		}
 	} else if field.Items != nil {
 		if field.Items.LinkType == ASSET {
-			col.ConTableName = getConTableName(tableName, toSnakeCase(field.ID))
+			col.ConTableName = getConTableName(tableName, fieldColumnName(field))
 			assetJoinAlias := getJoinAlias(path, columnName, ASSET_TABLE_NAME)
 			if path == "" {
 				col.JoinAlias = tableName
@@ -541,40 +686,36 @@ func NewPGSQLProcedureColumn(columnName string, field *ContentTypeField, items m
 			col.IsAsset = true
 			col.Reference = &PGSQLProcedureReference{
 				TableName:  ASSET_TABLE_NAME,
-				ForeignKey: toSnakeCase(field.ID),
+				ForeignKey: fieldColumnName(field),
 				JoinAlias:  assetJoinAlias,
 				Localized:  col.Localized,
 			}
-		} else if field.Items.LinkType != "" {
-			conLinkType := getFieldLinkContentType(field.Items.Validations)
-			if conLinkType != "" && conLinkType != ENTRY {
-				col.ConTableName = getConTableName(tableName, toSnakeCase(field.ID))
-				conLinkTableName := toSnakeCase(conLinkType)
-				conJoinAlias := getJoinAlias(path, columnName, conLinkTableName)
-				if path == "" {
-					col.JoinAlias = tableName
-				} else {
-					col.JoinAlias = conJoinAlias
-				}
+		} else if isPolymorphicLink(field.Items.LinkType, field.Items.Validations) {
+			addProcedureCandidates(col, field.Items.Validations, items, maxIncludeDepth, includeDepth, path)
+			if len(col.Candidates) == 0 {
+				col.SqlType = "text[]"
+			} else {
+				// the connection rows of the candidates are aggregated by the entry reference
+				col.ConTableName = getConTableName(tableName, fieldColumnName(field))
 				col.Reference = &PGSQLProcedureReference{
-					TableName:  conLinkTableName,
-					ForeignKey: toSnakeCase(field.ID),
-					Columns:    make([]*PGSQLProcedureColumn, 0),
-					JoinAlias:  conJoinAlias,
+					TableName:  pgEntryReference,
+					ForeignKey: fieldColumnName(field),
+					JoinAlias:  getJoinAlias(path, columnName, "entry"),
 					Localized:  col.Localized,
 				}
-				if includeDepth <= maxIncludeDepth && items[conLinkType] != nil {
-					itemTableName := toSnakeCase(items[conLinkType].Sys.ID)
-					for _, f := range items[conLinkType].Fields {
-						if !f.Omitted {
-							fieldColumnName := toSnakeCase(f.ID)
-							procColumn := NewPGSQLProcedureColumn(fieldColumnName, f, items, itemTableName, maxIncludeDepth, includeDepth+1, getPath(path, columnName))
-							procColumn.JoinAlias = conJoinAlias
-							col.Reference.Columns = append(col.Reference.Columns, procColumn)
-						}
-					}
+				for _, c := range col.Candidates {
+					c.ConTableName = col.ConTableName
 				}
 			}
+		} else if conLinkType := getFieldLinkContentType(field.Items.Validations); conLinkType != "" {
+			col.ConTableName = getConTableName(tableName, fieldColumnName(field))
+			conJoinAlias := getJoinAlias(path, columnName, pgTableName(conLinkType))
+			if path == "" {
+				col.JoinAlias = tableName
+			} else {
+				col.JoinAlias = conJoinAlias
+			}
+			col.Reference = newPGSQLProcedureReference(conLinkType, fieldColumnName(field), conJoinAlias, col.Localized, items, maxIncludeDepth, includeDepth, getPath(path, columnName), true)
 		}
 	}
 
@@ -585,24 +726,72 @@ func NewPGSQLProcedureColumn(columnName string, field *ContentTypeField, items m
 	return col
 }
 
+// newPGSQLProcedureReference joins the table of the content type, its fields are
+// included up to the max include depth
+func newPGSQLProcedureReference(contentType string, foreignKey string, joinAlias string, localized bool, items map[string]*ContentType, maxIncludeDepth int64, includeDepth int64, path string, withFields bool) *PGSQLProcedureReference {
+	ref := &PGSQLProcedureReference{
+		TableName:   pgTableName(contentType),
+		ContentType: contentType,
+		ForeignKey:  foreignKey,
+		Columns:     make([]*PGSQLProcedureColumn, 0),
+		JoinAlias:   joinAlias,
+		Localized:   localized,
+	}
+	if withFields && includeDepth <= maxIncludeDepth && items[contentType] != nil {
+		itemTableName := pgTableName(items[contentType].Sys.ID)
+		for _, f := range items[contentType].Fields {
+			if !f.Omitted {
+				fieldColumnName := fieldColumnName(f)
+				procColumn := NewPGSQLProcedureColumn(fieldColumnName, f, items, itemTableName, maxIncludeDepth, includeDepth+1, path)
+				procColumn.setJoinAlias(joinAlias)
+				ref.Columns = append(ref.Columns, procColumn)
+			}
+		}
+	}
+	return ref
+}
+
+// addProcedureCandidates adds a copy of the column joining each content type the
+// polymorphic link can reference. The fields of the links without validation are
+// not included, only their sys.
+func addProcedureCandidates(col *PGSQLProcedureColumn, validations []*FieldValidation, items map[string]*ContentType, maxIncludeDepth int64, includeDepth int64, path string) {
+	withFields := len(getFieldLinkContentTypes(validations)) > 0
+	for _, ct := range getLinkCandidates(validations, items) {
+		joinAlias := getJoinAlias(path, col.ColumnName, pgTableName(ct))
+		candidate := *col
+		candidate.JoinAlias = col.TableName
+		candidate.Candidates = nil
+		candidate.Reference = newPGSQLProcedureReference(ct, col.ColumnName, joinAlias, col.Localized, items, maxIncludeDepth, includeDepth, getPath(path, col.ColumnName), withFields)
+		candidate.Reference.HasLocalized = getHasLocalized(candidate.Reference)
+		col.Candidates = append(col.Candidates, &candidate)
+	}
+	// the alias of the nested columns is set by the referencing column
+	col.JoinAlias = col.TableName
+}
+
+// setJoinAlias sets the alias of the table the column is selected from
+func (c *PGSQLProcedureColumn) setJoinAlias(alias string) {
+	c.JoinAlias = alias
+	for _, candidate := range c.Candidates {
+		candidate.JoinAlias = alias
+	}
+}
+
 func mapFieldType(fieldName string, fieldType string, fieldItems *FieldTypeArrayItem, field *ContentTypeField) string {
 	switch fieldType {
 	case "Integer":
 		return "integer"
 	case "Number":
-		return "decimal"
+		return "numeric"
 	case "Date":
-		return "date"
+		return "timestamptz"
 	case "Location":
-		return "point"
-	case "Object":
+		return PGLocationPoint
+	case "Object", "RichText":
 		return "jsonb"
 	case "Symbol":
 		return "text"
 	case "Link":
-		if field.LinkType == "Entry" && len(field.Validations) == 0 {
-			return "text"
-		}
 		return "json"
 	case "Text":
 		return "text"
@@ -612,9 +801,6 @@ func mapFieldType(fieldName string, fieldType string, fieldItems *FieldTypeArray
 		if fieldItems != nil {
 			switch fieldItems.Type {
 			case "Link":
-				if fieldItems.LinkType == "Entry" && len(fieldItems.Validations) == 0 {
-					return "text[]"
-				}
 				return "json"
 			default:
 				return fmt.Sprintf("%s[]", mapFieldType(fieldName, fieldItems.Type, nil, nil))
@@ -640,45 +826,26 @@ func getHasLocalized(ref *PGSQLProcedureReference) bool {
 				return true
 			}
 		}
+		for _, c := range col.Candidates {
+			if c.Reference.HasLocalized {
+				return true
+			}
+		}
 	}
 	return false
 }
 
+// getJoinAlias returns the alias of the table joined by the column at the path, the long
+// paths of the deep includes are shortened with a hash to leave room for the suffixes
 func getJoinAlias(path string, columnName, tableName string) string {
-	if len(path) == 0 {
-		return fmt.Sprintf("%s__%s", columnName, tableName)
-	}
-	return truncatePath(fmt.Sprintf("%s__%s__%s", truncatePath(path), truncateColumn(columnName), tableName))
+	return pgIdentifier(fmt.Sprintf("%s__%s", getPath(path, columnName), tableName), pgMaxAliasLength)
 }
 
 func getPath(path string, columnName string) string {
 	if len(path) == 0 {
 		return columnName
 	}
-	return fmt.Sprintf("%s__%s", truncatePath(path), truncateColumn(columnName))
-
-}
-
-func truncatePath(path string) string {
-	idx := strings.LastIndex(path, "__")
-	if idx == -1 {
-		return path
-	}
-	re := regexp.MustCompile(`_(\S)[^_]*`)
-	return fmt.Sprintf("%s__%s", path[:idx], re.ReplaceAllString(path[idx+1:], "$1"))
-}
-
-func truncateColumn(column string) string {
-	items := strings.Split(column, "_")
-	if len(items) < 3 {
-		return column
-	}
-	for idx, item := range items {
-		if idx < (len(items) - 2) {
-			items[idx] = item[:1]
-		}
-	}
-	return strings.Join(items, "_")
+	return fmt.Sprintf("%s__%s", path, columnName)
 }
 
 func getDeleteTriggers(references []*PGSQLReference) []*PGSQLDeleteTrigger {
@@ -0,0 +1,296 @@
+package gontentful
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+type SchemaChangePolicy string
+
+const (
+	// SchemaChangeRefuse fails the sync on any content model change
+	SchemaChangeRefuse SchemaChangePolicy = "refuse"
+	// SchemaChangeAdditive applies additive changes in place and refuses destructive ones
+	SchemaChangeAdditive SchemaChangePolicy = "additive"
+	// SchemaChangeMigrate applies additive changes in place and migrates the whole
+	// schema (MigratePGSQL + SwapSchemas) on destructive ones
+	SchemaChangeMigrate SchemaChangePolicy = "migrate"
+)
+
+const (
+	selectStoredSchema = "SELECT table_name, fields FROM _schema"
+	addColumnTpl       = `ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS "%s" %s;`
+)
+
+type PGSchemaChange struct {
+	TableName   string
+	Field       string
+	Kind        string
+	Destructive bool
+	// ModelOnly is set for the label and disabled edits, only the stored model changes
+	ModelOnly bool
+}
+
+type pgSchemaAction int
+
+const (
+	pgSchemaUnchanged pgSchemaAction = iota
+	pgSchemaApply
+	pgSchemaMigrate
+)
+
+// PGSchemaSync compares the content types with the models stored in the _schema table.
+type PGSchemaSync struct {
+	Schema  *PGSQLSchema
+	Types   []*ContentType
+	Policy  SchemaChangePolicy
+	Changes []*PGSchemaChange
+	// Migrated is set when the schema was re-created with all the entries
+	Migrated bool
+	// SyncOptions are the options of the rows synced by the migration
+	SyncOptions *PGSyncOptions
+	// Config is the schema config of the compared and migrated schema
+	Config *PGSchemaConfig
+}
+
+type pgStoredModel struct {
+	TableName string         `db:"table_name"`
+	Fields    content.Fields `db:"fields"`
+}
+
+func ParseSchemaChangePolicy(policy string) (SchemaChangePolicy, error) {
+	switch p := SchemaChangePolicy(policy); p {
+	case SchemaChangeRefuse, SchemaChangeAdditive, SchemaChangeMigrate:
+		return p, nil
+	case "":
+		return SchemaChangeAdditive, nil
+	}
+	return "", fmt.Errorf("unknown schema change policy: %s", policy)
+}
+
+// NewPGSchemaSync compares the content types with the tables of the schema config, the
+// config is applied again when the types have it applied already.
+func NewPGSchemaSync(schemaName string, locales []*Locale, types []*ContentType, policy SchemaChangePolicy, cfg *PGSchemaConfig) (*PGSchemaSync, error) {
+	schema, err := NewPGSQLSchema(schemaName, locales, "", types, 0, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &PGSchemaSync{
+		Schema: schema,
+		Types:  types,
+		Policy: policy,
+		Config: cfg,
+	}, nil
+}
+
+func (c *PGSchemaChange) String() string {
+	if c.Field == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.TableName)
+	}
+	return fmt.Sprintf("%s %s.%s", c.Kind, c.TableName, c.Field)
+}
+
+// Detect compares the stored models with the content types.
+func (s *PGSchemaSync) Detect(databaseURL string) ([]*PGSchemaChange, error) {
+	db, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	txn, err := db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if s.Schema.SchemaName != "" {
+		// the search path of the transaction only, the connections are pooled
+		_, err = txn.Exec("SELECT set_config('search_path', $1, true)", s.Schema.SchemaName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stored := make(map[string]content.Fields)
+	exists, err := tableExists(txn, "_schema")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		models := make([]*pgStoredModel, 0)
+		err = txn.Select(&models, selectStoredSchema)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range models {
+			stored[m.TableName] = m.Fields
+		}
+	}
+
+	return s.detect(stored), nil
+}
+
+// detect compares the stored models by table with the content types
+func (s *PGSchemaSync) detect(stored map[string]content.Fields) []*PGSchemaChange {
+	s.Changes = make([]*PGSchemaChange, 0)
+	tables := make(map[string]bool)
+	for _, tbl := range s.Schema.Tables {
+		tables[tbl.TableName] = true
+		fields, ok := stored[tbl.TableName]
+		if !ok {
+			s.Changes = append(s.Changes, &PGSchemaChange{TableName: tbl.TableName, Kind: "create table"})
+			continue
+		}
+		s.Changes = append(s.Changes, compareModelFields(tbl.TableName, fields, tbl.Schema.Fields)...)
+	}
+	for _, tableName := range sortedKeys(stored) {
+		if !tables[tableName] {
+			s.Changes = append(s.Changes, &PGSchemaChange{TableName: tableName, Kind: "drop table", Destructive: true})
+		}
+	}
+
+	return s.Changes
+}
+
+func compareModelFields(tableName string, stored content.Fields, fields content.Fields) []*PGSchemaChange {
+	changes := make([]*PGSchemaChange, 0)
+	storedByID := make(map[string]*content.Field)
+	for _, f := range stored {
+		storedByID[f.ID] = f
+	}
+	ids := make(map[string]bool)
+	for _, f := range fields {
+		ids[f.ID] = true
+		sf := storedByID[f.ID]
+		switch {
+		case sf == nil:
+			changes = append(changes, &PGSchemaChange{TableName: tableName, Field: f.ID, Kind: "add column"})
+		case sf.List != f.List || sf.Reference != f.Reference || (!f.Reference && sf.Type != f.Type):
+			changes = append(changes, &PGSchemaChange{TableName: tableName, Field: f.ID, Kind: "change column type", Destructive: true})
+		case sf.Type != f.Type || sf.Localized != f.Localized:
+			// the column is the same, only the model and the generated functions change
+			changes = append(changes, &PGSchemaChange{TableName: tableName, Field: f.ID, Kind: "update field"})
+		case sf.Label != f.Label || sf.Disabled != f.Disabled:
+			changes = append(changes, &PGSchemaChange{TableName: tableName, Field: f.ID, Kind: "update model", ModelOnly: true})
+		}
+	}
+	for _, f := range stored {
+		if !ids[f.ID] {
+			changes = append(changes, &PGSchemaChange{TableName: tableName, Field: f.ID, Kind: "drop column", Destructive: true})
+		}
+	}
+	return changes
+}
+
+func (s *PGSchemaSync) destructive() []string {
+	res := make([]string, 0)
+	for _, c := range s.Changes {
+		if c.Destructive {
+			res = append(res, c.String())
+		}
+	}
+	return res
+}
+
+// Exec detects the content model changes and applies them according to the policy.
+// The client is used to re-sync all the entries when the schema is migrated.
+func (s *PGSchemaSync) Exec(databaseURL string, client *Client) error {
+	changes, err := s.Detect(databaseURL)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, c := range changes {
+		log.Printf("content model change: %s", c.String())
+	}
+
+	action, err := s.action()
+	if err != nil {
+		log.Printf("schema changes refused by %s policy", s.Policy)
+		return err
+	}
+	switch action {
+	case pgSchemaMigrate:
+		log.Printf("destructive schema changes, migrating schema %s", s.Schema.SchemaName)
+		return s.migrate(databaseURL, client)
+	case pgSchemaApply:
+		log.Printf("applying %d additive schema changes", len(changes))
+		return s.applyAdditive(databaseURL)
+	}
+	return nil
+}
+
+// action returns how the policy handles the detected changes. The label and disabled
+// edits only update the stored models, they are applied by every policy.
+func (s *PGSchemaSync) action() (pgSchemaAction, error) {
+	if len(s.Changes) == 0 {
+		return pgSchemaUnchanged, nil
+	}
+	schemaChanges := 0
+	for _, c := range s.Changes {
+		if !c.ModelOnly {
+			schemaChanges++
+		}
+	}
+
+	destructive := s.destructive()
+	switch {
+	case schemaChanges > 0 && s.Policy == SchemaChangeRefuse:
+		return pgSchemaUnchanged, fmt.Errorf("content model changed, %d schema changes refused", schemaChanges)
+	case len(destructive) > 0 && s.Policy == SchemaChangeMigrate:
+		return pgSchemaMigrate, nil
+	case len(destructive) > 0:
+		return pgSchemaUnchanged, fmt.Errorf("destructive content model changes refused: %s", strings.Join(destructive, ", "))
+	}
+	return pgSchemaApply, nil
+}
+
+func (s *PGSchemaSync) applyAdditive(databaseURL string) error {
+	// new tables and columns, the stored models and the functions of the changed tables,
+	// the steps the models do not show (e.g. column types drifted) are refused
+	diff := NewPGSchemaDiff(s.Schema)
+	diff.AdditiveOnly = true
+	err := diff.Exec(databaseURL)
+	if err != nil {
+		return err
+	}
+	for _, step := range diff.Steps {
+		log.Printf("schema change applied: %s", step.String())
+	}
+	return nil
+}
+
+func (s *PGSchemaSync) migrate(databaseURL string, client *Client) error {
+	schemaName := s.Schema.SchemaName
+	newSchemaName := fmt.Sprintf(newSchemaNameTpl, schemaName)
+	oldSchemaName := fmt.Sprintf(oldSchemaNameTpl, schemaName)
+
+	res, err := client.Spaces.Sync("")
+	if err != nil {
+		return err
+	}
+
+	err = MigratePGSQL(databaseURL, newSchemaName, s.Schema.Locales, s.Types, s.Types, res.Items, res.Token, true, false, s.Config, s.SyncOptions)
+	if err != nil {
+		return err
+	}
+
+	err = SwapSchemas(databaseURL, schemaName, oldSchemaName, newSchemaName)
+	if err != nil {
+		return err
+	}
+	log.Printf("schema %s migrated, previous schema kept as %s", schemaName, oldSchemaName)
+	s.Migrated = true
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package gontentful
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+func schemaChangesTestStored(types []*ContentType) map[string]content.Fields {
+	stored := make(map[string]content.Fields)
+	for _, t := range types {
+		stored[pgTableName(t.Sys.ID)] = TransformModel(t).Fields
+	}
+	return stored
+}
+
+func TestCompareModelFields(t *testing.T) {
+	stored := TransformModel(diffTestTypes()[0]).Fields
+
+	types := diffTestTypes()
+	fields := types[0].Fields
+	fields[0].Name = "Title"
+	fields[1].Disabled = true
+	fields[2].Type = "Integer"
+	fields[3].Localized = true
+	types[0].Fields = append(fields[:4], &ContentTypeField{ID: "provider", Name: "Provider", Type: "Symbol"})
+
+	changes := compareModelFields("game", stored, TransformModel(types[0]).Fields)
+	expected := []string{
+		"update model game.name",
+		"update model game.slug",
+		"change column type game.rating",
+		"update field game.logo",
+		"add column game.provider",
+		"drop column game.tags",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	for i, c := range changes {
+		if c.String() != expected[i] {
+			t.Errorf("unexpected change %s, expected %s", c.String(), expected[i])
+		}
+	}
+	if !changes[0].ModelOnly || !changes[1].ModelOnly || changes[3].ModelOnly || !changes[2].Destructive || changes[4].Destructive {
+		t.Errorf("unexpected change flags %+v %+v", changes[2], changes[3])
+	}
+}
+
+func TestPGSchemaSyncDetect(t *testing.T) {
+	types := diffTestTypes()
+	stored := schemaChangesTestStored(types)
+	stored["old"] = stored["tag"]
+	delete(stored, "tag")
+
+	sync, err := NewPGSchemaSync("content", []*Locale{{Code: "en", Default: true}}, types, SchemaChangeAdditive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := sync.detect(stored)
+	res := make([]string, 0)
+	for _, c := range changes {
+		res = append(res, c.String())
+	}
+	if strings.Join(res, ", ") != "create table tag, drop table old" || !changes[1].Destructive {
+		t.Errorf("unexpected changes %v", res)
+	}
+
+	if changes = sync.detect(schemaChangesTestStored(types)); len(changes) != 0 {
+		t.Errorf("unexpected changes of the same models %v", changes)
+	}
+
+	cfg := &PGSchemaConfig{LocationType: PGLocationGeography}
+	sync, err = NewPGSchemaSync("content", []*Locale{{Code: "en", Default: true}}, types, SchemaChangeAdditive, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sync.Config != cfg || sync.Schema.LocationType != PGLocationGeography {
+		t.Errorf("schema config not applied %+v", sync.Schema)
+	}
+}
+
+func TestPGSchemaSyncPolicy(t *testing.T) {
+	labelOnly := []*PGSchemaChange{{TableName: "game", Field: "name", Kind: "update model", ModelOnly: true}}
+	additive := append(labelOnly, &PGSchemaChange{TableName: "game", Field: "slug", Kind: "add column"})
+	destructive := append(additive, &PGSchemaChange{TableName: "game", Field: "tags", Kind: "drop column", Destructive: true})
+
+	for _, c := range []struct {
+		policy   SchemaChangePolicy
+		changes  []*PGSchemaChange
+		expected pgSchemaAction
+		err      string
+	}{
+		{SchemaChangeRefuse, nil, pgSchemaUnchanged, ""},
+		{SchemaChangeRefuse, labelOnly, pgSchemaApply, ""},
+		{SchemaChangeRefuse, additive, pgSchemaUnchanged, "content model changed, 1 schema changes refused"},
+		{SchemaChangeAdditive, additive, pgSchemaApply, ""},
+		{SchemaChangeAdditive, destructive, pgSchemaUnchanged, "destructive content model changes refused: drop column game.tags"},
+		{SchemaChangeMigrate, additive, pgSchemaApply, ""},
+		{SchemaChangeMigrate, destructive, pgSchemaMigrate, ""},
+	} {
+		sync := &PGSchemaSync{Policy: c.policy, Changes: c.changes}
+		action, err := sync.action()
+		if action != c.expected || (err == nil) != (c.err == "") || (err != nil && err.Error() != c.err) {
+			t.Errorf("%s policy of %d changes: unexpected action %d, %v", c.policy, len(c.changes), action, err)
+		}
+	}
+
+	if p, err := ParseSchemaChangePolicy(""); p != SchemaChangeAdditive || err != nil {
+		t.Errorf("unexpected default policy %s", p)
+	}
+	if _, err := ParseSchemaChangePolicy("drop"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
@@ -0,0 +1,417 @@
+package gontentful
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PGIndexBtree   = "btree"
+	PGIndexGin     = "gin"
+	PGIndexTrigram = "trigram"
+	// PGIndexNone drops the default index of the slug, code and key fields
+	PGIndexNone = "none"
+)
+
+var pgTextSearchConfigRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// PGSchemaConfig customizes the postgres tables generated for the content types:
+//
+//	contentTypes:
+//	  game:
+//	    fields:
+//	      releaseDate: { column: released_at }
+//	      description: { exclude: true }
+//	      tags: { index: gin }
+//	      name: { index: trigram, localized: false }
+//	      rtp: { type: real }
+//	      title: { search: A }
+//	    indexes:
+//	      - { fields: [priority], where: "priority > 0" }
+//	    unique:
+//	      - [studio, name]
+//	textSearch:
+//	  en-GB: english
+//	locationType: geography(Point,4326)
+//
+// The config is applied to the content types, the schema, the sync, the publish
+// and the query use the same columns then.
+type PGSchemaConfig struct {
+	ContentTypes map[string]*PGContentTypeConfig `json:"contentTypes" yaml:"contentTypes"`
+	// TextSearch maps the locale codes or languages to text search configurations,
+	// overriding the defaults (german, swedish, finnish...)
+	TextSearch map[string]string `json:"textSearch" yaml:"textSearch"`
+	// LocationType is the column type of the Location fields, PGLocationPoint or
+	// PGLocationGeography (requires PostGIS)
+	LocationType string `json:"locationType" yaml:"locationType"`
+}
+
+type PGContentTypeConfig struct {
+	Fields  map[string]*PGFieldConfig `json:"fields" yaml:"fields"`
+	Indexes []*PGIndexConfig          `json:"indexes" yaml:"indexes"`
+	// Unique lists the fields unique together in each locale
+	Unique [][]string `json:"unique" yaml:"unique"`
+}
+
+type PGFieldConfig struct {
+	// Column renames the column of the field
+	Column string `json:"column" yaml:"column"`
+	// Exclude skips the field, false includes an omitted field
+	Exclude *bool `json:"exclude" yaml:"exclude"`
+	// Type overrides the column type, named the way information_schema reports it
+	// (text, integer, bigint, jsonb...) not to be altered by every migration
+	Type string `json:"type" yaml:"type"`
+	// Localized overrides the localization of the field, slug is not localized by default
+	Localized *bool `json:"localized" yaml:"localized"`
+	// Index is btree, gin, trigram or none
+	Index string `json:"index" yaml:"index"`
+	// Search is the full-text search weight of the field: A (highest), B, C or D
+	Search string `json:"search" yaml:"search"`
+}
+
+type PGIndexConfig struct {
+	Name   string   `json:"name" yaml:"name"`
+	Fields []string `json:"fields" yaml:"fields"`
+	// Method is btree (the default), gin or trigram
+	Method string `json:"method" yaml:"method"`
+	// Where is the predicate of a partial index, an sql expression of the columns
+	Where  string `json:"where" yaml:"where"`
+	Unique bool   `json:"unique" yaml:"unique"`
+}
+
+// LoadPGSchemaConfig reads a yaml (or json) schema config, environment variables
+// like ${SCHEMA} are expanded before parsing.
+func LoadPGSchemaConfig(path string) (*PGSchemaConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema config %s: %s", path, err.Error())
+	}
+	return ParsePGSchemaConfig([]byte(os.ExpandEnv(string(data))))
+}
+
+func ParsePGSchemaConfig(data []byte) (*PGSchemaConfig, error) {
+	cfg := &PGSchemaConfig{}
+	err := yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema config: %s", err.Error())
+	}
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *PGSchemaConfig) validate() error {
+	switch c.LocationType {
+	case "", PGLocationPoint, PGLocationGeography:
+	default:
+		return fmt.Errorf("unknown location type %q in schema config", c.LocationType)
+	}
+	for _, code := range sortedKeys(c.TextSearch) {
+		if !pgTextSearchConfigRegex.MatchString(c.TextSearch[code]) {
+			return fmt.Errorf("invalid text search configuration %q of %s in schema config", c.TextSearch[code], code)
+		}
+	}
+	for _, ctID := range sortedKeys(c.ContentTypes) {
+		ct := c.ContentTypes[ctID]
+		if ct == nil {
+			continue
+		}
+		for _, fieldID := range sortedKeys(ct.Fields) {
+			f := ct.Fields[fieldID]
+			if f == nil {
+				continue
+			}
+			if strings.HasPrefix(f.Column, "_") || len(f.Column) > pgMaxIdentifierLength {
+				return fmt.Errorf("invalid column %q of %s.%s in schema config", f.Column, ctID, fieldID)
+			}
+			switch f.Index {
+			case "", PGIndexBtree, PGIndexGin, PGIndexTrigram, PGIndexNone:
+			default:
+				return fmt.Errorf("unknown index %q of %s.%s in schema config", f.Index, ctID, fieldID)
+			}
+			switch strings.ToUpper(f.Search) {
+			case "", "A", "B", "C", "D":
+			default:
+				return fmt.Errorf("unknown search weight %q of %s.%s in schema config", f.Search, ctID, fieldID)
+			}
+		}
+		for _, idx := range ct.Indexes {
+			if len(idx.Fields) == 0 {
+				return fmt.Errorf("index of %s has no fields in schema config", ctID)
+			}
+			switch idx.Method {
+			case "", PGIndexBtree, PGIndexGin, PGIndexTrigram:
+			default:
+				return fmt.Errorf("unknown index method %q of %s in schema config", idx.Method, ctID)
+			}
+			if idx.Unique && idx.Method != "" && idx.Method != PGIndexBtree {
+				return fmt.Errorf("unique index of %s must be a btree index in schema config", ctID)
+			}
+		}
+		for _, fields := range ct.Unique {
+			if len(fields) == 0 {
+				return fmt.Errorf("unique constraint of %s has no fields in schema config", ctID)
+			}
+		}
+	}
+	return nil
+}
+
+// locationType returns the column type of the Location fields, point without config
+func (c *PGSchemaConfig) locationType() string {
+	if c == nil || c.LocationType == "" {
+		return PGLocationPoint
+	}
+	return c.LocationType
+}
+
+// contentType returns the config of the content type, nil without config
+func (c *PGSchemaConfig) contentType(id string) *PGContentTypeConfig {
+	if c == nil {
+		return nil
+	}
+	return c.ContentTypes[id]
+}
+
+// field returns the config of the field, nil without config
+func (c *PGContentTypeConfig) field(id string) *PGFieldConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Fields[id]
+}
+
+// columnName returns the column of the field, renamed by the config
+func (c *PGContentTypeConfig) columnName(fieldID string) string {
+	if f := c.field(fieldID); f != nil && f.Column != "" {
+		return f.Column
+	}
+	return pgColumnName(fieldID)
+}
+
+// ApplyContentTypes returns copies of the content types carrying the config: the
+// excluded fields are omitted, the localization is overridden and the renamed
+// columns and the indexes are kept for the schema. The Location fields get the
+// location type of the config. Applying it again is a no-op.
+func (c *PGSchemaConfig) ApplyContentTypes(types []*ContentType) []*ContentType {
+	if c == nil {
+		return types
+	}
+	res := make([]*ContentType, 0, len(types))
+	for _, t := range types {
+		ctc := c.contentType(t.Sys.ID)
+		if ctc == nil && !c.hasLocationFields(t) {
+			res = append(res, t)
+			continue
+		}
+		ct := *t
+		ct.pg = ctc
+		ct.Fields = make([]*ContentTypeField, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fc := c.locationField(f, ctc.field(f.ID))
+			if fc == nil {
+				ct.Fields = append(ct.Fields, f)
+				continue
+			}
+			field := *f
+			field.pg = fc
+			if fc.Exclude != nil {
+				field.Omitted = *fc.Exclude
+			}
+			if fc.Localized != nil {
+				field.Localized = *fc.Localized
+			}
+			ct.Fields = append(ct.Fields, &field)
+		}
+		res = append(res, &ct)
+	}
+	return res
+}
+
+// hasLocationFields tells whether the Location fields of the content type get another
+// column type than point
+func (c *PGSchemaConfig) hasLocationFields(t *ContentType) bool {
+	if c.locationType() == PGLocationPoint {
+		return false
+	}
+	for _, f := range t.Fields {
+		if f.Type == "Location" {
+			return true
+		}
+	}
+	return false
+}
+
+// locationField returns the field config with the location type of the config as
+// the column type of a Location field, unless the field config has its own type
+func (c *PGSchemaConfig) locationField(f *ContentTypeField, fc *PGFieldConfig) *PGFieldConfig {
+	if f.Type != "Location" || c.locationType() == PGLocationPoint || (fc != nil && fc.Type != "") {
+		return fc
+	}
+	res := &PGFieldConfig{}
+	if fc != nil {
+		*res = *fc
+	}
+	res.Type = c.locationType()
+	return res
+}
+
+// validateContentTypes returns an error if the config refers to missing fields, the
+// content types are validated with the config applied
+func (c *PGSchemaConfig) validateContentTypes(types []*ContentType) error {
+	if c == nil {
+		return nil
+	}
+	for _, t := range types {
+		ctc := c.contentType(t.Sys.ID)
+		if ctc == nil {
+			continue
+		}
+		fields := make(map[string]*ContentTypeField)
+		for _, f := range t.Fields {
+			fields[f.ID] = f
+		}
+		for _, fieldID := range sortedKeys(ctc.Fields) {
+			f := fields[fieldID]
+			if f == nil {
+				return fmt.Errorf("unknown field %s of %s in schema config", fieldID, t.Sys.ID)
+			}
+			fc := ctc.Fields[fieldID]
+			if fc != nil && fc.Type != "" && isLinkField(f) {
+				return fmt.Errorf("the type of the link field %s of %s can't be overridden", fieldID, t.Sys.ID)
+			}
+			if fc != nil && fc.Search != "" && pgSearchKind(f) == "" {
+				return fmt.Errorf("the %s field %s of %s can't be searched", f.Type, fieldID, t.Sys.ID)
+			}
+		}
+		indexed := make([]string, 0)
+		for _, idx := range ctc.Indexes {
+			indexed = append(indexed, idx.Fields...)
+		}
+		for _, u := range ctc.Unique {
+			indexed = append(indexed, u...)
+		}
+		for _, fieldID := range indexed {
+			f := fields[fieldID]
+			if f == nil || f.Omitted {
+				return fmt.Errorf("the index of %s refers to the unknown or excluded field %s in schema config", t.Sys.ID, fieldID)
+			}
+		}
+	}
+	return nil
+}
+
+func isLinkField(f *ContentTypeField) bool {
+	return f.LinkType != "" || (f.Items != nil && f.Items.LinkType != "")
+}
+
+// fieldColumnName returns the column of the field, renamed by the schema config
+func fieldColumnName(f *ContentTypeField) string {
+	if f.pg != nil && f.pg.Column != "" {
+		return f.pg.Column
+	}
+	return pgColumnName(f.ID)
+}
+
+// fieldColumnType returns the column type of the field, overridden by the schema config
+func fieldColumnType(f *ContentTypeField) string {
+	if f.pg != nil && f.pg.Type != "" {
+		return f.pg.Type
+	}
+	return getColumnType(f.Type, f.Items)
+}
+
+// isLocalizedColumn tells whether the column is synced in every locale, the slug is
+// taken from the default locale unless the schema config localizes it
+func isLocalizedColumn(f *ContentTypeField) bool {
+	if f.pg != nil && f.pg.Localized != nil {
+		return *f.pg.Localized
+	}
+	return f.Localized && strings.ToLower(f.ID) != "slug"
+}
+
+// fieldIndex returns the index method of the field, the slug, code and key fields
+// are indexed by default
+func fieldIndex(f *ContentTypeField) string {
+	if f.pg != nil && f.pg.Index != "" {
+		return f.pg.Index
+	}
+	if isIndex(f.ID) {
+		return PGIndexBtree
+	}
+	return ""
+}
+
+// newPGSQLIndexes returns the gin and trigram indexes of the fields and the indexes and
+// unique constraints of the content type in the schema config
+func newPGSQLIndexes(tableName string, item *ContentType) []*PGSQLIndex {
+	indexes := make([]*PGSQLIndex, 0)
+	columns := make(map[string]string)
+	for _, f := range item.Fields {
+		if f.Omitted {
+			continue
+		}
+		columns[f.ID] = fieldColumnName(f)
+		switch method := fieldIndex(f); method {
+		case PGIndexGin, PGIndexTrigram:
+			indexes = append(indexes, newPGSQLIndex(tableName, "", method, []string{columns[f.ID]}, "", false))
+		}
+	}
+	if item.pg == nil {
+		return indexes
+	}
+	for _, idx := range item.pg.Indexes {
+		cols := make([]string, 0)
+		for _, fieldID := range idx.Fields {
+			cols = append(cols, columns[fieldID])
+		}
+		method := idx.Method
+		if method == "" {
+			method = PGIndexBtree
+		}
+		indexes = append(indexes, newPGSQLIndex(tableName, idx.Name, method, cols, idx.Where, idx.Unique))
+	}
+	for _, fields := range item.pg.Unique {
+		cols := make([]string, 0)
+		for _, fieldID := range fields {
+			cols = append(cols, columns[fieldID])
+		}
+		indexes = append(indexes, newPGSQLIndex(tableName, "", PGIndexBtree, cols, "", true))
+	}
+	return indexes
+}
+
+// newPGSQLIndex returns the index of the columns, the btree indexes are per locale
+func newPGSQLIndex(tableName string, name string, method string, columns []string, where string, unique bool) *PGSQLIndex {
+	if name == "" {
+		kind := method
+		if unique {
+			kind = "unique"
+		}
+		name = pgIndexName(append(append([]string{tableName}, columns...), kind)...)
+	}
+	exprs := make([]string, 0)
+	for _, col := range columns {
+		if method == PGIndexTrigram {
+			exprs = append(exprs, pgQuoteIdent(col)+" public.gin_trgm_ops")
+		} else {
+			exprs = append(exprs, pgQuoteIdent(col))
+		}
+	}
+	if method == PGIndexBtree {
+		exprs = append(exprs, "_locale")
+	}
+	return &PGSQLIndex{
+		Name:    name,
+		Method:  method,
+		Columns: strings.Join(exprs, ","),
+		Where:   where,
+		Unique:  unique,
+	}
+}
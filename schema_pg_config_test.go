@@ -0,0 +1,157 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const schemaConfigTest = `
+contentTypes:
+  game:
+    fields:
+      releaseDate: { column: released_at }
+      description: { exclude: true }
+      tags: { index: gin }
+      name: { index: trigram }
+      slug: { localized: true, index: none }
+      rtp: { type: real }
+    indexes:
+      - { fields: [rtp], where: "rtp > 90" }
+    unique:
+      - [name, releaseDate]
+`
+
+func schemaConfigTestTypes() []*ContentType {
+	return []*ContentType{{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{
+		{ID: "name", Type: "Symbol", Localized: true},
+		{ID: "slug", Type: "Symbol", Localized: true},
+		{ID: "releaseDate", Type: "Date"},
+		{ID: "description", Type: "Text"},
+		{ID: "tags", Type: "Array", Items: &FieldTypeArrayItem{Type: "Symbol"}},
+		{ID: "rtp", Type: "Number"},
+	}}}
+}
+
+func TestParsePGSchemaConfig(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(schemaConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ContentTypes["game"].Fields["releaseDate"].Column != "released_at" {
+		t.Errorf("unexpected config %+v", cfg.ContentTypes["game"])
+	}
+
+	for _, invalid := range []string{
+		`contentTypes: { game: { fields: { name: { index: hash } } } }`,
+		`contentTypes: { game: { fields: { name: { column: _name } } } }`,
+		`contentTypes: { game: { indexes: [{ fields: [] }] } }`,
+		`contentTypes: { game: { indexes: [{ fields: [tags], method: gin, unique: true }] } }`,
+		`locationType: geometry`,
+	} {
+		_, err = ParsePGSchemaConfig([]byte(invalid))
+		if err == nil {
+			t.Errorf("invalid config accepted: %s", invalid)
+		}
+	}
+
+	_, err = NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", schemaConfigTestTypes(), 0, &PGSchemaConfig{
+		ContentTypes: map[string]*PGContentTypeConfig{"game": {Unique: [][]string{{"description", "unknown"}}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("unknown field not detected: %v", err)
+	}
+}
+
+func TestPGSchemaConfigTables(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(schemaConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", schemaConfigTestTypes(), 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;`,
+		`"released_at" timestamptz,`,
+		`"rtp" real,`,
+		`CREATE INDEX IF NOT EXISTS "idx_game_tags_gin" ON "game" USING gin ("tags");`,
+		`CREATE INDEX IF NOT EXISTS "idx_game_name_trigram" ON "game" USING gin ("name" public.gin_trgm_ops);`,
+		`CREATE INDEX IF NOT EXISTS "idx_game_rtp_btree" ON "game" USING btree ("rtp",_locale) WHERE rtp > 90;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "idx_game_name_released_at_unique" ON "game" USING btree ("name","released_at",_locale);`,
+	} {
+		if !strings.Contains(tables, want) {
+			t.Errorf("missing %q in:\n%s", want, tables)
+		}
+	}
+	for _, unexpected := range []string{`"description" text`, `"release_date"`, `"idx_game_slug"`} {
+		if strings.Contains(tables, unexpected) {
+			t.Errorf("unexpected %s in:\n%s", unexpected, tables)
+		}
+	}
+
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(funcs, `"released_at" timestamptz`) || !strings.Contains(funcs, `"rtp" real`) {
+		t.Errorf("unexpected view columns:\n%s", funcs)
+	}
+}
+
+func TestPGSchemaConfigSync(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(schemaConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &Entry{}
+	err = json.Unmarshal([]byte(`{"sys": {"id": "g1", "type": "Entry", "contentType": {"sys": {"id": "game"}}}, "fields": {
+		"name": {"en": "Game", "de": "Spiel"},
+		"slug": {"en": "game", "de": "spiel"},
+		"releaseDate": {"en": "2023-01-02"},
+		"description": {"en": "Long text"}
+	}}`), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := []*Locale{{Code: "en", Default: true}, {Code: "de"}}
+
+	schema := NewPGSyncSchema("public", locales, cfg.ApplyContentTypes(schemaConfigTestTypes()), []*Entry{item}, true, nil)
+	rows := make(map[string]*PGSyncRow)
+	for _, r := range schema.Tables["game"].Rows {
+		rows[r.Locale] = r
+	}
+	de := rows["de"]
+	if de.FieldValues["released_at"] != "2023-01-02" || de.FieldValues["slug"] != "spiel" || de.FieldValues["description"] != nil {
+		t.Errorf("unexpected row %v", de.FieldValues)
+	}
+	if strings.Contains(strings.Join(de.FieldColumns, ","), "description") {
+		t.Errorf("excluded column synced %v", de.FieldColumns)
+	}
+}
+
+func TestPGSchemaConfigQuery(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(schemaConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := pgQueryTest(url.Values{
+		"fields.releaseDate[gte]": []string{"2023-01-01"},
+		"order":                   []string{"-fields.releaseDate"},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Filters[0] != `"game"."released_at" >= $1[1]::timestamptz` || q.Order != `"game"."released_at" DESC NULLS LAST` {
+		t.Errorf("unexpected query %v %s", q.Filters, q.Order)
+	}
+	if _, err = pgQueryTest(url.Values{"fields.description": []string{"excluded"}}, cfg); err == nil {
+		t.Error("filter of an excluded field accepted")
+	}
+}
@@ -0,0 +1,701 @@
+package gontentful
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const (
+	selectSchemaColumns = `SELECT table_name, column_name, data_type, udt_name FROM information_schema.columns
+	WHERE table_schema = current_schema() ORDER BY table_name, ordinal_position`
+	selectSchemaIndexes = "SELECT tablename, indexname FROM pg_indexes WHERE schemaname = current_schema()"
+	alterColumnTypeTpl  = `ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s USING %s;`
+	dropColumnTpl       = `ALTER TABLE "%s" DROP COLUMN IF EXISTS "%s";`
+	dropTableTpl        = `DROP FUNCTION IF EXISTS "%[1]s_query";
+DROP FUNCTION IF EXISTS "%[1]s_view" CASCADE;
+DROP TABLE IF EXISTS "%[1]s" CASCADE;
+DELETE FROM _schema WHERE table_name = '%[1]s';`
+	dropConTableTpl      = `DROP TABLE IF EXISTS "%s" CASCADE;`
+	dropDeleteTriggerTpl = `DROP TRIGGER IF EXISTS "%[1]s_delete" ON "%[1]s";
+DROP FUNCTION IF EXISTS "%[1]s_delete_trigger";`
+	dropIndexTpl       = `DROP INDEX IF EXISTS "%s";`
+	conTableToEntryTpl = `ALTER TABLE "%[1]s" RENAME COLUMN "%[2]s" TO "_entry";
+ALTER TABLE "%[1]s" RENAME COLUMN "%[2]s_sys_id" TO "_entry_sys_id";
+ALTER TABLE "%[1]s" ADD COLUMN IF NOT EXISTS "_content_type" TEXT;
+UPDATE "%[1]s" SET "_content_type" = '%[3]s';`
+	conTableFromEntryTpl = `DELETE FROM "%[1]s" WHERE "_content_type" <> '%[3]s';
+ALTER TABLE "%[1]s" DROP COLUMN IF EXISTS "_content_type";
+ALTER TABLE "%[1]s" RENAME COLUMN "_entry" TO "%[2]s";
+ALTER TABLE "%[1]s" RENAME COLUMN "_entry_sys_id" TO "%[2]s_sys_id";`
+)
+
+// destructivePGSteps are the kinds of steps dropping or converting data
+var destructivePGSteps = map[string]bool{
+	"drop table":              true,
+	"drop connection table":   true,
+	"change connection table": true,
+	"drop column":             true,
+	"change column type":      true,
+	"drop index":              true,
+}
+
+// PGSchemaStep is a DDL statement of a schema migration.
+type PGSchemaStep struct {
+	Kind      string
+	TableName string
+	Column    string
+	SQL       string
+}
+
+// PGSchemaDiff migrates a schema in place: it compares the stored models and the
+// columns of the database with the target schema and alters only what changed.
+// The functions and materialized views are regenerated for the changed tables and
+// the tables depending on them.
+type PGSchemaDiff struct {
+	Schema *PGSQLSchema
+	Steps  []*PGSchemaStep
+	// AdditiveOnly refuses to Exec the steps dropping or converting data
+	AdditiveOnly bool
+}
+
+type pgSchemaState struct {
+	// Models are the stored models by table, nil without a _schema table
+	Models map[string]content.Fields
+	// Columns are the column types by table, in the format of getColumnType
+	Columns map[string]map[string]string
+	Indexes map[string]map[string]bool
+}
+
+type pgSchemaColumn struct {
+	TableName  string `db:"table_name"`
+	ColumnName string `db:"column_name"`
+	DataType   string `db:"data_type"`
+	UdtName    string `db:"udt_name"`
+}
+
+type pgSchemaIndex struct {
+	TableName string `db:"tablename"`
+	IndexName string `db:"indexname"`
+}
+
+func NewPGSchemaDiff(schema *PGSQLSchema) *PGSchemaDiff {
+	return &PGSchemaDiff{
+		Schema: schema,
+	}
+}
+
+func (s *PGSchemaStep) String() string {
+	if s.Column == "" {
+		return fmt.Sprintf("%s %s", s.Kind, s.TableName)
+	}
+	return fmt.Sprintf("%s %s.%s", s.Kind, s.TableName, s.Column)
+}
+
+func (s *PGSchemaStep) Destructive() bool {
+	return destructivePGSteps[s.Kind]
+}
+
+// Plan returns the steps migrating the database to the schema, without applying them.
+func (d *PGSchemaDiff) Plan(databaseURL string) ([]*PGSchemaStep, error) {
+	db, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	txn, err := db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if d.Schema.SchemaName != "" {
+		// the search path of the transaction only, the connections are pooled
+		_, err = txn.Exec("SELECT set_config('search_path', $1, true)", d.Schema.SchemaName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := loadPGSchemaState(txn)
+	if err != nil {
+		return nil, err
+	}
+	d.Steps, err = diffPGSchema(state, d.Schema)
+	return d.Steps, err
+}
+
+// Exec plans and applies the steps in one transaction.
+func (d *PGSchemaDiff) Exec(databaseURL string) error {
+	db, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	txn, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	if d.Schema.SchemaName != "" {
+		// set schema in use
+		_, err = txn.Exec(fmt.Sprintf("SET search_path='%s'", d.Schema.SchemaName))
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err := loadPGSchemaState(txn)
+	if err != nil {
+		return err
+	}
+	d.Steps, err = diffPGSchema(state, d.Schema)
+	if err != nil {
+		return err
+	}
+	if d.AdditiveOnly {
+		err = d.checkAdditive()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, step := range d.Steps {
+		_, err = txn.Exec(step.SQL)
+		if err != nil {
+			return fmt.Errorf("%s: %s", step.String(), err.Error())
+		}
+	}
+
+	return txn.Commit()
+}
+
+// checkAdditive fails on the planned steps dropping or converting data
+func (d *PGSchemaDiff) checkAdditive() error {
+	destructive := make([]string, 0)
+	for _, step := range d.Steps {
+		if step.Destructive() {
+			destructive = append(destructive, step.String())
+		}
+	}
+	if len(destructive) > 0 {
+		return fmt.Errorf("destructive schema changes refused: %s", strings.Join(destructive, ", "))
+	}
+	return nil
+}
+
+// Render returns the SQL script of the planned steps.
+func (d *PGSchemaDiff) Render() string {
+	var buf strings.Builder
+	for _, step := range d.Steps {
+		fmt.Fprintf(&buf, "-- %s\n%s\n", step.String(), strings.TrimSpace(step.SQL))
+	}
+	return buf.String()
+}
+
+func (d *PGSchemaDiff) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "schema %s: %d changes\n", d.Schema.SchemaName, len(d.Steps))
+	for _, step := range d.Steps {
+		fmt.Fprintf(&buf, "  %s\n", step.String())
+	}
+	return buf.String()
+}
+
+func loadPGSchemaState(q sqlx.Queryer) (*pgSchemaState, error) {
+	state := &pgSchemaState{
+		Columns: make(map[string]map[string]string),
+		Indexes: make(map[string]map[string]bool),
+	}
+
+	exists, err := tableExists(q, "_schema")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		models := make([]*pgStoredModel, 0)
+		err = sqlx.Select(q, &models, selectStoredSchema)
+		if err != nil {
+			return nil, err
+		}
+		state.Models = make(map[string]content.Fields)
+		for _, m := range models {
+			state.Models[m.TableName] = m.Fields
+		}
+	}
+
+	columns := make([]*pgSchemaColumn, 0)
+	err = sqlx.Select(q, &columns, selectSchemaColumns)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range columns {
+		if state.Columns[c.TableName] == nil {
+			state.Columns[c.TableName] = make(map[string]string)
+		}
+		state.Columns[c.TableName][c.ColumnName] = pgColumnType(c.DataType, c.UdtName)
+	}
+
+	indexes := make([]*pgSchemaIndex, 0)
+	err = sqlx.Select(q, &indexes, selectSchemaIndexes)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range indexes {
+		if state.Indexes[i.TableName] == nil {
+			state.Indexes[i.TableName] = make(map[string]bool)
+		}
+		state.Indexes[i.TableName][i.IndexName] = true
+	}
+
+	return state, nil
+}
+
+// pgColumnType maps the information_schema types to the types of getColumnType
+func pgColumnType(dataType string, udtName string) string {
+	switch dataType {
+	case "ARRAY":
+		return pgColumnType(strings.TrimPrefix(udtName, "_"), "") + " ARRAY"
+	case "USER-DEFINED":
+		return pgColumnType(udtName, "")
+	case "int4":
+		return "integer"
+	case "bool":
+		return "boolean"
+	case "timestamp with time zone":
+		return "timestamptz"
+	case "timestamp without time zone":
+		return "timestamp"
+	case "geography":
+		return PGLocationGeography
+	}
+	return dataType
+}
+
+// diffPGSchema returns the ordered steps migrating the state to the schema:
+// dropped tables, new tables, column changes, index and model changes, the
+// references and last the functions of the affected tables.
+func diffPGSchema(state *pgSchemaState, schema *PGSQLSchema) ([]*PGSchemaStep, error) {
+	steps := make([]*PGSchemaStep, 0)
+	affected := make(map[string]bool)
+
+	if state.Models == nil {
+		sql, err := renderPGSchemaHeader(schema)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, &PGSchemaStep{Kind: "create schema", TableName: schema.SchemaName, SQL: sql})
+		state.Models = make(map[string]content.Fields)
+	}
+
+	tables := make(map[string]*PGSQLTable)
+	for _, tbl := range schema.Tables {
+		tables[tbl.TableName] = tbl
+	}
+	conTables := make(map[string]*PGSQLTable)
+	for _, tbl := range schema.ConTables {
+		conTables[tbl.TableName] = tbl
+	}
+	triggers := make(map[string]bool)
+	for _, t := range schema.DeleteTriggers {
+		triggers[t.TableName] = true
+	}
+
+	// sys timestamps of the schemas created without time zone
+	for _, tableName := range []string{ASSET_TABLE_NAME, "_schema"} {
+		steps = append(steps, diffPGTimestamps(tableName, state.Columns[tableName])...)
+	}
+
+	// removed content types
+	for _, tableName := range sortedKeys(state.Models) {
+		if tables[tableName] == nil {
+			steps = append(steps, &PGSchemaStep{Kind: "drop table", TableName: tableName, SQL: fmt.Sprintf(dropTableTpl, tableName)})
+		}
+	}
+
+	// removed connection tables and the delete triggers deleting from them
+	for _, tableName := range sortedKeys(state.Columns) {
+		if !isPGConTable(tableName, state.Models, tables) {
+			continue
+		}
+		if tbl := conTables[tableName]; tbl != nil {
+			step, recreate := diffPGConTable(tbl, state.Columns[tableName], tables, triggers)
+			if step != nil {
+				steps = append(steps, step)
+				affected[pgConTableParent(tableName)] = true
+			}
+			if recreate {
+				// created again with the new connection tables
+				delete(state.Columns, tableName)
+			}
+			continue
+		}
+		sql := fmt.Sprintf(dropConTableTpl, tableName)
+		parent, ref := pgConTableReference(tableName, state.Columns[tableName])
+		if ref != "" && !triggers[ref] {
+			sql = fmt.Sprintf("%s\n%s", fmt.Sprintf(dropDeleteTriggerTpl, ref), sql)
+		}
+		steps = append(steps, &PGSchemaStep{Kind: "drop connection table", TableName: tableName, SQL: sql})
+		affected[parent] = true
+	}
+
+	for _, tbl := range schema.Tables {
+		columns, ok := state.Columns[tbl.TableName]
+		if !ok {
+			sql, err := renderPGSchemaTables(schema, tbl)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, &PGSchemaStep{Kind: "create table", TableName: tbl.TableName, SQL: sql})
+			affected[tbl.TableName] = true
+			continue
+		}
+
+		tableSteps := diffPGTimestamps(tbl.TableName, columns)
+		tableSteps = append(tableSteps, diffPGColumns(tbl, columns)...)
+		tableSteps = append(tableSteps, diffPGIndexes(tbl, schema, state.Indexes[tbl.TableName])...)
+		if len(tableSteps) > 0 || len(compareModelFields(tbl.TableName, state.Models[tbl.TableName], tbl.Schema.Fields)) > 0 {
+			sql, err := renderPGSchemaTables(schema, tbl)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, tableSteps...)
+			steps = append(steps, &PGSchemaStep{Kind: "update model", TableName: tbl.TableName, SQL: sql})
+			affected[tbl.TableName] = true
+		}
+	}
+
+	if len(steps) == 0 {
+		return steps, nil
+	}
+
+	// the new connection tables are created with the references
+	refs := *schema
+	refs.ConTables = make([]*PGSQLTable, 0)
+	names := make([]string, 0)
+	for _, tbl := range schema.ConTables {
+		if _, ok := state.Columns[tbl.TableName]; !ok {
+			refs.ConTables = append(refs.ConTables, tbl)
+			names = append(names, tbl.TableName)
+			affected[pgConTableParent(tbl.TableName)] = true
+		}
+	}
+	sql, err := NewPGReferences(&refs).Render()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		steps = append(steps, &PGSchemaStep{Kind: "create connection tables", TableName: strings.Join(names, ", "), SQL: sql})
+	} else {
+		steps = append(steps, &PGSchemaStep{Kind: "update references", TableName: "table_references", SQL: sql})
+	}
+
+	funcs, err := renderPGSchemaFunctions(schema, pgDependents(schema, affected))
+	if err != nil {
+		return nil, err
+	}
+	if funcs != nil {
+		steps = append(steps, funcs)
+	}
+
+	return steps, nil
+}
+
+func diffPGColumns(tbl *PGSQLTable, columns map[string]string) []*PGSchemaStep {
+	steps := make([]*PGSchemaStep, 0)
+	target := make(map[string]bool)
+	for _, col := range tbl.Columns {
+		target[col.ColumnName] = true
+		current, ok := columns[col.ColumnName]
+		switch {
+		case !ok:
+			steps = append(steps, &PGSchemaStep{
+				Kind:      "add column",
+				TableName: tbl.TableName,
+				Column:    col.ColumnName,
+				SQL:       fmt.Sprintf(addColumnTpl, tbl.TableName, col.ColumnName, col.ColumnType),
+			})
+		case current != col.ColumnType:
+			steps = append(steps, &PGSchemaStep{
+				Kind:      "change column type",
+				TableName: tbl.TableName,
+				Column:    col.ColumnName,
+				SQL:       fmt.Sprintf(alterColumnTypeTpl, tbl.TableName, col.ColumnName, col.ColumnType, pgColumnCast(col.ColumnName, current, col.ColumnType)),
+			})
+		}
+	}
+	for _, name := range sortedKeys(columns) {
+		// the system columns start with _
+		if !target[name] && !strings.HasPrefix(name, "_") {
+			steps = append(steps, &PGSchemaStep{
+				Kind:      "drop column",
+				TableName: tbl.TableName,
+				Column:    name,
+				SQL:       fmt.Sprintf(dropColumnTpl, tbl.TableName, name),
+			})
+		}
+	}
+	return steps
+}
+
+// diffPGTimestamps converts the sys timestamps stored without time zone, they were
+// parsed from the UTC sys dates
+func diffPGTimestamps(tableName string, columns map[string]string) []*PGSchemaStep {
+	steps := make([]*PGSchemaStep, 0)
+	for _, name := range []string{"_created_at", "_updated_at"} {
+		if columns[name] == "timestamp" {
+			steps = append(steps, &PGSchemaStep{
+				Kind:      "change column type",
+				TableName: tableName,
+				Column:    name,
+				SQL:       fmt.Sprintf(alterColumnTypeTpl, tableName, name, "timestamptz", pgColumnCast(name, "timestamp", "timestamptz")),
+			})
+		}
+	}
+	return steps
+}
+
+// diffPGIndexes drops the field indexes of the table the schema no longer creates,
+// the missing ones are created by the model and references steps.
+func diffPGIndexes(tbl *PGSQLTable, schema *PGSQLSchema, indexes map[string]bool) []*PGSchemaStep {
+	steps := make([]*PGSchemaStep, 0)
+	expected := map[string]bool{
+		pgIndexName(tbl.TableName, "_sys_id_locale"):       true,
+		pgIndexName(tbl.TableName, "_space_sys_id_locale"): true,
+		pgIndexName(tbl.TableName, "_space"):               true,
+		pgIndexName(tbl.TableName, "_sys_id"):              true,
+		pgIndexName(tbl.TableName, "_locale"):              true,
+	}
+	// the index of each column, the names may be shortened
+	columns := make(map[string]string)
+	for _, col := range tbl.Columns {
+		columns[pgIndexName(tbl.TableName, col.ColumnName)] = col.ColumnName
+		if col.IsIndex {
+			expected[pgIndexName(tbl.TableName, col.ColumnName)] = true
+		}
+	}
+	for _, idx := range tbl.Indexes {
+		expected[idx.Name] = true
+	}
+	for _, ref := range schema.References {
+		if ref.TableName == tbl.TableName {
+			expected[pgIndexName(tbl.TableName, ref.ForeignKey)] = true
+		}
+	}
+
+	for _, name := range sortedKeys(indexes) {
+		// indexes of the dropped columns are dropped with the columns
+		column, ok := columns[name]
+		if expected[name] || !ok {
+			continue
+		}
+		steps = append(steps, &PGSchemaStep{
+			Kind:      "drop index",
+			TableName: tbl.TableName,
+			Column:    column,
+			SQL:       fmt.Sprintf(dropIndexTpl, name),
+		})
+	}
+	return steps
+}
+
+// pgColumnCast returns the USING expression converting the column to the new type
+func pgColumnCast(column string, from string, to string) string {
+	fromBase, fromArray := strings.CutSuffix(from, " ARRAY")
+	toBase, toArray := strings.CutSuffix(to, " ARRAY")
+	col := fmt.Sprintf(`"%s"`, column)
+
+	switch {
+	case fromArray && toArray:
+		return fmt.Sprintf("%s::%s[]", col, toBase)
+	case fromArray:
+		return pgScalarCast(fmt.Sprintf("(%s)[1]", col), fromBase, toBase)
+	case toArray:
+		return fmt.Sprintf("CASE WHEN %s IS NULL THEN NULL ELSE ARRAY[%s] END", col, pgScalarCast(col, fromBase, toBase))
+	}
+	return pgScalarCast(col, fromBase, toBase)
+}
+
+func pgScalarCast(expr string, from string, to string) string {
+	switch {
+	case from == to:
+		return expr
+	case to == "jsonb":
+		return fmt.Sprintf("to_jsonb(%s)", expr)
+	case from == "jsonb" && to == "text":
+		return fmt.Sprintf("%s #>> '{}'", expr)
+	case from == "jsonb":
+		return fmt.Sprintf("(%s #>> '{}')::%s", expr, to)
+	case from == "timestamp" && to == "timestamptz":
+		return fmt.Sprintf("%s AT TIME ZONE 'UTC'", expr)
+	case from == PGLocationPoint && to == PGLocationGeography:
+		return fmt.Sprintf("ST_SetSRID(ST_MakePoint((%[1]s)[0], (%[1]s)[1]), 4326)::geography", expr)
+	case from == PGLocationGeography && to == PGLocationPoint:
+		return fmt.Sprintf("point(ST_X(%[1]s::geometry), ST_Y(%[1]s::geometry))", expr)
+	}
+	return fmt.Sprintf("%s::%s", expr, to)
+}
+
+// diffPGConTable converts the connection table between one referenced content type
+// and several ones, keeping the rows. Any other change of the reference recreates
+// the table, its rows are written again by the next sync of the entries.
+func diffPGConTable(tbl *PGSQLTable, columns map[string]string, tables map[string]*PGSQLTable, triggers map[string]bool) (*PGSchemaStep, bool) {
+	changed := len(columns) != len(tbl.Columns)+1
+	for _, col := range tbl.Columns {
+		if _, ok := columns[col.ColumnName]; !ok {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+
+	_, ref := pgConTableReference(tbl.TableName, columns)
+	_, entries := columns[pgEntryReference]
+	target := tbl.Columns[2].ColumnName
+	step := &PGSchemaStep{Kind: "change connection table", TableName: tbl.TableName}
+	recreate := false
+	switch {
+	case ref != "" && target == pgEntryReference:
+		step.SQL = fmt.Sprintf(conTableToEntryTpl, tbl.TableName, ref, pgTableContentType(ref, tables))
+	case entries && target != pgEntryReference:
+		step.SQL = fmt.Sprintf(conTableFromEntryTpl, tbl.TableName, target, pgTableContentType(target, tables))
+	default:
+		step.SQL = fmt.Sprintf(dropConTableTpl, tbl.TableName)
+		recreate = true
+	}
+	if ref != "" && ref != target && !triggers[ref] {
+		step.SQL = fmt.Sprintf("%s\n%s", fmt.Sprintf(dropDeleteTriggerTpl, ref), step.SQL)
+	}
+	return step, recreate
+}
+
+// pgTableContentType returns the content type id of the table
+func pgTableContentType(tableName string, tables map[string]*PGSQLTable) string {
+	if tbl := tables[tableName]; tbl != nil && tbl.Schema != nil && tbl.Schema.ID != "" {
+		return tbl.Schema.ID
+	}
+	return tableName
+}
+
+// isPGConTable tells whether the table is a connection table of a content table
+func isPGConTable(tableName string, models map[string]content.Fields, tables map[string]*PGSQLTable) bool {
+	idx := strings.Index(tableName, "__")
+	if idx < 1 {
+		return false
+	}
+	parent := tableName[:idx]
+	_, stored := models[parent]
+	return stored || tables[parent] != nil
+}
+
+func pgConTableParent(tableName string) string {
+	idx := strings.Index(tableName, "__")
+	if idx < 1 {
+		return tableName
+	}
+	return tableName[:idx]
+}
+
+// pgConTableReference returns the parent and the referenced table of a connection table
+// from its columns: <parent>, <parent>_sys_id, <reference>, <reference>_sys_id, _locale.
+// The reference is empty for the tables linking entries of several content types.
+func pgConTableReference(tableName string, columns map[string]string) (string, string) {
+	parent := pgConTableParent(tableName)
+	for _, name := range sortedKeys(columns) {
+		if name != parent && !strings.HasPrefix(name, "_") && !strings.HasSuffix(name, "_sys_id") {
+			return parent, name
+		}
+	}
+	return parent, ""
+}
+
+// pgDependents returns the tables and the tables referencing them, recursively
+func pgDependents(schema *PGSQLSchema, tables map[string]bool) map[string]bool {
+	res := make(map[string]bool)
+	queue := sortedKeys(tables)
+	for len(queue) > 0 {
+		tableName := queue[0]
+		queue = queue[1:]
+		if res[tableName] {
+			continue
+		}
+		res[tableName] = true
+		for _, dep := range schema.Dependencies {
+			if dep.Reference == tableName && !res[dep.TableName] {
+				queue = append(queue, dep.TableName)
+			}
+		}
+	}
+	return res
+}
+
+func renderPGSchemaHeader(schema *PGSQLSchema) (string, error) {
+	header := *schema
+	header.Tables = nil
+	header.Functions = nil
+	header.DeleteTriggers = nil
+	header.ContentTypePublish = false
+	tables, err := header.Render()
+	if err != nil {
+		return "", err
+	}
+	funcs, err := NewPGFunctions(&header).Render()
+	if err != nil {
+		return "", err
+	}
+	return tables + funcs, nil
+}
+
+// renderPGSchemaTables renders the create table, indexes and _schema model of the table
+func renderPGSchemaTables(schema *PGSQLSchema, tbl *PGSQLTable) (string, error) {
+	s := *schema
+	s.Tables = []*PGSQLTable{tbl}
+	s.DropTables = false
+	// without the schema header, like a content type publish
+	s.ContentTypePublish = true
+	return s.Render()
+}
+
+// renderPGSchemaFunctions regenerates the _view and _query functions and the materialized
+// views of the tables, the delete triggers are re-created for every table.
+func renderPGSchemaFunctions(schema *PGSQLSchema, tables map[string]bool) (*PGSchemaStep, error) {
+	s := *schema
+	s.Tables = make([]*PGSQLTable, 0)
+	s.Functions = make([]*PGSQLProcedure, 0)
+	for _, tbl := range schema.Tables {
+		if tables[tbl.TableName] {
+			s.Tables = append(s.Tables, tbl)
+		}
+	}
+	for _, proc := range schema.Functions {
+		if tables[proc.TableName] {
+			s.Functions = append(s.Functions, proc)
+		}
+	}
+	if len(s.Functions) == 0 {
+		return nil, nil
+	}
+	// drops the _view functions with their materialized views, re-created with data
+	s.DropTables = true
+	s.ContentTypePublish = true
+
+	sql, err := NewPGFunctions(&s).Render()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(s.Functions))
+	for _, proc := range s.Functions {
+		names = append(names, proc.TableName)
+	}
+	sort.Strings(names)
+	return &PGSchemaStep{Kind: "regenerate functions", TableName: strings.Join(names, ", "), SQL: sql}, nil
+}
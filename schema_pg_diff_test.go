@@ -0,0 +1,298 @@
+package gontentful
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+func diffTestTypes() []*ContentType {
+	return []*ContentType{
+		{
+			Sys:          &Sys{ID: "game"},
+			Name:         "Game",
+			DisplayField: "name",
+			Fields: []*ContentTypeField{
+				{ID: "name", Name: "Name", Type: "Symbol"},
+				{ID: "slug", Name: "Slug", Type: "Symbol"},
+				{ID: "rating", Name: "Rating", Type: "Number"},
+				{ID: "logo", Name: "Logo", Type: "Link", LinkType: "Asset"},
+				{ID: "tags", Name: "Tags", Type: "Array", Items: &FieldTypeArrayItem{
+					Type:        "Link",
+					LinkType:    "Entry",
+					Validations: []*FieldValidation{{LinkContentType: []string{"tag"}}},
+				}},
+			},
+		},
+		{
+			Sys:          &Sys{ID: "tag"},
+			Name:         "Tag",
+			DisplayField: "name",
+			Fields: []*ContentTypeField{
+				{ID: "name", Name: "Name", Type: "Symbol"},
+			},
+		},
+	}
+}
+
+func diffTestState(types []*ContentType) *pgSchemaState {
+	models := make(map[string]content.Fields)
+	for _, t := range types {
+		models[toSnakeCase(t.Sys.ID)] = TransformModel(t).Fields
+	}
+	return &pgSchemaState{
+		Models: models,
+		Columns: map[string]map[string]string{
+			"game":       {"_id": "text", "_sys_id": "text", "name": "text", "slug": "text", "rating": "numeric", "logo": "text", "tags": "text ARRAY"},
+			"game__tags": {"_id": "integer", "game": "text", "game_sys_id": "text", "tag": "text", "tag_sys_id": "text", "_locale": "text"},
+			"tag":        {"_id": "text", "_sys_id": "text", "name": "text"},
+		},
+		Indexes: map[string]map[string]bool{
+			"game": {"game_pkey": true, "idx_game__sys_id": true, "idx_game_slug": true, "idx_game_logo": true},
+		},
+	}
+}
+
+func TestDiffPGSchemaUnchanged(t *testing.T) {
+	types := diffTestTypes()
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps, err := diffPGSchema(diffTestState(types), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("unexpected steps %v", steps)
+	}
+}
+
+func TestDiffPGSchema(t *testing.T) {
+	types := diffTestTypes()
+	stored := diffTestTypes()
+	// rating was an integer, studio a removed content type, logo and tags are new fields
+	stored[0].Fields = []*ContentTypeField{
+		{ID: "name", Name: "Name", Type: "Symbol"},
+		{ID: "slug", Name: "Slug", Type: "Symbol"},
+		{ID: "rating", Name: "Rating", Type: "Integer"},
+		{ID: "oldField", Name: "Old", Type: "Symbol"},
+	}
+	stored = append(stored, &ContentType{Sys: &Sys{ID: "studio"}, Name: "Studio", DisplayField: "name"})
+	state := diffTestState(stored)
+	state.Columns["game"] = map[string]string{"_id": "text", "_sys_id": "text", "name": "text", "slug": "text", "rating": "integer", "old_field": "text"}
+	state.Columns["game__old_studios"] = map[string]string{"_id": "integer", "game": "text", "game_sys_id": "text", "studio": "text", "studio_sys_id": "text", "_locale": "text"}
+	state.Columns["studio"] = map[string]string{"_id": "text", "_sys_id": "text", "name": "text"}
+	delete(state.Columns, "game__tags")
+	state.Indexes["game"]["idx_game_rating"] = true
+
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0)
+	for _, s := range steps {
+		got = append(got, s.String())
+	}
+	want := []string{
+		"drop table studio",
+		"drop connection table game__old_studios",
+		"change column type game.rating",
+		"add column game.logo",
+		"add column game.tags",
+		"drop column game.old_field",
+		"drop index game.rating",
+		"update model game",
+		"create connection tables game__tags",
+		"regenerate functions game",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected steps:\n%s", strings.Join(got, "\n"))
+	}
+
+	sql := map[string]string{}
+	for _, s := range steps {
+		sql[s.String()] = s.SQL
+	}
+	if !strings.Contains(sql["drop connection table game__old_studios"], `DROP TRIGGER IF EXISTS "studio_delete" ON "studio";`) {
+		t.Errorf("delete trigger of the removed connection table kept:\n%s", sql["drop connection table game__old_studios"])
+	}
+	if sql["change column type game.rating"] != `ALTER TABLE "game" ALTER COLUMN "rating" TYPE numeric USING "rating"::numeric;` {
+		t.Errorf("unexpected alter column %s", sql["change column type game.rating"])
+	}
+	if !strings.Contains(sql["create connection tables game__tags"], `CREATE TABLE IF NOT EXISTS "game__tags"`) {
+		t.Errorf("connection table not created:\n%s", sql["create connection tables game__tags"])
+	}
+	funcs := sql["regenerate functions game"]
+	if !strings.Contains(funcs, `DROP FUNCTION IF EXISTS "game_view" CASCADE;`) || strings.Contains(funcs, "tag_view") || strings.Contains(funcs, "WITH NO DATA") {
+		t.Errorf("unexpected functions:\n%s", funcs)
+	}
+}
+
+func TestDiffPGSchemaAdditiveOnly(t *testing.T) {
+	types := diffTestTypes()
+	stored := diffTestTypes()
+	stored[0].Fields = stored[0].Fields[:3]
+	state := diffTestState(stored)
+	state.Columns["game"] = map[string]string{"_id": "text", "_sys_id": "text", "name": "text", "slug": "text", "rating": "numeric"}
+	delete(state.Columns, "game__tags")
+
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := NewPGSchemaDiff(schema)
+	diff.Steps, err = diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// new fields only
+	if err = diff.checkAdditive(); err != nil {
+		t.Errorf("additive steps refused: %s\n%s", err.Error(), diff.String())
+	}
+
+	state = diffTestState(stored)
+	state.Columns["game"] = map[string]string{"_id": "text", "_sys_id": "text", "name": "text", "slug": "text", "rating": "integer", "old_field": "text"}
+	diff.Steps, err = diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = diff.checkAdditive()
+	if err == nil || err.Error() != "destructive schema changes refused: change column type game.rating, drop column game.old_field" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDiffPGSchemaDependents(t *testing.T) {
+	types := diffTestTypes()
+	state := diffTestState(types)
+	// a new field of tag changes the view of game joining the tags
+	types[1].Fields = append(types[1].Fields, &ContentTypeField{ID: "color", Name: "Color", Type: "Symbol"})
+
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := steps[len(steps)-1]
+	if last.String() != "regenerate functions game, tag" {
+		t.Errorf("unexpected functions step %s", last.String())
+	}
+}
+
+func TestDiffPGSchemaTimestamps(t *testing.T) {
+	types := diffTestTypes()
+	state := diffTestState(types)
+	state.Columns["game"]["_created_at"] = "timestamp"
+	state.Columns["tag"]["_created_at"] = "timestamptz"
+	state.Columns[ASSET_TABLE_NAME] = map[string]string{"_id": "text", "_updated_at": "timestamp"}
+
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0)
+	for _, s := range steps {
+		got = append(got, s.String())
+	}
+	want := "change column type _asset._updated_at,change column type game._created_at,update model game,update references table_references,regenerate functions game"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected steps %v", got)
+	}
+	if steps[1].SQL != `ALTER TABLE "game" ALTER COLUMN "_created_at" TYPE timestamptz USING "_created_at" AT TIME ZONE 'UTC';` {
+		t.Errorf("unexpected alter column %s", steps[1].SQL)
+	}
+}
+
+func TestPGColumnCast(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"integer", "numeric", `"c"::numeric`},
+		{"timestamp", "timestamptz", `"c" AT TIME ZONE 'UTC'`},
+		{"date", "timestamptz", `"c"::timestamptz`},
+		{"point", "geography(Point,4326)", `ST_SetSRID(ST_MakePoint(("c")[0], ("c")[1]), 4326)::geography`},
+		{"text", "jsonb", `to_jsonb("c")`},
+		{"jsonb", "text", `"c" #>> '{}'`},
+		{"jsonb", "integer", `("c" #>> '{}')::integer`},
+		{"text ARRAY", "text", `("c")[1]`},
+		{"text", "text ARRAY", `CASE WHEN "c" IS NULL THEN NULL ELSE ARRAY["c"] END`},
+		{"text ARRAY", "integer ARRAY", `"c"::integer[]`},
+	}
+	for _, tt := range tests {
+		if got := pgColumnCast("c", tt.from, tt.to); got != tt.want {
+			t.Errorf("%s -> %s: got %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if got := pgColumnType("ARRAY", "_int4"); got != "integer ARRAY" {
+		t.Errorf("unexpected array type %s", got)
+	}
+	if got := pgColumnType("timestamp with time zone", "timestamptz"); got != "timestamptz" {
+		t.Errorf("unexpected timestamp type %s", got)
+	}
+	if got := pgColumnType("USER-DEFINED", "geography"); got != PGLocationGeography {
+		t.Errorf("unexpected geography type %s", got)
+	}
+}
+
+func TestDiffPGSchemaPolymorphic(t *testing.T) {
+	types := diffTestTypes()
+	state := diffTestState(types)
+	// the tags can link games as well
+	types[0].Fields[4].Items.Validations = []*FieldValidation{{LinkContentType: []string{"tag", "game"}}}
+
+	schema, err := NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", types, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].String() != "change connection table game__tags" {
+		t.Fatalf("unexpected step %s", steps[0].String())
+	}
+	for _, want := range []string{
+		`DROP TRIGGER IF EXISTS "tag_delete" ON "tag";`,
+		`ALTER TABLE "game__tags" RENAME COLUMN "tag" TO "_entry";`,
+		`UPDATE "game__tags" SET "_content_type" = 'tag';`,
+	} {
+		if !strings.Contains(steps[0].SQL, want) {
+			t.Errorf("missing %q in:\n%s", want, steps[0].SQL)
+		}
+	}
+
+	// and back to the tags only
+	state.Columns["game__tags"] = map[string]string{"_id": "integer", "game": "text", "game_sys_id": "text", "_entry": "text", "_entry_sys_id": "text", "_content_type": "text", "_locale": "text"}
+	schema, err = NewPGSQLSchema("content", []*Locale{{Code: "en", Default: true}}, "", diffTestTypes(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err = diffPGSchema(state, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].String() != "change connection table game__tags" || !strings.HasPrefix(steps[0].SQL, `DELETE FROM "game__tags" WHERE "_content_type" <> 'tag';`) {
+		t.Errorf("unexpected step %s:\n%s", steps[0].String(), steps[0].SQL)
+	}
+	if steps[len(steps)-1].String() != "regenerate functions game" {
+		t.Errorf("unexpected functions step %s", steps[len(steps)-1].String())
+	}
+}
@@ -66,11 +66,11 @@ INSERT INTO {{ $.SchemaName }}.{{ $.ModelsTableName }} (
 	{{ .Revision }},
 	{{ .Version }},
 	{{ .PublishedVersion }},
-	to_timestamp('{{ .CreatedAt }}', 'YYYY-MM-DDThh24:mi:ss.mssZ'),
+	'{{ .CreatedAt }}'::timestamptz,
 	'system',
-	to_timestamp('{{ .UpdatedAt }}', 'YYYY-MM-DDThh24:mi:ss.mssZ'),
+	'{{ .UpdatedAt }}'::timestamptz,
 	'system',
-	{{ if .PublishedAt }}to_timestamp('{{ .PublishedAt }}','YYYY-MM-DDThh24:mi:ss.mssZ'){{ else }}NULL{{ end }},
+	{{ if .PublishedAt }}'{{ .PublishedAt }}'::timestamptz{{ else }}NULL{{ end }},
 	'{{ .PublishedBy }}'
 )
 ON CONFLICT (id) DO UPDATE
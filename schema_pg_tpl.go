@@ -8,6 +8,18 @@ CREATE SCHEMA IF NOT EXISTS {{ $.SchemaName }};
 CREATE EXTENSION IF NOT EXISTS unaccent WITH SCHEMA public;
 --
 {{- end }}
+{{- if $.PostGIS }}
+CREATE EXTENSION IF NOT EXISTS postgis WITH SCHEMA public;
+--
+{{- end }}
+{{- if $.Trigram }}
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+--
+{{- end }}
+{{- if and $.Search (not $.SchemaName) }}
+CREATE EXTENSION IF NOT EXISTS unaccent WITH SCHEMA public;
+--
+{{- end }}
 CREATE TABLE IF NOT EXISTS _asset (
 	_id text primary key,
 	_sys_id text not null,
@@ -16,14 +28,36 @@ CREATE TABLE IF NOT EXISTS _asset (
 	file_name text,
 	content_type text,
 	url text,
+	size bigint,
+	width integer,
+	height integer,
+	upload text,
+	details jsonb,
+	focal_point jsonb,
+	{{- if $.SpaceColumns }}
+	_space text not null,
+	_environment text not null,
+	{{- end }}
 	_locale text not null,
 	_version integer not null default 0,
-	_created_at timestamp without time zone default now(),
+	_created_at timestamptz default now(),
 	_created_by text not null,
-	_updated_at timestamp without time zone default now(),
+	_updated_at timestamptz default now(),
 	_updated_by text not null
 );
+{{- if $.SpaceColumns }}
+CREATE UNIQUE INDEX IF NOT EXISTS _asset__space__sys_id__locale ON _asset (_space, _environment, _sys_id, _locale);
+{{- else }}
 CREATE UNIQUE INDEX IF NOT EXISTS _asset__sys_id__locale ON _asset (_sys_id, _locale);
+{{- end }}
+-- file metadata columns of the schemas created before
+ALTER TABLE _asset
+	ADD COLUMN IF NOT EXISTS size bigint,
+	ADD COLUMN IF NOT EXISTS width integer,
+	ADD COLUMN IF NOT EXISTS height integer,
+	ADD COLUMN IF NOT EXISTS upload text,
+	ADD COLUMN IF NOT EXISTS details jsonb,
+	ADD COLUMN IF NOT EXISTS focal_point jsonb;
 --
 CREATE TABLE IF NOT EXISTS _schema (
 	table_name text primary key,
@@ -33,9 +67,9 @@ CREATE TABLE IF NOT EXISTS _schema (
 	displayField text not null,
 	fields jsonb not null default '[]'::jsonb,
 	_version integer not null default 0,
-	_created_at timestamp without time zone default now(),
+	_created_at timestamptz default now(),
 	_created_by text not null,
-	_updated_at timestamp without time zone default now(),
+	_updated_at timestamptz default now(),
 	_updated_by text not null
 );
 CREATE UNIQUE INDEX IF NOT EXISTS _schema_model ON _schema (model);
@@ -44,31 +78,43 @@ CREATE UNIQUE INDEX IF NOT EXISTS _schema_model ON _schema (model);
 {{ range $tblidx, $tbl := $.Tables }}
 --
 {{- if $.DropTables }}
-DROP TABLE IF EXISTS {{ $tbl.TableName }} CASCADE;
+DROP TABLE IF EXISTS "{{ $tbl.TableName }}" CASCADE;
 {{ end -}}
 --
-CREATE TABLE IF NOT EXISTS {{ $tbl.TableName }} (
+CREATE TABLE IF NOT EXISTS "{{ $tbl.TableName }}" (
 	_id text primary key,
 	_sys_id text not null,
 	{{- range $colidx, $col := $tbl.Columns }}
 	"{{ .ColumnName }}" {{ .ColumnType }},
 	{{- end }}
+	{{- if $.SpaceColumns }}
+	_space text not null,
+	_environment text not null,
+	{{- end }}
 	_locale text not null,
 	_version integer not null default 0,
-	_created_at timestamp without time zone not null default now(),
+	_created_at timestamptz not null default now(),
 	_created_by text not null,
-	_updated_at timestamp without time zone not null default now(),
+	_updated_at timestamptz not null default now(),
 	_updated_by text not null
 );
 --
-CREATE UNIQUE INDEX IF NOT EXISTS idx_{{ $tbl.TableName }}__sys_id_locale ON {{ $tbl.TableName }}(_sys_id,_locale);
-CREATE INDEX IF NOT EXISTS idx_{{ $tbl.TableName }}__sys_id ON {{ $tbl.TableName }}(_sys_id);
-CREATE INDEX IF NOT EXISTS idx_{{ $tbl.TableName }}__locale ON {{ $tbl.TableName }}(_locale);
+{{- if $.SpaceColumns }}
+CREATE UNIQUE INDEX IF NOT EXISTS {{ Index $tbl.TableName "_space_sys_id_locale" | Ident }} ON "{{ $tbl.TableName }}"(_space,_environment,_sys_id,_locale);
+CREATE INDEX IF NOT EXISTS {{ Index $tbl.TableName "_space" | Ident }} ON "{{ $tbl.TableName }}"(_space,_environment);
+{{- else }}
+CREATE UNIQUE INDEX IF NOT EXISTS {{ Index $tbl.TableName "_sys_id_locale" | Ident }} ON "{{ $tbl.TableName }}"(_sys_id,_locale);
+{{- end }}
+CREATE INDEX IF NOT EXISTS {{ Index $tbl.TableName "_sys_id" | Ident }} ON "{{ $tbl.TableName }}"(_sys_id);
+CREATE INDEX IF NOT EXISTS {{ Index $tbl.TableName "_locale" | Ident }} ON "{{ $tbl.TableName }}"(_locale);
 {{- range $tbl.Columns -}}
 {{- if .IsIndex }}
-CREATE INDEX IF NOT EXISTS idx_{{ $tbl.TableName }}_{{ .ColumnName }} ON {{ $tbl.TableName }}({{ .ColumnName }},_locale);
+CREATE INDEX IF NOT EXISTS {{ Index $tbl.TableName .ColumnName | Ident }} ON "{{ $tbl.TableName }}"("{{ .ColumnName }}",_locale);
 {{ end -}}
 {{- end }}
+{{- range $tbl.Indexes }}
+CREATE {{ if .Unique }}UNIQUE {{ end }}INDEX IF NOT EXISTS {{ Ident .Name }} ON "{{ $tbl.TableName }}" USING {{ if eq .Method "btree" }}btree{{ else }}gin{{ end }} ({{ if and .Unique $.SpaceColumns }}_space,_environment,{{ end }}{{ .Columns }}){{ if .Where }} WHERE {{ .Where }}{{ end }};
+{{- end }}
 --
 INSERT INTO _schema (
 	table_name,
@@ -90,9 +136,9 @@ INSERT INTO _schema (
 	'{{ $tbl.Schema.DisplayField }}',
 	'{{ $tbl.Schema.Fields | marshal }}'::jsonb,
 	{{ $tbl.Schema.Version }},
-	to_timestamp('{{ $tbl.Schema.CreatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ $tbl.Schema.CreatedAt }}'::timestamptz,
 	'sync',
-	to_timestamp('{{ $tbl.Schema.UpdatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ $tbl.Schema.UpdatedAt }}'::timestamptz,
 	'sync'
 )
 ON CONFLICT (table_name) DO UPDATE
@@ -881,9 +881,9 @@ INSERT INTO {{ $.SchemaName }}._models (
 	'{{ $tbl.Data.Description }}',
 	'{{ $tbl.Data.DisplayField }}',
 	{{ $tbl.Data.Version }},
-	to_timestamp('{{ $tbl.Data.CreatedAt }}', 'YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ $tbl.Data.CreatedAt }}'::timestamptz,
 	'system',
-	to_timestamp('{{ $tbl.Data.UpdatedAt }}', 'YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ $tbl.Data.UpdatedAt }}'::timestamptz,
 	'system'
 )
 ON CONFLICT (name) DO UPDATE
@@ -0,0 +1,209 @@
+package gontentful
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// pgSearchColumn is the weighted tsvector of the searchable fields in the materialized views
+	pgSearchColumn = "_search"
+	// pgRelevanceOrder orders the entries by the rank of the full-text search
+	pgRelevanceOrder = "relevance"
+	// pgDefaultTextSearch is the text search configuration of the languages without stemming
+	pgDefaultTextSearch = "simple"
+)
+
+// pgTextSearchConfigs maps the languages of the locales to the text search
+// configurations of postgres
+var pgTextSearchConfigs = map[string]string{
+	"ar": "arabic",
+	"ca": "catalan",
+	"da": "danish",
+	"de": "german",
+	"el": "greek",
+	"en": "english",
+	"es": "spanish",
+	"eu": "basque",
+	"fi": "finnish",
+	"fr": "french",
+	"ga": "irish",
+	"hi": "hindi",
+	"hu": "hungarian",
+	"hy": "armenian",
+	"id": "indonesian",
+	"it": "italian",
+	"lt": "lithuanian",
+	"nb": "norwegian",
+	"ne": "nepali",
+	"nl": "dutch",
+	"nn": "norwegian",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sr": "serbian",
+	"sv": "swedish",
+	"ta": "tamil",
+	"tr": "turkish",
+	"yi": "yiddish",
+}
+
+// PGSQLSearchColumn is a searchable column of the materialized views
+type PGSQLSearchColumn struct {
+	ColumnName string
+	Weight     string
+	// Kind is text, array or json
+	Kind string
+}
+
+// pgTextSearchConfig returns the text search configuration of the locale, the
+// configured ones by locale code or language first
+func pgTextSearchConfig(locale string, configs map[string]string) string {
+	locale = strings.ToLower(locale)
+	lang, _, _ := strings.Cut(locale, "-")
+	for code, config := range configs {
+		if strings.ToLower(code) == locale {
+			return config
+		}
+	}
+	for code, config := range configs {
+		if strings.ToLower(code) == lang {
+			return config
+		}
+	}
+	if config, ok := pgTextSearchConfigs[lang]; ok {
+		return config
+	}
+	return pgDefaultTextSearch
+}
+
+// textSearchConfig returns the text search configuration of the locale
+func (c *PGSchemaConfig) textSearchConfig(locale string) string {
+	if c == nil {
+		return pgTextSearchConfig(locale, nil)
+	}
+	return pgTextSearchConfig(locale, c.TextSearch)
+}
+
+// searchable tells whether the content type has searchable fields
+func (c *PGContentTypeConfig) searchable() bool {
+	if c == nil {
+		return false
+	}
+	for id := range c.Fields {
+		if c.searchField(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchField tells whether the field is in the tsvector of the materialized views
+func (c *PGContentTypeConfig) searchField(id string) bool {
+	f := c.field(id)
+	return f != nil && f.Search != "" && (f.Exclude == nil || !*f.Exclude)
+}
+
+// pgSearchKind returns how the field is searched, empty if it can't be
+func pgSearchKind(f *ContentTypeField) string {
+	switch f.Type {
+	case "Symbol", "Text":
+		return "text"
+	case "Array":
+		if f.Items != nil && f.Items.Type == "Symbol" {
+			return "array"
+		}
+	case "Object", "RichText":
+		return "json"
+	}
+	return ""
+}
+
+// newPGSQLSearchColumns returns the searchable columns of the content type
+func newPGSQLSearchColumns(item *ContentType) []*PGSQLSearchColumn {
+	cols := make([]*PGSQLSearchColumn, 0)
+	for _, f := range item.Fields {
+		if f.Omitted || f.pg == nil || f.pg.Search == "" {
+			continue
+		}
+		cols = append(cols, &PGSQLSearchColumn{
+			ColumnName: fieldColumnName(f),
+			Weight:     strings.ToUpper(f.pg.Search),
+			Kind:       pgSearchKind(f),
+		})
+	}
+	return cols
+}
+
+// pgUnaccent removes the accents of the text expression, the dictionary is qualified
+// not to depend on the search path
+func pgUnaccent(expr string) string {
+	return fmt.Sprintf("public.unaccent('public.unaccent'::regdictionary, %s)", expr)
+}
+
+// pgSearchVector returns the weighted tsvector of the searchable columns
+func pgSearchVector(cols []*PGSQLSearchColumn, config string) string {
+	vectors := make([]string, 0)
+	for _, col := range cols {
+		ident := pgQuoteIdent(col.ColumnName)
+		var vector string
+		switch col.Kind {
+		case "array":
+			vector = fmt.Sprintf("to_tsvector('%s', %s)", config, pgUnaccent(fmt.Sprintf("coalesce(array_to_string(%s, ' '), '')", ident)))
+		case "json":
+			vector = fmt.Sprintf("jsonb_to_tsvector('%s', coalesce(%s, '{}'::jsonb), '[\"string\"]')", config, ident)
+		default:
+			vector = fmt.Sprintf("to_tsvector('%s', %s)", config, pgUnaccent(fmt.Sprintf("coalesce(%s, '')", ident)))
+		}
+		vectors = append(vectors, fmt.Sprintf("setweight(%s, '%s')", vector, col.Weight))
+	}
+	return strings.Join(vectors, " || ")
+}
+
+// pgSearchQuery returns the tsquery of the web search syntax text ("quoted phrases",
+// or, -excluded words), the text is a bound parameter of the query
+func pgSearchQuery(config string, text string) string {
+	return fmt.Sprintf("websearch_to_tsquery('%s', %s)", config, pgUnaccent(text))
+}
+
+// pgSearchFilter returns the full-text filter of the materialized view, the column is
+// matched too when the search is limited to a field
+func pgSearchFilter(tableName string, config string, text string, column string) string {
+	query := pgSearchQuery(config, text)
+	filter := fmt.Sprintf("%s.%s @@ %s", pgQuoteIdent(tableName), pgSearchColumn, query)
+	if column != "" {
+		filter += fmt.Sprintf(" AND to_tsvector('%s', %s) @@ %s", config, pgUnaccent(column+"::text"), query)
+	}
+	return filter
+}
+
+// pgSearchRank returns the relevance of the entries for the search
+func pgSearchRank(tableName string, config string, text string) string {
+	return fmt.Sprintf("ts_rank_cd(%s.%s, %s)", pgQuoteIdent(tableName), pgSearchColumn, pgSearchQuery(config, text))
+}
+
+// pgSearchText returns the text the entries are ranked by: the query parameter or the
+// first match filter of a searchable field
+func pgSearchText(filters url.Values, ctc *PGContentTypeConfig) string {
+	if !ctc.searchable() {
+		return ""
+	}
+	if text := filters.Get("query"); text != "" {
+		return text
+	}
+	keys := make([]string, 0)
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := strings.TrimSuffix(key, "[match]")
+		if f != key && ctc.searchField(strings.TrimPrefix(f, "fields.")) {
+			return strings.Join(filters[key], ",")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package gontentful
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const searchConfigTest = `
+textSearch:
+  en-GB: english
+  fi: simple
+contentTypes:
+  game:
+    fields:
+      name: { search: A }
+      tags: { search: b }
+      description: { search: C }
+`
+
+func TestPGTextSearchConfig(t *testing.T) {
+	configs := map[string]string{"en-GB": "english", "fi": "simple"}
+	for locale, want := range map[string]string{
+		"de":    "german",
+		"sv-SE": "swedish",
+		"en-gb": "english",
+		"fi-FI": "simple",
+		"nb-NO": "norwegian",
+		"ja":    "simple",
+	} {
+		if got := pgTextSearchConfig(locale, configs); got != want {
+			t.Errorf("%s: got %s, want %s", locale, got, want)
+		}
+	}
+}
+
+func TestPGSearchSchema(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(searchConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParsePGSchemaConfig([]byte(`contentTypes: { game: { fields: { name: { search: E } } } }`))
+	if err == nil {
+		t.Error("invalid search weight accepted")
+	}
+	_, err = NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", schemaConfigTestTypes(), 0, &PGSchemaConfig{
+		ContentTypes: map[string]*PGContentTypeConfig{"game": {Fields: map[string]*PGFieldConfig{"releaseDate": {Search: "A"}}}},
+	})
+	if err == nil {
+		t.Error("search of a date field accepted")
+	}
+
+	locales := []*Locale{{Code: "en", Default: true}, {Code: "de"}}
+	schema, err := NewPGSQLSchema("", locales, "", schemaConfigTestTypes(), 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tables, "CREATE EXTENSION IF NOT EXISTS unaccent WITH SCHEMA public;") {
+		t.Errorf("missing unaccent extension:\n%s", tables)
+	}
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`setweight(to_tsvector('german', public.unaccent('public.unaccent'::regdictionary, coalesce("name", ''))), 'A')`,
+		`setweight(to_tsvector('english', public.unaccent('public.unaccent'::regdictionary, coalesce(array_to_string("tags", ' '), ''))), 'B') AS _search FROM "game_view"('en', ARRAY[]::text[])`,
+		`setweight(to_tsvector('english', public.unaccent('public.unaccent'::regdictionary, coalesce("description", ''))), 'C')`,
+		`CREATE INDEX IF NOT EXISTS "mv_game_de_search_idx" ON "mv_game_de" USING gin (_search);`,
+	} {
+		if !strings.Contains(funcs, want) {
+			t.Errorf("missing %q in:\n%s", want, funcs)
+		}
+	}
+}
+
+func TestPGSearchQuery(t *testing.T) {
+	cfg, err := ParsePGSchemaConfig([]byte(searchConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := pgQueryTest(url.Values{
+		"locale": []string{"de"},
+		"query":  []string{"it's \"dead or alive\""},
+		"order":  []string{"relevance,sys.id"},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Filters[0] != `"game"._search @@ websearch_to_tsquery('german', public.unaccent('public.unaccent'::regdictionary, $1[1]))` {
+		t.Errorf("unexpected filter %s", q.Filters[0])
+	}
+	if q.Order != `ts_rank_cd("game"._search, websearch_to_tsquery('german', public.unaccent('public.unaccent'::regdictionary, $1[2]))) DESC NULLS LAST,"game"._sys_id NULLS LAST` {
+		t.Errorf("unexpected order %s", q.Order)
+	}
+	if q.Args[0] != `it's "dead or alive"` || q.Args[1] != q.Args[0] {
+		t.Errorf("unexpected args %q", q.Args)
+	}
+
+	q, err = pgQueryTest(url.Values{
+		"fields.name[match]": []string{"dead"},
+		"fields.slug[match]": []string{"dead"},
+		"order":              []string{"-relevance"},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := `websearch_to_tsquery('english', public.unaccent('public.unaccent'::regdictionary, $1[1]))`
+	if q.Filters[0] != `"game"._search @@ `+query+` AND to_tsvector('english', public.unaccent('public.unaccent'::regdictionary, "game"."name"::text)) @@ `+query ||
+		q.Filters[1] != `"game"."slug" ILIKE '%' || $1[2] || '%'` {
+		t.Errorf("unexpected filters %v", q.Filters)
+	}
+	if q.Order != `ts_rank_cd("game"._search, websearch_to_tsquery('english', public.unaccent('public.unaccent'::regdictionary, $1[3]))) NULLS LAST` {
+		t.Errorf("unexpected order %s", q.Order)
+	}
+
+	tag, err := ParsePGQuery("", "en", url.Values{
+		"content_type": []string{"tag"},
+		"query":        []string{"dead"},
+	}, cfg)
+	if err == nil {
+		_, err = tag.compile(content.Fields{{ID: "name", Type: "text"}})
+	}
+	if err == nil {
+		t.Error("search of a content type without searchable fields accepted")
+	}
+	tag, err = ParsePGQuery("", "en", url.Values{
+		"content_type": []string{"tag"},
+		"order":        []string{"relevance"},
+	}, cfg)
+	if err != nil || len(tag.orders) != 0 {
+		t.Errorf("relevance order without a search %v %v", err, tag.orders)
+	}
+}
@@ -0,0 +1,199 @@
+package gontentful
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultSpacesConcurrency = 1
+	defaultEnvironment       = "master"
+)
+
+// SpacesConfig describes several spaces/environments synced into one database.
+// With SharedSchema set all spaces are written into the same tables, qualified
+// by the _space and _environment columns, otherwise each space uses its own schema.
+type SpacesConfig struct {
+	SharedSchema string         `json:"sharedSchema" yaml:"sharedSchema"`
+	Concurrency  int            `json:"concurrency" yaml:"concurrency"`
+	Spaces       []*SpaceConfig `json:"spaces" yaml:"spaces"`
+	// Tables customizes the tables of the content types, see PGSchemaConfig
+	Tables *PGSchemaConfig `json:"tables" yaml:"tables"`
+}
+
+type SpaceConfig struct {
+	Name          string `json:"name" yaml:"name"`
+	SpaceID       string `json:"space" yaml:"space"`
+	EnvironmentID string `json:"environment" yaml:"environment"`
+	CdnToken      string `json:"token" yaml:"token"`
+	CmaToken      string `json:"cma" yaml:"cma"`
+	CdnURL        string `json:"cdnUrl" yaml:"cdnUrl"`
+	CmaURL        string `json:"cmaUrl" yaml:"cmaUrl"`
+	SchemaName    string `json:"schema" yaml:"schema"`
+}
+
+// LoadSpacesConfig reads a yaml (or json) spaces config, environment variables
+// like ${CF_TOKEN} are expanded before parsing.
+func LoadSpacesConfig(path string) (*SpacesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spaces config %s: %s", path, err.Error())
+	}
+	return ParseSpacesConfig([]byte(os.ExpandEnv(string(data))))
+}
+
+func ParseSpacesConfig(data []byte) (*SpacesConfig, error) {
+	cfg := &SpacesConfig{}
+	err := yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spaces config: %s", err.Error())
+	}
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *SpacesConfig) validate() error {
+	if len(c.Spaces) == 0 {
+		return fmt.Errorf("spaces config has no spaces")
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultSpacesConcurrency
+	}
+	if c.Tables != nil {
+		err := c.Tables.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]bool)
+	schemas := make(map[string]bool)
+	for _, s := range c.Spaces {
+		if s.SpaceID == "" {
+			return fmt.Errorf("space id is missing in spaces config")
+		}
+		if s.EnvironmentID == "" {
+			s.EnvironmentID = defaultEnvironment
+		}
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("%s_%s", s.SpaceID, s.EnvironmentID)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicated space name in spaces config: %s", s.Name)
+		}
+		names[s.Name] = true
+
+		if c.Shared() {
+			continue
+		}
+		if s.SchemaName == "" {
+			return fmt.Errorf("schema is missing for space %s", s.Name)
+		}
+		if schemas[s.SchemaName] {
+			return fmt.Errorf("schema %s is used by more than one space, set sharedSchema to sync them into shared tables", s.SchemaName)
+		}
+		schemas[s.SchemaName] = true
+	}
+	return nil
+}
+
+func (c *SpacesConfig) Shared() bool {
+	return c.SharedSchema != ""
+}
+
+// GetSpace returns the space by name or by space id.
+func (c *SpacesConfig) GetSpace(name string) *SpaceConfig {
+	for _, s := range c.Spaces {
+		if s.Name == name {
+			return s
+		}
+	}
+	for _, s := range c.Spaces {
+		if s.SpaceID == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *SpacesConfig) GetSchemaName(s *SpaceConfig) string {
+	if c.Shared() {
+		return c.SharedSchema
+	}
+	return s.SchemaName
+}
+
+func (s *SpaceConfig) ClientOptions(cdnURL string, cmaURL string) *ClientOptions {
+	opts := &ClientOptions{
+		SpaceID:       s.SpaceID,
+		EnvironmentID: s.EnvironmentID,
+		CdnToken:      s.CdnToken,
+		CdnURL:        s.CdnURL,
+		CmaToken:      s.CmaToken,
+		CmaURL:        s.CmaURL,
+	}
+	if opts.CdnURL == "" {
+		opts.CdnURL = cdnURL
+	}
+	if opts.CmaURL == "" {
+		opts.CmaURL = cmaURL
+	}
+	return opts
+}
+
+// MergeContentTypes unions the content types of several spaces, fields missing
+// from the first definition of a content type are appended from the later ones.
+func MergeContentTypes(sets ...[]*ContentType) []*ContentType {
+	res := make([]*ContentType, 0)
+	byID := make(map[string]*ContentType)
+	for _, items := range sets {
+		for _, item := range items {
+			ct := byID[item.Sys.ID]
+			if ct == nil {
+				ct = &ContentType{
+					Sys:          item.Sys,
+					Name:         item.Name,
+					Description:  item.Description,
+					DisplayField: item.DisplayField,
+					Fields:       append(make([]*ContentTypeField, 0), item.Fields...),
+				}
+				byID[item.Sys.ID] = ct
+				res = append(res, ct)
+				continue
+			}
+			for _, f := range item.Fields {
+				if !hasField(ct.Fields, f.ID) {
+					ct.Fields = append(ct.Fields, f)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// MergeLocales unions the locales of several spaces, the first default locale wins.
+func MergeLocales(sets ...[]*Locale) []*Locale {
+	res := make([]*Locale, 0)
+	codes := make(map[string]bool)
+	hasDefault := false
+	for _, locales := range sets {
+		for _, l := range locales {
+			if codes[l.Code] {
+				continue
+			}
+			codes[l.Code] = true
+			loc := *l
+			if loc.Default && hasDefault {
+				loc.Default = false
+			}
+			hasDefault = hasDefault || loc.Default
+			res = append(res, &loc)
+		}
+	}
+	return res
+}
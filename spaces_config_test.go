@@ -0,0 +1,175 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const spacesConfigTest = `
+sharedSchema: content
+spaces:
+  - space: s1
+  - name: brand-b
+    space: s2
+    environment: staging
+`
+
+func TestParseSpacesConfig(t *testing.T) {
+	cfg, err := ParseSpacesConfig([]byte(spacesConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Concurrency != 1 || !cfg.Shared() {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	s1 := cfg.GetSpace("s1")
+	if s1 == nil || s1.Name != "s1_master" || s1.EnvironmentID != "master" || cfg.GetSchemaName(s1) != "content" {
+		t.Errorf("unexpected space %+v", s1)
+	}
+	if cfg.GetSpace("brand-b").SpaceID != "s2" || cfg.GetSpace("unknown") != nil {
+		t.Error("unexpected space lookup")
+	}
+
+	for _, invalid := range []string{
+		`spaces: []`,
+		`spaces: [{ name: a }]`,
+		`spaces: [{ space: s1, schema: a }, { space: s1, schema: b }]`,
+		`spaces: [{ space: s1 }]`,
+		`spaces: [{ space: s1, schema: a }, { space: s2, schema: a }]`,
+	} {
+		if _, err = ParseSpacesConfig([]byte(invalid)); err == nil {
+			t.Errorf("invalid config accepted: %s", invalid)
+		}
+	}
+}
+
+func TestMergeSpaces(t *testing.T) {
+	types := MergeContentTypes(
+		[]*ContentType{{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{{ID: "name"}}}},
+		[]*ContentType{{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{{ID: "name"}, {ID: "slug"}}}, {Sys: &Sys{ID: "studio"}}},
+	)
+	if len(types) != 2 || len(types[0].Fields) != 2 || types[0].Fields[1].ID != "slug" {
+		t.Errorf("unexpected content types %+v", types)
+	}
+
+	locales := MergeLocales(
+		[]*Locale{{Code: "en", Default: true}},
+		[]*Locale{{Code: "de", Default: true}, {Code: "en"}},
+	)
+	if len(locales) != 2 || !locales[0].Default || locales[1].Default {
+		t.Errorf("unexpected locales %+v", locales)
+	}
+}
+
+func TestPGSpacesSchemas(t *testing.T) {
+	cfg, err := ParseSpacesConfig([]byte(spacesConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := [][]*Locale{{{Code: "en", Default: true}}, {{Code: "de", Default: true}}}
+	types := [][]*ContentType{schemaConfigTestTypes(), schemaConfigTestTypes()}
+	schemas, err := NewPGSpacesSchemas(cfg, locales, types, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != 1 || schemas[0].SchemaName != "content" || !schemas[0].SpaceColumns {
+		t.Fatalf("unexpected schemas %+v", schemas)
+	}
+	tables, err := schemas[0].Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tables, "_space text not null") || !strings.Contains(tables, `ON "game"(_space,_environment,_sys_id,_locale)`) {
+		t.Errorf("missing space columns:\n%s", tables)
+	}
+}
+
+func TestPGSpaceSyncSchema(t *testing.T) {
+	types := []*ContentType{{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{
+		{ID: "name", Type: "Symbol", Localized: true},
+		{ID: "related", Type: "Array", Items: &FieldTypeArrayItem{Type: "Link", LinkType: "Entry", Validations: []*FieldValidation{{LinkContentType: []string{"game"}}}}},
+	}}}
+	item := &Entry{}
+	err := json.Unmarshal([]byte(`{"sys": {"id": "g1", "type": "Entry", "contentType": {"sys": {"id": "game"}}}, "fields": {
+		"name": {"en": "Game"},
+		"related": {"en": [{"sys": {"type": "Link", "linkType": "Entry", "id": "g2"}}]}
+	}}`), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := NewPGSpaceSyncSchema("content", "s1", "master", []*Locale{{Code: "en", Default: true}}, types, []*Entry{item}, true, nil)
+	tbl := schema.Tables["game"]
+	if !strings.Contains(strings.Join(tbl.Columns, ","), "_space,_environment") {
+		t.Errorf("missing space columns %v", tbl.Columns)
+	}
+	row := tbl.Rows[0]
+	values := row.Fields()
+	if row.ID != "s1_master_g1_en" || values[0] != "s1_master_g1_en" || values[1] != "g1" || row.Space != "s1" || row.Environment != "master" {
+		t.Errorf("unexpected row %s %v", row.ID, values)
+	}
+	con := schema.ConTables["game__related"]
+	if con == nil || len(con.Rows) != 1 || !reflect.DeepEqual(con.Rows[0][:4], []interface{}{"s1_master_g1_en", "g1", "s1_master_g2_en", "g2"}) {
+		t.Errorf("unexpected connection rows %+v", con)
+	}
+
+	// the single space rows keep the <sysid>_<locale> ids
+	schema = NewPGSyncSchema("public", []*Locale{{Code: "en", Default: true}}, types, []*Entry{item}, true, nil)
+	if id := schema.Tables["game"].Rows[0].ID; id != "g1_en" {
+		t.Errorf("unexpected id %s", id)
+	}
+}
+
+func TestPGSpacesQuery(t *testing.T) {
+	shared, err := ParseSpacesConfig([]byte(spacesConfigTest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := ParsePGSpacesQuery(shared, "en", url.Values{"content_type": []string{"game"}, "space": []string{"brand-b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Queries) != 1 || q.Queries[0].SchemaName != "content" {
+		t.Fatalf("unexpected queries %+v", q.Queries)
+	}
+	c, err := q.Queries[0].compile(pgQueryTestModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Args, []string{"s2:staging"}) {
+		t.Errorf("unexpected space filter %v %q", c.Filters, c.Args)
+	}
+	if _, err = ParsePGSpacesQuery(shared, "en", url.Values{"content_type": []string{"game"}, "space": []string{"unknown"}}); err == nil {
+		t.Error("unknown space accepted")
+	}
+
+	separate, err := ParseSpacesConfig([]byte(`spaces: [{ space: s1, schema: a }, { space: s2, schema: b }]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = ParsePGSpacesQuery(separate, "en", url.Values{"content_type": []string{"game"}, "skip": []string{"10"}, "limit": []string{"5"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every schema returns the rows up to the end of the merged page
+	if len(q.Queries) != 2 || q.Queries[0].SchemaName != "a" || q.Queries[1].SchemaName != "b" || q.Queries[0].Skip != 0 || q.Queries[1].Limit != 15 {
+		t.Errorf("unexpected queries %+v %+v", q.Queries[0], q.Queries[1])
+	}
+
+	items := []map[string]interface{}{
+		{"sys": map[string]interface{}{"id": "a"}, "rtp": 96.5},
+		{"sys": map[string]interface{}{"id": "b"}},
+		{"sys": map[string]interface{}{"id": "c"}, "rtp": 97.0},
+	}
+	sortItems(items, "-fields.rtp")
+	ids := make([]string, 0)
+	for _, item := range items {
+		ids = append(ids, item["sys"].(map[string]interface{})["id"].(string))
+	}
+	if !reflect.DeepEqual(ids, []string{"c", "a", "b"}) {
+		t.Errorf("unexpected merged order %v", ids)
+	}
+}
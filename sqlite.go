@@ -0,0 +1,227 @@
+package gontentful
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLiteDriverName is the database/sql driver of the sqlite mirror. The default is the
+// name registered by the pure Go modernc.org/sqlite driver, the binary has to import it.
+var SQLiteDriverName = "sqlite"
+
+const sqliteTemplate = `
+CREATE TABLE IF NOT EXISTS _asset (
+	_id TEXT PRIMARY KEY,
+	_sys_id TEXT NOT NULL,
+	{{- range $.AssetColumns }}
+	"{{ . }}" TEXT,
+	{{- end }}
+	_locale TEXT NOT NULL,
+	_version INTEGER NOT NULL DEFAULT 0,
+	_created_at TEXT,
+	_created_by TEXT NOT NULL,
+	_updated_at TEXT,
+	_updated_by TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS _asset__sys_id__locale ON _asset (_sys_id, _locale);
+--
+CREATE TABLE IF NOT EXISTS _schema (
+	table_name TEXT PRIMARY KEY,
+	model TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT,
+	display_field TEXT,
+	fields TEXT NOT NULL DEFAULT '[]',
+	_version INTEGER NOT NULL DEFAULT 0
+);
+--
+CREATE TABLE IF NOT EXISTS _sync (
+	id INTEGER PRIMARY KEY,
+	token TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+{{ range $tblidx, $tbl := $.Tables }}
+--
+CREATE TABLE IF NOT EXISTS "{{ $tbl.TableName }}" (
+	_id TEXT PRIMARY KEY,
+	_sys_id TEXT NOT NULL,
+	{{- range $colidx, $col := $tbl.Columns }}
+	"{{ .ColumnName }}" {{ .ColumnType | sqliteType }},
+	{{- end }}
+	_locale TEXT NOT NULL,
+	_version INTEGER NOT NULL DEFAULT 0,
+	_created_at TEXT,
+	_created_by TEXT NOT NULL,
+	_updated_at TEXT,
+	_updated_by TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_{{ $tbl.TableName }}__sys_id_locale" ON "{{ $tbl.TableName }}" (_sys_id, _locale);
+CREATE INDEX IF NOT EXISTS "idx_{{ $tbl.TableName }}__locale" ON "{{ $tbl.TableName }}" (_locale);
+{{- range $tbl.Columns -}}
+{{- if .IsIndex }}
+CREATE INDEX IF NOT EXISTS "idx_{{ $tbl.TableName }}_{{ .ColumnName }}" ON "{{ $tbl.TableName }}" ("{{ .ColumnName }}", _locale);
+{{- end -}}
+{{- end }}
+{{- end }}
+{{ range $tblidx, $tbl := $.ConTables }}
+--
+CREATE TABLE IF NOT EXISTS "{{ $tbl.TableName }}" (
+	{{- range $colidx, $col := $tbl.Columns }}
+	"{{ .ColumnName }}" TEXT{{ if .Required }} NOT NULL{{ end }},
+	{{- end }}
+	PRIMARY KEY ("{{ (index $tbl.Columns 0).ColumnName }}", "{{ (index $tbl.Columns 2).ColumnName }}")
+);
+CREATE INDEX IF NOT EXISTS "idx_{{ $tbl.TableName }}__sys_id" ON "{{ $tbl.TableName }}" ("{{ (index $tbl.Columns 1).ColumnName }}");
+{{- end }}
+`
+
+const (
+	upsertSQLiteSchema = `INSERT INTO _schema (table_name, model, name, description, display_field, fields, _version) VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (table_name) DO UPDATE SET name = excluded.name, description = excluded.description, display_field = excluded.display_field, fields = excluded.fields, _version = excluded._version`
+	selectSQLiteSyncToken = "SELECT token FROM _sync WHERE id = 0"
+	upsertSQLiteSyncToken = "INSERT INTO _sync (id, token) VALUES (0, ?) ON CONFLICT (id) DO UPDATE SET token = excluded.token, created_at = CURRENT_TIMESTAMP"
+)
+
+var sqliteFuncMap = template.FuncMap{
+	"sqliteType": sqliteColumnType,
+}
+
+// SQLiteSchema is the sqlite version of the postgres schema: the same tables and
+// connection tables, with JSON text columns instead of arrays, points and jsonb.
+type SQLiteSchema struct {
+	Tables       []*PGSQLTable
+	ConTables    []*PGSQLTable
+	AssetColumns []string
+}
+
+func NewSQLiteSchema(locales []*Locale, types []*ContentType) (*SQLiteSchema, error) {
+	schema, err := NewPGSQLSchema("", locales, "", types, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteSchema{
+		Tables:       schema.Tables,
+		ConTables:    schema.ConTables,
+		AssetColumns: schema.AssetColumns,
+	}, nil
+}
+
+func (s *SQLiteSchema) Render() (string, error) {
+	tmpl, err := template.New("").Funcs(sqliteFuncMap).Parse(sqliteTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buff bytes.Buffer
+	err = tmpl.Execute(&buff, s)
+	if err != nil {
+		return "", err
+	}
+
+	return buff.String(), nil
+}
+
+func (s *SQLiteSchema) Exec(path string) error {
+	str, err := s.Render()
+	if err != nil {
+		return err
+	}
+
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	txn, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	for _, stmt := range strings.Split(str, "--") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		_, err = txn.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tbl := range s.Tables {
+		fields, err := json.Marshal(tbl.Schema.Fields)
+		if err != nil {
+			return err
+		}
+		_, err = txn.Exec(upsertSQLiteSchema, tbl.TableName, tbl.Schema.ID, tbl.Schema.Name, tbl.Schema.Description, tbl.Schema.DisplayField, string(fields), tbl.Schema.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+func sqliteColumnType(columnType string) string {
+	if strings.HasSuffix(columnType, "ARRAY") {
+		// arrays are stored as json
+		return "TEXT"
+	}
+	switch columnType {
+	case "integer":
+		return "INTEGER"
+	case "numeric":
+		return "REAL"
+	case "boolean":
+		return "INTEGER"
+	}
+	// text, timestamptz, point and jsonb
+	return "TEXT"
+}
+
+func openSQLite(path string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(SQLiteDriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %s", path, err.Error())
+	}
+	// sqlite has a single writer
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec("PRAGMA journal_mode=WAL")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func GetSQLiteSyncToken(path string) (string, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var syncToken string
+	err = db.Get(&syncToken, selectSQLiteSyncToken)
+	if err != nil {
+		return "", err
+	}
+	return syncToken, nil
+}
+
+func SaveSQLiteSyncToken(path string, token string) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(upsertSQLiteSyncToken, token)
+	return err
+}
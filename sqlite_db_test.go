@@ -0,0 +1,146 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	// pure Go sqlite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteTestEntries = `[
+	{ "sys": { "id": "a1", "type": "Entry", "version": 2, "contentType": { "sys": { "id": "article" } } }, "fields": {
+		"title": { "en": "First", "de": "Erste" },
+		"code": { "en": "123" },
+		"rating": { "en": 4.5 },
+		"players": { "en": 2 },
+		"tags": { "en": ["a", "b"] },
+		"related": { "en": [{ "sys": { "type": "Link", "linkType": "Entry", "id": "a2" } }] }
+	} },
+	{ "sys": { "id": "a2", "type": "Entry", "version": 1, "contentType": { "sys": { "id": "article" } } }, "fields": {
+		"title": { "en": "Second" },
+		"code": { "en": "0123" },
+		"rating": { "en": 3 },
+		"players": { "en": 10 },
+		"tags": { "en": ["c"] }
+	} }
+]`
+
+func TestSQLiteQueryExec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "content.db")
+	locales, types := sqliteTestSchema(t)
+	schema, err := NewSQLiteSchema(locales, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = schema.Exec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make([]*Entry, 0)
+	err = json.Unmarshal([]byte(sqliteTestEntries), &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync, err := NewSQLiteSync(locales, types, entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sync.Exec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(params string) (int64, []map[string]interface{}, error) {
+		qv, err := url.ParseQuery(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := ParseSQLiteQuery("en", qv)
+		if err != nil {
+			return 0, nil, err
+		}
+		total, data, err := q.Exec(path)
+		if err != nil {
+			return 0, nil, err
+		}
+		items := make([]map[string]interface{}, 0)
+		err = json.Unmarshal([]byte(data), &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total, items, nil
+	}
+	ids := func(items []map[string]interface{}) []string {
+		res := make([]string, 0)
+		for _, item := range items {
+			res = append(res, item["sys"].(map[string]interface{})["id"].(string))
+		}
+		return res
+	}
+
+	for params, expected := range map[string][]string{
+		// the numeric text is compared as text
+		"content_type=article&fields.code=123":                   {"a1"},
+		"content_type=article&fields.code[in]=0123,9":            {"a2"},
+		"content_type=article&fields.players=2":                  {"a1"},
+		"content_type=article&fields.players[gt]=2":              {"a2"},
+		"content_type=article&fields.rating[gte]=4":              {"a1"},
+		"content_type=article&fields.tags=c":                     {"a2"},
+		"content_type=article&fields.tags[all]=a,b":              {"a1"},
+		"content_type=article&fields.tags[nin]=a":                {"a2"},
+		"content_type=article&fields.related.sys.id=a2":          {"a1"},
+		"content_type=article&fields.related[exists]=false":      {"a2"},
+		"content_type=article&sys.id=a2":                         {"a2"},
+		"content_type=article&sys.id[in]=a1,a2&order=-sys.id":    {"a2", "a1"},
+		"content_type=article&order=fields.players&limit=1":      {"a1"},
+		"content_type=article&order=-fields.rating&skip=1":       {"a2"},
+		"content_type=article&fields.title[match]=eco&locale=en": {"a2"},
+	} {
+		_, items, err := query(params)
+		if err != nil {
+			t.Errorf("%s: %s", params, err.Error())
+			continue
+		}
+		if got := ids(items); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: got %v, expected %v", params, got, expected)
+		}
+	}
+
+	total, items, err := query("content_type=article&locale=de&order=sys.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || items[0]["title"] != "Erste" || items[1]["code"] != "0123" || items[0]["players"] != float64(2) {
+		t.Errorf("unexpected localized items %d %v", total, items)
+	}
+
+	if _, _, err = query("content_type=article&fields.players=two"); err == nil {
+		t.Error("invalid integer accepted")
+	}
+	if _, _, err = query("content_type=article&fields.related.fields.title=x"); err == nil {
+		t.Error("nested filter accepted")
+	}
+
+	// the DeletedEntry items of the sync have no content type
+	deleted := []*Entry{{Sys: &Sys{ID: "a2", Type: DELETED_ENTRY}}}
+	sync, err = NewSQLiteSync(locales, types, deleted, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sync.Exec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, items, err = query("content_type=article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(items); total != 1 || !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("deleted entry not removed %d %v", total, got)
+	}
+}
@@ -0,0 +1,396 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const selectSQLiteModel = "SELECT fields FROM _schema WHERE table_name = ?"
+
+// SQLiteQuery translates the same query parameters as ParsePGQuery to sqlite SQL.
+// Missing localized rows fall back to the default locale, references are returned
+// as links ({"sys":{"id":...}}) and not resolved.
+type SQLiteQuery struct {
+	TableName     string
+	Locale        string
+	DefaultLocale string
+	// Filters, Args and Order are compiled with the model of the table by Exec
+	Filters []string
+	Args    []interface{}
+	Order   string
+	Skip    int
+	Limit   int
+	filters []*pgFilter
+	orders  []*pgOrder
+}
+
+func ParseSQLiteQuery(defaultLocale string, q url.Values) (*SQLiteQuery, error) {
+	contentType := q.Get("content_type")
+	q.Del("content_type")
+
+	locale := q.Get("locale")
+	q.Del("locale")
+	if locale == "" || locale == "*" {
+		locale = defaultLocale
+	}
+
+	skip, _ := strconv.Atoi(q.Get("skip"))
+	q.Del("skip")
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	q.Del("limit")
+
+	order := q.Get("order")
+	q.Del("order")
+
+	q.Del("include")
+	q.Del("select")
+
+	query := &SQLiteQuery{
+		TableName:     pgTableName(contentType),
+		Locale:        fmtLocale(locale),
+		DefaultLocale: fmtLocale(defaultLocale),
+		Skip:          skip,
+		Limit:         limit,
+	}
+
+	var err error
+	query.orders, err = parsePGOrder(order, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range query.orders {
+		if o.Kind != pgFilterField && o.Kind != pgFilterSys {
+			// the links are not resolved
+			return nil, fmt.Errorf("unsupported order: %s", o.Key)
+		}
+	}
+
+	query.filters, err = createFilters(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range query.filters {
+		if !sqliteFilterSupported(f) {
+			return nil, fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+	}
+
+	return query, nil
+}
+
+// sqliteFilterSupported tells whether the filter can be compiled to sqlite, the links
+// are not resolved so only their ids can be filtered
+func sqliteFilterSupported(f *pgFilter) bool {
+	if f.Comparer == "near" || f.Comparer == "within" {
+		return false
+	}
+	switch f.Kind {
+	case pgFilterField, pgFilterSys:
+		return true
+	case pgFilterReference:
+		return len(f.Path) == 2 && f.Path[0] == "sys" && f.Path[1] == "id"
+	}
+	return false
+}
+
+// compile compiles the filters and the order with the model of the table, the values
+// are bound with the type of their column
+func (s *SQLiteQuery) compile(model map[string]*content.Field) error {
+	fields := make(map[string]*content.Field)
+	for _, f := range model {
+		fields[f.ID] = f
+	}
+
+	s.Filters = make([]string, 0)
+	s.Args = make([]interface{}, 0)
+	for _, f := range s.filters {
+		err := s.addFilter(f, fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	orders := make([]string, 0)
+	for _, o := range s.orders {
+		col := fmt.Sprintf("t.%s", o.Field)
+		if o.Kind == pgFilterField {
+			if fields[o.Field] == nil {
+				return fmt.Errorf("unknown field %s of %s: %s", o.Field, s.TableName, o.Key)
+			}
+			col = fmt.Sprintf(`t."%s"`, pgColumnName(o.Field))
+		}
+		desc := ""
+		if o.Desc {
+			desc = " DESC"
+		}
+		orders = append(orders, fmt.Sprintf("%s%s NULLS LAST", col, desc))
+	}
+	s.Order = strings.Join(orders, ", ")
+	return nil
+}
+
+func (s *SQLiteQuery) addFilter(f *pgFilter, fields map[string]*content.Field) error {
+	// the sys columns and the link ids are text
+	col := fmt.Sprintf("t.%s", f.Field)
+	mf := &content.Field{ID: f.Field, Type: "text"}
+	if f.Kind != pgFilterSys {
+		mf = fields[f.Field]
+		if mf == nil {
+			return fmt.Errorf("unknown field %s of %s: %s", f.Field, s.TableName, f.Key)
+		}
+		if f.Kind == pgFilterReference && !mf.Reference {
+			return fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		col = fmt.Sprintf(`t."%s"`, pgColumnName(f.Field))
+	}
+
+	if f.Comparer == "exists" {
+		if strings.Join(f.Values, ",") == "false" {
+			s.add(fmt.Sprintf("%s IS NULL", col))
+		} else {
+			s.add(fmt.Sprintf("%s IS NOT NULL", col))
+		}
+		return nil
+	}
+	if mf.Reference && f.Kind != pgFilterReference {
+		return fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	if f.Comparer == "match" {
+		if mf.Type != "text" && mf.Type != "longtext" {
+			return fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		s.add(fmt.Sprintf("%s LIKE '%%' || ? || '%%'", col), strings.Join(f.Values, ","))
+		return nil
+	}
+
+	args := make([]interface{}, 0)
+	for _, v := range splitPGValues(f.Values) {
+		arg, err := sqliteArg(v, mf)
+		if err != nil {
+			return fmt.Errorf("invalid value of %s: %s", f.Key, err.Error())
+		}
+		args = append(args, arg)
+	}
+	params := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+
+	if mf.List {
+		// the lists are json arrays
+		inArray := fmt.Sprintf(`(json_valid(%[1]s) AND json_type(%[1]s) = 'array' AND EXISTS (SELECT 1 FROM json_each(%[1]s) WHERE json_each.value IN (%[2]s)))`, col, params)
+		switch f.Comparer {
+		case "", "in":
+			s.add(inArray, args...)
+		case "ne", "nin":
+			s.add(fmt.Sprintf("NOT COALESCE(%s, 0)", inArray), args...)
+		case "all":
+			s.add(fmt.Sprintf(`(json_valid(%[1]s) AND json_type(%[1]s) = 'array' AND (SELECT COUNT(DISTINCT json_each.value) FROM json_each(%[1]s) WHERE json_each.value IN (%[2]s)) = %[3]d)`, col, params, len(args)), args...)
+		default:
+			return fmt.Errorf("unsupported filter: %s", f.Key)
+		}
+		return nil
+	}
+
+	switch f.Comparer {
+	case "":
+		s.add(fmt.Sprintf("%s = ?", col), args[0])
+	case "ne":
+		s.add(fmt.Sprintf("%s IS NOT ?", col), args[0])
+	case "lt", "lte", "gt", "gte":
+		s.add(fmt.Sprintf("%s %s ?", col, pgComparerOps[f.Comparer]), args[0])
+	case "in":
+		s.add(fmt.Sprintf("%s IN (%s)", col, params), args...)
+	case "nin":
+		s.add(fmt.Sprintf("NOT COALESCE(%s IN (%s), 0)", col, params), args...)
+	default:
+		return fmt.Errorf("unsupported filter: %s", f.Key)
+	}
+	return nil
+}
+
+func (s *SQLiteQuery) add(filter string, args ...interface{}) {
+	s.Filters = append(s.Filters, filter)
+	s.Args = append(s.Args, args...)
+}
+
+// sqliteArg returns the value of the filter with the type of the field column
+func sqliteArg(v string, f *content.Field) (interface{}, error) {
+	switch f.Type {
+	case "text", "longtext", "date":
+		return v, nil
+	case "int":
+		return strconv.ParseInt(v, 10, 64)
+	case "float":
+		return strconv.ParseFloat(v, 64)
+	case "bool":
+		return strconv.ParseBool(v)
+	}
+	if f.Reference {
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %s", f.Type)
+}
+
+// Where returns the locale and filter conditions and their arguments.
+func (s *SQLiteQuery) Where() (string, []interface{}) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	if s.Locale == s.DefaultLocale {
+		conds = append(conds, "t._locale = ?")
+		args = append(args, s.Locale)
+	} else {
+		conds = append(conds, fmt.Sprintf(`(t._locale = ? OR (t._locale = ? AND NOT EXISTS (SELECT 1 FROM "%s" l WHERE l._sys_id = t._sys_id AND l._locale = ?)))`, s.TableName))
+		args = append(args, s.Locale, s.DefaultLocale, s.Locale)
+	}
+	conds = append(conds, s.Filters...)
+	args = append(args, s.Args...)
+	return strings.Join(conds, " AND "), args
+}
+
+// Render returns the count and the items statements with their arguments.
+func (s *SQLiteQuery) Render() (string, string, []interface{}) {
+	where, args := s.Where()
+	count := fmt.Sprintf(`SELECT COUNT(*) FROM "%s" t WHERE %s`, s.TableName, where)
+	items := fmt.Sprintf(`SELECT t.* FROM "%s" t WHERE %s`, s.TableName, where)
+	if s.Order != "" {
+		items = items + " ORDER BY " + s.Order
+	}
+	if s.Limit > 0 || s.Skip > 0 {
+		limit := s.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		items = items + fmt.Sprintf(" LIMIT %d OFFSET %d", limit, s.Skip)
+	}
+	return count, items, args
+}
+
+func (s *SQLiteQuery) Exec(path string) (int64, string, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer db.Close()
+
+	fields, err := s.model(db)
+	if err != nil {
+		return 0, "", err
+	}
+	err = s.compile(fields)
+	if err != nil {
+		return 0, "", err
+	}
+
+	countStmt, itemsStmt, args := s.Render()
+
+	var count int64
+	err = db.Get(&count, countStmt, args...)
+	if err != nil {
+		return 0, "", err
+	}
+
+	rows, err := db.Queryx(itemsStmt, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	items := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		row := make(map[string]interface{})
+		err = rows.MapScan(row)
+		if err != nil {
+			return 0, "", err
+		}
+		items = append(items, sqliteItem(row, fields))
+	}
+	if err = rows.Err(); err != nil {
+		return 0, "", err
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return 0, "", err
+	}
+	return count, string(data), nil
+}
+
+func (s *SQLiteQuery) model(db *sqlx.DB) (map[string]*content.Field, error) {
+	res := make(map[string]*content.Field)
+	if s.TableName == ASSET_TABLE_NAME {
+		for _, c := range assetColumns {
+			res[c] = &content.Field{ID: toCamelCase(c), Type: "text"}
+		}
+		return res, nil
+	}
+
+	var fields content.Fields
+	err := db.Get(&fields, selectSQLiteModel, s.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown content type %s: %s", s.TableName, err.Error())
+	}
+	for _, f := range fields {
+		res[pgColumnName(f.ID)] = f
+	}
+	return res, nil
+}
+
+// sqliteItem formats a row like the postgres _query function: sys plus the fields by id.
+func sqliteItem(row map[string]interface{}, fields map[string]*content.Field) map[string]interface{} {
+	item := map[string]interface{}{
+		"sys": map[string]interface{}{"id": sqliteString(row["_sys_id"])},
+	}
+	for col, f := range fields {
+		v := row[col]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		if v == nil {
+			item[f.ID] = nil
+			continue
+		}
+		item[f.ID] = sqliteFieldValue(v, f)
+	}
+	return item
+}
+
+func sqliteFieldValue(v interface{}, f *content.Field) interface{} {
+	s, isString := v.(string)
+	switch {
+	case f.Reference && f.List && isString:
+		ids := make([]string, 0)
+		json.Unmarshal([]byte(s), &ids)
+		links := make([]interface{}, 0)
+		for _, id := range ids {
+			links = append(links, map[string]interface{}{"sys": map[string]interface{}{"id": id}})
+		}
+		return links
+	case f.Reference:
+		return map[string]interface{}{"sys": map[string]interface{}{"id": sqliteString(v)}}
+	case isString && f.Type == "location":
+		if loc, ok := parsePGLocation(s); ok {
+			return loc
+		}
+	case isString && (f.List || f.Type == "json" || strings.HasPrefix(s, "{")):
+		var j interface{}
+		if json.Unmarshal([]byte(s), &j) == nil {
+			return j
+		}
+	case f.Type == "bool":
+		if n, ok := v.(int64); ok {
+			return n != 0
+		}
+	}
+	return v
+}
+
+func sqliteString(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,221 @@
+package gontentful
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	deleteSQLiteRows    = `DELETE FROM "%s" WHERE _sys_id = ?`
+	deleteSQLiteConRows = `DELETE FROM "%s" WHERE "%s" = ?`
+	selectSQLiteTable   = "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?"
+	selectSQLiteSysID   = `SELECT COUNT(*) > 0 FROM "%s" WHERE _sys_id = ?`
+)
+
+// SQLiteSync writes the rows of the postgres sync builders into the sqlite mirror.
+// The deleted entries and assets are removed with their connection rows.
+type SQLiteSync struct {
+	Schema    *PGSyncSchema
+	ConTables map[string][]*PGSQLTable
+}
+
+func NewSQLiteSync(locales []*Locale, types []*ContentType, entries []*Entry, opts *PGSyncOptions) (*SQLiteSync, error) {
+	schema, err := NewPGSQLSchema("", locales, "", types, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	conTables := make(map[string][]*PGSQLTable)
+	for _, con := range schema.ConTables {
+		tableName := con.Columns[0].ColumnName
+		conTables[tableName] = append(conTables[tableName], con)
+	}
+	return &SQLiteSync{
+		// plain (non template) values, they are bound as statement parameters
+		Schema:    NewPGSyncSchema("", locales, types, entries, true, opts),
+		ConTables: conTables,
+	}, nil
+}
+
+func (s *SQLiteSync) Exec(path string) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	txn, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	for _, tableName := range sortedKeys(s.Schema.Tables) {
+		tbl := s.Schema.Tables[tableName]
+		stmt := sqliteUpsert(tbl.TableName, tbl.Columns)
+		for _, row := range tbl.Rows {
+			_, err = txn.Exec(stmt, sqliteValues(row.Fields())...)
+			if err != nil {
+				return fmt.Errorf("failed to upsert %s %s: %s", tbl.TableName, row.ID, err.Error())
+			}
+		}
+	}
+
+	for _, tableName := range sortedKeys(s.Schema.DeletedConTables) {
+		tbl := s.Schema.DeletedConTables[tableName]
+		for _, row := range tbl.Rows {
+			_, err = txn.Exec(fmt.Sprintf(deleteSQLiteConRows, tbl.TableName, tbl.Columns[0]), row[0])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, tableName := range sortedKeys(s.Schema.ConTables) {
+		err = s.replaceConRows(txn, s.Schema.ConTables[tableName])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sys := range s.Schema.DeletedItems {
+		err = s.delete(txn, sys)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+func (s *SQLiteSync) replaceConRows(txn *sqlx.Tx, tbl *PGSyncConTable) error {
+	deleted := make(map[interface{}]bool)
+	stmt := sqliteInsert(tbl.TableName, tbl.Columns)
+	for _, row := range tbl.Rows {
+		if !deleted[row[0]] {
+			_, err := txn.Exec(fmt.Sprintf(deleteSQLiteConRows, tbl.TableName, tbl.Columns[0]), row[0])
+			if err != nil {
+				return err
+			}
+			deleted[row[0]] = true
+		}
+		_, err := txn.Exec(stmt, sqliteValues(row)...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteSync) delete(txn *sqlx.Tx, sys *Sys) error {
+	tableName := ASSET_TABLE_NAME
+	if sys.Type == DELETED_ENTRY {
+		if sys.ContentType == nil || sys.ContentType.Sys == nil {
+			// the DeletedEntry items of the sync have no content type
+			found, err := s.deletedEntryTable(txn, sys.ID)
+			if err != nil || found == "" {
+				return err
+			}
+			tableName = found
+		} else {
+			tableName = pgTableName(sys.ContentType.Sys.ID)
+		}
+	}
+
+	exists, err := sqliteTableExists(txn, tableName)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = txn.Exec(fmt.Sprintf(deleteSQLiteRows, tableName), sys.ID)
+	if err != nil {
+		return err
+	}
+
+	// no foreign keys, the connection rows are deleted with the entry
+	for _, con := range s.ConTables[tableName] {
+		_, err = txn.Exec(fmt.Sprintf(deleteSQLiteConRows, con.TableName, con.Columns[1].ColumnName), sys.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletedEntryTable looks up the content table of the deleted entry by its sys id
+func (s *SQLiteSync) deletedEntryTable(txn *sqlx.Tx, sysID string) (string, error) {
+	for _, tableName := range s.Schema.contentTables {
+		exists, err := sqliteTableExists(txn, tableName)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			continue
+		}
+		err = sqlx.Get(txn, &exists, fmt.Sprintf(selectSQLiteSysID, tableName), sysID)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return tableName, nil
+		}
+	}
+	return "", nil
+}
+
+func sqliteTableExists(q sqlx.Queryer, tableName string) (bool, error) {
+	var exists bool
+	err := sqlx.Get(q, &exists, selectSQLiteTable, tableName)
+	return exists, err
+}
+
+func sqliteInsert(tableName string, columns []string) string {
+	return fmt.Sprintf(`INSERT INTO "%s" ("%s") VALUES (%s)`, tableName, strings.Join(columns, `", "`), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+}
+
+func sqliteUpsert(tableName string, columns []string) string {
+	updates := make([]string, 0)
+	for _, c := range columns {
+		if c != "_id" {
+			updates = append(updates, fmt.Sprintf(`"%[1]s" = excluded."%[1]s"`, c))
+		}
+	}
+	return fmt.Sprintf("%s ON CONFLICT (_id) DO UPDATE SET %s", sqliteInsert(tableName, columns), strings.Join(updates, ", "))
+}
+
+func sqliteValues(values []interface{}) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = sqliteValue(v)
+	}
+	return res
+}
+
+// sqliteValue converts the sync row values, arrays and objects are stored as json text.
+func sqliteValue(v interface{}) interface{} {
+	switch f := v.(type) {
+	case nil:
+		return nil
+	case *pq.StringArray:
+		data, _ := json.Marshal([]string(*f))
+		return string(data)
+	case pq.StringArray:
+		data, _ := json.Marshal([]string(f))
+		return string(data)
+	case string, bool, int, int64, float64:
+		return f
+	case driver.Valuer:
+		val, err := f.Value()
+		if err == nil {
+			return val
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
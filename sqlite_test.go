@@ -0,0 +1,199 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const sqliteTypes = `
+[
+	{
+		"sys": { "id": "article", "revision": 1 },
+		"name": "Article",
+		"displayField": "title",
+		"fields": [
+			{ "id": "title", "name": "Title", "type": "Symbol", "localized": true },
+			{ "id": "code", "name": "Code", "type": "Symbol" },
+			{ "id": "rating", "name": "Rating", "type": "Number" },
+			{ "id": "players", "name": "Players", "type": "Integer" },
+			{ "id": "tags", "name": "Tags", "type": "Array", "items": { "type": "Symbol" } },
+			{ "id": "related", "name": "Related", "type": "Array", "items": { "type": "Link", "linkType": "Entry" } }
+		]
+	}
+]`
+
+func sqliteTestSchema(t *testing.T) ([]*Locale, []*ContentType) {
+	types := make([]*ContentType, 0)
+	err := json.Unmarshal([]byte(sqliteTypes), &types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := []*Locale{{Code: "en", Default: true}, {Code: "de"}}
+	return locales, types
+}
+
+func TestSQLiteSchemaRender(t *testing.T) {
+	locales, types := sqliteTestSchema(t)
+
+	schema, err := NewSQLiteSchema(locales, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, err := schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{
+		`CREATE TABLE IF NOT EXISTS "article" (`,
+		`"title" TEXT,`,
+		`"rating" REAL,`,
+		`"tags" TEXT,`,
+		`CREATE TABLE IF NOT EXISTS "article__related" (`,
+		"CREATE TABLE IF NOT EXISTS _asset (",
+		"CREATE TABLE IF NOT EXISTS _sync (",
+	} {
+		if !strings.Contains(str, s) {
+			t.Errorf("missing %q in:\n%s", s, str)
+		}
+	}
+	if strings.Contains(str, "ARRAY") || strings.Contains(str, "jsonb") {
+		t.Errorf("postgres types in sqlite schema:\n%s", str)
+	}
+}
+
+func TestSQLiteValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{"text", "text"},
+		{true, true},
+		{pq.StringArray{"a", "b"}, `["a","b"]`},
+		{&pq.StringArray{"c"}, `["c"]`},
+		{map[string]interface{}{"lat": 1.5}, `{"lat":1.5}`},
+	}
+	for _, tt := range tests {
+		if got := sqliteValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sqliteValue(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteUpsert(t *testing.T) {
+	got := sqliteUpsert("article", []string{"_id", "title"})
+	want := `INSERT INTO "article" ("_id", "title") VALUES (?, ?) ON CONFLICT (_id) DO UPDATE SET "title" = excluded."title"`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseSQLiteQuery(t *testing.T) {
+	q := url.Values{}
+	q.Set("content_type", "article")
+	q.Set("locale", "de")
+	q.Set("fields.rating[gte]", "3")
+	q.Set("fields.tags[in]", "a,b")
+	q.Set("fields.code", "123")
+	q.Set("sys.id[ne]", "a2")
+	q.Set("order", "-fields.rating,sys.id")
+	q.Set("skip", "10")
+	q.Set("limit", "5")
+
+	locales, types := sqliteTestSchema(t)
+	schema, err := NewSQLiteSchema(locales, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := make(map[string]*content.Field)
+	for _, f := range schema.Tables[0].Schema.Fields {
+		model[pgColumnName(f.ID)] = f
+	}
+
+	query, err := ParseSQLiteQuery("en", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = query.compile(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, items, args := query.Render()
+
+	if !strings.HasPrefix(count, `SELECT COUNT(*) FROM "article" t WHERE (t._locale = ? OR (t._locale = ?`) {
+		t.Errorf("unexpected count statement: %s", count)
+	}
+	if !strings.Contains(items, `t."rating" >= ?`) {
+		t.Errorf("missing rating filter: %s", items)
+	}
+	if !strings.Contains(items, "t._sys_id IS NOT ?") {
+		t.Errorf("missing sys id filter: %s", items)
+	}
+	if !strings.HasSuffix(items, `ORDER BY t."rating" DESC NULLS LAST, t._sys_id NULLS LAST LIMIT 5 OFFSET 10`) {
+		t.Errorf("unexpected order and pagination: %s", items)
+	}
+	// locale fallback, code as text, rating as a number, tags in the json array, sys id
+	want := []interface{}{"de", "en", "de", "123", float64(3), "a", "b", "a2"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+	if strings.Count(items, "?") != len(args) {
+		t.Errorf("%d placeholders for %d args: %s", strings.Count(items, "?"), len(args), items)
+	}
+
+	for _, invalid := range []string{
+		"content_type=article&fields.related.fields.title=x",
+		"content_type=article&fields.related[near]=1,2",
+		"content_type=article&query=x",
+		"content_type=article&order=fields.related.fields.title",
+	} {
+		qv, _ := url.ParseQuery(invalid)
+		if _, err = ParseSQLiteQuery("en", qv); err == nil {
+			t.Errorf("unsupported query accepted: %s", invalid)
+		}
+	}
+	for _, invalid := range []string{
+		"content_type=article&fields.players=abc",
+		"content_type=article&fields.unknown=1",
+		"content_type=article&fields.rating[match]=1",
+		"content_type=article&fields.title.sys.id=x",
+	} {
+		qv, _ := url.ParseQuery(invalid)
+		query, err = ParseSQLiteQuery("en", qv)
+		if err == nil {
+			err = query.compile(model)
+		}
+		if err == nil {
+			t.Errorf("invalid query compiled: %s", invalid)
+		}
+	}
+}
+
+func TestSQLiteItem(t *testing.T) {
+	fields := map[string]*content.Field{
+		"title":   {ID: "title", Type: "text"},
+		"tags":    {ID: "tags", Type: "text", List: true},
+		"related": {ID: "related", Type: "article", List: true, Reference: true},
+		"active":  {ID: "active", Type: "bool"},
+	}
+	row := map[string]interface{}{
+		"_sys_id": []byte("a1"),
+		"title":   []byte("Hello"),
+		"tags":    `["x"]`,
+		"related": `["b2"]`,
+		"active":  int64(1),
+	}
+
+	got, _ := json.Marshal(sqliteItem(row, fields))
+	want := `{"active":true,"related":[{"sys":{"id":"b2"}}],"sys":{"id":"a1"},"tags":["x"],"title":"Hello"}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
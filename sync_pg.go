@@ -12,6 +12,7 @@ import (
 
 var (
 	metaColumns           = []string{"_locale", "_version", "_created_at", "_created_by", "_updated_at", "_updated_by"}
+	spaceColumns          = []string{"_space", "_environment"}
 	localizedAssetColumns = map[string]bool{
 		"title":       true,
 		"description": true,
@@ -28,6 +29,8 @@ type PGSyncRow struct {
 	Version      int
 	CreatedAt    string
 	UpdatedAt    string
+	Space        string
+	Environment  string
 }
 
 type PGSyncTable struct {
@@ -50,6 +53,56 @@ type PGSyncSchema struct {
 	ConTables        map[string]*PGSyncConTable
 	DeletedConTables map[string]*PGSyncConTable
 	InitSync         bool
+	SpaceID          string
+	EnvironmentID    string
+	DeletedItems     []*Sys
+	Options          *PGSyncOptions
+	// content types of the synced entries by sys id
+	entryTypes map[string]string
+	// connection tables of the content type tables
+	conTableNames map[string][]string
+	// tables of the content types, the deleted entries are looked up in
+	contentTables []string
+	// tables of the deleted entries by sys id, see resolveDeletedEntries
+	deletedTables map[string]string
+}
+
+// PGSyncOptions are the options of the synced rows, the defaults are used when nil.
+type PGSyncOptions struct {
+	// AssetURLs rewrites the urls of the asset files, the contentful urls are kept when nil
+	AssetURLs AssetURLRewriter
+	// Mirror has copied the asset files of the synced items, its urls are written
+	// when AssetURLs is nil
+	Mirror *AssetMirror
+	// LocationType is the column type of the Location fields, the LocationType of
+	// the schema config (point when empty)
+	LocationType string
+}
+
+func (o *PGSyncOptions) assetURLs() AssetURLRewriter {
+	switch {
+	case o == nil:
+		return ContentfulAssetURLs{}
+	case o.AssetURLs != nil:
+		return o.AssetURLs
+	case o.Mirror != nil:
+		return o.Mirror
+	}
+	return ContentfulAssetURLs{}
+}
+
+func (o *PGSyncOptions) locationType() string {
+	if o == nil || o.LocationType == "" {
+		return PGLocationPoint
+	}
+	return o.LocationType
+}
+
+func (o *PGSyncOptions) mirror() *AssetMirror {
+	if o == nil {
+		return nil
+	}
+	return o.Mirror
 }
 
 type PGSyncField struct {
@@ -63,7 +116,14 @@ type PGSyncConTable struct {
 	Rows      [][]interface{}
 }
 
-func NewPGSyncSchema(schemaName string, locales []*Locale, types []*ContentType, entries []*Entry, initSync bool) *PGSyncSchema {
+func NewPGSyncSchema(schemaName string, locales []*Locale, types []*ContentType, entries []*Entry, initSync bool, opts *PGSyncOptions) *PGSyncSchema {
+	return NewPGSpaceSyncSchema(schemaName, "", "", locales, types, entries, initSync, opts)
+}
+
+// NewPGSpaceSyncSchema creates a sync schema for tables shared by several spaces/environments.
+// When spaceID is set every row is written with _space and _environment and its _id is
+// qualified as <space>_<environment>_<sysid>_<locale>.
+func NewPGSpaceSyncSchema(schemaName string, spaceID string, environmentID string, locales []*Locale, types []*ContentType, entries []*Entry, initSync bool, opts *PGSyncOptions) *PGSyncSchema {
 
 	defLocale := getDefaultLocale(locales)
 
@@ -76,21 +136,45 @@ func NewPGSyncSchema(schemaName string, locales []*Locale, types []*ContentType,
 		ConTables:        make(map[string]*PGSyncConTable),
 		DeletedConTables: make(map[string]*PGSyncConTable),
 		InitSync:         initSync,
+		SpaceID:          spaceID,
+		EnvironmentID:    environmentID,
+		DeletedItems:     make([]*Sys, 0),
+		Options:          opts,
+		entryTypes:       make(map[string]string),
+		conTableNames:    make(map[string][]string),
+		contentTables:    make([]string, 0),
+		deletedTables:    make(map[string]string),
 	}
 
 	columnsByContentType := getColumnsByContentType(types)
+	for _, t := range types {
+		tableName := pgTableName(t.Sys.ID)
+		schema.contentTables = append(schema.contentTables, tableName)
+		for _, col := range sortedKeys(columnsByContentType[t.Sys.ID].columnReferences) {
+			schema.conTableNames[tableName] = append(schema.conTableNames[tableName], getConTableName(tableName, col))
+		}
+	}
+
+	for _, item := range entries {
+		if item.Sys.Type == ENTRY && item.Sys.ContentType != nil && item.Sys.ContentType.Sys != nil {
+			schema.entryTypes[item.Sys.ID] = item.Sys.ContentType.Sys.ID
+		}
+	}
 
 	for _, item := range entries {
 		switch item.Sys.Type {
 		case ENTRY:
 			contentType := item.Sys.ContentType.Sys.ID
-			tableName := toSnakeCase(contentType)
-			appendTables(schema, item, tableName, columnsByContentType[contentType].fieldColumns, columnsByContentType[contentType].columnReferences, columnsByContentType[contentType].localizedColumns, !initSync)
+			tableName := pgTableName(contentType)
+			appendTables(schema, item, tableName, columnsByContentType[contentType].fieldColumns, columnsByContentType[contentType].columnReferences, columnsByContentType[contentType].localizedColumns, columnsByContentType[contentType].columnTypes, columnsByContentType[contentType].columnNames, !initSync)
 		case ASSET:
-			appendTables(schema, item, ASSET_TABLE_NAME, assetColumns, nil, localizedAssetColumns, !initSync)
+			appendTables(schema, item, ASSET_TABLE_NAME, assetColumns, nil, localizedAssetColumns, nil, nil, !initSync)
+		case DELETED_ENTRY, DELETED_ASSET:
+			// deleted by Exec in every locale, see deleteStatements
+			schema.DeletedItems = append(schema.DeletedItems, item.Sys)
 			// case DELETED_ENTRY:
 			// 	contentType := item.Sys.ContentType.Sys.ID
-			// 	tableName := toSnakeCase(contentType)
+			// 	tableName := pgTableName(contentType)
 			// 	if schema.Deleted[tableName] == nil {
 			// 		schema.Deleted[tableName] = &PGDeletedTable{
 			// 			TableName: tableName,
@@ -114,9 +198,12 @@ func NewPGSyncSchema(schemaName string, locales []*Locale, types []*ContentType,
 	return schema
 }
 
-func newPGSyncTable(tableName string, fieldColumns []string) *PGSyncTable {
+func newPGSyncTable(tableName string, fieldColumns []string, scoped bool) *PGSyncTable {
 	columns := []string{"_id", "_sys_id"}
 	columns = append(columns, fieldColumns...)
+	if scoped {
+		columns = append(columns, spaceColumns...)
+	}
 	columns = append(columns, metaColumns...)
 
 	return &PGSyncTable{
@@ -154,6 +241,9 @@ func (r *PGSyncRow) Fields() []interface{} {
 	for _, fieldColumn := range r.FieldColumns {
 		values = append(values, r.FieldValues[fieldColumn])
 	}
+	if r.Space != "" {
+		values = append(values, r.Space, r.Environment)
+	}
 	values = append(values, r.Locale, r.Version, r.CreatedAt, "sync", r.UpdatedAt, "sync")
 	return values
 }
@@ -319,5 +409,111 @@ func (s *PGSyncSchema) deltaSync(txn *sqlx.Tx) error {
 		return err
 	}
 
+	err = s.resolveDeletedEntries(txn)
+	if err != nil {
+		return err
+	}
+	err = s.deleteItems(txn)
+	if err != nil {
+		return err
+	}
+
 	return txn.Commit()
 }
+
+// pgSyncDelete is a delete statement of the deleted entries and assets
+type pgSyncDelete struct {
+	TableName string
+	SQL       string
+	Args      []interface{}
+}
+
+// deletedSysIDs returns the sys ids of the deleted entries and assets by table, the
+// deleted entries without content type by the tables resolveDeletedEntries found them in
+func (s *PGSyncSchema) deletedSysIDs() map[string][]string {
+	deleted := make(map[string][]string)
+	for _, sys := range s.DeletedItems {
+		tableName := ASSET_TABLE_NAME
+		if sys.Type == DELETED_ENTRY {
+			if sys.ContentType != nil && sys.ContentType.Sys != nil {
+				tableName = pgTableName(sys.ContentType.Sys.ID)
+			} else if t, ok := s.deletedTables[sys.ID]; ok {
+				tableName = t
+			} else {
+				continue
+			}
+		}
+		deleted[tableName] = append(deleted[tableName], sys.ID)
+	}
+	return deleted
+}
+
+// resolveDeletedEntries looks up the tables of the deleted entries in the content
+// tables, the DeletedEntry items of the sync have no content type
+func (s *PGSyncSchema) resolveDeletedEntries(txn *sqlx.Tx) error {
+	sysIDs := make([]string, 0)
+	for _, sys := range s.DeletedItems {
+		if sys.Type == DELETED_ENTRY && (sys.ContentType == nil || sys.ContentType.Sys == nil) {
+			sysIDs = append(sysIDs, sys.ID)
+		}
+	}
+	if len(sysIDs) == 0 {
+		return nil
+	}
+	for _, tableName := range s.contentTables {
+		existing, err := s.selectSyncRows(txn, tableName, sysIDs)
+		if err != nil {
+			return err
+		}
+		for _, row := range existing {
+			s.deletedTables[formatReportValue(row["_sys_id"], false)] = tableName
+		}
+	}
+	return nil
+}
+
+// deleteStatements returns the deletes of the rows of the deleted entries and assets
+// in every locale, preceded by the deletes of their connection rows. The connection
+// rows linking to them are deleted by the delete triggers of the tables.
+func (s *PGSyncSchema) deleteStatements() []*pgSyncDelete {
+	res := make([]*pgSyncDelete, 0)
+	deleted := s.deletedSysIDs()
+	for _, tableName := range sortedKeys(deleted) {
+		where := "_sys_id = ANY($1)"
+		args := []interface{}{pq.Array(deleted[tableName])}
+		if s.SpaceID != "" {
+			where = where + spaceRowsFilter
+			args = append(args, s.SpaceID, s.EnvironmentID)
+		}
+		for _, con := range s.conTableNames[tableName] {
+			res = append(res, &pgSyncDelete{
+				TableName: con,
+				SQL:       fmt.Sprintf(`DELETE FROM %s WHERE %s IN (SELECT _id FROM %s WHERE %s)`, pgQuoteIdent(con), pgQuoteIdent(tableName), pgQuoteIdent(tableName), where),
+				Args:      args,
+			})
+		}
+		res = append(res, &pgSyncDelete{
+			TableName: tableName,
+			SQL:       fmt.Sprintf("DELETE FROM %s WHERE %s", pgQuoteIdent(tableName), where),
+			Args:      args,
+		})
+	}
+	return res
+}
+
+func (s *PGSyncSchema) deleteItems(txn *sqlx.Tx) error {
+	for _, d := range s.deleteStatements() {
+		exists, err := tableExists(txn, d.TableName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		_, err = txn.Exec(d.SQL, d.Args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete from %s: %s", d.TableName, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package gontentful
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	selectTableExists = "SELECT to_regclass($1) IS NOT NULL"
+	selectSyncRows    = `SELECT * FROM "%s" WHERE _sys_id = ANY($1)`
+	selectConRows     = `SELECT * FROM "%s" WHERE "%s" = ANY($1)`
+	spaceRowsFilter   = " AND _space = $2 AND _environment = $3"
+)
+
+// PGSyncReport lists the changes a sync would make, created by PGSyncSchema.DryRun.
+type PGSyncReport struct {
+	SchemaName string             `json:"schema"`
+	Inserts    []*PGSyncRowChange `json:"inserts"`
+	Updates    []*PGSyncRowChange `json:"updates"`
+	Deletes    []*PGSyncRowChange `json:"deletes"`
+	ConTables  []*PGSyncConChange `json:"conTables"`
+	MatViews   []string           `json:"matViews"`
+	Unchanged  int                `json:"unchanged"`
+}
+
+type PGSyncRowChange struct {
+	TableName   string             `json:"table"`
+	SysID       string             `json:"sysId"`
+	Locale      string             `json:"locale,omitempty"`
+	Version     int                `json:"version,omitempty"`
+	PrevVersion int                `json:"prevVersion,omitempty"`
+	Fields      []*PGSyncFieldDiff `json:"fields,omitempty"`
+}
+
+type PGSyncFieldDiff struct {
+	Column string `json:"column"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+type PGSyncConChange struct {
+	TableName string   `json:"table"`
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+}
+
+type pgConChanges struct {
+	column  string
+	parents map[string]bool
+	refs    map[string]bool
+}
+
+// DryRun compares the sync rows with the current rows (by _sys_id, _locale and _version)
+// in a read only transaction and reports what Exec would change. Nothing is written.
+func (s *PGSyncSchema) DryRun(databaseURL string) (*PGSyncReport, error) {
+	db, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	txn, err := db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if s.SchemaName != "" {
+		// set schema name
+		_, err = txn.Exec(fmt.Sprintf("SET search_path='%s'", s.SchemaName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &PGSyncReport{
+		SchemaName: s.SchemaName,
+		Inserts:    make([]*PGSyncRowChange, 0),
+		Updates:    make([]*PGSyncRowChange, 0),
+		Deletes:    make([]*PGSyncRowChange, 0),
+		ConTables:  make([]*PGSyncConChange, 0),
+		MatViews:   make([]string, 0),
+	}
+	changed := make(map[string]bool)
+	// the _id of the deleted rows by table
+	deletedParents := make(map[string][]string)
+
+	err = s.reportRows(txn, report, changed)
+	if err != nil {
+		return nil, err
+	}
+	err = s.resolveDeletedEntries(txn)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reportDeletes(txn, report, changed, deletedParents)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reportConTables(txn, report, changed, deletedParents)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reportMatViews(databaseURL, report, changed)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (s *PGSyncSchema) reportRows(txn *sqlx.Tx, report *PGSyncReport, changed map[string]bool) error {
+	for _, tableName := range sortedKeys(s.Tables) {
+		tbl := s.Tables[tableName]
+		sysIDs := make([]string, 0)
+		for _, row := range tbl.Rows {
+			sysIDs = append(sysIDs, row.SysID)
+		}
+		existing, err := s.selectSyncRows(txn, tableName, sysIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range tbl.Rows {
+			change := &PGSyncRowChange{
+				TableName: tableName,
+				SysID:     row.SysID,
+				Locale:    row.Locale,
+				Version:   row.Version,
+			}
+			cur := existing[fmt.Sprintf("%s_%s", row.SysID, row.Locale)]
+			if cur == nil {
+				report.Inserts = append(report.Inserts, change)
+				changed[tableName] = true
+				continue
+			}
+			change.PrevVersion = reportVersion(cur["_version"])
+			for _, col := range row.FieldColumns {
+				oldValue := formatReportValue(cur[col], false)
+				newValue := formatReportValue(row.FieldValues[col], !s.InitSync)
+				if oldValue != newValue {
+					change.Fields = append(change.Fields, &PGSyncFieldDiff{col, oldValue, newValue})
+				}
+			}
+			if len(change.Fields) == 0 && change.PrevVersion == change.Version {
+				report.Unchanged++
+				continue
+			}
+			report.Updates = append(report.Updates, change)
+			changed[tableName] = true
+		}
+	}
+	return nil
+}
+
+func (s *PGSyncSchema) reportDeletes(txn *sqlx.Tx, report *PGSyncReport, changed map[string]bool, deletedParents map[string][]string) error {
+	deleted := s.deletedSysIDs()
+	for _, tableName := range sortedKeys(deleted) {
+		existing, err := s.selectSyncRows(txn, tableName, deleted[tableName])
+		if err != nil {
+			return err
+		}
+		for _, key := range sortedKeys(existing) {
+			cur := existing[key]
+			report.Deletes = append(report.Deletes, &PGSyncRowChange{
+				TableName:   tableName,
+				SysID:       formatReportValue(cur["_sys_id"], false),
+				Locale:      formatReportValue(cur["_locale"], false),
+				PrevVersion: reportVersion(cur["_version"]),
+			})
+			changed[tableName] = true
+			deletedParents[tableName] = append(deletedParents[tableName], formatReportValue(cur["_id"], false))
+		}
+	}
+	return nil
+}
+
+func (s *PGSyncSchema) reportConTables(txn *sqlx.Tx, report *PGSyncReport, changed map[string]bool, deletedParents map[string][]string) error {
+	conChanges := make(map[string]*pgConChanges)
+	getChanges := func(tbl *PGSyncConTable) *pgConChanges {
+		if conChanges[tbl.TableName] == nil {
+			conChanges[tbl.TableName] = &pgConChanges{tbl.Columns[0], make(map[string]bool), make(map[string]bool)}
+		}
+		return conChanges[tbl.TableName]
+	}
+	for _, tbl := range s.ConTables {
+		c := getChanges(tbl)
+		for _, row := range tbl.Rows {
+			parent := formatReportValue(row[0], !s.InitSync)
+			c.parents[parent] = true
+			c.refs[fmtConRef(parent, formatReportValue(row[2], !s.InitSync))] = true
+		}
+	}
+	for _, tbl := range s.DeletedConTables {
+		c := getChanges(tbl)
+		for _, row := range tbl.Rows {
+			c.parents[formatReportValue(row[0], !s.InitSync)] = true
+		}
+	}
+	for tableName, parents := range deletedParents {
+		// the connection rows of the deleted rows are removed
+		for _, con := range s.conTableNames[tableName] {
+			c := getChanges(&PGSyncConTable{TableName: con, Columns: []string{tableName}})
+			for _, parent := range parents {
+				c.parents[parent] = true
+			}
+		}
+	}
+
+	for _, tableName := range sortedKeys(conChanges) {
+		c := conChanges[tableName]
+		existing, err := selectConRefs(txn, tableName, c.column, sortedKeys(c.parents))
+		if err != nil {
+			return err
+		}
+		change := &PGSyncConChange{TableName: tableName}
+		for _, ref := range sortedKeys(c.refs) {
+			if !existing[ref] {
+				change.Added = append(change.Added, ref)
+			}
+		}
+		for _, ref := range sortedKeys(existing) {
+			if !c.refs[ref] {
+				change.Removed = append(change.Removed, ref)
+			}
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			report.ConTables = append(report.ConTables, change)
+			changed[c.column] = true
+		}
+	}
+	return nil
+}
+
+func (s *PGSyncSchema) reportMatViews(databaseURL string, report *PGSyncReport, changed map[string]bool) error {
+	views := make(map[string]bool)
+	for _, tableName := range sortedKeys(changed) {
+		tableNames, err := getDependencies(databaseURL, s.SchemaName, tableName)
+		if err != nil {
+			// no table_references, only the table's own views are affected
+			tableNames = []string{tableName}
+		}
+		for _, tn := range tableNames {
+			for _, loc := range s.Locales {
+				views[pgMatViewName(pgTableName(tn), loc.Code)] = true
+			}
+		}
+	}
+	report.MatViews = sortedKeys(views)
+	return nil
+}
+
+func (s *PGSyncSchema) selectSyncRows(txn *sqlx.Tx, tableName string, sysIDs []string) (map[string]map[string]interface{}, error) {
+	res := make(map[string]map[string]interface{})
+	exists, err := tableExists(txn, tableName)
+	if err != nil || !exists {
+		return res, err
+	}
+
+	stmt := fmt.Sprintf(selectSyncRows, tableName)
+	args := []interface{}{pq.Array(sysIDs)}
+	if s.SpaceID != "" {
+		stmt = stmt + spaceRowsFilter
+		args = append(args, s.SpaceID, s.EnvironmentID)
+	}
+	rows, err := txn.Queryx(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+		err = rows.MapScan(row)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s_%s", formatReportValue(row["_sys_id"], false), formatReportValue(row["_locale"], false))
+		res[key] = row
+	}
+	return res, rows.Err()
+}
+
+func selectConRefs(txn *sqlx.Tx, tableName string, parentColumn string, parents []string) (map[string]bool, error) {
+	res := make(map[string]bool)
+	exists, err := tableExists(txn, tableName)
+	if err != nil || !exists {
+		return res, err
+	}
+
+	rows, err := txn.Queryx(fmt.Sprintf(selectConRows, tableName, parentColumn), pq.Array(parents))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// con table columns: table, table_sys_id, ref, ref_sys_id, _locale
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		if len(row) > 2 {
+			res[fmtConRef(formatReportValue(row[0], false), formatReportValue(row[2], false))] = true
+		}
+	}
+	return res, rows.Err()
+}
+
+func tableExists(q sqlx.Queryer, tableName string) (bool, error) {
+	var exists bool
+	err := sqlx.Get(q, &exists, selectTableExists, pgQuoteIdent(tableName))
+	return exists, err
+}
+
+func fmtConRef(parent string, ref string) string {
+	return fmt.Sprintf("%s -> %s", parent, ref)
+}
+
+func reportVersion(v interface{}) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// formatReportValue formats database and sync values the same way so they can be compared,
+// quoted is set for values created in template format (see convertFieldValue).
+func formatReportValue(v interface{}, quoted bool) string {
+	switch f := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return normalizeReportValue(string(f))
+	case time.Time:
+		return f.UTC().Format(time.RFC3339Nano)
+	case driver.Valuer:
+		val, err := f.Value()
+		if err == nil {
+			return formatReportValue(val, false)
+		}
+	case string:
+		if quoted && len(f) > 1 && strings.HasPrefix(f, "'") && strings.HasSuffix(f, "'") {
+			f = strings.ReplaceAll(f[1:len(f)-1], "''", "'")
+		}
+		return normalizeReportValue(f)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func normalizeReportValue(s string) string {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		// the timestamptz columns are read as time.Time
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return s
+	}
+	var j interface{}
+	if err := json.Unmarshal([]byte(s), &j); err == nil {
+		b, _ := json.Marshal(j)
+		return string(b)
+	}
+	var a pq.StringArray
+	if err := a.Scan([]byte(s)); err == nil {
+		return fmt.Sprintf("{%s}", strings.Join(a, ","))
+	}
+	return s
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *PGSyncReport) JSON() (string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *PGSyncReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "schema %s: %d inserts, %d updates, %d deletes, %d unchanged\n", r.SchemaName, len(r.Inserts), len(r.Updates), len(r.Deletes), r.Unchanged)
+	for _, c := range r.Inserts {
+		fmt.Fprintf(&sb, "+ %s %s (%s) v%d\n", c.TableName, c.SysID, c.Locale, c.Version)
+	}
+	for _, c := range r.Updates {
+		fmt.Fprintf(&sb, "~ %s %s (%s) v%d -> v%d\n", c.TableName, c.SysID, c.Locale, c.PrevVersion, c.Version)
+		for _, f := range c.Fields {
+			fmt.Fprintf(&sb, "    %s: %s -> %s\n", f.Column, f.Old, f.New)
+		}
+	}
+	for _, c := range r.Deletes {
+		fmt.Fprintf(&sb, "- %s %s (%s) v%d\n", c.TableName, c.SysID, c.Locale, c.PrevVersion)
+	}
+	for _, c := range r.ConTables {
+		fmt.Fprintf(&sb, "~ %s: %d added, %d removed\n", c.TableName, len(c.Added), len(c.Removed))
+		for _, ref := range c.Added {
+			fmt.Fprintf(&sb, "    + %s\n", ref)
+		}
+		for _, ref := range c.Removed {
+			fmt.Fprintf(&sb, "    - %s\n", ref)
+		}
+	}
+	if len(r.MatViews) > 0 {
+		fmt.Fprintf(&sb, "materialized views: %s\n", strings.Join(r.MatViews, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func syncReportTestTypes() []*ContentType {
+	return []*ContentType{{Sys: &Sys{ID: "game"}, Fields: []*ContentTypeField{
+		{ID: "name", Type: "Symbol", Localized: true},
+		{ID: "related", Type: "Array", Items: &FieldTypeArrayItem{Type: "Link", LinkType: "Entry", Validations: []*FieldValidation{{LinkContentType: []string{"game"}}}}},
+	}}}
+}
+
+// syncReportTestDeleted returns the deleted entry of the sync, without content type,
+// and a deleted asset
+func syncReportTestDeleted(t *testing.T) []*Entry {
+	res := &SyncResponse{}
+	err := json.Unmarshal([]byte(unpublishEntry), res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(res.Items, &Entry{Sys: &Sys{ID: "a1", Type: DELETED_ASSET}})
+}
+
+// syncReportTestSchema returns the sync schema of the deleted items, the deleted entry
+// found in the game table
+func syncReportTestSchema(t *testing.T, spaceID string) *PGSyncSchema {
+	locales := []*Locale{{Code: "en", Default: true}}
+	schema := NewPGSpaceSyncSchema("public", spaceID, "master", locales, syncReportTestTypes(), syncReportTestDeleted(t), false, nil)
+	if deleted := schema.deletedSysIDs(); !reflect.DeepEqual(deleted, map[string][]string{ASSET_TABLE_NAME: {"a1"}}) {
+		t.Errorf("deleted entry without table %v", deleted)
+	}
+	schema.deletedTables["7qpbdz6NUtWA6kS72QJeuB"] = "game"
+	return schema
+}
+
+func TestPGSyncDeleteStatements(t *testing.T) {
+	entry := syncReportTestDeleted(t)[0]
+	if entry.Sys.Type != DELETED_ENTRY || entry.Sys.ContentType != nil {
+		t.Fatalf("unexpected deleted entry %+v", entry.Sys)
+	}
+
+	schema := syncReportTestSchema(t, "")
+	if !reflect.DeepEqual(schema.contentTables, []string{"game"}) {
+		t.Errorf("unexpected content tables %v", schema.contentTables)
+	}
+	deleted := schema.deletedSysIDs()
+	if !reflect.DeepEqual(deleted, map[string][]string{"game": {"7qpbdz6NUtWA6kS72QJeuB"}, ASSET_TABLE_NAME: {"a1"}}) {
+		t.Errorf("unexpected deleted sys ids %v", deleted)
+	}
+	if tables := schema.ChangedTables(); !reflect.DeepEqual(tables, []string{ASSET_TABLE_NAME, "game"}) {
+		t.Errorf("unexpected changed tables %v", tables)
+	}
+
+	stmts := schema.deleteStatements()
+	if len(stmts) != 3 {
+		t.Fatalf("unexpected delete statements %+v", stmts)
+	}
+	if stmts[0].TableName != ASSET_TABLE_NAME || stmts[0].SQL != `DELETE FROM "_asset" WHERE _sys_id = ANY($1)` {
+		t.Errorf("unexpected asset delete %+v", stmts[0])
+	}
+	// the connection rows are deleted before the rows
+	if stmts[1].TableName != "game__related" || stmts[1].SQL != `DELETE FROM "game__related" WHERE "game" IN (SELECT _id FROM "game" WHERE _sys_id = ANY($1))` {
+		t.Errorf("unexpected connection delete %+v", stmts[1])
+	}
+	if stmts[2].TableName != "game" || stmts[2].SQL != `DELETE FROM "game" WHERE _sys_id = ANY($1)` || len(stmts[2].Args) != 1 {
+		t.Errorf("unexpected entry delete %+v", stmts[2])
+	}
+
+	// the shared schemas only delete the rows of the synced space
+	schema = syncReportTestSchema(t, "s1")
+	stmts = schema.deleteStatements()
+	if len(stmts) != 3 || !strings.HasSuffix(stmts[2].SQL, "_sys_id = ANY($1) AND _space = $2 AND _environment = $3") ||
+		!reflect.DeepEqual(stmts[2].Args[1:], []interface{}{"s1", "master"}) || !strings.HasSuffix(stmts[1].SQL, "AND _environment = $3)") {
+		t.Errorf("unexpected space deletes %+v %+v", stmts[1], stmts[2])
+	}
+}
+
+func TestPGSyncReport(t *testing.T) {
+	report := &PGSyncReport{
+		SchemaName: "public",
+		Inserts:    []*PGSyncRowChange{{TableName: "game", SysID: "g2", Locale: "en", Version: 1}},
+		Updates: []*PGSyncRowChange{{TableName: "game", SysID: "g3", Locale: "en", Version: 3, PrevVersion: 2,
+			Fields: []*PGSyncFieldDiff{{Column: "name", Old: "Old", New: "New"}}}},
+		Deletes:   []*PGSyncRowChange{{TableName: "game", SysID: "g1", Locale: "en", PrevVersion: 4}},
+		ConTables: []*PGSyncConChange{{TableName: "game__related", Removed: []string{fmtConRef("g1_en", "g3_en")}}},
+		MatViews:  []string{"game__en"},
+		Unchanged: 5,
+	}
+
+	expected := `schema public: 1 inserts, 1 updates, 1 deletes, 5 unchanged
++ game g2 (en) v1
+~ game g3 (en) v2 -> v3
+    name: Old -> New
+- game g1 (en) v4
+~ game__related: 0 added, 1 removed
+    - g1_en -> g3_en
+materialized views: game__en
+`
+	if s := report.String(); s != expected {
+		t.Errorf("unexpected report:\n%s", s)
+	}
+
+	j, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(j, `"deletes": [`) || !strings.Contains(j, `"prevVersion": 4`) || !strings.Contains(j, `"removed": [`) {
+		t.Errorf("unexpected json report:\n%s", j)
+	}
+}
+
+func TestFormatReportValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	for _, c := range []struct {
+		v        interface{}
+		quoted   bool
+		expected string
+	}{
+		{nil, false, "NULL"},
+		{"'it''s'", true, "it's"},
+		{"'it''s'", false, "'it''s'"},
+		{[]byte(`{"b": 1, "a": 2}`), false, `{"a":2,"b":1}`},
+		{[]byte(`{a,"b c"}`), false, "{a,b c}"},
+		{ts, false, "2024-01-02T02:04:05Z"},
+		{"2024-01-02T03:04:05+01:00", false, "2024-01-02T02:04:05Z"},
+		{int64(3), false, "3"},
+	} {
+		if s := formatReportValue(c.v, c.quoted); s != c.expected {
+			t.Errorf("formatReportValue(%v) = %s, expected %s", c.v, s, c.expected)
+		}
+	}
+	if reportVersion(int64(7)) != 7 || reportVersion("7") != 0 {
+		t.Error("unexpected report version")
+	}
+}
@@ -0,0 +1,168 @@
+package gontentful
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+type PGSpacesSync struct {
+	Config   *SpacesConfig
+	CdnURL   string
+	InitSync bool
+	DryRun   bool
+	Reports  map[string]*PGSyncReport
+	// SyncOptions are the options of the synced rows
+	SyncOptions *PGSyncOptions
+	mu          sync.Mutex
+}
+
+func NewPGSpacesSync(cfg *SpacesConfig, cdnURL string, initSync bool) *PGSpacesSync {
+	return &PGSpacesSync{
+		Config:   cfg,
+		CdnURL:   cdnURL,
+		InitSync: initSync,
+		Reports:  make(map[string]*PGSyncReport),
+	}
+}
+
+// Exec syncs every configured space, running at most Config.Concurrency spaces at once.
+func (s *PGSpacesSync) Exec(databaseURL string) error {
+	var ch = make(chan *SpaceConfig, len(s.Config.Spaces))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make([]string, 0)
+
+	wg.Add(s.Config.Concurrency)
+	for i := 0; i < s.Config.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for sc := range ch {
+				err := s.syncSpace(databaseURL, sc)
+				if err != nil {
+					log.Printf("[%s] sync failed: %s", sc.Name, err.Error())
+					mu.Lock()
+					errs = append(errs, fmt.Sprintf("%s: %s", sc.Name, err.Error()))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, sc := range s.Config.Spaces {
+		ch <- sc
+	}
+	close(ch)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to sync spaces: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *PGSpacesSync) syncSpace(databaseURL string, sc *SpaceConfig) error {
+	client := NewClient(sc.ClientOptions(s.CdnURL, ""))
+	schemaName := s.Config.GetSchemaName(sc)
+
+	syncToken := ""
+	if !s.InitSync {
+		log.Printf("[%s] retrieving sync token...", sc.Name)
+		token, err := s.getSyncToken(databaseURL, schemaName, sc)
+		if err != nil && !IsNoSyncToken(err) {
+			return fmt.Errorf("failed to get sync token: %s", err.Error())
+		}
+		syncToken = token
+	}
+	if len(syncToken) == 0 {
+		log.Printf("[%s] init sync...", sc.Name)
+	} else {
+		log.Printf("[%s] continue sync...", sc.Name)
+	}
+
+	res, err := client.Spaces.Sync(syncToken)
+	if err != nil {
+		return err
+	}
+
+	space, err := client.Spaces.GetSpace()
+	if err != nil {
+		return err
+	}
+
+	types, err := client.ContentTypes.GetTypes()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] exec %d items...", sc.Name, len(res.Items))
+	var schema *PGSyncSchema
+	if s.Config.Shared() {
+		schema = NewPGSpaceSyncSchema(schemaName, sc.SpaceID, sc.EnvironmentID, space.Locales, s.Config.Tables.ApplyContentTypes(types.Items), res.Items, len(syncToken) == 0, s.SyncOptions)
+	} else {
+		schema = NewPGSyncSchema(schemaName, space.Locales, s.Config.Tables.ApplyContentTypes(types.Items), res.Items, len(syncToken) == 0, s.SyncOptions)
+	}
+	if s.DryRun {
+		// report only, the sync token is not advanced
+		report, err := schema.DryRun(databaseURL)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.Reports[sc.Name] = report
+		s.mu.Unlock()
+		log.Printf("[%s] dry run done", sc.Name)
+		return nil
+	}
+
+	err = schema.Exec(databaseURL)
+	if err != nil {
+		return err
+	}
+
+	err = s.saveSyncToken(databaseURL, schemaName, sc, res.Token)
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] sync done", sc.Name)
+
+	return nil
+}
+
+func (s *PGSpacesSync) getSyncToken(databaseURL string, schemaName string, sc *SpaceConfig) (string, error) {
+	if s.Config.Shared() {
+		return GetSpaceSyncToken(databaseURL, schemaName, sc.SpaceID, sc.EnvironmentID)
+	}
+	return GetSyncToken(databaseURL, schemaName)
+}
+
+func (s *PGSpacesSync) saveSyncToken(databaseURL string, schemaName string, sc *SpaceConfig, token string) error {
+	if s.Config.Shared() {
+		return SaveSpaceSyncToken(databaseURL, schemaName, sc.SpaceID, sc.EnvironmentID, token)
+	}
+	return SaveSyncToken(databaseURL, schemaName, token)
+}
+
+// NewPGSpacesSchemas creates the postgres schema(s) of the configured spaces. In shared
+// mode the content types and locales of all spaces are merged into one schema.
+func NewPGSpacesSchemas(cfg *SpacesConfig, locales [][]*Locale, types [][]*ContentType, includeDepth int64) ([]*PGSQLSchema, error) {
+	if cfg.Shared() {
+		schema, err := NewPGSQLSchema(cfg.SharedSchema, MergeLocales(locales...), "", MergeContentTypes(types...), includeDepth, cfg.Tables)
+		if err != nil {
+			return nil, err
+		}
+		schema.SpaceColumns = true
+		return []*PGSQLSchema{schema}, nil
+	}
+
+	res := make([]*PGSQLSchema, 0)
+	for i, sc := range cfg.Spaces {
+		schema, err := NewPGSQLSchema(sc.SchemaName, locales[i], "", types[i], includeDepth, cfg.Tables)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, schema)
+	}
+	return res, nil
+}
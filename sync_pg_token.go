@@ -2,7 +2,10 @@ package gontentful
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 const (
@@ -13,22 +16,41 @@ const (
 
 func GetSyncToken(databaseURL string, schemaName string) (string, error) {
 	var syncToken string
-	db, _ := sql.Open("postgres", databaseURL)
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
 	schemaPrefix := ""
 	if schemaName != "" {
 		schemaPrefix = fmt.Sprintf("%s.", schemaName)
 	}
 	row := db.QueryRow(fmt.Sprintf(selectSyncToken, schemaPrefix))
-	err := row.Scan(&syncToken)
+	err = row.Scan(&syncToken)
 	if err != nil {
 		return "", err
 	}
 	return syncToken, nil
 }
 
+// IsNoSyncToken reports whether the sync token lookup failed because no token is stored
+// yet (the sync table or its row is missing), the sync starts with an init sync then.
+func IsNoSyncToken(err error) bool {
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	var pqErr *pq.Error
+	// undefined_table
+	return errors.As(err, &pqErr) && pqErr.Code == "42P01"
+}
+
 func SaveSyncToken(databaseURL string, schemaName string, token string) error {
-	var err error
-	db, _ := sql.Open("postgres", databaseURL)
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
 
 	schemaPrefix := ""
 	if len(schemaName) > 0 {
@@ -51,3 +73,54 @@ func SaveSyncToken(databaseURL string, schemaName string, token string) error {
 
 	return nil
 }
+
+const (
+	createSpaceSyncTable = "CREATE TABLE IF NOT EXISTS %s_sync_spaces ( space text not null, environment text not null, token text, created_at timestamp without time zone DEFAULT now(), primary key (space, environment) );"
+	insertSpaceSyncToken = "INSERT INTO %s_sync_spaces (space, environment, token) VALUES ($1, $2, $3) ON CONFLICT (space, environment) DO UPDATE SET token = EXCLUDED.token, created_at=now();"
+	selectSpaceSyncToken = "SELECT token FROM %s_sync_spaces WHERE space = $1 AND environment = $2;"
+)
+
+func GetSpaceSyncToken(databaseURL string, schemaName string, spaceID string, environmentID string) (string, error) {
+	var syncToken string
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	schemaPrefix := ""
+	if schemaName != "" {
+		schemaPrefix = fmt.Sprintf("%s.", schemaName)
+	}
+	row := db.QueryRow(fmt.Sprintf(selectSpaceSyncToken, schemaPrefix), spaceID, environmentID)
+	err = row.Scan(&syncToken)
+	if err != nil {
+		return "", err
+	}
+	return syncToken, nil
+}
+
+func SaveSpaceSyncToken(databaseURL string, schemaName string, spaceID string, environmentID string, token string) error {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schemaPrefix := ""
+	if len(schemaName) > 0 {
+		schemaPrefix = fmt.Sprintf("%s.", schemaName)
+	}
+
+	_, err = db.Exec(fmt.Sprintf(createSpaceSyncTable, schemaPrefix))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(insertSpaceSyncToken, schemaPrefix), spaceID, environmentID, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
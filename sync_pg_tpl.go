@@ -3,11 +3,15 @@ package gontentful
 const pgSyncTemplate = `
 {{ range $tblname, $tbl := .Tables }}
 {{ range $itemidx, $item := .Rows }}
-INSERT INTO {{ $.SchemaName }}.{{ $tbl.TableName }} (
+INSERT INTO {{ $.SchemaName }}."{{ $tbl.TableName }}" (
 	_id,
 	_sys_id,
 	{{- range $k, $v := .FieldColumns }}
-	{{ $v }},
+	"{{ $v }}",
+	{{- end }}
+	{{- if $.SpaceID }}
+	_space,
+	_environment,
 	{{- end }}
 	_locale,
 	_version,
@@ -21,17 +25,21 @@ INSERT INTO {{ $.SchemaName }}.{{ $tbl.TableName }} (
 	{{- range $k, $v := .FieldColumns }}
 	{{ $item.GetFieldValue $v }},
 	{{- end }}
+	{{- if $.SpaceID }}
+	'{{ .Space }}',
+	'{{ .Environment }}',
+	{{- end }}
 	'{{ .Locale }}',
 	'{{ .Version }}',
-	to_timestamp('{{ .CreatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ .CreatedAt }}'::timestamptz,
 	'sync',
-	to_timestamp('{{ .UpdatedAt }}','YYYY-MM-DDThh24:mi:ss.usZ'),
+	'{{ .UpdatedAt }}'::timestamptz,
 	'sync'
 )
 ON CONFLICT (_id) DO UPDATE
 SET
 	{{- range $k, $v := .FieldColumns }}
-	{{ $v }} = EXCLUDED.{{ $v }},
+	"{{ $v }}" = EXCLUDED."{{ $v }}",
 	{{- end }}
 	_locale = EXCLUDED._locale,
 	_version= EXCLUDED._version,
@@ -44,24 +52,24 @@ SET
 {{- end -}}
 {{ range $tblname, $tbl := $.Deleted }}
 {{ range $idx, $sys_id := .SysIDs }}
-DELETE FROM {{ $.SchemaName }}.{{ $tbl.TableName }} WHERE _sys_id = '{{ $sys_id }}' CASCADE
+DELETE FROM {{ $.SchemaName }}."{{ $tbl.TableName }}" WHERE _sys_id = '{{ $sys_id }}' CASCADE
 {{- end -}}
 {{- end -}}
 {{ range $tblidx, $tbl := .DeletedConTables }}
 {{ range $rowidx, $row := $tbl.Rows }}
-DELETE FROM {{ $.SchemaName }}.{{ $tbl.TableName }} WHERE {{ index $tbl.Columns 0 }} = {{ (index $row 0) }};
+DELETE FROM {{ $.SchemaName }}."{{ $tbl.TableName }}" WHERE "{{ index $tbl.Columns 0 }}" = {{ (index $row 0) }};
 {{- end -}}
 {{- end -}}
 {{ range $tblidx, $tbl := .ConTables }}
 {{ $prevId := "" }}
 {{ range $rowidx, $row := $tbl.Rows }}
 {{if ne $prevId (index $row 0) -}}
-DELETE FROM {{ $.SchemaName }}.{{ $tbl.TableName }} WHERE {{ index $tbl.Columns 0 }} = {{ (index $row 0) }};
+DELETE FROM {{ $.SchemaName }}."{{ $tbl.TableName }}" WHERE "{{ index $tbl.Columns 0 }}" = {{ (index $row 0) }};
 {{ end -}}
 {{ $prevId = (index $row 0) -}}
-INSERT INTO {{ $.SchemaName }}.{{ $tbl.TableName }} (
+INSERT INTO {{ $.SchemaName }}."{{ $tbl.TableName }}" (
 	{{- range $k, $v := $tbl.Columns }}
-	{{- if $k -}},{{- end -}}{{ $v }}
+	{{- if $k -}},{{- end -}}"{{ $v }}"
 	{{- end }}
 ) VALUES (
 	{{- range $k, $v := $row }}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/lib/pq"
@@ -19,9 +20,11 @@ type columnData struct {
 	fieldColumns     []string
 	columnReferences map[string]string
 	localizedColumns map[string]bool
+	columnTypes      map[string]string
+	columnNames      map[string]string
 }
 
-func appendTables(schema *PGSyncSchema, item *Entry, tableName string, fieldColumns []string, refColumns map[string]string, localizedColumns map[string]bool, templateFormat bool) {
+func appendTables(schema *PGSyncSchema, item *Entry, tableName string, fieldColumns []string, refColumns map[string]string, localizedColumns map[string]bool, columnTypes map[string]string, columnNames map[string]string, templateFormat bool) {
 	fieldsByLocale := make(map[string][]*rowField, 0)
 	defaultLocale := strings.ToLower(schema.DefaultLocale)
 
@@ -32,15 +35,21 @@ func appendTables(schema *PGSyncSchema, item *Entry, tableName string, fieldColu
 			continue // no locale, continue
 		}
 
-		// snace_case column name
-		columnName := toSnakeCase(fieldName)
+		// snace_case column name, the excluded fields have no column
+		columnName := pgColumnName(fieldName)
+		if columnNames != nil {
+			columnName, ok = columnNames[fieldName]
+			if !ok {
+				continue
+			}
+		}
 
 		// iterate over locale fields
 		for _, loc := range schema.Locales {
 			// create table
 			tbl := schema.Tables[tableName]
 			if tbl == nil {
-				tbl = newPGSyncTable(tableName, fieldColumns)
+				tbl = newPGSyncTable(tableName, fieldColumns, schema.SpaceID != "")
 				schema.Tables[tableName] = tbl
 			}
 			locale := strings.ToLower(loc.Code)
@@ -62,17 +71,18 @@ func appendTables(schema *PGSyncSchema, item *Entry, tableName string, fieldColu
 		// table
 		tbl := schema.Tables[tableName]
 		if tbl != nil {
-			appendRowsToTable(item, tbl, rowFields, fieldColumns, templateFormat, schema.ConTables, schema.DeletedConTables, refColumns, tableName, locale)
+			appendRowsToTable(item, tbl, rowFields, fieldColumns, columnTypes, templateFormat, schema.ConTables, schema.DeletedConTables, refColumns, schema.entryTypes, tableName, locale, schema.SpaceID, schema.EnvironmentID, schema.Options)
 		}
 	}
 }
 
-func appendRowsToTable(item *Entry, tbl *PGSyncTable, rowFields []*rowField, fieldColumns []string, templateFormat bool, conTables map[string]*PGSyncConTable, deletedConTables map[string]*PGSyncConTable, refColumns map[string]string, tableName string, locale string) {
+func appendRowsToTable(item *Entry, tbl *PGSyncTable, rowFields []*rowField, fieldColumns []string, columnTypes map[string]string, templateFormat bool, conTables map[string]*PGSyncConTable, deletedConTables map[string]*PGSyncConTable, refColumns map[string]string, entryTypes map[string]string, tableName string, locale string, spaceID string, environmentID string, opts *PGSyncOptions) {
 	fieldValues := make(map[string]interface{})
-	id := fmtSysID(item.Sys.ID, templateFormat, locale)
+	idPrefix := fmtSpacePrefix(spaceID, environmentID)
+	id := fmtSysID(idPrefix+item.Sys.ID, templateFormat, locale)
 	fieldValues["_id"] = id
 	for _, rowField := range rowFields {
-		fieldValues[rowField.fieldName] = convertFieldValue(rowField.fieldValue, templateFormat, locale)
+		fieldValues[rowField.fieldName] = convertColumnValue(rowField.fieldValue, columnTypes[rowField.fieldName], templateFormat, locale, opts.locationType())
 		// append con tables with Array Links
 		if _, ok := refColumns[rowField.fieldName]; ok {
 			links, ok := rowField.fieldValue.([]interface{})
@@ -82,7 +92,7 @@ func appendRowsToTable(item *Entry, tbl *PGSyncTable, rowFields []*rowField, fie
 				if conTables[conTableName] == nil {
 					conTables[conTableName] = &PGSyncConTable{
 						TableName: conTableName,
-						Columns:   []string{tableName, fmt.Sprintf("%s_sys_id", tableName), refColumns[rowField.fieldName], fmt.Sprintf("%s_sys_id", refColumns[rowField.fieldName]), "_locale"},
+						Columns:   getConTableColumnNames(tableName, refColumns[rowField.fieldName]),
 						Rows:      make([][]interface{}, 0),
 					}
 				}
@@ -90,13 +100,21 @@ func appendRowsToTable(item *Entry, tbl *PGSyncTable, rowFields []*rowField, fie
 					f, ok := e.(map[string]interface{})
 					if ok {
 						sysConID := convertSysID(f, templateFormat)
-						conID := convertSys(f, templateFormat, locale)
+						conID := convertSys(f, templateFormat, locale, idPrefix)
 						if id != "" && conID != "" && !addedRefs[conID] {
 							var conRow []interface{}
 							if templateFormat {
-								conRow = []interface{}{id, fmt.Sprintf("'%s'", item.Sys.ID), conID, sysConID, fmt.Sprintf("'%s'", locale)}
+								conRow = []interface{}{id, fmt.Sprintf("'%s'", item.Sys.ID), conID, sysConID}
+							} else {
+								conRow = []interface{}{id, item.Sys.ID, conID, sysConID}
+							}
+							if refColumns[rowField.fieldName] == ENTRY {
+								conRow = append(conRow, conContentType(entryTypes[convertSysID(f, false)], templateFormat))
+							}
+							if templateFormat {
+								conRow = append(conRow, fmt.Sprintf("'%s'", locale))
 							} else {
-								conRow = []interface{}{id, item.Sys.ID, conID, sysConID, locale}
+								conRow = append(conRow, locale)
 							}
 							conTables[conTableName].Rows = append(conTables[conTableName].Rows, conRow)
 							addedRefs[conID] = true
@@ -122,24 +140,117 @@ func appendRowsToTable(item *Entry, tbl *PGSyncTable, rowFields []*rowField, fie
 		}
 		assetFile, ok := fieldValues[rowField.fieldName].(*AssetFile)
 		if ok {
-			url := assetFile.URL
-			fileName := assetFile.FileName
-			contentType := assetFile.ContentType
-			if templateFormat {
-				url = fmt.Sprintf("'%s'", url)
-				fileName = fmt.Sprintf("'%s'", fileName)
-				contentType = fmt.Sprintf("'%s'", contentType)
-			}
-			fieldValues["url"] = url
-			fieldValues["file_name"] = fileName
-			fieldValues["content_type"] = contentType
+			mergeMaps(fieldValues, assetFileValues(assetFile, item.Sys.ID, locale, templateFormat, opts.assetURLs()))
 		}
-
 	}
 	row := newPGSyncRow(item, fieldColumns, fieldValues, locale)
+	if spaceID != "" {
+		row.ID = fmt.Sprintf("%s%s", idPrefix, row.ID)
+		row.Space = spaceID
+		row.Environment = environmentID
+	}
 	tbl.Rows = append(tbl.Rows, row)
 }
 
+// assetFileValues returns the _asset file columns, the url rewritten by assetURLs
+func assetFileValues(file *AssetFile, sysID string, locale string, t bool, assetURLs AssetURLRewriter) map[string]interface{} {
+	url := file.URL
+	fileName := file.FileName
+	if url != "" && fileName != "" {
+		fn := GetImageFileName(fileName, sysID, strings.ToLower(locale))
+		url = assetURLs.URL(url, fn)
+		if assetURLs.Mirrored() {
+			fileName = fn
+		}
+	}
+
+	values := map[string]interface{}{
+		"url":          convertFieldValue(url, t, locale),
+		"file_name":    convertFieldValue(fileName, t, locale),
+		"content_type": convertFieldValue(file.ContentType, t, locale),
+		"size":         nil,
+		"width":        nil,
+		"height":       nil,
+		"upload":       nil,
+		"details":      nil,
+		"focal_point":  nil,
+	}
+	if file.Upload != "" {
+		// not processed yet
+		values["upload"] = convertFieldValue(file.Upload, t, locale)
+	}
+	if file.Details != nil {
+		if file.Details.Size > 0 {
+			values["size"] = file.Details.Size
+		}
+		if img := file.Details.Image; img != nil {
+			values["width"] = img.Width
+			values["height"] = img.Height
+			if img.FocalPoint != nil {
+				fp, _ := json.Marshal(img.FocalPoint)
+				values["focal_point"] = convertFieldValue(string(fp), t, locale)
+			}
+		}
+		details, _ := json.Marshal(file.Details)
+		values["details"] = convertFieldValue(string(details), t, locale)
+	}
+	return values
+}
+
+// convertColumnValue converts the value of a field to the column type of its field type,
+// the Location fields to the location type
+func convertColumnValue(v interface{}, fieldType string, t bool, locale string, locationType string) interface{} {
+	switch fieldType {
+	case "Location":
+		loc, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		l, ok := formatPGLocation(loc, locationType)
+		if !ok {
+			return nil
+		}
+		return convertFieldValue(l, t, locale)
+	case "Object", "RichText":
+		// any json value, arrays included
+		data, err := json.Marshal(v)
+		if err != nil {
+			log.Fatal("failed to marshal content field")
+		}
+		return convertFieldValue(string(data), t, locale)
+	}
+	return convertFieldValue(v, t, locale)
+}
+
+// formatPGLocation formats a {lat, lon} value as a (lon,lat) point or an EWKT geography
+func formatPGLocation(loc map[string]interface{}, locationType string) (string, bool) {
+	lat, latOK := loc["lat"].(float64)
+	lon, lonOK := loc["lon"].(float64)
+	if !latOK || !lonOK {
+		return "", false
+	}
+	x := strconv.FormatFloat(lon, 'f', -1, 64)
+	y := strconv.FormatFloat(lat, 'f', -1, 64)
+	if locationType == PGLocationGeography {
+		return fmt.Sprintf("SRID=4326;POINT(%s %s)", x, y), true
+	}
+	return fmt.Sprintf("(%s,%s)", x, y), true
+}
+
+// parsePGLocation parses the point or EWKT text of formatPGLocation into a {lat, lon} value
+func parsePGLocation(s string) (map[string]interface{}, bool) {
+	var x, y float64
+	s = strings.TrimPrefix(s, "SRID=4326;")
+	_, err := fmt.Sscanf(s, "(%g,%g)", &x, &y)
+	if err != nil {
+		_, err = fmt.Sscanf(s, "POINT(%g %g)", &x, &y)
+	}
+	if err != nil {
+		return nil, false
+	}
+	return map[string]interface{}{"lat": y, "lon": x}, true
+}
+
 func convertFieldValue(v interface{}, t bool, locale string) interface{} {
 	switch f := v.(type) {
 	case map[string]interface{}:
@@ -192,16 +303,23 @@ func convertFieldValue(v interface{}, t bool, locale string) interface{} {
 	return v
 }
 
-func convertSys(f map[string]interface{}, t bool, locale string) string {
+func convertSys(f map[string]interface{}, t bool, locale string, idPrefix string) string {
 	s, ok := f["sys"].(map[string]interface{})
 	if ok {
 		if s["type"] == LINK {
-			return fmtSysID(s["id"], t, locale)
+			return fmtSysID(fmt.Sprintf("%s%v", idPrefix, s["id"]), t, locale)
 		}
 	}
 	return ""
 }
 
+func fmtSpacePrefix(spaceID string, environmentID string) string {
+	if spaceID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s_%s_", spaceID, environmentID)
+}
+
 func fmtSysID(id interface{}, t bool, l string) string {
 	if t {
 		return fmt.Sprintf("'%v_%s'", id, l)
@@ -223,12 +341,27 @@ func convertSysID(f map[string]interface{}, t bool) string {
 	return ""
 }
 
+// conContentType returns the content type of the linked entry, NULL if the entry is
+// not synced with the link, the views resolve it then
+func conContentType(contentType string, t bool) interface{} {
+	if contentType == "" {
+		if t {
+			return "NULL"
+		}
+		return nil
+	}
+	if t {
+		return fmt.Sprintf("'%s'", contentType)
+	}
+	return contentType
+}
+
 func getColumnsByContentType(types []*ContentType) map[string]*columnData {
 	typeColumns := make(map[string]*columnData)
 	for _, t := range types {
 		if typeColumns[t.Sys.ID] == nil {
 			fieldColumns, refColumns, locColumns := getContentTypeColumns(t)
-			typeColumns[t.Sys.ID] = &columnData{fieldColumns, refColumns, locColumns}
+			typeColumns[t.Sys.ID] = &columnData{fieldColumns, refColumns, locColumns, getContentTypeColumnTypes(t), getContentTypeColumnNames(t)}
 		}
 	}
 	return typeColumns
@@ -240,7 +373,7 @@ func getContentTypeColumns(t *ContentType) ([]string, map[string]string, map[str
 	localizedColumns := make(map[string]bool)
 	for _, f := range t.Fields {
 		if !f.Omitted {
-			colName := toSnakeCase(f.ID)
+			colName := fieldColumnName(f)
 			fieldColumns = append(fieldColumns, colName)
 			if f.Items != nil {
 				linkType := getFieldLinkType(f.Items.LinkType, f.Items.Validations)
@@ -248,10 +381,32 @@ func getContentTypeColumns(t *ContentType) ([]string, map[string]string, map[str
 					refColumns[colName] = linkType
 				}
 			}
-			if f.Localized && strings.ToLower(f.ID) != "slug" {
+			if isLocalizedColumn(f) {
 				localizedColumns[colName] = true
 			}
 		}
 	}
 	return fieldColumns, refColumns, localizedColumns
 }
+
+// getContentTypeColumnTypes returns the field types by column
+func getContentTypeColumnTypes(t *ContentType) map[string]string {
+	columnTypes := make(map[string]string)
+	for _, f := range t.Fields {
+		if !f.Omitted {
+			columnTypes[fieldColumnName(f)] = f.Type
+		}
+	}
+	return columnTypes
+}
+
+// getContentTypeColumnNames returns the columns by field id
+func getContentTypeColumnNames(t *ContentType) map[string]string {
+	columnNames := make(map[string]string)
+	for _, f := range t.Fields {
+		if !f.Omitted {
+			columnNames[f.ID] = fieldColumnName(f)
+		}
+	}
+	return columnNames
+}
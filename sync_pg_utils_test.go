@@ -0,0 +1,359 @@
+package gontentful
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+const assetMetadataItem = `{
+	"sys": {"id": "a1", "type": "Asset", "version": 3, "createdAt": "2023-01-02T10:00:00.000Z"},
+	"fields": {
+		"title": {"en": "Logo", "de": "Logo DE"},
+		"description": {"en": "The logo's description"},
+		"file": {
+			"en": {
+				"url": "//images.ctfassets.net/s/a1/logo.png",
+				"fileName": "logo.png",
+				"contentType": "image/png",
+				"details": {"size": 12345, "image": {"width": 640, "height": 480, "focalPoint": {"x": 0.25, "y": 0.5}}}
+			},
+			"de": {
+				"upload": "https://upload.example.com/logo-de.pdf",
+				"fileName": "logo-de.pdf",
+				"contentType": "application/pdf"
+			}
+		}
+	}
+}`
+
+func TestAssetFileValues(t *testing.T) {
+	item := &Entry{}
+	err := json.Unmarshal([]byte(assetMetadataItem), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := []*Locale{{Code: "en", Default: true}, {Code: "de"}}
+
+	for _, initSync := range []bool{true, false} {
+		schema := NewPGSyncSchema("public", locales, nil, []*Entry{item}, initSync, nil)
+		rows := make(map[string]*PGSyncRow)
+		for _, r := range schema.Tables[ASSET_TABLE_NAME].Rows {
+			rows[r.Locale] = r
+		}
+
+		en := rows["en"].FieldValues
+		if en["size"] != int64(12345) || en["width"] != 640 || en["height"] != 480 || en["upload"] != nil {
+			t.Errorf("unexpected en file columns %v", en)
+		}
+		details := `{"size":12345,"image":{"width":640,"height":480,"focalPoint":{"x":0.25,"y":0.5}}}`
+		focalPoint := `{"x":0.25,"y":0.5}`
+		if !initSync {
+			details = "'" + details + "'"
+			focalPoint = "'" + focalPoint + "'"
+		}
+		if en["details"] != details || en["focal_point"] != focalPoint {
+			t.Errorf("unexpected en details %v %v", en["details"], en["focal_point"])
+		}
+
+		de := rows["de"].FieldValues
+		upload := "https://upload.example.com/logo-de.pdf"
+		if !initSync {
+			upload = "'" + upload + "'"
+		}
+		if de["upload"] != upload || de["size"] != nil || de["width"] != nil || de["details"] != nil {
+			t.Errorf("unexpected de file columns %v", de)
+		}
+	}
+}
+
+func TestPGPublishAsset(t *testing.T) {
+	item := &PublishedEntry{}
+	err := json.Unmarshal([]byte(assetMetadataItem), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewPGPublish("public", []*Locale{{Code: "en", Default: true}}, nil, item, nil)
+	if len(q.Rows) != 1 {
+		t.Fatalf("unexpected rows %v", q.Rows)
+	}
+	row := q.Rows[0]
+	if row.GetFieldValue("description") != "'The logo''s description'" || row.GetFieldValue("width") != "640" || row.GetFieldValue("upload") != "NULL" {
+		t.Errorf("unexpected asset columns %v", row.FieldValues)
+	}
+}
+
+func TestAssetFunctionsRender(t *testing.T) {
+	types := []*ContentType{{
+		Sys:          &Sys{ID: "game"},
+		Name:         "Game",
+		DisplayField: "name",
+		Fields: []*ContentTypeField{
+			{ID: "name", Name: "Name", Type: "Symbol"},
+			{ID: "logo", Name: "Logo", Type: "Link", LinkType: "Asset"},
+		},
+	}}
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}, {Code: "de", FallbackCode: "en"}}, "", types, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := template.New("").Funcs(NewPGFunctions(schema).funcMap()).Parse(pgFuncTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buff bytes.Buffer
+	err = tmpl.Execute(&buff, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buff.String(), `'details', "logo___asset_fallback1".details`) {
+		t.Errorf("asset file details not selected:\n%s", buff.String())
+	}
+}
+
+func TestConvertColumnValue(t *testing.T) {
+	location := map[string]interface{}{"lat": 52.52, "lon": 13.405}
+	richText := map[string]interface{}{"nodeType": "document", "content": []interface{}{}}
+	object := []interface{}{map[string]interface{}{"label": "it's"}}
+
+	tests := []struct {
+		fieldType string
+		value     interface{}
+		copy      interface{}
+		template  interface{}
+	}{
+		{"Location", location, "(13.405,52.52)", "'(13.405,52.52)'"},
+		{"Location", map[string]interface{}{"lat": 1.5}, nil, nil},
+		{"RichText", richText, `{"content":[],"nodeType":"document"}`, `'{"content":[],"nodeType":"document"}'`},
+		{"Object", object, `[{"label":"it's"}]`, `'[{"label":"it''s"}]'`},
+		{"Date", "2023-01-02T10:00:00.000+02:00", "2023-01-02T10:00:00.000+02:00", "'2023-01-02T10:00:00.000+02:00'"},
+		{"Number", 1.25, 1.25, 1.25},
+	}
+	for _, tt := range tests {
+		if got := convertColumnValue(tt.value, tt.fieldType, false, "en", PGLocationPoint); got != tt.copy {
+			t.Errorf("%s copy value %v, want %v", tt.fieldType, got, tt.copy)
+		}
+		if got := convertColumnValue(tt.value, tt.fieldType, true, "en", PGLocationPoint); got != tt.template {
+			t.Errorf("%s template value %v, want %v", tt.fieldType, got, tt.template)
+		}
+	}
+}
+
+func TestPGLocationRoundTrip(t *testing.T) {
+	location := map[string]interface{}{"lat": -33.8688, "lon": 151.2093}
+	for _, locationType := range []string{PGLocationPoint, PGLocationGeography} {
+		s, ok := formatPGLocation(location, locationType)
+		if !ok {
+			t.Fatalf("%s: location not formatted", locationType)
+		}
+		back, ok := parsePGLocation(s)
+		if !ok || back["lat"] != location["lat"] || back["lon"] != location["lon"] {
+			t.Errorf("%s: %s parsed as %v", locationType, s, back)
+		}
+	}
+	if _, ok := parsePGLocation("garbage"); ok {
+		t.Errorf("invalid location parsed")
+	}
+
+	f := &content.Field{ID: "venue", Type: "location"}
+	if got, ok := sqliteFieldValue("(151.2093,-33.8688)", f).(map[string]interface{}); !ok || got["lat"] != -33.8688 {
+		t.Errorf("unexpected sqlite location %v", got)
+	}
+}
+
+func TestSysDateRoundTrip(t *testing.T) {
+	// timestamptz columns are read back as time.Time, in the session time zone
+	stored, _ := time.Parse(time.RFC3339, "2023-01-02T12:00:00+02:00")
+	if got, want := formatReportValue(stored, false), formatReportValue("'2023-01-02T10:00:00.000Z'", true); got != want {
+		t.Errorf("stored %s, synced %s", got, want)
+	}
+}
+
+func TestLocationFunctionsRender(t *testing.T) {
+	types := []*ContentType{{
+		Sys:          &Sys{ID: "venue"},
+		Name:         "Venue",
+		DisplayField: "name",
+		Fields: []*ContentTypeField{
+			{ID: "name", Name: "Name", Type: "Symbol"},
+			{ID: "location", Name: "Location", Type: "Location"},
+			{ID: "openedAt", Name: "Opened", Type: "Date"},
+		},
+	}}
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	str, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"location" point`,
+		`"opened_at" timestamptz`,
+		"_updated_at timestamptz",
+		`json_build_object(''lat'', ("venue"."location")[1], ''lon'', ("venue"."location")[0])`,
+		`CREATE INDEX IF NOT EXISTS "mv_venue_en_location_geo_idx" ON "mv_venue_en" USING gist ("location");`,
+	} {
+		if !strings.Contains(str, want) {
+			t.Errorf("missing %q in:\n%s", want, str)
+		}
+	}
+
+	tables, err := schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tables, `"opened_at" timestamptz`) || !strings.Contains(tables, `"location" point`) || strings.Contains(tables, "postgis") {
+		t.Errorf("unexpected tables:\n%s", tables)
+	}
+
+	// the location type of the schema config
+	cfg := &PGSchemaConfig{LocationType: PGLocationGeography}
+	schema, err = NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "", types, 2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err = schema.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tables, `"location" geography(Point,4326)`) || !strings.Contains(tables, "postgis") || schema.LocationType != PGLocationGeography {
+		t.Errorf("unexpected geography tables:\n%s", tables)
+	}
+	str, err = NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(str, `ST_Y("venue"."location"::geometry)`) {
+		t.Errorf("missing geography location json in:\n%s", str)
+	}
+
+	item := &Entry{Sys: &Sys{ID: "v1", Type: ENTRY, ContentType: &ContentType{Sys: &Sys{ID: "venue"}}}, Fields: map[string]interface{}{
+		"location": map[string]interface{}{"en": map[string]interface{}{"lat": 52.52, "lon": 13.405}},
+	}}
+	sync := NewPGSyncSchema("public", []*Locale{{Code: "en", Default: true}}, cfg.ApplyContentTypes(types), []*Entry{item}, true, &PGSyncOptions{LocationType: PGLocationGeography})
+	if v := sync.Tables["venue"].Rows[0].FieldValues["location"]; v != "SRID=4326;POINT(13.405 52.52)" {
+		t.Errorf("unexpected geography value %v", v)
+	}
+}
+
+func polymorphicTestTypes() []*ContentType {
+	return []*ContentType{
+		{
+			Sys:  &Sys{ID: "page"},
+			Name: "Page",
+			Fields: []*ContentTypeField{
+				{ID: "title", Name: "Title", Type: "Symbol"},
+				{ID: "hero", Name: "Hero", Type: "Link", LinkType: "Entry", Validations: []*FieldValidation{{LinkContentType: []string{"game", "tag"}}}},
+				{ID: "blocks", Name: "Blocks", Type: "Array", Items: &FieldTypeArrayItem{
+					Type:        "Link",
+					LinkType:    "Entry",
+					Validations: []*FieldValidation{{LinkContentType: []string{"game", "tag"}}},
+				}},
+				{ID: "related", Name: "Related", Type: "Array", Items: &FieldTypeArrayItem{Type: "Link", LinkType: "Entry"}},
+			},
+		},
+		{Sys: &Sys{ID: "game"}, Name: "Game", Fields: []*ContentTypeField{{ID: "name", Name: "Name", Type: "Symbol"}}},
+		{Sys: &Sys{ID: "tag"}, Name: "Tag", Fields: []*ContentTypeField{{ID: "name", Name: "Name", Type: "Symbol", Localized: true}}},
+	}
+}
+
+func TestPolymorphicSchema(t *testing.T) {
+	schema, err := NewPGSQLSchema("public", []*Locale{{Code: "en", Default: true}}, "page", polymorphicTestTypes(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cons := make([]string, 0)
+	for _, con := range schema.ConTables {
+		cons = append(cons, con.TableName+"("+strings.Join(getConTableColumnNames("page", con.Columns[2].ColumnName), ",")+")")
+	}
+	want := "page__blocks(page,page_sys_id,_entry,_entry_sys_id,_content_type,_locale),page__related(page,page_sys_id,_entry,_entry_sys_id,_content_type,_locale)"
+	if strings.Join(cons, ",") != want {
+		t.Errorf("unexpected connection tables %s", strings.Join(cons, ","))
+	}
+	deps := make([]string, 0)
+	for _, d := range schema.Dependencies {
+		deps = append(deps, d.Reference)
+	}
+	// the related entries can be of any content type
+	if strings.Join(deps, ",") != "game,tag,game,tag,game,page,tag" {
+		t.Errorf("unexpected dependencies %v", deps)
+	}
+	for _, ref := range schema.References {
+		if ref.Reference != "page" {
+			t.Errorf("unexpected reference %s of %s", ref.Reference, ref.TableName)
+		}
+	}
+
+	refs, err := NewPGReferences(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(refs, `"_content_type" TEXT,`) || !strings.Contains(refs, `"_entry" TEXT NOT NULL`) {
+		t.Errorf("unexpected connection tables:\n%s", refs)
+	}
+
+	funcs, err := NewPGFunctions(schema).Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"hero" json`,
+		`LEFT JOIN "game" "hero__game" ON "hero__game"._sys_id = "page"."hero"`,
+		`LEFT JOIN "tag" "hero__tag" ON "hero__tag"._sys_id = "page"."hero"`,
+		`'sys', json_build_object('id', "hero__game"._sys_id, 'type', 'Entry', 'contentType', json_build_object('sys', json_build_object('id', 'game')))`,
+		`LEFT JOIN "tag" "blocks__tag" ON "blocks__tag"._id = "page__blocks"._entry AND ("page__blocks"._content_type IS NULL OR "page__blocks"._content_type = 'tag')`,
+		`'name',"blocks__game"."name"`,
+		`LEFT JOIN "page" "related__page" ON "related__page"._id = "page__related"._entry`,
+		") l WHERE l.item IS NOT NULL",
+	} {
+		if !strings.Contains(funcs, want) {
+			t.Errorf("missing %q in:\n%s", want, funcs)
+		}
+	}
+	// the fields of the links without validation are not included
+	if strings.Contains(funcs, `"related__game"."name"`) {
+		t.Errorf("unexpected fields of the related entries:\n%s", funcs)
+	}
+}
+
+func TestPolymorphicSyncRows(t *testing.T) {
+	entries := make([]*Entry, 0)
+	err := json.Unmarshal([]byte(`[
+		{"sys": {"id": "p1", "type": "Entry", "contentType": {"sys": {"id": "page"}}}, "fields": {
+			"title": {"en": "Home"},
+			"blocks": {"en": [{"sys": {"type": "Link", "linkType": "Entry", "id": "g1"}}, {"sys": {"type": "Link", "linkType": "Entry", "id": "t9"}}]}
+		}},
+		{"sys": {"id": "g1", "type": "Entry", "contentType": {"sys": {"id": "game"}}}, "fields": {"name": {"en": "Game"}}}
+	]`), &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := []*Locale{{Code: "en", Default: true}}
+
+	schema := NewPGSyncSchema("public", locales, polymorphicTestTypes(), entries, true, nil)
+	con := schema.ConTables["page__blocks"]
+	if strings.Join(con.Columns, ",") != "page,page_sys_id,_entry,_entry_sys_id,_content_type,_locale" {
+		t.Fatalf("unexpected columns %v", con.Columns)
+	}
+	// t9 is not synced, its content type is resolved by the view
+	if len(con.Rows) != 2 || con.Rows[0][4] != "game" || con.Rows[1][4] != nil {
+		t.Errorf("unexpected rows %v", con.Rows)
+	}
+
+	schema = NewPGSyncSchema("public", locales, polymorphicTestTypes(), entries, false, nil)
+	con = schema.ConTables["page__blocks"]
+	if con.Rows[0][4] != "'game'" || con.Rows[1][4] != "NULL" || con.Rows[1][5] != "'en'" {
+		t.Errorf("unexpected template rows %v", con.Rows)
+	}
+}
@@ -0,0 +1,268 @@
+package gontentful
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchInterval   = 30 * time.Second
+	defaultWatchMaxBackoff = 10 * time.Minute
+)
+
+// PGSyncWatcher runs the postgres sync continuously on the stored sync token.
+type PGSyncWatcher struct {
+	SchemaName string
+	Client     *Client
+	InitSync   bool
+	Interval   time.Duration
+	MaxBackoff time.Duration
+	// ReadyLag is the maximum age of the last successful sync reported as ready
+	ReadyLag     time.Duration
+	SchemaPolicy SchemaChangePolicy
+	// SchemaConfig customizes the tables of the content types
+	SchemaConfig *PGSchemaConfig
+	// SyncOptions are the options of the synced rows, the files of the assets are
+	// copied by its Mirror before the rows are written
+	SyncOptions *PGSyncOptions
+
+	mu     sync.RWMutex
+	status PGSyncStatus
+}
+
+type PGSyncStatus struct {
+	SyncToken     string    `json:"syncToken"`
+	LastRun       time.Time `json:"lastRun"`
+	LastSuccess   time.Time `json:"lastSuccess"`
+	LastError     string    `json:"lastError,omitempty"`
+	Runs          int       `json:"runs"`
+	Failures      int       `json:"failures"`
+	Items         int       `json:"items"`
+	TotalItems    int       `json:"totalItems"`
+	ChangedTables []string  `json:"changedTables"`
+	Refreshed     []string  `json:"refreshed"`
+}
+
+func NewPGSyncWatcher(schemaName string, client *Client, interval time.Duration) *PGSyncWatcher {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	return &PGSyncWatcher{
+		SchemaName:   schemaName,
+		Client:       client,
+		Interval:     interval,
+		MaxBackoff:   defaultWatchMaxBackoff,
+		ReadyLag:     5 * interval,
+		SchemaPolicy: SchemaChangeAdditive,
+	}
+}
+
+// Run syncs every Interval until the context is cancelled. A sync in progress is not
+// interrupted, so the current transaction is always finished before Run returns.
+// Failed runs are retried with an exponential backoff capped at MaxBackoff.
+func (w *PGSyncWatcher) Run(ctx context.Context, databaseURL string) error {
+	initSync := w.InitSync
+	for {
+		err := w.runOnce(databaseURL, initSync)
+		wait := w.Interval
+		if err != nil {
+			failures := w.fail(err)
+			wait = w.backoff(failures)
+			log.Printf("sync failed (%d): %s, retrying in %s", failures, err.Error(), wait)
+		} else {
+			initSync = false
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("sync watcher stopped")
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *PGSyncWatcher) runOnce(databaseURL string, initSync bool) error {
+	w.mu.Lock()
+	w.status.LastRun = time.Now()
+	w.status.Runs++
+	w.mu.Unlock()
+
+	syncToken := ""
+	if !initSync {
+		token, err := GetSyncToken(databaseURL, w.SchemaName)
+		if err != nil && !IsNoSyncToken(err) {
+			// a failed lookup is retried, it must not start an init sync
+			return fmt.Errorf("failed to get sync token: %s", err.Error())
+		}
+		syncToken = token
+	}
+
+	res, err := w.Client.Spaces.Sync(syncToken)
+	if err != nil {
+		return err
+	}
+
+	changedTables := make([]string, 0)
+	refreshed := make([]string, 0)
+	if len(res.Items) > 0 {
+		if mirror := w.SyncOptions.mirror(); mirror != nil {
+			report, err := mirror.Exec(context.Background(), res.Items)
+			if err != nil {
+				return err
+			}
+			if len(report.Failed) > 0 {
+				log.Print(report.String())
+			}
+		}
+
+		space, err := w.Client.Spaces.GetSpace()
+		if err != nil {
+			return err
+		}
+		types, err := w.Client.ContentTypes.GetTypes()
+		if err != nil {
+			return err
+		}
+
+		contentTypes := w.SchemaConfig.ApplyContentTypes(types.Items)
+
+		schemaSync, err := NewPGSchemaSync(w.SchemaName, space.Locales, contentTypes, w.SchemaPolicy, w.SchemaConfig)
+		if err != nil {
+			return err
+		}
+		schemaSync.SyncOptions = w.SyncOptions
+		err = schemaSync.Exec(databaseURL, w.Client)
+		if err != nil {
+			return err
+		}
+		if schemaSync.Migrated {
+			// the migrated schema has all the entries and its own sync token
+			w.succeed("", len(res.Items), nil, nil)
+			return nil
+		}
+
+		schema := NewPGSyncSchema(w.SchemaName, space.Locales, contentTypes, res.Items, len(syncToken) == 0, w.SyncOptions)
+		err = schema.Exec(databaseURL)
+		if err != nil {
+			return err
+		}
+
+		changedTables = schema.ChangedTables()
+		refreshed, err = NewPGMatViews(&PGSQLSchema{Locales: space.Locales}).ExecTables(databaseURL, w.SchemaName, changedTables)
+		if err != nil {
+			// the data is committed, the views are refreshed again with the next change
+			log.Printf("failed to refresh materialized views: %s", err.Error())
+		}
+	}
+
+	// an idle sync returns the same token, there is nothing to save
+	if res.Token != syncToken {
+		err = SaveSyncToken(databaseURL, w.SchemaName, res.Token)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.succeed(res.Token, len(res.Items), changedTables, refreshed)
+
+	if len(res.Items) > 0 {
+		log.Printf("synced %d items, refreshed %d tables", len(res.Items), len(refreshed))
+	}
+
+	return nil
+}
+
+func (w *PGSyncWatcher) succeed(token string, items int, changedTables []string, refreshed []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if token != "" {
+		w.status.SyncToken = token
+	}
+	w.status.LastSuccess = time.Now()
+	w.status.LastError = ""
+	w.status.Failures = 0
+	w.status.Items = items
+	w.status.TotalItems += items
+	w.status.ChangedTables = changedTables
+	w.status.Refreshed = refreshed
+}
+
+func (w *PGSyncWatcher) fail(err error) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.LastError = err.Error()
+	w.status.Failures++
+	return w.status.Failures
+}
+
+func (w *PGSyncWatcher) backoff(failures int) time.Duration {
+	wait := w.Interval
+	for i := 1; i < failures && wait < w.MaxBackoff; i++ {
+		wait = wait * 2
+	}
+	if wait > w.MaxBackoff {
+		wait = w.MaxBackoff
+	}
+	return wait
+}
+
+func (w *PGSyncWatcher) Status() PGSyncStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.status
+}
+
+// Ready reports whether the last successful sync is more recent than ReadyLag.
+func (w *PGSyncWatcher) Ready() bool {
+	status := w.Status()
+	return !status.LastSuccess.IsZero() && time.Since(status.LastSuccess) < w.ReadyLag
+}
+
+// Handler serves /healthz, /readyz and the JSON /status of the watcher.
+func (w *PGSyncWatcher) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(rw http.ResponseWriter, r *http.Request) {
+		if !w.Ready() {
+			http.Error(rw, "sync lag exceeded", http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/status", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(w.Status())
+	})
+	return mux
+}
+
+// ChangedTables returns the content tables written by Exec, connection tables
+// are reported by their parent table.
+func (s *PGSyncSchema) ChangedTables() []string {
+	tables := make(map[string]bool)
+	for tableName, tbl := range s.Tables {
+		if len(tbl.Rows) > 0 {
+			tables[tableName] = true
+		}
+	}
+	for _, tbl := range s.ConTables {
+		tables[tbl.Columns[0]] = true
+	}
+	for _, tbl := range s.DeletedConTables {
+		tables[tbl.Columns[0]] = true
+	}
+	for tableName := range s.deletedSysIDs() {
+		tables[tableName] = true
+	}
+	return sortedKeys(tables)
+}
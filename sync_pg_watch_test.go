@@ -0,0 +1,108 @@
+package gontentful
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestPGSyncWatcherBackoff(t *testing.T) {
+	w := NewPGSyncWatcher("public", nil, time.Second)
+	w.MaxBackoff = 5 * time.Second
+	for failures, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if wait := w.backoff(failures); wait != expected {
+			t.Errorf("backoff(%d) = %s, expected %s", failures, wait, expected)
+		}
+	}
+	if NewPGSyncWatcher("public", nil, 0).Interval != defaultWatchInterval {
+		t.Error("unexpected default interval")
+	}
+}
+
+func TestPGSyncWatcherStatus(t *testing.T) {
+	w := NewPGSyncWatcher("public", nil, time.Second)
+	if w.Ready() {
+		t.Error("ready before the first sync")
+	}
+
+	if failures := w.fail(errors.New("boom")); failures != 1 || w.Status().LastError != "boom" {
+		t.Errorf("unexpected failure status %+v", w.Status())
+	}
+	w.succeed("token", 3, []string{"game"}, []string{"mv_game_en"})
+	w.succeed("", 2, nil, nil)
+	status := w.Status()
+	if !w.Ready() || status.SyncToken != "token" || status.Failures != 0 || status.LastError != "" || status.TotalItems != 5 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	srv := httptest.NewServer(w.Handler())
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	served := PGSyncStatus{}
+	err = json.NewDecoder(res.Body).Decode(&served)
+	if err != nil || served.SyncToken != "token" || served.TotalItems != 5 {
+		t.Errorf("unexpected served status %+v %v", served, err)
+	}
+
+	// the lag exceeded
+	w.ReadyLag = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	res, err = http.Get(srv.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected readyz status %d", res.StatusCode)
+	}
+	res, err = http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected healthz status %d", res.StatusCode)
+	}
+}
+
+func TestPGSyncWatcherTokenError(t *testing.T) {
+	// the client is not used, a failed token lookup must not start an init sync
+	w := NewPGSyncWatcher("public", nil, time.Second)
+	err := w.runOnce("postgres://user@127.0.0.1:1/db?sslmode=disable&connect_timeout=1", false)
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to get sync token") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if status := w.Status(); status.Runs != 1 || !status.LastSuccess.IsZero() {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestIsNoSyncToken(t *testing.T) {
+	for _, c := range []struct {
+		err      error
+		expected bool
+	}{
+		{sql.ErrNoRows, true},
+		{fmt.Errorf("scan: %w", sql.ErrNoRows), true},
+		{&pq.Error{Code: "42P01"}, true},
+		{&pq.Error{Code: "28P01"}, false},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	} {
+		if IsNoSyncToken(c.err) != c.expected {
+			t.Errorf("IsNoSyncToken(%v) != %v", c.err, c.expected)
+		}
+	}
+}
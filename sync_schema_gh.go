@@ -22,23 +22,34 @@ func NewGHSyncSchema(sys *Sys, schema *ContentType) *GHSyncSchema {
 	}
 }
 
-func (s *GHSyncSchema) Exec(repo string) error {
+func (s *GHSyncSchema) Exec(r ContentRepository) error {
 	ctx := context.Background()
-	cfg := getConfig(ctx, owner, repo, branch)
 
-	ct := TransformModel(s.Schema)
-	cb, err := json.Marshal(ct)
+	entries, err := s.blobEntries(ctx, r)
 	if err != nil {
 		return err
 	}
-	sc := string(cb)
-	path := filepath.Join(cfg.WorkDir, s.FolderName, content.JsonSchemaName)
 
-	// Upload to github
-	_, err = gh.CommitBlob(ctx, cfg.Token, owner, repo, branch, path, &sc, "feat(content type): update files")
+	// Upload to the repository
+	_, err = r.CommitBlobs(ctx, entries, "feat(content type): update files")
+	return err
+}
+
+// BlobEntries returns the schema file entry without committing it (see PRPublisher).
+func (s *GHSyncSchema) BlobEntries(r ContentRepository) ([]gh.BlobEntry, error) {
+	return s.blobEntries(context.Background(), r)
+}
+
+func (s *GHSyncSchema) blobEntries(ctx context.Context, r ContentRepository) ([]gh.BlobEntry, error) {
+	cfg := getConfig(ctx, r)
 
+	ct := TransformModel(s.Schema)
+	cb, err := json.Marshal(ct)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	sc := string(cb)
+	path := filepath.Join(cfg.WorkDir, s.FolderName, content.JsonSchemaName)
+
+	return []gh.BlobEntry{{Path: path, Content: &sc}}, nil
 }
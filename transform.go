@@ -3,10 +3,12 @@ package gontentful
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
+	"reflect"
 	"strings"
 	"time"
 
@@ -14,8 +16,9 @@ import (
 )
 
 const (
-	cdnClientID = "yeGKJew8TyopStA61YrS4A"
-	imageCDNFmt = "//imagedelivery.net/%s/%s/%s/public"
+	cdnClientID          = "yeGKJew8TyopStA61YrS4A"
+	imageCDNFmt          = "//imagedelivery.net/%s/%s/%s/%s"
+	listValidationPrefix = "list."
 )
 
 func TransformModel(model *ContentType) *content.Schema {
@@ -64,39 +67,6 @@ func TransformModel(model *ContentType) *content.Schema {
 }
 
 func transformField(cf *content.Field, fieldType string, linkType string, validations []*FieldValidation, items *FieldTypeArrayItem) {
-	for _, v := range validations {
-		// if v.Unique {
-		cf.Validations = append(cf.Validations, &content.Validation{
-			Type:  "unique",
-			Value: v.Unique,
-		})
-		// }
-		if v.In != nil {
-			cf.Validations = append(cf.Validations, &content.Validation{
-				Type:  "in",
-				Value: v.In,
-			})
-		}
-		if v.Size != nil {
-			cf.Validations = append(cf.Validations, &content.Validation{
-				Type:  "size",
-				Value: *v.Size,
-			})
-		}
-		if v.Range != nil {
-			cf.Validations = append(cf.Validations, &content.Validation{
-				Type:  "range",
-				Value: *v.Range,
-			})
-		}
-		if v.Regexp != nil {
-			cf.Validations = append(cf.Validations, &content.Validation{
-				Type:  "regexp",
-				Value: *v.Regexp,
-			})
-		}
-	}
-
 	switch fieldType {
 	case "Symbol":
 		cf.Type = "text"
@@ -108,6 +78,12 @@ func transformField(cf *content.Field, fieldType string, linkType string, valida
 		cf.Type = "float"
 	case "Text":
 		cf.Type = "longtext"
+	case "Date":
+		cf.Type = "date"
+	case "Location":
+		cf.Type = "location"
+	case "RichText":
+		cf.Type = "richtext"
 	case "Link":
 		cf.Reference = true
 		if linkType == ASSET {
@@ -117,87 +93,119 @@ func transformField(cf *content.Field, fieldType string, linkType string, valida
 		}
 	case "Array":
 		cf.List = true
+		// the validations of the list itself (e.g. the number of items) are prefixed
+		cf.Validations = append(cf.Validations, transformValidations(validations, listValidationPrefix, "")...)
 		transformField(cf, items.Type, items.LinkType, items.Validations, nil)
+		return
 	case "Object":
 		cf.Type = "json"
 	}
+
+	linkContentType := ""
+	if cf.Reference {
+		linkContentType = cf.Type
+	}
+	cf.Validations = append(cf.Validations, transformValidations(validations, "", linkContentType)...)
 }
 
-func transformValidationFields(vals []*content.Validation) ([]*FieldValidation, bool) {
+// transformValidations converts each key of the contentful validations to a moonbase
+// validation, the message follows its validation. The linkContentType of the field
+// type is implied by the reference.
+func transformValidations(validations []*FieldValidation, prefix string, linkContentType string) []*content.Validation {
+	res := make([]*content.Validation, 0)
+	for _, v := range validations {
+		if len(v.LinkContentType) == 1 && v.LinkContentType[0] == linkContentType && linkContentType != "" {
+			continue
+		}
+		m := make(map[string]interface{})
+		b, _ := json.Marshal(v)
+		json.Unmarshal(b, &m)
+		message := m["message"]
+		delete(m, "message")
+		for _, k := range sortedKeys(m) {
+			res = append(res, &content.Validation{
+				Type:  prefix + k,
+				Value: m[k],
+			})
+		}
+		if message != nil && len(m) > 0 {
+			res = append(res, &content.Validation{
+				Type:  prefix + "message",
+				Value: message,
+			})
+		}
+	}
+	return res
+}
+
+// transformValidationFields returns the validations of the value (of the items of a list),
+// the validations of the list and whether the field is required.
+func transformValidationFields(vals []*content.Validation) ([]*FieldValidation, []*FieldValidation, bool) {
 	cfValidations := make([]*FieldValidation, 0)
+	listValidations := make([]*FieldValidation, 0)
 	required := false
 	for _, v := range vals {
-		if v.Type == "unique" && v.Value == true {
-			cfValidations = append(cfValidations, &FieldValidation{
-				Unique: true,
-			})
+		if v.Type == "required" {
+			required = v.Value == true
+			continue
 		}
 
-		if v.Type == "required" && v.Value == true {
-			required = true
+		key := v.Type
+		target := &cfValidations
+		if strings.HasPrefix(key, listValidationPrefix) {
+			key = strings.TrimPrefix(key, listValidationPrefix)
+			target = &listValidations
 		}
 
-		if v.Type == "in" {
-			strarr := make([]string, 0)
-			for _, i := range v.Value.([]interface{}) {
-				strarr = append(strarr, i.(string))
+		if key == "message" {
+			if msg, ok := v.Value.(string); ok && len(*target) > 0 {
+				(*target)[len(*target)-1].Message = msg
 			}
-			cfValidations = append(cfValidations, &FieldValidation{
-				In: strarr,
-			})
+			continue
 		}
 
-		if v.Type == "size" {
-			m, _ := v.Value.(map[string]interface{})
-			rv := &RangeValidation{}
-			for k, v := range m {
-				if k == "min" {
-					i := int(v.(float64))
-					rv.Min = &i
-				}
-				if k == "max" {
-					i := int(v.(float64))
-					rv.Max = &i
-				}
-			}
-			cfValidations = append(cfValidations, &FieldValidation{
-				Size: rv,
-			})
+		fv := decodeFieldValidation(key, v.Value)
+		if fv != nil {
+			*target = append(*target, fv)
 		}
+	}
+	return cfValidations, listValidations, required
+}
 
-		if v.Type == "regexp" {
-			m, _ := v.Value.(map[string]interface{})
-			rv := &RegexpValidation{}
-			for k, v := range m {
-				if k == "pattern" {
-					rv.Pattern = int(v.(float64))
-				}
-				if k == "flags" {
-					rv.Flags = int(v.(float64))
-				}
-			}
-			cfValidations = append(cfValidations, &FieldValidation{
-				Regexp: rv,
-			})
-		}
+// decodeFieldValidation returns nil for the unknown, empty (e.g. unique: false) or
+// invalid validations.
+func decodeFieldValidation(key string, value interface{}) *FieldValidation {
+	b, err := json.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return nil
+	}
+	fv := &FieldValidation{}
+	err = json.Unmarshal(b, fv)
+	if err != nil || reflect.DeepEqual(fv, &FieldValidation{}) {
+		return nil
 	}
-	return cfValidations, required
+	return fv
 }
 
 func transformToContentfulField(cf *ContentTypeField, fieldType string, validations []*content.Validation, list bool, reference bool) {
-
-	cfVals, required := transformValidationFields(validations)
+	cfVals, listVals, required := transformValidationFields(validations)
 	if required {
 		cf.Required = true
 	}
 	if list {
 		cf.Type = ARRAY
 		cf.Items = &FieldTypeArrayItem{}
+		if len(listVals) > 0 {
+			cf.Validations = listVals
+		}
 
 		if reference {
 			cf.Items.Type, cf.Items.LinkType, cf.Items.Validations = setReferenceField(cfVals, fieldType)
 		} else {
 			cf.Items.Type = GetContentfulType(fieldType)
+			if len(cfVals) > 0 {
+				cf.Items.Validations = cfVals
+			}
 		}
 	} else if reference {
 		cf.Type, cf.LinkType, cf.Validations = setReferenceField(cfVals, fieldType)
@@ -207,15 +215,20 @@ func transformToContentfulField(cf *ContentTypeField, fieldType string, validati
 	}
 }
 
+// setReferenceField links the assets or the entries of the field type, any entry
+// without a field type. An explicit linkContentType validation is kept.
 func setReferenceField(cfVals []*FieldValidation, fieldType string) (t string, lt string, vs []*FieldValidation) {
 	t = LINK
 	lt = ENTRY
+	vs = cfVals
 	if fieldType == ASSET_TABLE_NAME {
 		lt = ASSET
+		return
 	}
-	vs = append(cfVals, &FieldValidation{
-		LinkContentType: append(make([]string, 0), fieldType),
-	})
+	if fieldType == "" || getFieldLinkContentType(cfVals) != "" {
+		return
+	}
+	vs = append([]*FieldValidation{{LinkContentType: []string{fieldType}}}, cfVals...)
 	return
 }
 
@@ -232,6 +245,12 @@ func GetContentfulType(fieldType string) string {
 		returnVal = "Number"
 	case "longtext":
 		returnVal = "Text"
+	case "date":
+		returnVal = "Date"
+	case "location":
+		returnVal = "Location"
+	case "richtext":
+		returnVal = "RichText"
 	case "_asset":
 		returnVal = "Asset"
 	case "json":
@@ -355,7 +374,7 @@ func formatSchemaRecursive(schema *content.Schema) []*ContentType {
 	return res
 }
 
-func TransformEntry(locales []*Locale, model *Entry, brand string, fmtVideoURL func(string) string) map[string]*content.ContentData {
+func TransformEntry(locales []*Locale, model *Entry, assetURLs AssetURLRewriter) map[string]*content.ContentData {
 	res := make(map[string]*content.ContentData, 0)
 	for _, loc := range locales {
 		data := &content.ContentData{
@@ -397,7 +416,7 @@ func TransformEntry(locales []*Locale, model *Entry, brand string, fmtVideoURL f
 
 			if data.Fields[fn] == nil {
 				if model.Sys.Type == ASSET && fn == "file" {
-					data.Fields[fn] = replaceAssetFile(brand, locValue, model.Sys.ID, strings.ToLower(contentLoc), fmtVideoURL)
+					data.Fields[fn] = replaceAssetFile(locValue, model.Sys.ID, strings.ToLower(contentLoc), assetURLs)
 				} else {
 					data.Fields[fn] = locValue
 				}
@@ -415,7 +434,7 @@ func TransformEntry(locales []*Locale, model *Entry, brand string, fmtVideoURL f
 	return res
 }
 
-func TransformPublishedEntry(locales []*Locale, model *PublishedEntry, localizedFields map[string]bool, brand string, fmtVideoURL func(string) string) map[string]*content.ContentData {
+func TransformPublishedEntry(locales []*Locale, model *PublishedEntry, localizedFields map[string]bool, assetURLs AssetURLRewriter) map[string]*content.ContentData {
 	res := make(map[string]*content.ContentData, 0)
 	for _, loc := range locales {
 		data := &content.ContentData{
@@ -455,7 +474,7 @@ func TransformPublishedEntry(locales []*Locale, model *PublishedEntry, localized
 
 			if data.Fields[fn] == nil {
 				if model.Sys.Type == ASSET && fn == "file" {
-					data.Fields[fn] = replaceAssetFile(brand, locValue, model.Sys.ID, strings.ToLower(contentLoc), fmtVideoURL)
+					data.Fields[fn] = replaceAssetFile(locValue, model.Sys.ID, strings.ToLower(contentLoc), assetURLs)
 				} else {
 					data.Fields[fn] = locValue
 				}
@@ -586,24 +605,22 @@ func formatEntry(id string, contentType string, contents map[string]content.Cont
 	return e, includes
 }
 
-func replaceAssetFile(brand string, file interface{}, sysID string, loc string, fmtVideoURL func(string) string) interface{} {
+func replaceAssetFile(file interface{}, sysID string, loc string, assetURLs AssetURLRewriter) interface{} {
 	if originalfileMap, ok := file.(map[string]interface{}); ok {
 		// clone map
 		fileMap := make(map[string]interface{})
 		for k, v := range originalfileMap {
 			fileMap[k] = v
 		}
-		fileName := fileMap["fileName"].(string)
+		fileName, _ := fileMap["fileName"].(string)
 		if fileName != "" {
-			url := fileMap["url"].(string)
+			url, _ := fileMap["url"].(string)
 			if url != "" {
 				fn := GetImageFileName(fileName, sysID, loc)
-				fileMap["fileName"] = fn
-				if IsVideoFile(fileName) {
-					fileMap["url"] = fmtVideoURL(fmt.Sprintf("%s/%s", brand, fn))
-				} else {
-					fileMap["url"] = fmt.Sprintf(imageCDNFmt, cdnClientID, brand, fn)
+				if assetURLs.Mirrored() {
+					fileMap["fileName"] = fn
 				}
+				fileMap["url"] = assetURLs.URL(url, fn)
 			}
 		}
 		return fileMap
@@ -621,7 +638,7 @@ func GetAssetImageURL(entry *Entry, imageURLs map[string]string) {
 				if fileName != "" {
 					url := fileContent["url"].(string)
 					if url != "" {
-						imageURLs[GetImageFileName(fileName, entry.Sys.ID, loc)] = fmt.Sprintf("http:%s", url)
+						imageURLs[GetImageFileName(fileName, entry.Sys.ID, loc)] = contentfulFileURL(url)
 					}
 				}
 			}
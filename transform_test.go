@@ -0,0 +1,89 @@
+package gontentful
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moonwalker/moonbase/pkg/content"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// TestSchemaRoundTrip transforms the contentful content types of testdata/transform to
+// moonbase schemas (compared with the golden *.moonbase.json files) and back.
+func TestSchemaRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/transform/*.contentful.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no content types in testdata/transform")
+	}
+
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".contentful.json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ct := &ContentType{}
+			err = json.Unmarshal(data, ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			schema := TransformModel(ct)
+			got, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata/transform", name+".moonbase.json")
+			if *updateGolden {
+				err = os.WriteFile(golden, append(got, '\n'), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(want)) != string(got) {
+				t.Errorf("moonbase schema differs from %s:\n%s", golden, got)
+			}
+
+			// the schema file is read back like from the repository
+			read := &content.Schema{}
+			err = json.Unmarshal(want, read)
+			if err != nil {
+				t.Fatal(err)
+			}
+			back := &ContentType{}
+			b, _ := json.Marshal(formatSchema(read))
+			json.Unmarshal(b, back)
+
+			assertModelJSON(t, ct, back)
+		})
+	}
+}
+
+func assertModelJSON(t *testing.T, want *ContentType, got *ContentType) {
+	t.Helper()
+	model := func(ct *ContentType) string {
+		b, _ := json.MarshalIndent(&ContentType{
+			Name:         ct.Name,
+			Description:  ct.Description,
+			DisplayField: ct.DisplayField,
+			Fields:       ct.Fields,
+		}, "", "  ")
+		return string(b)
+	}
+	if model(want) != model(got) {
+		t.Errorf("round trip differs:\nwant %s\ngot  %s", model(want), model(got))
+	}
+}
@@ -31,8 +31,8 @@ type Entries struct {
 }
 
 type Include struct {
-	Entry []*Entry `json:"entry,omitempty"`
-	Asset []*Entry `json:"asset,omitempty"`
+	Entry []*Entry `json:"Entry,omitempty"`
+	Asset []*Entry `json:"Asset,omitempty"`
 }
 
 type Fields map[string]interface{}
@@ -78,6 +78,9 @@ type ContentType struct {
 	Description  string              `json:"description,omitempty"`
 	Fields       []*ContentTypeField `json:"fields,omitempty"`
 	DisplayField string              `json:"displayField,omitempty"`
+
+	// the postgres schema config of the content type, see PGSchemaConfig
+	pg *PGContentTypeConfig
 }
 
 type ContentTypes struct {
@@ -99,6 +102,9 @@ type ContentTypeField struct {
 	Omitted      bool                   `json:"omitted,omitempty"`
 	Validations  []*FieldValidation     `json:"validations,omitempty"`
 	DefaultValue map[string]interface{} `json:"defaultValue,omitempty"`
+
+	// the postgres schema config of the field, see PGSchemaConfig
+	pg *PGFieldConfig
 }
 
 type FieldTypeArrayItem struct {
@@ -108,8 +114,8 @@ type FieldTypeArrayItem struct {
 }
 
 type RegexpValidation struct {
-	Pattern int `json:"pattern"`
-	Flags   int `json:"flags"`
+	Pattern string `json:"pattern"`
+	Flags   string `json:"flags,omitempty"`
 }
 
 type RangeValidation struct {
@@ -117,14 +123,40 @@ type RangeValidation struct {
 	Max *int `json:"max,omitempty"`
 }
 
+type NumberRangeValidation struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+type DateRangeValidation struct {
+	Min    string `json:"min,omitempty"`
+	Max    string `json:"max,omitempty"`
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+}
+
+type AssetImageDimensionsValidation struct {
+	Width  *RangeValidation `json:"width,omitempty"`
+	Height *RangeValidation `json:"height,omitempty"`
+}
+
+// FieldValidation is one Contentful validation, a single key is set (and the message).
 type FieldValidation struct {
-	LinkContentType   []string          `json:"linkContentType"`
-	LinkMimetypeGroup []string          `json:"linkMimetypeGroup"`
-	Unique            bool              `json:"unique"`
-	In                []string          `json:"in,omitempty"`
-	Size              *RangeValidation  `json:"size,omitempty"`
-	Range             *RangeValidation  `json:"range,omitempty"`
-	Regexp            *RegexpValidation `json:"regexp,omitempty"`
+	LinkContentType      []string                        `json:"linkContentType,omitempty"`
+	LinkMimetypeGroup    []string                        `json:"linkMimetypeGroup,omitempty"`
+	Unique               bool                            `json:"unique,omitempty"`
+	In                   []interface{}                   `json:"in,omitempty"`
+	Size                 *RangeValidation                `json:"size,omitempty"`
+	Range                *NumberRangeValidation          `json:"range,omitempty"`
+	Regexp               *RegexpValidation               `json:"regexp,omitempty"`
+	ProhibitRegexp       *RegexpValidation               `json:"prohibitRegexp,omitempty"`
+	DateRange            *DateRangeValidation            `json:"dateRange,omitempty"`
+	AssetImageDimensions *AssetImageDimensionsValidation `json:"assetImageDimensions,omitempty"`
+	AssetFileSize        *RangeValidation                `json:"assetFileSize,omitempty"`
+	EnabledNodeTypes     []string                        `json:"enabledNodeTypes,omitempty"`
+	EnabledMarks         []string                        `json:"enabledMarks,omitempty"`
+	Nodes                map[string][]*FieldValidation   `json:"nodes,omitempty"`
+	Message              string                          `json:"message,omitempty"`
 }
 
 type CreateSpace struct {
@@ -150,16 +182,27 @@ type AssetFields struct {
 }
 
 type AssetFile struct {
-	URL         string `json:"url"`
-	FileName    string `json:"fileName"`
-	ContentType string `json:"contentType"`
-	Details     struct {
-		Size  int `json:"size"`
-		Image struct {
-			Width  int `json:"width"`
-			Height int `json:"height"`
-		} `json:"image"`
-	} `json:"details"`
+	URL         string            `json:"url"`
+	FileName    string            `json:"fileName"`
+	ContentType string            `json:"contentType"`
+	Upload      string            `json:"upload,omitempty"`
+	Details     *AssetFileDetails `json:"details,omitempty"`
+}
+
+type AssetFileDetails struct {
+	Size  int64              `json:"size,omitempty"`
+	Image *AssetImageDetails `json:"image,omitempty"`
+}
+
+type AssetImageDetails struct {
+	Width      int              `json:"width"`
+	Height     int              `json:"height"`
+	FocalPoint *AssetFocalPoint `json:"focalPoint,omitempty"`
+}
+
+type AssetFocalPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
 type PublishFields map[string]map[string]interface{}